@@ -2,28 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"net"
-	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"strings"
 	"syscall"
 	"time"
 
 	"Mansoor88-6/time-tracking-agent/internal/auth"
+	"Mansoor88-6/time-tracking-agent/internal/automation"
 	"Mansoor88-6/time-tracking-agent/internal/client"
 	"Mansoor88-6/time-tracking-agent/internal/collector"
 	"Mansoor88-6/time-tracking-agent/internal/config"
 	"Mansoor88-6/time-tracking-agent/internal/database"
 	"Mansoor88-6/time-tracking-agent/internal/device"
+	"Mansoor88-6/time-tracking-agent/internal/handler"
 	"Mansoor88-6/time-tracking-agent/internal/logger"
 	"Mansoor88-6/time-tracking-agent/internal/platform"
 	"Mansoor88-6/time-tracking-agent/internal/queue"
+	"Mansoor88-6/time-tracking-agent/internal/repository"
+	"Mansoor88-6/time-tracking-agent/internal/router"
+	"Mansoor88-6/time-tracking-agent/internal/secrets"
 	"Mansoor88-6/time-tracking-agent/internal/server"
 	"Mansoor88-6/time-tracking-agent/internal/service"
+	"Mansoor88-6/time-tracking-agent/internal/sink"
 	"Mansoor88-6/time-tracking-agent/internal/tracker"
 	"Mansoor88-6/time-tracking-agent/internal/ui"
 
@@ -34,8 +38,14 @@ import (
 var Version = "dev"
 
 func main() {
+	// Subcommands run instead of the agent
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(runReport(os.Args[2:]))
+	}
+
 	// Parse command line flags
 	configPath := flag.String("config", "", "Path to configuration file (auto-detected if empty)")
+	dryRun := flag.Bool("dry-run", false, "Print events to stdout instead of sending them; never contacts the backend or touches the queue")
 	flag.Parse()
 
 	// Resolve config path (auto-detect if not specified)
@@ -62,6 +72,11 @@ func main() {
 		os.Exit(1)
 	}
 	defer log.Sync()
+	if err := logger.SetRedaction(cfg.Log.Redact); err != nil {
+		// Err on the side of not leaking what the setting was meant to hide
+		logger.SetRedaction(string(logger.RedactHash))
+		log.Warn("Invalid log.redact, hashing titles and URLs", zap.Error(err))
+	}
 
 	log.Info("Starting time-tracking agent",
 		zap.String("version", Version),
@@ -82,6 +97,14 @@ func main() {
 		}
 	}()
 
+	// Keep analytics reads off the tracking write path if configured
+	if cfg.StorageReadConns > 0 {
+		if err := db.OpenReadPool(cfg.StorageReadConns); err != nil {
+			log.Warn("Failed to open read-only database pool, reads will share the main connection", zap.Error(err))
+		}
+	}
+	db.StartMaintenance(time.Duration(cfg.StorageMaintenanceInterval) * time.Minute)
+
 	// Initialize platform
 	platformInstance, err := platform.NewPlatform()
 	if err != nil {
@@ -90,7 +113,7 @@ func main() {
 
 	// Get or generate device ID
 	deviceManager := device.NewDeviceManager()
-	deviceID, err := deviceManager.GetOrGenerateDeviceID(cfg.Device.ID)
+	deviceID, err := deviceManager.GetOrGenerateDeviceID(cfg.Device.ID, filepath.Dir(cfg.StoragePath))
 	if err != nil {
 		log.Fatal("Failed to get device ID", zap.Error(err))
 	}
@@ -99,7 +122,7 @@ func main() {
 	if cfg.Device.ID == "" {
 		log.Info("Generated device ID", zap.String("device_id", deviceID))
 		cfg.Device.ID = deviceID
-		if err := saveConfigField(resolvedConfigPath, "id:", fmt.Sprintf("  id: \"%s\"", deviceID)); err != nil {
+		if err := config.Save(resolvedConfigPath, cfg); err != nil {
 			log.Warn("Failed to save device ID to config", zap.Error(err))
 		} else {
 			log.Info("Device ID saved to config")
@@ -108,19 +131,75 @@ func main() {
 		log.Info("Using configured device ID", zap.String("device_id", deviceID))
 	}
 
+	// Keep the device token out of the plaintext config if configured
+	var tokenStore secrets.Store
+	if cfg.Auth.TokenStorage != "config" {
+		// The machine ID keys the encrypted token file. Falling back to
+		// another key would leave an existing token undecryptable and
+		// silently force a full re-authorization.
+		machineID, err := deviceManager.MachineID()
+		if err != nil {
+			log.Fatal("Failed to get machine ID for token storage (set auth.token_storage to config to store the token in plaintext)", zap.Error(err))
+		}
+		tokenStore, err = secrets.NewStore(cfg.Auth.TokenStorage, filepath.Dir(cfg.StoragePath), machineID, log.Logger)
+		if err != nil {
+			log.Warn("Invalid token storage, keeping the device token in config", zap.Error(err))
+		} else {
+			loadSecureToken(resolvedConfigPath, cfg, tokenStore, log.Logger)
+		}
+	}
+
+	// Shared by the API client and the token exchange so both honor the
+	// backend TLS and proxy settings
+	backendTransport, err := client.NewTransport(
+		client.TLSOptions{
+			CAFile:   cfg.Backend.TLS.CAFile,
+			CertFile: cfg.Backend.TLS.CertFile,
+			KeyFile:  cfg.Backend.TLS.KeyFile,
+		},
+		client.ProxyOptions{
+			URL:      cfg.Backend.Proxy.URL,
+			Username: cfg.Backend.Proxy.Username,
+			Password: cfg.Backend.Proxy.Password,
+		},
+	)
+	if err != nil {
+		log.Fatal("Invalid backend transport settings", zap.Error(err))
+	}
+
+	// Create device authorization service
+	deviceAuth := auth.NewDeviceAuthService(
+		platformInstance,
+		cfg.Auth.CallbackPort,
+		cfg.Backend.BaseURL,
+		log.Logger,
+	)
+	deviceAuth.SetFixedCallbackPort(cfg.Auth.CallbackPortFixed)
+	deviceAuth.SetTransport(backendTransport)
+
+	// Refreshes the device token before it expires or when the backend
+	// rejects it, saving the new token back to config
+	tokenManager := auth.NewTokenManager(
+		deviceAuth,
+		deviceID,
+		cfg.Device.Name,
+		func(token string) {
+			if err := saveDeviceToken(resolvedConfigPath, cfg, tokenStore, token); err != nil {
+				log.Warn("Failed to save refreshed device token", zap.Error(err))
+			} else {
+				log.Info("Refreshed device token saved")
+			}
+		},
+		log.Logger,
+	)
+
 	// Check if device token exists, if not, perform authorization
 	deviceToken := cfg.Auth.DeviceToken
-	if deviceToken == "" {
+	if *dryRun {
+		log.Info("Dry run: events are printed, not sent; skipping device authorization")
+	} else if deviceToken == "" {
 		log.Info("No device token found, starting authorization flow")
 
-		// Create device authorization service
-		deviceAuth := auth.NewDeviceAuthService(
-			platformInstance,
-			cfg.Auth.CallbackPort,
-			cfg.Backend.BaseURL,
-			log.Logger,
-		)
-
 		// Retry authorization up to 3 times (user may close the browser, etc.)
 		var code string
 		for attempt := 1; attempt <= 3; attempt++ {
@@ -142,23 +221,23 @@ func main() {
 		}
 
 		// Exchange code for token
-		tokenResp, err := deviceAuth.ExchangeCodeForToken(code, deviceID)
+		tokenResp, err := deviceAuth.ExchangeCodeForToken(context.Background(), code, deviceID)
 		if err != nil {
 			log.Fatal("Token exchange failed", zap.Error(err))
 		}
 
 		deviceToken = tokenResp.AccessToken
+		tokenManager.SetExpiresIn(tokenResp.ExpiresIn)
 		log.Info("Device authorized successfully",
 			zap.String("device_id", tokenResp.DeviceID),
 			zap.Int("expires_in", tokenResp.ExpiresIn),
 		)
 
-		// Save token to config file
-		cfg.Auth.DeviceToken = deviceToken
-		if err := saveConfig(resolvedConfigPath, cfg); err != nil {
-			log.Warn("Failed to save device token to config", zap.Error(err))
+		// Save token to config file or the secure store
+		if err := saveDeviceToken(resolvedConfigPath, cfg, tokenStore, deviceToken); err != nil {
+			log.Warn("Failed to save device token", zap.Error(err))
 		} else {
-			log.Info("Device token saved to config")
+			log.Info("Device token saved")
 		}
 	} else {
 		log.Info("Using existing device token")
@@ -172,13 +251,56 @@ func main() {
 		log.Logger,
 	)
 
+	if !cfg.Backend.DisableCompression {
+		apiClient.SetCompressionThreshold(cfg.Backend.CompressionThreshold)
+	}
+	apiClient.SetMaxPayloadBytes(cfg.Backend.MaxPayloadBytes)
+	apiClient.SetSigningSecret(cfg.Backend.SigningSecret)
+	if mapping, err := client.NewFieldMapping(cfg.Backend.FieldCase, cfg.Backend.FieldNames); err != nil {
+		log.Warn("Invalid backend field mapping, sending default field names", zap.Error(err))
+	} else {
+		apiClient.SetFieldMapping(mapping)
+	}
+	apiClient.SetTransport(backendTransport)
+	if len(cfg.Backend.FallbackURLs) > 0 && !*dryRun {
+		apiClient.SetFallbackURLs(cfg.Backend.FallbackURLs)
+		selectCtx, selectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := apiClient.SelectEndpoint(selectCtx); err != nil {
+			log.Warn("No backend endpoint passed the health check", zap.Error(err))
+		}
+		selectCancel()
+	}
+	if !*dryRun {
+		versionCtx, versionCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if version, err := apiClient.NegotiateVersion(versionCtx); err != nil {
+			log.Info("Backend API version not advertised, using v1", zap.Error(err))
+		} else {
+			log.Info("Negotiated backend API version", zap.String("version", version.String()))
+		}
+		versionCancel()
+	}
+
 	// Set device token in API client
 	if deviceToken != "" {
 		apiClient.SetDeviceToken(deviceToken)
+		tokenManager.SetToken(deviceToken)
+	}
+	apiClient.SetTokenRefresher(tokenManager)
+	if !*dryRun {
+		tokenManager.StartAutoRefresh(apiClient.SetDeviceToken)
+		defer tokenManager.StopAutoRefresh()
 	}
 
 	// Initialize event queue
 	eventQueue := queue.NewEventQueue(db.DB, log.Logger)
+	if err := eventQueue.SetMaxSize(cfg.Queue.MaxSize, queue.OverflowPolicy(cfg.Queue.Overflow)); err != nil {
+		log.Warn("Invalid queue config, queue is unbounded", zap.Error(err))
+	}
+	eventQueue.SetDeadLetterAfter(cfg.Queue.DeadLetterAfter)
+	if !*dryRun {
+		eventQueue.StartRetention(time.Duration(cfg.Queue.RetentionDays) * 24 * time.Hour)
+		defer eventQueue.StopRetention()
+	}
 
 	// Initialize event collector
 	eventCollector := collector.NewEventCollector(
@@ -186,6 +308,10 @@ func main() {
 		time.Duration(cfg.Tracking.BatchFlushInterval)*time.Second,
 		log.Logger,
 	)
+	eventCollector.SetCoalesce(cfg.Tracking.CoalesceEvents)
+	if !*dryRun {
+		eventCollector.SetStager(queue.NewStagingArea(db.DB, log.Logger))
+	}
 
 	// Create a callback variable that will be set after tracking service is created
 	var sessionEndCallback func(*service.ActiveSession)
@@ -200,6 +326,7 @@ func main() {
 		log.Logger,
 		time.Duration(cfg.Tracking.SessionInactivityTimeout)*time.Second,
 	)
+	sessionManager.SetAccumulate(cfg.Tracking.AccumulateSessions)
 
 	// Initialize window tracker
 	windowTracker := tracker.NewWindowTracker(
@@ -207,6 +334,10 @@ func main() {
 		time.Duration(cfg.Tracking.WindowPollInterval)*time.Second,
 		log.Logger,
 	)
+	windowTracker.SetMinDwell(time.Duration(cfg.Tracking.MinDwellMs) * time.Millisecond)
+	if err := windowTracker.SetEmptyWindowPolicy(cfg.Tracking.EmptyWindow); err != nil {
+		log.Warn("Invalid empty window policy, ignoring empty windows", zap.Error(err))
+	}
 
 	// Initialize activity tracker
 	activityTracker := tracker.NewActivityTracker(
@@ -215,6 +346,7 @@ func main() {
 		time.Duration(cfg.Tracking.AwayThreshold)*time.Second,
 		log.Logger,
 	)
+	activityTracker.SetMouseMoveInterval(time.Duration(cfg.Tracking.MouseMoveIntervalMs) * time.Millisecond)
 
 	// Initialize tracking service with session manager
 	trackingService := service.NewTrackingService(
@@ -232,52 +364,146 @@ func main() {
 	// Set up session manager callback to use tracking service's OnSessionEnd
 	sessionEndCallback = trackingService.OnSessionEnd
 
+	// Report periods when the agent was not running as offline time
+	trackingService.SetLivenessStore(
+		repository.NewAgentStateRepository(db.DB),
+		time.Duration(cfg.Tracking.LivenessInterval)*time.Second,
+	)
+	trackingService.SetHeartbeatInterval(time.Duration(cfg.Tracking.HeartbeatInterval) * time.Second)
+	trackingService.SetSplitAtMidnight(cfg.Tracking.SplitAtMidnight)
+	trackingService.SetIncludePower(cfg.Tracking.IncludePower)
+	if err := trackingService.SetFullscreenAction(cfg.Tracking.FullscreenAction); err != nil {
+		log.Warn("Invalid fullscreen action, tracking fullscreen windows normally", zap.Error(err))
+	}
+	applyPrivacyConfig(trackingService, cfg.Privacy, log.Logger)
+	applyProjectRules(trackingService, cfg.Projects, log.Logger)
+	if cfg.Categories.Enabled {
+		categorizer, err := service.LoadCategorizer(cfg.Categories.RulesFile)
+		if err != nil {
+			log.Warn("Failed to load category rules, events will not be categorized", zap.Error(err))
+		} else {
+			trackingService.SetCategorizer(categorizer)
+		}
+	}
+	trackingService.SetShutdownSendTimeout(time.Duration(cfg.Backend.ShutdownSendTimeout) * time.Second)
+	trackingService.SetSendWorkers(cfg.Backend.SendWorkers)
+	trackingService.SetDequeueBatchSize(cfg.Queue.BatchSize)
+
+	// Keep a local JSON-lines copy of every batch if configured
+	var eventLog *sink.JSONLSink
+	if cfg.EventLog.Enabled {
+		maxBytes := int64(max(cfg.EventLog.MaxSizeMB, 0)) * 1024 * 1024
+		eventLog, err = sink.NewJSONLSink(cfg.EventLog.Path, maxBytes, max(cfg.EventLog.MaxBackups, 0))
+		if err != nil {
+			log.Warn("Failed to open event log, continuing without it", zap.Error(err))
+		} else {
+			trackingService.SetEventSink(eventLog)
+			log.Info("Writing events to local event log", zap.String("path", cfg.EventLog.Path))
+		}
+	}
+
+	// Print batches instead of sending them, to check what would leave the machine
+	if *dryRun {
+		trackingService.SetDryRun(sink.NewConsoleSink(os.Stdout))
+	}
+
+	// Fire local automations (webhooks/commands) for matching events
+	var automationEngine *automation.Engine
+	if len(cfg.Automation.Rules) > 0 {
+		rules := make([]automation.Rule, 0, len(cfg.Automation.Rules))
+		for _, r := range cfg.Automation.Rules {
+			rules = append(rules, automation.Rule{
+				Name:        r.Name,
+				Application: r.Application,
+				Domain:      r.Domain,
+				Status:      r.Status,
+				Webhook:     r.Webhook,
+				Command:     r.Command,
+				MinInterval: time.Duration(r.MinInterval) * time.Second,
+			})
+		}
+		automationEngine = automation.NewEngine(rules, log.Logger)
+		automationEngine.Start()
+		trackingService.SetAutomation(automationEngine)
+	}
+
+	// Manual timer: explicitly timed tasks are recorded as local time entries
+	timeEntryRepo := repository.NewTimeEntryRepository(db.DB)
+	timeEntryRepo.SetReadDB(db.Reader())
+	timeEntryService := service.NewTimeEntryService(timeEntryRepo, log.Logger)
+	if err := timeEntryService.SetOverlapPolicy(cfg.HTTPServer.Overlap); err != nil {
+		log.Warn("Invalid http_server.overlap, rejecting overlapping time entries", zap.Error(err))
+	}
+	manualTimer := service.NewManualTimer(timeEntryService, deviceID, log.Logger)
+
 	// Initialize browser event server (for browser extension)
-	var browserHTTPServer *http.Server
+	browserServer := server.NewBrowserServerController(sessionManager, cfg.Server.Port, log.Logger)
+	if cfg.Tracking.PresenceEnabled {
+		browserServer.SetPresenceHandler(activityTracker.SetPresence)
+	}
+	browserServer.SetStatusProvider(trackingService.GetStatus)
+	browserServer.SetReadinessCheck(trackingService.Ready)
+	browserServer.SetTimer(manualTimer)
+	reloader := &configReloader{
+		path: resolvedConfigPath,
+		apply: reloadTargets{
+			trackingService: trackingService,
+			sessionManager:  sessionManager,
+			activityTracker: activityTracker,
+			windowTracker:   windowTracker,
+			eventCollector:  eventCollector,
+			timeEntries:     timeEntryService,
+			browserServer:   browserServer,
+			log:             log.Logger,
+		}.apply,
+		log:     log.Logger,
+		applied: cfg,
+	}
+	browserServer.SetReloadHandler(reloader.reload)
+	browserServer.SetThresholdsHandler(activityTracker.SetThresholds)
+	trackingService.SetReadyMaxBacklog(cfg.Server.ReadyMaxBacklog)
+	applyURLGranularity(browserServer, cfg.Server.URLGranularity, log.Logger)
+	applyURLNormalization(browserServer, cfg.Server)
+	browserServer.SetToken(cfg.Server.Token)
+	browserServer.SetRateLimit(cfg.Server.RateLimit, cfg.Server.RateBurst)
+	if cfg.Server.Token == "" {
+		log.Warn("server.token is not set; any local web page can post browser events and read time entries")
+	}
 
 	if cfg.Server.Enabled {
-		browserEventServer := server.NewBrowserEventServer(sessionManager, log.Logger)
-
-		// Try the configured port; if busy, try nearby ports
-		browserListener, browserPort, err := listenWithFallback(cfg.Server.Port, log)
-		if err != nil {
+		if err := browserServer.Start(); err != nil {
 			log.Error("Failed to start browser event server on any port", zap.Error(err))
-		} else {
-			browserHTTPServer = &http.Server{
-				Handler:      browserEventServer,
-				ReadTimeout:  15 * time.Second,
-				WriteTimeout: 15 * time.Second,
-				IdleTimeout:  60 * time.Second,
-			}
-
-			// Start browser event server in goroutine
-			go func() {
-				log.Info("Browser event server started for extension",
-					zap.Int("configured_port", cfg.Server.Port),
-					zap.Int("actual_port", browserPort),
-				)
-				if err := browserHTTPServer.Serve(browserListener); err != nil && err != http.ErrServerClosed {
-					log.Error("Browser event server error", zap.Error(err))
-				}
-			}()
-
-			if browserPort != cfg.Server.Port {
-				log.Warn("Browser extension server is on a different port than configured!",
-					zap.Int("configured", cfg.Server.Port),
-					zap.Int("actual", browserPort),
-					zap.String("note", "Make sure the browser extension is configured to use the correct port"),
-				)
-			}
 		}
 	} else {
 		log.Info("Browser event server disabled in configuration")
 	}
 
+	// Initialize metrics server (for Prometheus scrapers)
+	var metricsServer *server.MetricsServer
+	if cfg.Metrics.Enabled {
+		metricsServer = server.NewMetricsServer(trackingService, cfg.Metrics.Address, log.Logger)
+		metricsServer.Start()
+	}
+
+	// Initialize the local time entry API
+	var timeEntryServer *server.Server
+	if cfg.HTTPServer.Enabled {
+		timeEntryHandler := handler.NewTimeEntryHandler(timeEntryService, log.Logger)
+		timeEntryServer = server.New(cfg.HTTPServer.Address, router.New(timeEntryHandler, cfg.Server.Token, log.Logger), log.Logger)
+		go func() {
+			if err := timeEntryServer.Start(); err != nil {
+				log.Error("Time entry API server error", zap.Error(err))
+			}
+		}()
+	}
+
 	// Purge any queued events that are too old for the backend to accept.
 	// The backend rejects events with timestamps older than ~24h, so we drop
 	// them now to avoid flooding the backend with guaranteed-to-fail requests.
-	if err := eventQueue.PurgeExpiredEvents(24 * time.Hour); err != nil {
-		log.Warn("Failed to purge expired queued events", zap.Error(err))
+	if !*dryRun {
+		if err := eventQueue.PurgeExpiredEvents(24 * time.Hour); err != nil {
+			log.Warn("Failed to purge expired queued events", zap.Error(err))
+		}
 	}
 
 	// Start tracking service
@@ -294,6 +520,14 @@ func main() {
 		cfg.Backend.BaseURL,
 		logsPath,
 	)
+	trayManager.SetReauth(tokenManager.NeedsReauth, func(ctx context.Context) error {
+		token, err := tokenManager.Reauthorize(ctx)
+		if err != nil {
+			return err
+		}
+		apiClient.SetDeviceToken(token)
+		return nil
+	})
 
 	// Start tray in background (on Windows)
 	trayCtx, trayCancel := context.WithCancel(context.Background())
@@ -311,6 +545,17 @@ func main() {
 		zap.String("backend_url", cfg.Backend.BaseURL),
 	)
 
+	// Reload runtime-adjustable settings on SIGHUP; on Windows, which has no
+	// SIGHUP, POST /api/v1/admin/reload does the same
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			log.Info("Received SIGHUP, reloading configuration")
+			reloader.reload()
+		}
+	}()
+
 	// Wait for interrupt signal or tray quit
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -326,14 +571,37 @@ func main() {
 
 	log.Info("Shutting down time-tracking agent...")
 
-	// Stop browser event server if enabled
-	if browserHTTPServer != nil {
+	// Stop browser event server if running
+	if browserServer.IsRunning() {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		defer cancel()
-		if err := browserHTTPServer.Shutdown(ctx); err != nil {
+		if err := browserServer.Stop(ctx); err != nil {
 			log.Warn("Browser event server shutdown error", zap.Error(err))
-		} else {
-			log.Info("Browser event server stopped")
+		}
+	}
+
+	// Stop metrics server if running
+	if metricsServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := metricsServer.Stop(ctx); err != nil {
+			log.Warn("Metrics server shutdown error", zap.Error(err))
+		}
+	}
+
+	// Stop time entry API server if running
+	if timeEntryServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := timeEntryServer.Shutdown(ctx); err != nil {
+			log.Warn("Time entry API server shutdown error", zap.Error(err))
+		}
+	}
+
+	// Record a running manual timer so its time isn't lost
+	if manualTimer.Current() != nil {
+		if _, err := manualTimer.Stop(time.Now()); err != nil {
+			log.Warn("Failed to record running timer", zap.Error(err))
 		}
 	}
 
@@ -344,21 +612,32 @@ func main() {
 		close(done)
 	}()
 
-	// Wait for shutdown with timeout
+	// Wait for shutdown with timeout: the tracker/goroutine stop budget plus
+	// the final flush's send budget
+	stopTimeout := 3*time.Second + time.Duration(cfg.Backend.ShutdownSendTimeout)*time.Second
 	select {
 	case <-done:
 		log.Info("Tracking service stopped successfully")
-	case <-time.After(3 * time.Second):
+	case <-time.After(stopTimeout):
 		log.Warn("Shutdown timeout reached, forcing immediate exit")
 		os.Exit(1)
 	}
 
-	// Cleanup old queued events (older than 7 days with >10 retries) - quick, don't wait
-	go func() {
-		if err := eventQueue.CleanupOldEvents(7 * 24 * time.Hour); err != nil {
-			log.Error("Failed to cleanup old events", zap.Error(err))
-		}
-	}()
+	if automationEngine != nil {
+		automationEngine.Stop()
+	}
+	if eventLog != nil {
+		eventLog.Close()
+	}
+
+	// Dead-letter old queued events that exhausted their retries - quick, don't wait
+	if !*dryRun {
+		go func() {
+			if err := eventQueue.CleanupOldEvents(7 * 24 * time.Hour); err != nil {
+				log.Error("Failed to cleanup old events", zap.Error(err))
+			}
+		}()
+	}
 
 	log.Info("Time-tracking agent stopped")
 
@@ -367,70 +646,109 @@ func main() {
 	os.Exit(0)
 }
 
-// listenWithFallback tries to bind to the preferred port, then nearby ports,
-// then lets the OS pick a free port. Returns the listener and actual port.
-func listenWithFallback(preferredPort int, log *logger.Logger) (net.Listener, int, error) {
-	// Try preferred port
-	addr := fmt.Sprintf("localhost:%d", preferredPort)
-	listener, err := net.Listen("tcp", addr)
-	if err == nil {
-		return listener, preferredPort, nil
-	}
-	log.Warn("Preferred browser server port unavailable",
-		zap.Int("port", preferredPort),
-		zap.Error(err),
+// applyPrivacyConfig builds the privacy blocklist from config and installs it
+func applyPrivacyConfig(trackingService *service.TrackingService, privacy config.Privacy, log *zap.Logger) {
+	if len(privacy.Applications) == 0 && len(privacy.Domains) == 0 {
+		trackingService.SetPrivacyFilter(nil)
+		return
+	}
+
+	filter, err := service.NewPrivacyFilter(privacy.Action, privacy.Applications, privacy.Domains)
+	if err != nil {
+		log.Warn("Invalid privacy config, blocklist not applied", zap.Error(err))
+		return
+	}
+	trackingService.SetPrivacyFilter(filter)
+	log.Info("Privacy blocklist applied",
+		zap.String("action", privacy.Action),
+		zap.Int("applications", len(privacy.Applications)),
+		zap.Int("domains", len(privacy.Domains)),
 	)
+}
 
-	// Try nearby ports
-	for offset := 1; offset <= 10; offset++ {
-		altPort := preferredPort + offset
-		altAddr := fmt.Sprintf("localhost:%d", altPort)
-		listener, err = net.Listen("tcp", altAddr)
-		if err == nil {
-			return listener, altPort, nil
-		}
+// applyProjectRules sets the rules that attribute events to projects
+func applyProjectRules(trackingService *service.TrackingService, projects config.Projects, log *zap.Logger) {
+	if len(projects.Rules) == 0 {
+		trackingService.SetProjectResolver(nil)
+		return
 	}
 
-	// Last resort: OS-assigned port
-	listener, err = net.Listen("tcp", "localhost:0")
+	rules := make([]service.ProjectRule, 0, len(projects.Rules))
+	for _, r := range projects.Rules {
+		rules = append(rules, service.ProjectRule{
+			Project:      r.Project,
+			Applications: r.Applications,
+			Titles:       r.Titles,
+			Domains:      r.Domains,
+		})
+	}
+	resolver, err := service.NewProjectResolver(rules)
 	if err != nil {
-		return nil, 0, fmt.Errorf("could not bind to any port: %w", err)
+		log.Warn("Invalid project rules, events will not be attributed to projects", zap.Error(err))
+		return
 	}
-	actualPort := listener.Addr().(*net.TCPAddr).Port
-	return listener, actualPort, nil
+	trackingService.SetProjectResolver(resolver)
+	log.Info("Project rules applied", zap.Int("rules", len(rules)))
 }
 
-// saveConfig saves the device token back to the YAML config file.
-func saveConfig(path string, cfg *config.Config) error {
-	return saveConfigField(path, "device_token:", fmt.Sprintf("  device_token: \"%s\"", cfg.Auth.DeviceToken))
+// applyURLGranularity sets how much of extension URLs is kept. An invalid
+// value falls back to domain-only so a typo never leaks more than intended.
+func applyURLGranularity(browserServer *server.BrowserServerController, value string, log *zap.Logger) {
+	granularity, err := server.ParseURLGranularity(value)
+	if err != nil {
+		log.Warn("Invalid server.url_granularity, keeping domains only", zap.Error(err))
+		granularity = server.URLGranularityDomain
+	}
+	browserServer.SetURLGranularity(granularity)
 }
 
-// saveConfigField does a line-level find-and-replace in the config YAML.
-// It finds the first line whose trimmed content starts with fieldPrefix
-// and replaces the entire line with newLine.
-func saveConfigField(path string, fieldPrefix, newLine string) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+// applyURLNormalization sets which query parameters are stripped from
+// extension URLs, defaulting to the built-in tracking/session list
+func applyURLNormalization(browserServer *server.BrowserServerController, cfg config.Server) {
+	stripParams := cfg.URLStripParams
+	switch {
+	case cfg.URLKeepParams:
+		stripParams = nil
+	case len(stripParams) == 0:
+		stripParams = server.DefaultStripParams
 	}
+	browserServer.SetURLNormalization(stripParams, cfg.URLDropFragment)
+}
 
-	lines := strings.Split(string(data), "\n")
-	found := false
-	for i, line := range lines {
-		if strings.HasPrefix(strings.TrimSpace(line), fieldPrefix) {
-			lines[i] = newLine
-			found = true
-			break
-		}
+// loadSecureToken reads the device token from store into cfg. A token still
+// in the plaintext config is moved into the store and cleared from the file.
+func loadSecureToken(path string, cfg *config.Config, store secrets.Store, log *zap.Logger) {
+	token, err := store.Load()
+	if err == nil && token != "" {
+		cfg.Auth.DeviceToken = token
+		return
+	}
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		log.Warn("Failed to load device token from secure storage", zap.Error(err))
+		return
 	}
 
-	if !found {
-		return fmt.Errorf("field %q not found in config file", fieldPrefix)
+	if cfg.Auth.DeviceToken != "" {
+		if err := saveDeviceToken(path, cfg, store, cfg.Auth.DeviceToken); err != nil {
+			log.Warn("Failed to move device token to secure storage", zap.Error(err))
+		} else {
+			log.Info("Moved device token from config to secure storage")
+		}
 	}
+}
 
-	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+// saveDeviceToken persists token to store if one is configured, clearing
+// any plaintext copy from the config file, otherwise to the config file
+func saveDeviceToken(path string, cfg *config.Config, store secrets.Store, token string) error {
+	cfg.Auth.DeviceToken = token
+	if store == nil {
+		return config.Save(path, cfg)
 	}
 
-	return nil
+	if err := store.Save(token); err != nil {
+		return fmt.Errorf("failed to save token to secure storage: %w", err)
+	}
+	plain := *cfg
+	plain.Auth.DeviceToken = ""
+	return config.Save(path, &plain)
 }
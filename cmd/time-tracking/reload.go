@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/collector"
+	"Mansoor88-6/time-tracking-agent/internal/config"
+	"Mansoor88-6/time-tracking-agent/internal/logger"
+	"Mansoor88-6/time-tracking-agent/internal/server"
+	"Mansoor88-6/time-tracking-agent/internal/service"
+	"Mansoor88-6/time-tracking-agent/internal/tracker"
+
+	"go.uber.org/zap"
+)
+
+// reloadTargets are the running components that take new settings on reload
+type reloadTargets struct {
+	trackingService *service.TrackingService
+	sessionManager  *service.SessionManager
+	activityTracker *tracker.ActivityTracker
+	windowTracker   *tracker.WindowTracker
+	eventCollector  *collector.EventCollector
+	timeEntries     *service.TimeEntryService
+	browserServer   *server.BrowserServerController
+	log             *zap.Logger
+}
+
+// apply pushes the runtime-adjustable settings of cfg into the components
+func (t reloadTargets) apply(cfg *config.Config) {
+	if err := logger.SetLevel(cfg.Log.Level); err != nil {
+		t.log.Warn("Failed to apply log.level", zap.Error(err))
+	}
+	if err := logger.SetRedaction(cfg.Log.Redact); err != nil {
+		t.log.Warn("Failed to apply log.redact", zap.Error(err))
+	}
+
+	t.activityTracker.SetIdleThreshold(time.Duration(cfg.Tracking.IdleThreshold) * time.Second)
+	t.activityTracker.SetAwayThreshold(time.Duration(cfg.Tracking.AwayThreshold) * time.Second)
+	t.activityTracker.SetMouseMoveInterval(time.Duration(cfg.Tracking.MouseMoveIntervalMs) * time.Millisecond)
+	t.sessionManager.SetAccumulate(cfg.Tracking.AccumulateSessions)
+	t.windowTracker.SetPollInterval(time.Duration(cfg.Tracking.WindowPollInterval) * time.Second)
+	if err := t.windowTracker.SetEmptyWindowPolicy(cfg.Tracking.EmptyWindow); err != nil {
+		t.log.Warn("Failed to apply tracking.empty_window", zap.Error(err))
+	}
+	t.eventCollector.SetBatchSize(cfg.Tracking.BatchSize)
+	t.eventCollector.SetFlushInterval(time.Duration(cfg.Tracking.BatchFlushInterval) * time.Second)
+	if err := t.trackingService.SetFullscreenAction(cfg.Tracking.FullscreenAction); err != nil {
+		t.log.Warn("Failed to apply tracking.fullscreen_action", zap.Error(err))
+	}
+	applyPrivacyConfig(t.trackingService, cfg.Privacy, t.log)
+	applyProjectRules(t.trackingService, cfg.Projects, t.log)
+	if err := t.timeEntries.SetOverlapPolicy(cfg.HTTPServer.Overlap); err != nil {
+		t.log.Warn("Failed to apply http_server.overlap", zap.Error(err))
+	}
+
+	if err := t.browserServer.SetEnabled(cfg.Server.Enabled); err != nil {
+		t.log.Warn("Failed to apply server.enabled", zap.Error(err))
+	}
+	applyURLGranularity(t.browserServer, cfg.Server.URLGranularity, t.log)
+	applyURLNormalization(t.browserServer, cfg.Server)
+	t.browserServer.SetToken(cfg.Server.Token)
+	t.browserServer.SetRateLimit(cfg.Server.RateLimit, cfg.Server.RateBurst)
+	t.trackingService.SetReadyMaxBacklog(cfg.Server.ReadyMaxBacklog)
+}
+
+// configReloader re-reads the config file into the running components. SIGHUP
+// and the admin reload endpoint share one, so reloads never interleave.
+type configReloader struct {
+	path  string
+	apply func(cfg *config.Config) // Usually reloadTargets.apply
+	log   *zap.Logger
+
+	mu sync.Mutex
+	// applied is the config last loaded: the startup config, then each
+	// reload. Restart-only settings are diffed against it so each edit is
+	// warned about once rather than on every later reload.
+	applied *config.Config
+}
+
+// reload loads the config file and applies its runtime-adjustable settings,
+// warning about changed settings that need a restart
+func (r *configReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	newCfg, err := config.LoadConfig(r.path)
+	if err != nil {
+		r.log.Warn("Failed to reload config, keeping current settings", zap.Error(err))
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	r.apply(newCfg)
+	if changed := restartRequired(r.applied, newCfg); len(changed) > 0 {
+		r.log.Warn("Some changed settings only take effect after a restart, ignoring them",
+			zap.Strings("settings", changed),
+		)
+	}
+	r.applied = newCfg
+	r.log.Info("Configuration reloaded")
+	return nil
+}
+
+// restartRequired returns the settings that differ between the previously
+// applied and reloaded config but only take effect after a restart
+func restartRequired(previous, reloaded *config.Config) []string {
+	settings := []struct {
+		name               string
+		previous, reloaded any
+	}{
+		{"storage_path", previous.StoragePath, reloaded.StoragePath},
+		{"storage_read_conns", previous.StorageReadConns, reloaded.StorageReadConns},
+		{"storage_maintenance_interval", previous.StorageMaintenanceInterval, reloaded.StorageMaintenanceInterval},
+		{"http_server.enabled", previous.HTTPServer.Enabled, reloaded.HTTPServer.Enabled},
+		{"http_server.address", previous.HTTPServer.Address, reloaded.HTTPServer.Address},
+		{"log.format", previous.Log.Format, reloaded.Log.Format},
+		{"backend", previous.Backend, reloaded.Backend},
+		{"device", previous.Device, reloaded.Device},
+		{"auth.callback_port", previous.Auth.CallbackPort, reloaded.Auth.CallbackPort},
+		{"auth.callback_port_fixed", previous.Auth.CallbackPortFixed, reloaded.Auth.CallbackPortFixed},
+		{"auth.token_storage", previous.Auth.TokenStorage, reloaded.Auth.TokenStorage},
+		{"server.port", previous.Server.Port, reloaded.Server.Port},
+		{"metrics", previous.Metrics, reloaded.Metrics},
+		{"automation", previous.Automation, reloaded.Automation},
+		{"categories", previous.Categories, reloaded.Categories},
+		{"queue", previous.Queue, reloaded.Queue},
+		{"event_log", previous.EventLog, reloaded.EventLog},
+		{"tracking.coalesce_events", previous.Tracking.CoalesceEvents, reloaded.Tracking.CoalesceEvents},
+		{"tracking.session_inactivity_timeout", previous.Tracking.SessionInactivityTimeout, reloaded.Tracking.SessionInactivityTimeout},
+		{"tracking.min_dwell_ms", previous.Tracking.MinDwellMs, reloaded.Tracking.MinDwellMs},
+		{"tracking.liveness_interval", previous.Tracking.LivenessInterval, reloaded.Tracking.LivenessInterval},
+		{"tracking.heartbeat_interval", previous.Tracking.HeartbeatInterval, reloaded.Tracking.HeartbeatInterval},
+		{"tracking.split_at_midnight", previous.Tracking.SplitAtMidnight, reloaded.Tracking.SplitAtMidnight},
+		{"tracking.include_power", previous.Tracking.IncludePower, reloaded.Tracking.IncludePower},
+		{"tracking.presence_enabled", previous.Tracking.PresenceEnabled, reloaded.Tracking.PresenceEnabled},
+	}
+
+	var changed []string
+	for _, setting := range settings {
+		if !reflect.DeepEqual(setting.previous, setting.reloaded) {
+			changed = append(changed, setting.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"Mansoor88-6/time-tracking-agent/internal/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func writeConfig(t *testing.T, path, storagePath string) {
+	t.Helper()
+	data := "storage_path: " + storagePath + "\nbackend:\n  base_url: https://api.example.com\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// restartWarnings returns the settings named by each restart warning logged
+// since the last call
+func restartWarnings(logs *observer.ObservedLogs) []string {
+	var warnings []string
+	for _, entry := range logs.TakeAll() {
+		if settings, ok := entry.ContextMap()["settings"]; ok {
+			warnings = append(warnings, fmt.Sprint(settings))
+		}
+	}
+	return warnings
+}
+
+func TestReloadWarnsAboutRestartSettingsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "storage/a.db")
+	startup, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	var applied []*config.Config
+	r := &configReloader{
+		path:    path,
+		apply:   func(cfg *config.Config) { applied = append(applied, cfg) },
+		log:     zap.New(core),
+		applied: startup,
+	}
+
+	if err := r.reload(); err != nil {
+		t.Fatalf("unchanged reload: %v", err)
+	}
+	if warnings := restartWarnings(logs); len(warnings) != 0 {
+		t.Fatalf("unchanged reload warned %v", warnings)
+	}
+
+	writeConfig(t, path, "storage/b.db")
+	if err := r.reload(); err != nil {
+		t.Fatalf("reload after edit: %v", err)
+	}
+	if warnings := restartWarnings(logs); len(warnings) != 1 || warnings[0] != "[storage_path]" {
+		t.Fatalf("reload after edit warned %v, want storage_path once", warnings)
+	}
+
+	// The edit was already reported
+	if err := r.reload(); err != nil {
+		t.Fatalf("second reload: %v", err)
+	}
+	if warnings := restartWarnings(logs); len(warnings) != 0 {
+		t.Fatalf("second reload warned again: %v", warnings)
+	}
+	if len(applied) != 3 {
+		t.Fatalf("applied %d configs, want 3", len(applied))
+	}
+}
+
+func TestReloadKeepsSettingsOnLoadError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "storage/a.db")
+	startup, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	applies := 0
+	r := &configReloader{
+		path:    path,
+		apply:   func(*config.Config) { applies++ },
+		log:     zap.NewNop(),
+		applied: startup,
+	}
+
+	if err := os.WriteFile(path, []byte("backend: [unclosed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reload(); err == nil {
+		t.Fatal("reload of a broken config succeeded")
+	}
+	if applies != 0 || r.applied != startup {
+		t.Fatalf("broken config applied (%d applies)", applies)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/config"
+	"Mansoor88-6/time-tracking-agent/internal/database"
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/queue"
+
+	"go.uber.org/zap"
+)
+
+// reportDateLayout is the format of --from and --to
+const reportDateLayout = "2006-01-02"
+
+// reportEntry is the active time spent in one application or domain
+type reportEntry struct {
+	Name       string `json:"name"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// report summarizes locally stored events over a date range
+type report struct {
+	From         string        `json:"from"`
+	To           string        `json:"to"`
+	Events       int           `json:"events"`
+	ActiveMs     int64         `json:"active_ms"`
+	Applications []reportEntry `json:"applications"`
+	Domains      []reportEntry `json:"domains"`
+}
+
+// runReport implements `time-tracking report`: it prints active time per
+// application and per domain from the events stored in the local database,
+// without contacting the backend. Returns the process exit code.
+func runReport(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	configPath := flags.String("config", "", "Path to configuration file (auto-detected if empty)")
+	today := flags.Bool("today", false, "Report on today (the default when no range is given)")
+	fromFlag := flags.String("from", "", "First day to report on, YYYY-MM-DD")
+	toFlag := flags.String("to", "", "Last day to report on, YYYY-MM-DD (defaults to --from)")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: time-tracking report [--today | --from YYYY-MM-DD [--to YYYY-MM-DD]] [--json]")
+		fmt.Fprintln(flags.Output(), "Summarizes events stored locally (not yet delivered to the backend).")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	from, to, err := reportRange(*today, *fromFlag, *toFlag, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid date range: %v\n", err)
+		return 2
+	}
+
+	resolvedConfigPath, err := config.ResolveConfigPath(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find config: %v\n", err)
+		return 1
+	}
+	cfg, err := config.LoadConfig(resolvedConfigPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	// Read-only, so the report can run while the agent is writing
+	db, err := database.OpenReadOnly(cfg.StoragePath, zap.NewNop())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	events, err := queue.NewEventQueue(db.DB, zap.NewNop()).LocalEvents(from, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read events: %v\n", err)
+		return 1
+	}
+
+	r := buildReport(events, from, to)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	writeReport(os.Stdout, r)
+	return 0
+}
+
+// reportRange turns the date flags into a [from, to) range of local days
+func reportRange(today bool, fromFlag, toFlag string, now time.Time) (time.Time, time.Time, error) {
+	if fromFlag == "" {
+		if toFlag != "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("--to needs --from")
+		}
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		return start, start.AddDate(0, 0, 1), nil
+	}
+	if today {
+		return time.Time{}, time.Time{}, fmt.Errorf("--today can't be combined with --from")
+	}
+
+	from, err := time.ParseInLocation(reportDateLayout, fromFlag, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("--from: %w", err)
+	}
+	to := from
+	if toFlag != "" {
+		if to, err = time.ParseInLocation(reportDateLayout, toFlag, time.Local); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("--to: %w", err)
+		}
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--to is before --from")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// buildReport sums the duration of active events per application and per
+// URL host
+func buildReport(events []models.TrackingEvent, from, to time.Time) report {
+	r := report{
+		From:   from.Format(reportDateLayout),
+		To:     to.AddDate(0, 0, -1).Format(reportDateLayout),
+		Events: len(events),
+	}
+
+	applications := map[string]int64{}
+	domains := map[string]int64{}
+	for _, event := range events {
+		if event.Status != models.StatusActive || event.Duration == nil {
+			continue
+		}
+		duration := *event.Duration
+		r.ActiveMs += duration
+
+		if event.Application != nil && *event.Application != "" {
+			applications[*event.Application] += duration
+		}
+		if event.URL != nil {
+			if u, err := url.Parse(*event.URL); err == nil && u.Hostname() != "" {
+				domains[strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")] += duration
+			}
+		}
+	}
+
+	r.Applications = sortedEntries(applications)
+	r.Domains = sortedEntries(domains)
+	return r
+}
+
+// sortedEntries returns totals ordered by duration, longest first
+func sortedEntries(totals map[string]int64) []reportEntry {
+	entries := make([]reportEntry, 0, len(totals))
+	for name, ms := range totals {
+		entries = append(entries, reportEntry{Name: name, DurationMs: ms})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].DurationMs != entries[j].DurationMs {
+			return entries[i].DurationMs > entries[j].DurationMs
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// writeReport prints the report as text tables
+func writeReport(w io.Writer, r report) {
+	if r.From == r.To {
+		fmt.Fprintf(w, "Tracked time on %s\n", r.From)
+	} else {
+		fmt.Fprintf(w, "Tracked time from %s to %s\n", r.From, r.To)
+	}
+	fmt.Fprintf(w, "Active: %s across %d local events\n", formatReportDuration(r.ActiveMs), r.Events)
+
+	for _, section := range []struct {
+		title   string
+		entries []reportEntry
+	}{
+		{"APPLICATION", r.Applications},
+		{"DOMAIN", r.Domains},
+	} {
+		fmt.Fprintln(w)
+		if len(section.entries) == 0 {
+			fmt.Fprintf(w, "No %s time recorded\n", strings.ToLower(section.title))
+			continue
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "%s\tTIME\t\n", section.title)
+		for _, entry := range section.entries {
+			fmt.Fprintf(tw, "%s\t%s\t\n", entry.Name, formatReportDuration(entry.DurationMs))
+		}
+		tw.Flush()
+	}
+}
+
+// formatReportDuration formats milliseconds as e.g. 1h05m or 42s
+func formatReportDuration(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
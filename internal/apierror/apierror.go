@@ -0,0 +1,113 @@
+// Package apierror writes the JSON error envelope used by the agent's local
+// HTTP APIs: {"error": {"code": "...", "message": "..."}}
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"Mansoor88-6/time-tracking-agent/internal/client"
+)
+
+// Error codes shared by the local APIs. Handlers may use more specific
+// codes for domain errors.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+	CodeUnavailable      = "unavailable"
+
+	CodeInvalidTimeEntry = "invalid_time_entry"
+	CodeInvalidRange     = "invalid_range"
+	CodeInvalidSummary   = "invalid_summary"
+	CodeOverlap          = "overlap"
+	CodeTimerRunning     = "timer_running"
+	CodeTimerNotRunning  = "timer_not_running"
+
+	CodeBackendUnauthorized = "backend_unauthorized"
+	CodeBackendRateLimited  = "backend_rate_limited"
+	CodeBackendRejected     = "backend_rejected"
+	CodeBackendError        = "backend_error"
+)
+
+// Body is the error envelope
+type Body struct {
+	Error Detail `json:"error"`
+}
+
+// Detail describes one error
+type Detail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Write writes the envelope with status
+func Write(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Body{Error: Detail{Code: code, Message: message}})
+}
+
+// Error is a drop-in for http.Error that writes the envelope, with the code
+// derived from status
+func Error(w http.ResponseWriter, message string, status int) {
+	Write(w, status, codeForStatus(status), message)
+}
+
+// NotFound is a drop-in for http.NotFound
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Write(w, http.StatusNotFound, CodeNotFound, "Not found")
+}
+
+// Classify returns the status and code for an error from the backend
+// client, looking through wrapping. ok is false for any other error.
+func Classify(err error) (status int, code string, ok bool) {
+	var authErr *client.AuthError
+	var rateLimitErr *client.RateLimitError
+	var badRequestErr *client.BadRequestError
+	var backendErr *client.BackendError
+	switch {
+	case errors.As(err, &authErr):
+		return http.StatusBadGateway, CodeBackendUnauthorized, true
+	case errors.As(err, &rateLimitErr):
+		return http.StatusServiceUnavailable, CodeBackendRateLimited, true
+	case errors.As(err, &badRequestErr):
+		return http.StatusBadGateway, CodeBackendRejected, true
+	case errors.As(err, &backendErr):
+		return http.StatusBadGateway, CodeBackendError, true
+	}
+	return 0, "", false
+}
+
+// codeForStatus returns the generic code for an HTTP status
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
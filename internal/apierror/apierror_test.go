@@ -0,0 +1,102 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Mansoor88-6/time-tracking-agent/internal/client"
+)
+
+// decode returns the envelope written to rec
+func decode(t *testing.T, rec *httptest.ResponseRecorder) Detail {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var body Body
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q is not an envelope: %v", rec.Body.String(), err)
+	}
+	return body.Error
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, http.StatusConflict, CodeOverlap, `overlaps "entry" 3`)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", rec.Code)
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+	}
+	if got := decode(t, rec); got != (Detail{Code: CodeOverlap, Message: `overlaps "entry" 3`}) {
+		t.Errorf("envelope = %+v", got)
+	}
+}
+
+func TestErrorDerivesCode(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusBadRequest, CodeBadRequest},
+		{http.StatusUnauthorized, CodeUnauthorized},
+		{http.StatusNotFound, CodeNotFound},
+		{http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{http.StatusConflict, CodeConflict},
+		{http.StatusRequestEntityTooLarge, CodePayloadTooLarge},
+		{http.StatusTooManyRequests, CodeRateLimited},
+		{http.StatusServiceUnavailable, CodeUnavailable},
+		{http.StatusInternalServerError, CodeInternal},
+		{http.StatusBadGateway, CodeInternal},
+		{http.StatusTeapot, CodeBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		Error(rec, "message", tt.status)
+		if rec.Code != tt.status {
+			t.Errorf("Error(%d) wrote status %d", tt.status, rec.Code)
+		}
+		if got := decode(t, rec); got.Code != tt.want || got.Message != "message" {
+			t.Errorf("Error(%d) = %+v, want code %s", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NotFound(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if got := decode(t, rec); rec.Code != http.StatusNotFound || got.Code != CodeNotFound {
+		t.Errorf("NotFound = %d %+v", rec.Code, got)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantOK     bool
+	}{
+		{"auth", &client.AuthError{Message: "expired", StatusCode: 401}, http.StatusBadGateway, CodeBackendUnauthorized, true},
+		{"rate limited", &client.RateLimitError{Message: "slow down", StatusCode: 429}, http.StatusServiceUnavailable, CodeBackendRateLimited, true},
+		{"rejected", &client.BadRequestError{Message: "bad", StatusCode: 400}, http.StatusBadGateway, CodeBackendRejected, true},
+		{"backend", &client.BackendError{Message: "down", StatusCode: 500}, http.StatusBadGateway, CodeBackendError, true},
+		{"wrapped", fmt.Errorf("sending: %w", &client.AuthError{Message: "expired"}), http.StatusBadGateway, CodeBackendUnauthorized, true},
+		{"other", errors.New("disk full"), 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code, ok := Classify(tt.err)
+			if status != tt.wantStatus || code != tt.wantCode || ok != tt.wantOK {
+				t.Errorf("Classify = %d, %q, %v, want %d, %q, %v", status, code, ok, tt.wantStatus, tt.wantCode, tt.wantOK)
+			}
+		})
+	}
+}
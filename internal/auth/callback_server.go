@@ -2,7 +2,9 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
+	"html"
 	"net"
 	"net/http"
 	"time"
@@ -70,7 +72,7 @@ const (
 			align-items: center;
 			height: 100vh;
 			margin: 0;
-			background: linear-gradient(135deg, #f093fb 0%, #f5576c 100%);
+			background: linear-gradient(135deg, #f093fb 0%%, #f5576c 100%%);
 		}
 		.container {
 			background: white;
@@ -113,13 +115,16 @@ type CallbackServer struct {
 	errChan  chan error
 	logger   *zap.Logger
 	port     int
+	fixed    bool
+	state    string
 	// ActualPort is the port the server actually bound to (may differ from
 	// the requested port if that port was busy).
 	ActualPort int
 }
 
 // NewCallbackServer creates a new callback server.
-// preferredPort is tried first; if unavailable the OS picks a free port.
+// preferredPort is tried first; if unavailable, or not positive, the OS picks
+// a free port.
 func NewCallbackServer(preferredPort int, logger *zap.Logger) *CallbackServer {
 	return &CallbackServer{
 		codeChan: make(chan string, 1),
@@ -129,6 +134,18 @@ func NewCallbackServer(preferredPort int, logger *zap.Logger) *CallbackServer {
 	}
 }
 
+// SetFixedPort disables the fallback to other ports when the preferred port
+// is busy.
+func (s *CallbackServer) SetFixedPort(fixed bool) {
+	s.fixed = fixed
+}
+
+// SetState sets the state value a callback must carry to be accepted.
+// Callbacks with a missing or different state are rejected.
+func (s *CallbackServer) SetState(state string) {
+	s.state = state
+}
+
 // Start starts the callback server and waits for the authorization code.
 // It tries the preferred port first, then falls back to an OS-assigned port.
 func (s *CallbackServer) Start(ctx context.Context) (string, error) {
@@ -168,42 +185,53 @@ func (s *CallbackServer) Start(ctx context.Context) (string, error) {
 	}
 }
 
-// listen tries to bind to the preferred port; if that fails it binds to :0
-// (OS-assigned free port). Returns the listener and the actual port.
+// listen tries to bind to the preferred port; if that fails it tries a few
+// nearby ports and then :0 (OS-assigned free port), unless the port is fixed.
+// Returns the listener and the port it is actually bound to.
 func (s *CallbackServer) listen() (net.Listener, int, error) {
-	// Try preferred port
-	addr := fmt.Sprintf("127.0.0.1:%d", s.port)
-	listener, err := net.Listen("tcp", addr)
-	if err == nil {
-		return listener, s.port, nil
-	}
+	if s.port > 0 {
+		// Try preferred port
+		addr := fmt.Sprintf("127.0.0.1:%d", s.port)
+		listener, err := net.Listen("tcp", addr)
+		if err == nil {
+			return listener, listenerPort(listener), nil
+		}
+		if s.fixed {
+			return nil, 0, fmt.Errorf("callback port %d is unavailable: %w", s.port, err)
+		}
 
-	s.logger.Warn("Preferred callback port unavailable, trying alternative ports",
-		zap.Int("preferred_port", s.port),
-		zap.Error(err),
-	)
+		s.logger.Warn("Preferred callback port unavailable, trying alternative ports",
+			zap.Int("preferred_port", s.port),
+			zap.Error(err),
+		)
 
-	// Try a small range of nearby ports
-	for offset := 1; offset <= 20; offset++ {
-		altPort := s.port + offset
-		altAddr := fmt.Sprintf("127.0.0.1:%d", altPort)
-		listener, err = net.Listen("tcp", altAddr)
-		if err == nil {
-			s.logger.Info("Using alternative callback port", zap.Int("port", altPort))
-			return listener, altPort, nil
+		// Try a small range of nearby ports
+		for offset := 1; offset <= 20; offset++ {
+			altPort := s.port + offset
+			altAddr := fmt.Sprintf("127.0.0.1:%d", altPort)
+			listener, err = net.Listen("tcp", altAddr)
+			if err == nil {
+				s.logger.Info("Using alternative callback port", zap.Int("port", altPort))
+				return listener, altPort, nil
+			}
 		}
 	}
 
 	// Last resort: let the OS pick any available port
-	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
 		return nil, 0, fmt.Errorf("could not bind to any port: %w", err)
 	}
-	actualPort := listener.Addr().(*net.TCPAddr).Port
+	actualPort := listenerPort(listener)
 	s.logger.Info("Using OS-assigned callback port", zap.Int("port", actualPort))
 	return listener, actualPort, nil
 }
 
+// listenerPort returns the TCP port a listener is bound to
+func listenerPort(listener net.Listener) int {
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
 // Stop stops the callback server
 func (s *CallbackServer) Stop() error {
 	if s.server == nil {
@@ -220,19 +248,28 @@ func (s *CallbackServer) Stop() error {
 func (s *CallbackServer) handleCallback(w http.ResponseWriter, r *http.Request) {
 	code := r.URL.Query().Get("code")
 	errorParam := r.URL.Query().Get("error")
+	state := r.URL.Query().Get("state")
+
+	// Reject callbacks that don't carry the state we sent. These are not
+	// forwarded to errChan so a stray request can't abort a pending login.
+	if s.state != "" && subtle.ConstantTimeCompare([]byte(state), []byte(s.state)) != 1 {
+		s.logger.Warn("Rejected callback with invalid state",
+			zap.Bool("state_present", state != ""),
+		)
+		writeErrorPage(w, "Invalid or missing state parameter")
+		return
+	}
 
 	if errorParam != "" {
 		s.logger.Error("Authorization error", zap.String("error", errorParam))
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf(errorHTML, errorParam)))
+		writeErrorPage(w, errorParam)
 		s.errChan <- fmt.Errorf("authorization error: %s", errorParam)
 		return
 	}
 
 	if code == "" {
 		s.logger.Error("No authorization code received")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf(errorHTML, "No authorization code received")))
+		writeErrorPage(w, "No authorization code received")
 		s.errChan <- fmt.Errorf("no authorization code received")
 		return
 	}
@@ -253,3 +290,10 @@ func (s *CallbackServer) handleCallback(w http.ResponseWriter, r *http.Request)
 		s.Stop()
 	}()
 }
+
+// writeErrorPage renders errorHTML with an escaped message
+func writeErrorPage(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, errorHTML, html.EscapeString(message))
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func callback(s *CallbackServer, query string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.handleCallback(rec, httptest.NewRequest(http.MethodGet, "/callback?"+query, nil))
+	return rec
+}
+
+func TestCallbackRejectsBadState(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"missing state", "code=abc"},
+		{"wrong state", "code=abc&state=other"},
+		{"wrong state with error", "error=access_denied&state=other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewCallbackServer(0, zap.NewNop())
+			s.SetState("expected")
+
+			rec := callback(s, tt.query)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Invalid or missing state parameter") {
+				t.Fatalf("got %d %q, want the invalid state error page", rec.Code, rec.Body.String())
+			}
+			select {
+			case code := <-s.codeChan:
+				t.Fatalf("code %q delivered for a bad state", code)
+			case err := <-s.errChan:
+				t.Fatalf("bad state aborted the login: %v", err)
+			default:
+			}
+		})
+	}
+}
+
+func TestCallbackAcceptsMatchingState(t *testing.T) {
+	s := NewCallbackServer(0, zap.NewNop())
+	s.SetState("expected")
+
+	rec := callback(s, "code=abc&state=expected")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Registered Successfully") {
+		t.Fatalf("got %d %q, want the success page", rec.Code, rec.Body.String())
+	}
+	select {
+	case code := <-s.codeChan:
+		if code != "abc" {
+			t.Fatalf("code = %q, want abc", code)
+		}
+	default:
+		t.Fatal("no code delivered")
+	}
+}
+
+func TestCallbackAuthorizationError(t *testing.T) {
+	s := NewCallbackServer(0, zap.NewNop())
+	s.SetState("expected")
+
+	rec := callback(s, "error=%3Cscript%3E&state=expected")
+	if rec.Code != http.StatusBadRequest || strings.Contains(rec.Body.String(), "<script>") {
+		t.Fatalf("got %d %q, want an escaped error page", rec.Code, rec.Body.String())
+	}
+	select {
+	case err := <-s.errChan:
+		if !strings.Contains(err.Error(), "authorization error") {
+			t.Fatalf("err = %v, want an authorization error", err)
+		}
+	default:
+		t.Fatal("authorization error not reported")
+	}
+	if len(s.codeChan) != 0 {
+		t.Fatal("code delivered with an authorization error")
+	}
+}
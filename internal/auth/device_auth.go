@@ -3,6 +3,8 @@ package auth
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -19,7 +21,9 @@ import (
 type DeviceAuthService struct {
 	platform     platform.Platform
 	callbackPort int
+	fixedPort    bool
 	baseURL      string
+	transport    http.RoundTripper // nil uses http.DefaultTransport
 	logger       *zap.Logger
 }
 
@@ -45,12 +49,33 @@ func NewDeviceAuthService(
 	}
 }
 
+// SetFixedCallbackPort makes authorization fail when the callback port is
+// busy instead of falling back to another port. Use it when the redirect URI
+// is pre-registered with the backend.
+func (s *DeviceAuthService) SetFixedCallbackPort(fixed bool) {
+	s.fixedPort = fixed
+}
+
+// SetTransport sets the HTTP transport used for the token exchange, so it
+// shares the API client's TLS and proxy settings
+func (s *DeviceAuthService) SetTransport(transport http.RoundTripper) {
+	s.transport = transport
+}
+
 // AuthorizeDevice performs the OAuth-style device authorization flow.
 // It starts a local callback server, opens the browser for login, and waits
 // for the backend to redirect with an authorization code.
 func (s *DeviceAuthService) AuthorizeDevice(deviceID, deviceName string) (string, error) {
+	// Random state ties the callback to this authorization attempt
+	state, err := newState()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+
 	// Create callback server (will find an available port automatically)
 	callbackServer := NewCallbackServer(s.callbackPort, s.logger)
+	callbackServer.SetState(state)
+	callbackServer.SetFixedPort(s.fixedPort)
 
 	// Create context with timeout (5 minutes for user to log in)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
@@ -111,10 +136,11 @@ func (s *DeviceAuthService) AuthorizeDevice(deviceID, deviceName string) (string
 	// Now we know the actual port – build the auth URL
 	actualPort := callbackServer.ActualPort
 	redirectURI := fmt.Sprintf("http://localhost:%d/callback", actualPort)
-	authURL := fmt.Sprintf("%s/auth/device/authorize?deviceId=%s&redirectUri=%s",
+	authURL := fmt.Sprintf("%s/auth/device/authorize?deviceId=%s&redirectUri=%s&state=%s",
 		s.baseURL,
 		url.QueryEscape(deviceID),
 		url.QueryEscape(redirectURI),
+		url.QueryEscape(state),
 	)
 	if deviceName != "" {
 		authURL += "&deviceName=" + url.QueryEscape(deviceName)
@@ -157,61 +183,140 @@ func (s *DeviceAuthService) AuthorizeDevice(deviceID, deviceName string) (string
 	}
 }
 
-// ExchangeCodeForToken exchanges authorization code for device token
-func (s *DeviceAuthService) ExchangeCodeForToken(code, deviceID string) (*TokenResponse, error) {
-	tokenURL := fmt.Sprintf("%s/auth/device/token", s.baseURL)
+// newState returns a random hex string for the OAuth state parameter
+func newState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Token exchange retry policy: transient failures (network errors, 5xx, 429)
+// are retried with a doubling delay; other 4xx responses fail immediately
+const (
+	tokenExchangeAttempts = 3
+	tokenExchangeBackoff  = time.Second
+	tokenExchangeTimeout  = 30 * time.Second // per attempt
+)
 
-	// Create request body
-	reqBody := map[string]string{
+// TokenError is a token endpoint's non-success response
+type TokenError struct {
+	Op         string // "token exchange" or "token refresh"
+	StatusCode int
+	Body       string
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("%s failed: status %d, body: %s", e.Op, e.StatusCode, e.Body)
+}
+
+// Rejected reports whether the backend refused the request itself, as
+// opposed to being unavailable, so retrying it unchanged can't succeed
+func (e *TokenError) Rejected() bool {
+	return e.StatusCode < 500 && e.StatusCode != http.StatusTooManyRequests
+}
+
+// ExchangeCodeForToken exchanges authorization code for device token.
+// Cancelling ctx abandons the exchange, including any pending retry.
+func (s *DeviceAuthService) ExchangeCodeForToken(ctx context.Context, code, deviceID string) (*TokenResponse, error) {
+	return s.requestToken(ctx, "token exchange", "token", "", map[string]string{
 		"code":     code,
 		"deviceId": deviceID,
-	}
+	})
+}
 
-	jsonData, err := json.Marshal(reqBody)
+// RefreshDeviceToken exchanges a still-valid (or recently expired) device
+// token for a new one without user interaction. A *TokenError that reports
+// Rejected means the device must be authorized again with AuthorizeDevice.
+func (s *DeviceAuthService) RefreshDeviceToken(ctx context.Context, token, deviceID string) (*TokenResponse, error) {
+	return s.requestToken(ctx, "token refresh", "refresh", token, map[string]string{
+		"deviceId": deviceID,
+	})
+}
+
+// requestToken posts body to the device token endpoint named endpoint,
+// retrying transient failures. bearer, if set, is sent as the Authorization.
+func (s *DeviceAuthService) requestToken(ctx context.Context, op, endpoint, bearer string, body map[string]string) (*TokenResponse, error) {
+	tokenURL := fmt.Sprintf("%s/auth/device/%s", s.baseURL, endpoint)
+
+	jsonData, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	client := &http.Client{
+		Timeout:   tokenExchangeTimeout,
+		Transport: s.transport,
+	}
+
+	backoff := tokenExchangeBackoff
+	for attempt := 1; ; attempt++ {
+		tokenResp, retry, err := s.exchangeOnce(ctx, client, op, tokenURL, bearer, jsonData)
+		if err == nil {
+			s.logger.Info("Device token received",
+				zap.String("device_id", tokenResp.DeviceID),
+				zap.Int("expires_in", tokenResp.ExpiresIn),
+			)
+			return tokenResp, nil
+		}
+		if !retry || attempt == tokenExchangeAttempts || ctx.Err() != nil {
+			return nil, err
+		}
+
+		s.logger.Warn("Token request attempt failed, retrying",
+			zap.String("op", op),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s cancelled: %w", op, ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+// exchangeOnce makes a single token request. retry reports whether a failure
+// is transient and worth another attempt.
+func (s *DeviceAuthService) exchangeOnce(ctx context.Context, client *http.Client, op, tokenURL, bearer string, jsonData []byte) (tokenResp *TokenResponse, retry bool, err error) {
 	// Create HTTP request
-	req, err := http.NewRequest("POST", tokenURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, bytes.NewReader(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-
-	// Send request
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
 	}
 
+	// Send request
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, true, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, true, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Accept both 200 OK and 201 Created as success
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("token exchange failed: status %d, body: %s", resp.StatusCode, string(body))
+		tokenErr := &TokenError{Op: op, StatusCode: resp.StatusCode, Body: string(body)}
+		return nil, !tokenErr.Rejected(), tokenErr
 	}
 
 	// Parse response
-	var tokenResp TokenResponse
-	if err := json.Unmarshal(body, &tokenResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	tokenResp = &TokenResponse{}
+	if err := json.Unmarshal(body, tokenResp); err != nil {
+		return nil, false, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	s.logger.Info("Device token received",
-		zap.String("device_id", tokenResp.DeviceID),
-		zap.Int("expires_in", tokenResp.ExpiresIn),
-	)
-
-	return &tokenResp, nil
+	return tokenResp, false, nil
 }
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+
+	"go.uber.org/zap"
+)
+
+// exchangeBackend answers token exchanges with the statuses in failures, in
+// order, then with a token
+func exchangeBackend(t *testing.T, failures ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if r.URL.Path != "/auth/device/token" {
+			http.Error(w, "unexpected path", http.StatusNotFound)
+			return
+		}
+		n := int(requests.Add(1))
+		if n <= len(failures) {
+			http.Error(w, "failure", failures[n-1])
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "token-1", DeviceID: "device-1", ExpiresIn: 3600})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newTestDeviceAuth(baseURL string) *DeviceAuthService {
+	return NewDeviceAuthService(platform.NewFakePlatform(), 0, baseURL, zap.NewNop())
+}
+
+func TestExchangeCodeRetriesTransientFailures(t *testing.T) {
+	server, requests := exchangeBackend(t, http.StatusServiceUnavailable)
+
+	started := time.Now()
+	resp, err := newTestDeviceAuth(server.URL).ExchangeCodeForToken(context.Background(), "code", "device-1")
+	if err != nil {
+		t.Fatalf("ExchangeCodeForToken: %v", err)
+	}
+	if resp.AccessToken != "token-1" {
+		t.Fatalf("token = %q, want token-1", resp.AccessToken)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("made %d requests, want 2", got)
+	}
+	if elapsed := time.Since(started); elapsed < tokenExchangeBackoff {
+		t.Errorf("retried after %s, want at least %s", elapsed, tokenExchangeBackoff)
+	}
+}
+
+func TestExchangeCodeFailsImmediatelyOnRejection(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound} {
+		server, requests := exchangeBackend(t, status)
+
+		_, err := newTestDeviceAuth(server.URL).ExchangeCodeForToken(context.Background(), "code", "device-1")
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) || tokenErr.StatusCode != status || !tokenErr.Rejected() {
+			t.Fatalf("status %d: err = %v, want a rejected TokenError", status, err)
+		}
+		if got := requests.Load(); got != 1 {
+			t.Fatalf("status %d: made %d requests, want 1", status, got)
+		}
+	}
+}
+
+func TestExchangeCodeCancelledDuringBackoff(t *testing.T) {
+	server, requests := exchangeBackend(t, http.StatusTooManyRequests)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := newTestDeviceAuth(server.URL).ExchangeCodeForToken(ctx, "code", "device-1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the context's error", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("made %d requests, want 1", got)
+	}
+}
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// refreshCooldown is the minimum time between refresh attempts, so a backend
+// that keeps rejecting the token can't trap the agent in a loop
+const refreshCooldown = 10 * time.Minute
+
+// refreshLead is how long before expiry the token is refreshed proactively
+const refreshLead = 5 * time.Minute
+
+// autoRefreshRecheck is how often the auto-refresh loop looks again while
+// the token's expiry is unknown
+const autoRefreshRecheck = time.Hour
+
+// ErrReauthRequired is returned by RefreshToken once the backend has refused
+// to refresh the device token. Only the interactive Reauthorize clears it.
+var ErrReauthRequired = errors.New("device must be authorized again")
+
+// TokenManager tracks the device token's expiry and renews it without user
+// interaction. When the backend refuses a renewal the device is marked as
+// needing re-authorization, which the user completes from the tray.
+type TokenManager struct {
+	authService *DeviceAuthService
+	deviceID    string
+	deviceName  string
+	onRefresh   func(token string) // Called with the new token, e.g. to persist it
+	logger      *zap.Logger
+
+	mu          sync.Mutex
+	token       string
+	expiresAt   time.Time
+	lastAttempt time.Time
+	refreshing  bool
+	needsReauth bool
+
+	stopAutoRefresh chan struct{} // nil unless StartAutoRefresh was called
+	autoRefreshDone chan struct{}
+}
+
+// NewTokenManager creates a new token manager
+func NewTokenManager(
+	authService *DeviceAuthService,
+	deviceID string,
+	deviceName string,
+	onRefresh func(token string),
+	logger *zap.Logger,
+) *TokenManager {
+	return &TokenManager{
+		authService: authService,
+		deviceID:    deviceID,
+		deviceName:  deviceName,
+		onRefresh:   onRefresh,
+		logger:      logger,
+	}
+}
+
+// SetToken records the current device token, which refreshes are made with
+func (tm *TokenManager) SetToken(token string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.token = token
+}
+
+// SetExpiresIn records the lifetime (in seconds) of the current token
+func (tm *TokenManager) SetExpiresIn(seconds int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.setExpiresInLocked(seconds)
+}
+
+func (tm *TokenManager) setExpiresInLocked(seconds int) {
+	if seconds <= 0 {
+		tm.expiresAt = time.Time{}
+		return
+	}
+	tm.expiresAt = time.Now().Add(time.Duration(seconds) * time.Second)
+}
+
+// ExpiresAt returns when the current token expires (zero if unknown)
+func (tm *TokenManager) ExpiresAt() time.Time {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.expiresAt
+}
+
+// NeedsReauth reports whether the backend refused to refresh the token, so
+// the user has to authorize the device again
+func (tm *TokenManager) NeedsReauth() bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.needsReauth
+}
+
+// RefreshToken exchanges the current token for a new one and returns it.
+// It never opens a browser: if the backend refuses, the device is marked as
+// needing re-authorization and ErrReauthRequired is returned from then on.
+// Only one refresh runs at a time and attempts are rate limited; callers
+// that hit either guard get an error instead of a new token.
+func (tm *TokenManager) RefreshToken(ctx context.Context) (string, error) {
+	tm.mu.Lock()
+	if tm.needsReauth {
+		tm.mu.Unlock()
+		return "", ErrReauthRequired
+	}
+	if tm.refreshing {
+		tm.mu.Unlock()
+		return "", fmt.Errorf("token refresh already in progress")
+	}
+	if !tm.lastAttempt.IsZero() && time.Since(tm.lastAttempt) < refreshCooldown {
+		last := tm.lastAttempt
+		tm.mu.Unlock()
+		return "", fmt.Errorf("token refresh attempted %s ago, next attempt allowed after %s",
+			time.Since(last).Round(time.Second), refreshCooldown)
+	}
+	tm.refreshing = true
+	tm.lastAttempt = time.Now()
+	token := tm.token
+	tm.mu.Unlock()
+
+	defer func() {
+		tm.mu.Lock()
+		tm.refreshing = false
+		tm.mu.Unlock()
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	tm.logger.Info("Refreshing device token")
+
+	tokenResp, err := tm.authService.RefreshDeviceToken(ctx, token, tm.deviceID)
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) && tokenErr.Rejected() {
+		tm.mu.Lock()
+		tm.needsReauth = true
+		tm.mu.Unlock()
+		tm.logger.Warn("Backend refused to refresh the device token; sign in again from the tray menu",
+			zap.Error(err),
+		)
+		return "", fmt.Errorf("%w: %v", ErrReauthRequired, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("token refresh failed: %w", err)
+	}
+
+	tm.store(tokenResp)
+	tm.logger.Info("Device token refreshed",
+		zap.Int("expires_in", tokenResp.ExpiresIn),
+	)
+	return tokenResp.AccessToken, nil
+}
+
+// Reauthorize runs the interactive device authorization flow, opening the
+// browser for the user to sign in, and returns the new token. It is meant
+// for an explicit user action such as the tray's sign-in item, never for
+// the send path.
+func (tm *TokenManager) Reauthorize(ctx context.Context) (string, error) {
+	code, err := tm.authService.AuthorizeDevice(tm.deviceID, tm.deviceName)
+	if err != nil {
+		return "", fmt.Errorf("re-authorization failed: %w", err)
+	}
+
+	tokenResp, err := tm.authService.ExchangeCodeForToken(ctx, code, tm.deviceID)
+	if err != nil {
+		return "", fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	tm.mu.Lock()
+	tm.needsReauth = false
+	tm.lastAttempt = time.Time{}
+	tm.mu.Unlock()
+
+	tm.store(tokenResp)
+	tm.logger.Info("Device re-authorized",
+		zap.Int("expires_in", tokenResp.ExpiresIn),
+	)
+	return tokenResp.AccessToken, nil
+}
+
+// store makes tokenResp the current token and persists it
+func (tm *TokenManager) store(tokenResp *TokenResponse) {
+	tm.mu.Lock()
+	tm.token = tokenResp.AccessToken
+	tm.setExpiresInLocked(tokenResp.ExpiresIn)
+	tm.mu.Unlock()
+
+	if tm.onRefresh != nil {
+		tm.onRefresh(tokenResp.AccessToken)
+	}
+}
+
+// StartAutoRefresh refreshes the token refreshLead before it expires,
+// passing each new token to apply (e.g. the API client's SetDeviceToken).
+// Tokens of unknown lifetime are only refreshed when the backend rejects
+// them.
+func (tm *TokenManager) StartAutoRefresh(apply func(token string)) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.stopAutoRefresh != nil {
+		return
+	}
+	tm.stopAutoRefresh = make(chan struct{})
+	tm.autoRefreshDone = make(chan struct{})
+	go tm.autoRefreshLoop(apply, tm.stopAutoRefresh, tm.autoRefreshDone)
+}
+
+// StopAutoRefresh stops the loop started by StartAutoRefresh
+func (tm *TokenManager) StopAutoRefresh() {
+	tm.mu.Lock()
+	stop, done := tm.stopAutoRefresh, tm.autoRefreshDone
+	tm.stopAutoRefresh, tm.autoRefreshDone = nil, nil
+	tm.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (tm *TokenManager) autoRefreshLoop(apply func(token string), stop, done chan struct{}) {
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		timer := time.NewTimer(tm.nextRefreshIn(time.Now()))
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return
+		}
+
+		if tm.nextRefreshIn(time.Now()) > 0 {
+			continue
+		}
+		token, err := tm.RefreshToken(ctx)
+		if err != nil {
+			tm.logger.Warn("Proactive device token refresh failed", zap.Error(err))
+			continue
+		}
+		apply(token)
+	}
+}
+
+// nextRefreshIn returns how long until the token should be refreshed: 0 if
+// it is due, the cooldown if the last attempt was too recent, or
+// autoRefreshRecheck while the expiry is unknown or re-authorization is
+// pending
+func (tm *TokenManager) nextRefreshIn(now time.Time) time.Duration {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.expiresAt.IsZero() || tm.needsReauth {
+		return autoRefreshRecheck
+	}
+	wait := tm.expiresAt.Add(-refreshLead).Sub(now)
+	if !tm.lastAttempt.IsZero() {
+		if cooldown := tm.lastAttempt.Add(refreshCooldown).Sub(now); cooldown > wait {
+			wait = cooldown
+		}
+	}
+	return max(wait, 0)
+}
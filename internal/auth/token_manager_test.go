@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/client"
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+
+	"go.uber.org/zap"
+)
+
+// tokenBackend accepts batches sent with validToken and answers device token
+// refreshes with refresh
+type tokenBackend struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	validToken string
+	refresh    func(w http.ResponseWriter, bearer string)
+	refreshes  []string // bearer tokens refreshes were made with
+	batches    int
+}
+
+func newTokenBackend(t *testing.T, validToken string, refresh func(w http.ResponseWriter, bearer string)) *tokenBackend {
+	t.Helper()
+	b := &tokenBackend{validToken: validToken, refresh: refresh}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		switch {
+		case r.URL.Path == "/auth/device/refresh":
+			b.refreshes = append(b.refreshes, bearer)
+			b.refresh(w, bearer)
+		case strings.HasPrefix(r.URL.Path, "/auth/"):
+			http.Error(w, "unexpected auth request", http.StatusTeapot)
+		default:
+			b.batches++
+			if bearer != b.validToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+func (b *tokenBackend) Refreshes() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.refreshes...)
+}
+
+func newTestTokenManager(baseURL string, fake *platform.FakePlatform, onRefresh func(string)) *TokenManager {
+	service := NewDeviceAuthService(fake, 0, baseURL, zap.NewNop())
+	return NewTokenManager(service, "device-1", "Test Device", onRefresh, zap.NewNop())
+}
+
+func newRefreshingClient(baseURL, token string, tm *TokenManager) *client.APIClient {
+	c := client.NewAPIClient(baseURL, "", 5*time.Second, zap.NewNop())
+	c.SetDeviceToken(token)
+	c.SetTokenRefresher(tm)
+	return c
+}
+
+func testBatch() []models.TrackingEvent {
+	duration := int64(1000)
+	application := "app.exe"
+	return []models.TrackingEvent{{
+		EventID:     "event-1",
+		DeviceID:    "device-1",
+		Timestamp:   time.Now().UnixMilli(),
+		Status:      models.StatusActive,
+		Duration:    &duration,
+		Application: &application,
+	}}
+}
+
+func TestRefreshTokenReplacesRejectedToken(t *testing.T) {
+	backend := newTokenBackend(t, "new-token", func(w http.ResponseWriter, bearer string) {
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "new-token", DeviceID: "device-1", ExpiresIn: 3600})
+	})
+	fake := platform.NewFakePlatform()
+	var saved []string
+	tm := newTestTokenManager(backend.URL, fake, func(token string) { saved = append(saved, token) })
+	tm.SetToken("old-token")
+	c := newRefreshingClient(backend.URL, "old-token", tm)
+
+	if err := c.SendBatch(context.Background(), "device-1", testBatch()); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+
+	if got := backend.Refreshes(); len(got) != 1 || got[0] != "old-token" {
+		t.Errorf("refreshes made with %v, want [old-token]", got)
+	}
+	if len(saved) != 1 || saved[0] != "new-token" {
+		t.Errorf("onRefresh got %v, want [new-token]", saved)
+	}
+	if until := time.Until(tm.ExpiresAt()); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("token expires in %s, want about 1h", until)
+	}
+	if tm.NeedsReauth() {
+		t.Error("NeedsReauth after a successful refresh")
+	}
+	if urls := fake.OpenedURLs(); len(urls) != 0 {
+		t.Errorf("refresh opened the browser: %v", urls)
+	}
+}
+
+func TestRejectedRefreshDoesNotLoop(t *testing.T) {
+	backend := newTokenBackend(t, "valid-token", func(w http.ResponseWriter, bearer string) {
+		http.Error(w, "token revoked", http.StatusUnauthorized)
+	})
+	fake := platform.NewFakePlatform()
+	tm := newTestTokenManager(backend.URL, fake, func(string) {
+		t.Error("onRefresh called for a rejected refresh")
+	})
+	tm.SetToken("revoked-token")
+	c := newRefreshingClient(backend.URL, "revoked-token", tm)
+
+	for i := 0; i < 3; i++ {
+		done := make(chan error, 1)
+		go func() { done <- c.SendBatch(context.Background(), "device-1", testBatch()) }()
+
+		select {
+		case err := <-done:
+			var authErr *client.AuthError
+			if !errors.As(err, &authErr) || authErr.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("send %d: got %v, want a 401 AuthError", i, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("send %d blocked on token refresh", i)
+		}
+	}
+
+	if got := backend.Refreshes(); len(got) != 1 {
+		t.Errorf("refresh endpoint hit %d times, want 1", len(got))
+	}
+	if !tm.NeedsReauth() {
+		t.Error("NeedsReauth = false after the backend refused the refresh")
+	}
+	if _, err := tm.RefreshToken(context.Background()); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("RefreshToken after rejection = %v, want ErrReauthRequired", err)
+	}
+	if urls := fake.OpenedURLs(); len(urls) != 0 {
+		t.Errorf("refresh opened the browser: %v", urls)
+	}
+}
+
+func TestRefreshTokenCooldownAfterFailure(t *testing.T) {
+	backend := newTokenBackend(t, "valid-token", func(w http.ResponseWriter, bearer string) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	tm := newTestTokenManager(backend.URL, platform.NewFakePlatform(), nil)
+	tm.SetToken("token")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // skip the retry backoff; the attempt itself still counts
+
+	if _, err := tm.RefreshToken(ctx); err == nil {
+		t.Fatal("RefreshToken succeeded with a cancelled context")
+	}
+	if _, err := tm.RefreshToken(context.Background()); err == nil || !strings.Contains(err.Error(), "next attempt allowed") {
+		t.Errorf("second RefreshToken = %v, want the cooldown error", err)
+	}
+	if tm.NeedsReauth() {
+		t.Error("NeedsReauth after a failure that wasn't a rejection")
+	}
+}
+
+func TestNextRefreshIn(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		expiresAt   time.Time
+		lastAttempt time.Time
+		needsReauth bool
+		want        time.Duration
+	}{
+		{name: "unknown expiry", want: autoRefreshRecheck},
+		{name: "ahead of lead", expiresAt: now.Add(time.Hour), want: time.Hour - refreshLead},
+		{name: "within lead", expiresAt: now.Add(time.Minute), want: 0},
+		{name: "expired", expiresAt: now.Add(-time.Minute), want: 0},
+		{
+			name:        "recent attempt",
+			expiresAt:   now.Add(time.Minute),
+			lastAttempt: now.Add(-time.Minute),
+			want:        refreshCooldown - time.Minute,
+		},
+		{
+			name:        "needs reauth",
+			expiresAt:   now.Add(time.Minute),
+			needsReauth: true,
+			want:        autoRefreshRecheck,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := newTestTokenManager("http://unused", platform.NewFakePlatform(), nil)
+			tm.expiresAt = tt.expiresAt
+			tm.lastAttempt = tt.lastAttempt
+			tm.needsReauth = tt.needsReauth
+
+			if got := tm.nextRefreshIn(now); got != tt.want {
+				t.Errorf("nextRefreshIn = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoRefreshBeforeExpiry(t *testing.T) {
+	backend := newTokenBackend(t, "new-token", func(w http.ResponseWriter, bearer string) {
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "new-token", DeviceID: "device-1", ExpiresIn: 3600})
+	})
+	tm := newTestTokenManager(backend.URL, platform.NewFakePlatform(), nil)
+	tm.SetToken("old-token")
+	tm.SetExpiresIn(60) // inside refreshLead, so due immediately
+
+	applied := make(chan string, 1)
+	tm.StartAutoRefresh(func(token string) { applied <- token })
+	defer tm.StopAutoRefresh()
+
+	select {
+	case token := <-applied:
+		if token != "new-token" {
+			t.Errorf("applied %q, want new-token", token)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("token was not refreshed before expiry")
+	}
+	if got := backend.Refreshes(); len(got) != 1 {
+		t.Errorf("refresh endpoint hit %d times, want 1", len(got))
+	}
+}
@@ -0,0 +1,194 @@
+package automation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultMinInterval is the minimum time between firings of one rule
+	// when the rule doesn't set its own
+	defaultMinInterval = 60 * time.Second
+	// actionTimeout bounds a single webhook call or command
+	actionTimeout = 10 * time.Second
+	// queueSize is how many events may wait for evaluation before new ones are dropped
+	queueSize = 256
+)
+
+// Rule fires a webhook and/or command when an event matches all of its
+// non-empty criteria
+type Rule struct {
+	Name        string
+	Application string   // Case-insensitive match on the event application
+	Domain      string   // Matches the URL host or any subdomain of it
+	Status      string   // active, idle, away, offline
+	Webhook     string   // URL that receives the event as a JSON POST
+	Command     []string // Program and arguments; the event JSON is written to stdin
+	MinInterval time.Duration
+}
+
+// Matches reports whether event satisfies the rule's criteria
+func (r *Rule) Matches(event *models.TrackingEvent) bool {
+	if r.Application != "" {
+		if event.Application == nil || !strings.EqualFold(*event.Application, r.Application) {
+			return false
+		}
+	}
+	if r.Status != "" && !strings.EqualFold(event.Status, r.Status) {
+		return false
+	}
+	if r.Domain != "" {
+		if event.URL == nil {
+			return false
+		}
+		u, err := url.Parse(*event.URL)
+		if err != nil {
+			return false
+		}
+		host := strings.ToLower(u.Hostname())
+		domain := strings.ToLower(r.Domain)
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return false
+		}
+	}
+	return true
+}
+
+// Engine evaluates rules against events on a background worker so matching
+// and actions never block the tracking path
+type Engine struct {
+	rules      []Rule
+	events     chan models.TrackingEvent
+	lastFired  map[int]time.Time
+	httpClient *http.Client
+	logger     *zap.Logger
+	stopChan   chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewEngine creates a rule engine
+func NewEngine(rules []Rule, logger *zap.Logger) *Engine {
+	for i := range rules {
+		if rules[i].MinInterval <= 0 {
+			rules[i].MinInterval = defaultMinInterval
+		}
+	}
+
+	return &Engine{
+		rules:      rules,
+		events:     make(chan models.TrackingEvent, queueSize),
+		lastFired:  make(map[int]time.Time),
+		httpClient: &http.Client{Timeout: actionTimeout},
+		logger:     logger,
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start starts the worker
+func (e *Engine) Start() {
+	e.wg.Add(1)
+	go e.worker()
+
+	e.logger.Info("Automation rules started", zap.Int("rule_count", len(e.rules)))
+}
+
+// Stop stops the worker; events still queued are discarded
+func (e *Engine) Stop() {
+	close(e.stopChan)
+	e.wg.Wait()
+}
+
+// Submit queues an event for evaluation without blocking. If the worker is
+// backed up the event is dropped.
+func (e *Engine) Submit(event models.TrackingEvent) {
+	select {
+	case e.events <- event:
+	default:
+		e.logger.Debug("Automation queue full, dropping event")
+	}
+}
+
+func (e *Engine) worker() {
+	defer e.wg.Done()
+
+	for {
+		select {
+		case event := <-e.events:
+			e.evaluate(&event, time.Now())
+		case <-e.stopChan:
+			return
+		}
+	}
+}
+
+// evaluate fires every matching rule that isn't within its rate limit
+func (e *Engine) evaluate(event *models.TrackingEvent, now time.Time) {
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !rule.Matches(event) {
+			continue
+		}
+		if last, ok := e.lastFired[i]; ok && now.Sub(last) < rule.MinInterval {
+			continue
+		}
+		e.lastFired[i] = now
+
+		e.logger.Debug("Automation rule matched", zap.String("rule", rule.Name))
+		if err := e.fire(rule, event); err != nil {
+			e.logger.Warn("Automation action failed",
+				zap.String("rule", rule.Name),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// fire runs the rule's webhook and command with the event as JSON
+func (e *Engine) fire(rule *Rule, event *models.TrackingEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+
+	if rule.Webhook != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.Webhook, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("failed to create webhook request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := e.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("webhook failed: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		}
+	}
+
+	if len(rule.Command) > 0 {
+		cmd := exec.CommandContext(ctx, rule.Command[0], rule.Command[1:]...)
+		cmd.Stdin = bytes.NewReader(payload)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("command failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	return nil
+}
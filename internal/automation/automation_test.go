@@ -0,0 +1,155 @@
+package automation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// webhookRecorder is a webhook endpoint that records the events posted to it
+type webhookRecorder struct {
+	*httptest.Server
+	mu     sync.Mutex
+	events []models.TrackingEvent
+}
+
+func newWebhookRecorder(t *testing.T) *webhookRecorder {
+	t.Helper()
+	w := &webhookRecorder{}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var event models.TrackingEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.mu.Lock()
+		w.events = append(w.events, event)
+		w.mu.Unlock()
+	}))
+	t.Cleanup(w.Close)
+	return w
+}
+
+func (w *webhookRecorder) Events() []models.TrackingEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]models.TrackingEvent(nil), w.events...)
+}
+
+func appEvent(id, application, status string) *models.TrackingEvent {
+	return &models.TrackingEvent{EventID: id, Application: &application, Status: status}
+}
+
+func urlEvent(id, rawURL string) *models.TrackingEvent {
+	return &models.TrackingEvent{EventID: id, URL: &rawURL, Status: models.StatusActive}
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		event *models.TrackingEvent
+		want  bool
+	}{
+		{"application case-insensitive", Rule{Application: "Slack.exe"}, appEvent("e", "slack.exe", "active"), true},
+		{"application differs", Rule{Application: "slack.exe"}, appEvent("e", "code.exe", "active"), false},
+		{"application missing", Rule{Application: "slack.exe"}, urlEvent("e", "https://slack.com"), false},
+		{"status", Rule{Status: "idle"}, appEvent("e", "code.exe", "idle"), true},
+		{"status differs", Rule{Status: "idle"}, appEvent("e", "code.exe", "active"), false},
+		{"domain exact", Rule{Domain: "github.com"}, urlEvent("e", "https://github.com/x"), true},
+		{"subdomain", Rule{Domain: "github.com"}, urlEvent("e", "https://gist.github.com/x"), true},
+		{"lookalike domain", Rule{Domain: "github.com"}, urlEvent("e", "https://notgithub.com/x"), false},
+		{"domain without url", Rule{Domain: "github.com"}, appEvent("e", "code.exe", "active"), false},
+		{
+			"all criteria",
+			Rule{Application: "code.exe", Status: "active"},
+			appEvent("e", "code.exe", "active"),
+			true,
+		},
+		{
+			"one criterion fails",
+			Rule{Application: "code.exe", Status: "idle"},
+			appEvent("e", "code.exe", "active"),
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(tt.event); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateFiresMatchingRulesOnly(t *testing.T) {
+	webhook := newWebhookRecorder(t)
+	e := NewEngine([]Rule{
+		{Name: "slack", Application: "slack.exe", Webhook: webhook.URL},
+	}, zap.NewNop())
+
+	now := time.Now()
+	e.evaluate(appEvent("miss", "code.exe", "active"), now)
+	e.evaluate(appEvent("hit", "slack.exe", "active"), now)
+
+	events := webhook.Events()
+	if len(events) != 1 || events[0].EventID != "hit" {
+		t.Fatalf("webhook got %v, want only the matching event", events)
+	}
+}
+
+func TestEvaluateRespectsMinInterval(t *testing.T) {
+	webhook := newWebhookRecorder(t)
+	e := NewEngine([]Rule{
+		{Name: "any", Status: "active", Webhook: webhook.URL, MinInterval: time.Minute},
+	}, zap.NewNop())
+
+	start := time.Now()
+	e.evaluate(appEvent("first", "code.exe", "active"), start)
+	e.evaluate(appEvent("too-soon", "code.exe", "active"), start.Add(30*time.Second))
+	e.evaluate(appEvent("after-interval", "code.exe", "active"), start.Add(time.Minute))
+
+	var ids []string
+	for _, event := range webhook.Events() {
+		ids = append(ids, event.EventID)
+	}
+	if len(ids) != 2 || ids[0] != "first" || ids[1] != "after-interval" {
+		t.Fatalf("webhook got %v, want [first after-interval]", ids)
+	}
+}
+
+func TestMinIntervalDefaultsWhenUnset(t *testing.T) {
+	e := NewEngine([]Rule{{Name: "unset"}, {Name: "set", MinInterval: time.Second}}, zap.NewNop())
+
+	if got := e.rules[0].MinInterval; got != defaultMinInterval {
+		t.Errorf("unset MinInterval = %s, want %s", got, defaultMinInterval)
+	}
+	if got := e.rules[1].MinInterval; got != time.Second {
+		t.Errorf("set MinInterval = %s, want 1s", got)
+	}
+}
+
+func TestSubmitFiresInBackground(t *testing.T) {
+	webhook := newWebhookRecorder(t)
+	e := NewEngine([]Rule{{Name: "slack", Application: "slack.exe", Webhook: webhook.URL}}, zap.NewNop())
+	e.Start()
+	defer e.Stop()
+
+	e.Submit(*appEvent("hit", "slack.exe", "active"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(webhook.Events()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("submitted event never reached the webhook")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -2,10 +2,17 @@ package client
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"Mansoor88-6/time-tracking-agent/internal/models"
@@ -13,22 +20,50 @@ import (
 	"go.uber.org/zap"
 )
 
+// TokenRefresher obtains a new device token after the backend rejects the
+// current one
+type TokenRefresher interface {
+	RefreshToken(ctx context.Context) (string, error)
+}
+
 // APIClient handles communication with the backend API
 type APIClient struct {
-	baseURL     string
+	endpointsMu sync.RWMutex
+	baseURLs    []string // primary first, then fallbacks
+	active      int      // index of the base URL tried first
+	version     APIVersion
 	apiKey      string
 	deviceToken string // JWT token for device authentication
 	timeout     time.Duration
 	httpClient  *http.Client
 	logger      *zap.Logger
+
+	tokenMu        sync.RWMutex
+	tokenRefresher TokenRefresher
+
+	// compressionThreshold is the payload size in bytes above which batches
+	// are gzip-compressed; 0 disables compression
+	compressionThreshold int
+
+	// maxPayloadBytes caps the uncompressed JSON size of one batch request;
+	// larger batches are split. 0 disables splitting.
+	maxPayloadBytes int
+
+	// fieldMapping renames JSON fields for the backend's contract; nil
+	// sends the default names
+	fieldMapping *FieldMapping
+
+	// signingSecret, when set, HMAC-signs batch requests
+	signingSecret []byte
 }
 
 // NewAPIClient creates a new API client
 func NewAPIClient(baseURL, apiKey string, timeout time.Duration, logger *zap.Logger) *APIClient {
 	return &APIClient{
-		baseURL: baseURL,
-		apiKey:  apiKey,
-		timeout: timeout,
+		baseURLs: []string{baseURL},
+		version:  APIVersionV1,
+		apiKey:   apiKey,
+		timeout:  timeout,
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
@@ -38,36 +73,151 @@ func NewAPIClient(baseURL, apiKey string, timeout time.Duration, logger *zap.Log
 
 // SetDeviceToken sets the device JWT token
 func (c *APIClient) SetDeviceToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
 	c.deviceToken = token
 }
 
-// SendBatch sends a batch of events to the backend
-func (c *APIClient) SendBatch(deviceID string, events []models.TrackingEvent) error {
+// SetTokenRefresher sets the refresher used to obtain a new device token
+// when the backend responds with 401 Unauthorized
+func (c *APIClient) SetTokenRefresher(refresher TokenRefresher) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.tokenRefresher = refresher
+}
+
+// SetTransport replaces the HTTP transport, e.g. one built by NewTransport
+// for a private CA or mutual TLS
+func (c *APIClient) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+}
+
+// SetCompressionThreshold enables gzip compression for batch payloads larger
+// than threshold bytes. A threshold of 0 disables compression.
+func (c *APIClient) SetCompressionThreshold(threshold int) {
+	c.compressionThreshold = threshold
+}
+
+// SetMaxPayloadBytes splits batches whose JSON body would exceed maxBytes
+// into several requests. 0 or negative disables splitting.
+func (c *APIClient) SetMaxPayloadBytes(maxBytes int) {
+	c.maxPayloadBytes = maxBytes
+}
+
+// endpoint joins path elements onto baseURL
+func endpoint(baseURL string, elem ...string) (string, error) {
+	endpoint, err := url.JoinPath(baseURL, elem...)
+	if err != nil {
+		return "", fmt.Errorf("failed to build endpoint URL: %w", err)
+	}
+	return endpoint, nil
+}
+
+// SendBatch sends a batch of events to the backend. Cancelling ctx aborts
+// an in-flight request. If the device token is rejected with 401 and a
+// token refresher is set, the token is refreshed and the batch retried once.
+// Batches over the payload limit go out as several requests; if one fails
+// the error is returned and the whole batch should be retried, relying on
+// event IDs to drop the parts the backend already has.
+func (c *APIClient) SendBatch(ctx context.Context, deviceID string, events []models.TrackingEvent) error {
+	parts, err := splitBatch(deviceID, events, c.maxPayloadBytes, c.Version(), c.fieldMapping)
+	if err != nil {
+		return err
+	}
+	if len(parts) > 1 {
+		c.logger.Info("Splitting batch to fit the payload limit",
+			zap.Int("event_count", len(events)),
+			zap.Int("requests", len(parts)),
+			zap.Int("max_payload_bytes", c.maxPayloadBytes),
+		)
+	}
+
+	for _, part := range parts {
+		if err := c.sendWithRefresh(ctx, deviceID, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendWithRefresh sends one request, refreshing the device token and
+// retrying once on 401
+func (c *APIClient) sendWithRefresh(ctx context.Context, deviceID string, events []models.TrackingEvent) error {
+	err := c.sendBatch(ctx, deviceID, events)
+
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.StatusCode != http.StatusUnauthorized {
+		return err
+	}
+
+	c.tokenMu.RLock()
+	refresher := c.tokenRefresher
+	c.tokenMu.RUnlock()
+	if refresher == nil {
+		return err
+	}
+
+	token, refreshErr := refresher.RefreshToken(ctx)
+	if refreshErr != nil {
+		c.logger.Warn("Device token refresh failed", zap.Error(refreshErr))
+		return err
+	}
+	c.SetDeviceToken(token)
+
+	return c.sendBatch(ctx, deviceID, events)
+}
+
+// sendBatch sends one request in the negotiated API version, failing over
+// between base URLs. If the backend has no endpoint for a newer version the
+// client drops to v1 and resends.
+func (c *APIClient) sendBatch(ctx context.Context, deviceID string, events []models.TrackingEvent) error {
 	if len(events) == 0 {
 		return fmt.Errorf("cannot send empty batch")
 	}
-
-	batch := models.BatchEventRequest{
-		Events:        events,
-		DeviceID:      deviceID,
-		BatchTimestamp: time.Now().UnixMilli(),
+	version := c.Version()
+	err := c.withFailover(ctx, func(baseURL string) error {
+		return c.sendBatchTo(ctx, baseURL, deviceID, events, version)
+	})
+	if c.downgradeOn404(err, version) {
+		err = c.withFailover(ctx, func(baseURL string) error {
+			return c.sendBatchTo(ctx, baseURL, deviceID, events, APIVersionV1)
+		})
 	}
+	return err
+}
+
+func (c *APIClient) sendBatchTo(ctx context.Context, baseURL, deviceID string, events []models.TrackingEvent, version APIVersion) error {
 
-	jsonData, err := json.Marshal(batch)
+	jsonData, err := c.fieldMapping.marshalBody(batchBody(version, deviceID, events, time.Now()))
 	if err != nil {
 		return fmt.Errorf("failed to marshal batch: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/api/v1/events/batch", c.baseURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	endpoint, err := endpoint(baseURL, batchPath(version)...)
+	if err != nil {
+		return err
+	}
+	payload, compressed, err := c.encodeBody(jsonData)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(payload))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	c.signRequest(req, payload, time.Now())
 	// Prefer device token over API key
-	if c.deviceToken != "" {
-		req.Header.Set("Authorization", "Bearer "+c.deviceToken)
+	c.tokenMu.RLock()
+	deviceToken := c.deviceToken
+	c.tokenMu.RUnlock()
+	if deviceToken != "" {
+		req.Header.Set("Authorization", "Bearer "+deviceToken)
 	} else if c.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	}
@@ -91,6 +241,8 @@ func (c *APIClient) SendBatch(deviceID string, events []models.TrackingEvent) er
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		c.logger.Info("Batch sent successfully",
 			zap.Int("event_count", len(events)),
+			zap.Int("payload_bytes", len(payload)),
+			zap.Bool("compressed", compressed),
 			zap.Int("status_code", resp.StatusCode),
 			zap.Duration("duration", duration),
 		)
@@ -108,11 +260,16 @@ func (c *APIClient) SendBatch(deviceID string, events []models.TrackingEvent) er
 		)
 		return &AuthError{Message: errMsg, StatusCode: resp.StatusCode}
 	case http.StatusTooManyRequests:
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
 		c.logger.Warn("Rate limited",
 			zap.Int("status_code", resp.StatusCode),
+			zap.Duration("retry_after", retryAfter),
 		)
-		return &RateLimitError{Message: errMsg, StatusCode: resp.StatusCode}
-	case http.StatusBadRequest:
+		return &RateLimitError{Message: errMsg, StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		// With max_payload_bytes at or below the backend's limit, a batch is
+		// only too large here if a single event exceeds it, so retrying
+		// can't help
 		c.logger.Error("Invalid request",
 			zap.Int("status_code", resp.StatusCode),
 			zap.String("response", string(body)),
@@ -127,10 +284,41 @@ func (c *APIClient) SendBatch(deviceID string, events []models.TrackingEvent) er
 	}
 }
 
-// HealthCheck checks if the backend is reachable
-func (c *APIClient) HealthCheck() error {
-	url := fmt.Sprintf("%s/health", c.baseURL)
-	resp, err := c.httpClient.Get(url)
+// encodeBody gzip-compresses payloads above the compression threshold.
+// Small payloads are returned unchanged to avoid wasting CPU.
+func (c *APIClient) encodeBody(payload []byte) ([]byte, bool, error) {
+	if c.compressionThreshold <= 0 || len(payload) <= c.compressionThreshold {
+		return payload, false, nil
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(payload); err != nil {
+		return nil, false, fmt.Errorf("failed to compress batch: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, false, fmt.Errorf("failed to compress batch: %w", err)
+	}
+
+	return buf.Bytes(), true, nil
+}
+
+// HealthCheck checks if the backend endpoint currently in use is reachable
+func (c *APIClient) HealthCheck(ctx context.Context) error {
+	return c.healthCheckAt(ctx, c.activeURL())
+}
+
+// healthCheckAt checks if the backend at baseURL is reachable
+func (c *APIClient) healthCheckAt(ctx context.Context, baseURL string) error {
+	endpoint, err := endpoint(baseURL, "health")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("health check failed: %w", err)
 	}
@@ -144,8 +332,21 @@ func (c *APIClient) HealthCheck() error {
 }
 
 // ExchangeAuthorizationCode exchanges an authorization code for a device token
-func (c *APIClient) ExchangeAuthorizationCode(code, deviceID string) (map[string]interface{}, error) {
-	url := fmt.Sprintf("%s/auth/device/token", c.baseURL)
+func (c *APIClient) ExchangeAuthorizationCode(ctx context.Context, code, deviceID string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := c.withFailover(ctx, func(baseURL string) error {
+		var err error
+		result, err = c.exchangeAuthorizationCodeAt(ctx, baseURL, code, deviceID)
+		return err
+	})
+	return result, err
+}
+
+func (c *APIClient) exchangeAuthorizationCodeAt(ctx context.Context, baseURL, code, deviceID string) (map[string]interface{}, error) {
+	endpoint, err := endpoint(baseURL, "auth", "device", "token")
+	if err != nil {
+		return nil, err
+	}
 
 	reqBody := map[string]string{
 		"code":     code,
@@ -157,7 +358,7 @@ func (c *APIClient) ExchangeAuthorizationCode(code, deviceID string) (map[string
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -176,7 +377,11 @@ func (c *APIClient) ExchangeAuthorizationCode(code, deviceID string) (map[string
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("token exchange failed: status %d, body: %s", resp.StatusCode, string(body))
+		errMsg := fmt.Sprintf("token exchange failed: status %d, body: %s", resp.StatusCode, string(body))
+		if resp.StatusCode >= 500 {
+			return nil, &BackendError{Message: errMsg, StatusCode: resp.StatusCode}
+		}
+		return nil, errors.New(errMsg)
 	}
 
 	var result map[string]interface{}
@@ -187,6 +392,31 @@ func (c *APIClient) ExchangeAuthorizationCode(code, deviceID string) (map[string
 	return result, nil
 }
 
+// parseRetryAfter parses a Retry-After header value in either the
+// delay-seconds or the HTTP-date form. It returns 0 for a missing or
+// malformed value so callers fall back to their default retry cadence.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
 // Error types
 type AuthError struct {
 	Message    string
@@ -200,6 +430,9 @@ func (e *AuthError) Error() string {
 type RateLimitError struct {
 	Message    string
 	StatusCode int
+	// RetryAfter is the delay requested by the backend via the Retry-After
+	// header. Zero means the header was missing or could not be parsed.
+	RetryAfter time.Duration
 }
 
 func (e *RateLimitError) Error() string {
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// recordedRequest is one request received by a testBackend
+type recordedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// testBackend is an httptest server that records requests and answers them
+// with handle, or 200 if handle is nil
+type testBackend struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []recordedRequest
+	handle   func(w http.ResponseWriter, r *http.Request, body []byte)
+}
+
+func newTestBackend(t *testing.T, handle func(w http.ResponseWriter, r *http.Request, body []byte)) *testBackend {
+	t.Helper()
+	b := &testBackend{handle: handle}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		b.mu.Lock()
+		b.requests = append(b.requests, recordedRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+		b.mu.Unlock()
+		if b.handle != nil {
+			b.handle(w, r, body)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+// Requests returns the requests received so far
+func (b *testBackend) Requests() []recordedRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]recordedRequest(nil), b.requests...)
+}
+
+func newTestClient(baseURL string) *APIClient {
+	return NewAPIClient(baseURL, "test-key", 5*time.Second, zap.NewNop())
+}
+
+// testEvents returns n distinct active events
+func testEvents(n int) []models.TrackingEvent {
+	events := make([]models.TrackingEvent, n)
+	for i := range events {
+		duration := int64(1000 * (i + 1))
+		application := fmt.Sprintf("app-%d.exe", i)
+		events[i] = models.TrackingEvent{
+			EventID:     fmt.Sprintf("event-%d", i),
+			DeviceID:    "device-1",
+			Timestamp:   int64(1700000000000 + i*1000),
+			Status:      models.StatusActive,
+			Duration:    &duration,
+			Application: &application,
+		}
+	}
+	return events
+}
+
+func gunzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	plain, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading gzip body: %v", err)
+	}
+	return plain
+}
+
+func TestSendBatchCompressesLargePayloads(t *testing.T) {
+	backend := newTestBackend(t, nil)
+	c := newTestClient(backend.URL)
+	c.SetCompressionThreshold(64)
+
+	events := testEvents(5)
+	if err := c.SendBatch(context.Background(), "device-1", events); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+
+	requests := backend.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if got := req.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+
+	got := gunzip(t, req.Body)
+	var sent models.BatchEventRequest
+	if err := json.Unmarshal(got, &sent); err != nil {
+		t.Fatalf("decompressed body is not a batch: %v", err)
+	}
+	want, err := c.fieldMapping.marshalBody(batchBody(APIVersionV1, "device-1", events, time.UnixMilli(sent.BatchTimestamp)))
+	if err != nil {
+		t.Fatalf("marshalBody: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("decompressed body = %s\nwant %s", got, want)
+	}
+}
+
+func TestSendBatchLeavesSmallPayloadsUncompressed(t *testing.T) {
+	backend := newTestBackend(t, nil)
+	c := newTestClient(backend.URL)
+	c.SetCompressionThreshold(1 << 20)
+
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+
+	req := backend.Requests()[0]
+	if got := req.Header.Get("Content-Encoding"); got != "" {
+		t.Fatalf("Content-Encoding = %q, want none", got)
+	}
+	if !bytes.HasPrefix(req.Body, []byte("{")) {
+		t.Fatalf("body is not plain JSON: %q", req.Body)
+	}
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// splitBatch splits events into consecutive parts whose batch request JSON
+// for version, with fields renamed by mapping, stays within maxBytes. An event
+// too large to fit on its own is sent alone. maxBytes <= 0 returns events as
+// a single part.
+func splitBatch(deviceID string, events []models.TrackingEvent, maxBytes int, version APIVersion, mapping *FieldMapping) ([][]models.TrackingEvent, error) {
+	if maxBytes <= 0 || len(events) == 0 {
+		return [][]models.TrackingEvent{events}, nil
+	}
+
+	// Size of the request without events; timestamps have a fixed width
+	envelope, err := mapping.marshalBody(batchBody(version, deviceID, []models.TrackingEvent{}, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	overhead := len(envelope)
+
+	var parts [][]models.TrackingEvent
+	start, size := 0, overhead
+	for i, event := range events {
+		data, err := mapping.marshalEvent(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal event: %w", err)
+		}
+		eventSize := len(data)
+		if i > start {
+			eventSize++ // separating comma
+		}
+		if i > start && size+eventSize > maxBytes {
+			parts = append(parts, events[start:i])
+			start, size = i, overhead
+			eventSize = len(data)
+		}
+		size += eventSize
+	}
+	return append(parts, events[start:]), nil
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// batchSize returns the JSON size of a batch request holding events
+func batchSize(t *testing.T, c *APIClient, events []models.TrackingEvent) int {
+	t.Helper()
+	body, err := c.fieldMapping.marshalBody(batchBody(c.Version(), "device-1", events, time.Now()))
+	if err != nil {
+		t.Fatalf("marshalBody: %v", err)
+	}
+	return len(body)
+}
+
+func TestSendBatchSplitsOversizedBatches(t *testing.T) {
+	backend := newTestBackend(t, nil)
+	c := newTestClient(backend.URL)
+	c.SetCompressionThreshold(0)
+
+	events := testEvents(6)
+	// Room for three events per request
+	maxBytes := batchSize(t, c, events[:3])
+	c.SetMaxPayloadBytes(maxBytes)
+
+	if err := c.SendBatch(context.Background(), "device-1", events); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+
+	requests := backend.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	var delivered []string
+	for i, req := range requests {
+		if len(req.Body) > maxBytes {
+			t.Errorf("request %d is %d bytes, over the %d limit", i, len(req.Body), maxBytes)
+		}
+		var sent models.BatchEventRequest
+		if err := json.Unmarshal(req.Body, &sent); err != nil {
+			t.Fatalf("request %d is not a batch: %v", i, err)
+		}
+		for _, event := range sent.Events {
+			delivered = append(delivered, event.EventID)
+		}
+	}
+	if len(delivered) != len(events) {
+		t.Fatalf("delivered %v, want all %d events", delivered, len(events))
+	}
+	for i, event := range events {
+		if delivered[i] != event.EventID {
+			t.Fatalf("delivered %v, want the events once each in order", delivered)
+		}
+	}
+}
+
+func TestSplitBatch(t *testing.T) {
+	c := newTestClient("http://unused")
+	events := testEvents(4)
+	one := batchSize(t, c, events[:1])
+	two := batchSize(t, c, events[:2])
+
+	tests := []struct {
+		name     string
+		maxBytes int
+		want     []int
+	}{
+		{"disabled", 0, []int{4}},
+		{"fits", batchSize(t, c, events), []int{4}},
+		{"two per part", two, []int{2, 2}},
+		{"one short of two", two - 1, []int{1, 1, 1, 1}},
+		{"smaller than one event", one - 1, []int{1, 1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := splitBatch("device-1", events, tt.maxBytes, c.Version(), c.fieldMapping)
+			if err != nil {
+				t.Fatalf("splitBatch: %v", err)
+			}
+			var sizes []int
+			for _, part := range parts {
+				sizes = append(sizes, len(part))
+			}
+			if len(sizes) != len(tt.want) {
+				t.Fatalf("part sizes = %v, want %v", sizes, tt.want)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.want[i] {
+					t.Fatalf("part sizes = %v, want %v", sizes, tt.want)
+				}
+			}
+		})
+	}
+}
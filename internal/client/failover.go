@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+// SetFallbackURLs sets mirrored backends tried, in order, when the primary
+// base URL is unreachable or returns a 5xx. Must be called before use.
+func (c *APIClient) SetFallbackURLs(urls []string) {
+	c.endpointsMu.Lock()
+	defer c.endpointsMu.Unlock()
+	c.baseURLs = append(c.baseURLs[:1:1], urls...)
+	c.active = 0
+}
+
+// activeURL returns the base URL tried first: the last one that worked
+func (c *APIClient) activeURL() string {
+	c.endpointsMu.RLock()
+	defer c.endpointsMu.RUnlock()
+	return c.baseURLs[c.active]
+}
+
+// withFailover calls fn with each base URL, starting from the last one that
+// worked, until it succeeds or fails with an error another endpoint can't
+// fix. The URL that succeeded is tried first next time.
+func (c *APIClient) withFailover(ctx context.Context, fn func(baseURL string) error) error {
+	c.endpointsMu.RLock()
+	urls := c.baseURLs
+	start := c.active
+	c.endpointsMu.RUnlock()
+
+	var err error
+	for i := 0; i < len(urls); i++ {
+		index := (start + i) % len(urls)
+		if err = fn(urls[index]); err == nil {
+			c.setActive(index)
+			return nil
+		}
+		if ctx.Err() != nil || !shouldFailOver(err) || i == len(urls)-1 {
+			return err
+		}
+		c.logger.Warn("Backend endpoint failed, trying the next one",
+			zap.String("failed", urls[index]),
+			zap.String("next", urls[(index+1)%len(urls)]),
+			zap.Error(err),
+		)
+	}
+	return err
+}
+
+// setActive makes the base URL at index the first one tried
+func (c *APIClient) setActive(index int) {
+	c.endpointsMu.Lock()
+	defer c.endpointsMu.Unlock()
+	if c.active != index {
+		c.logger.Info("Switched backend endpoint",
+			zap.String("from", c.baseURLs[c.active]),
+			zap.String("to", c.baseURLs[index]),
+		)
+		c.active = index
+	}
+}
+
+// shouldFailOver reports whether err means the endpoint is down (connection
+// failure, timeout or 5xx) rather than that the request was rejected
+func shouldFailOver(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	var backendErr *BackendError
+	return errors.As(err, &backendErr) && backendErr.StatusCode >= 500
+}
+
+// SelectEndpoint health-checks the base URLs in order, primary first, and
+// makes the first live one the endpoint tried first. If none respond the
+// current choice is kept and the error from the last check is returned.
+func (c *APIClient) SelectEndpoint(ctx context.Context) error {
+	c.endpointsMu.RLock()
+	urls := c.baseURLs
+	c.endpointsMu.RUnlock()
+
+	var err error
+	for i, baseURL := range urls {
+		if err = c.healthCheckAt(ctx, baseURL); err == nil {
+			c.setActive(i)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+// flakyBackend is a testBackend answering 503 while down
+func flakyBackend(t *testing.T) (*testBackend, *atomic.Bool) {
+	t.Helper()
+	down := &atomic.Bool{}
+	b := newTestBackend(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return b, down
+}
+
+func TestSendBatchFailsOverToFallback(t *testing.T) {
+	primary, primaryDown := flakyBackend(t)
+	fallback, _ := flakyBackend(t)
+	c := newTestClient(primary.URL)
+	c.SetFallbackURLs([]string{fallback.URL})
+
+	primaryDown.Store(true)
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("SendBatch with the primary down: %v", err)
+	}
+	if got := len(fallback.Requests()); got != 1 {
+		t.Fatalf("fallback got %d requests, want 1", got)
+	}
+
+	// The fallback stays first while it works
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("second SendBatch: %v", err)
+	}
+	if got := len(primary.Requests()); got != 1 {
+		t.Errorf("primary got %d requests, want only the first failed one", got)
+	}
+	if got := len(fallback.Requests()); got != 2 {
+		t.Errorf("fallback got %d requests, want 2", got)
+	}
+}
+
+func TestSelectEndpointRecoversPrimary(t *testing.T) {
+	primary, primaryDown := flakyBackend(t)
+	fallback, fallbackDown := flakyBackend(t)
+	c := newTestClient(primary.URL)
+	c.SetFallbackURLs([]string{fallback.URL})
+
+	primaryDown.Store(true)
+	if err := c.SelectEndpoint(context.Background()); err != nil {
+		t.Fatalf("SelectEndpoint with the fallback up: %v", err)
+	}
+	if got := c.activeURL(); got != fallback.URL {
+		t.Fatalf("active = %q, want the fallback", got)
+	}
+
+	primaryDown.Store(false)
+	if err := c.SelectEndpoint(context.Background()); err != nil {
+		t.Fatalf("SelectEndpoint with the primary back: %v", err)
+	}
+	if got := c.activeURL(); got != primary.URL {
+		t.Fatalf("active = %q, want the primary back", got)
+	}
+
+	// No endpoint up keeps the current choice
+	primaryDown.Store(true)
+	fallbackDown.Store(true)
+	if err := c.SelectEndpoint(context.Background()); err == nil {
+		t.Fatal("SelectEndpoint succeeded with every endpoint down")
+	}
+	if got := c.activeURL(); got != primary.URL {
+		t.Fatalf("active = %q, want the primary kept", got)
+	}
+}
+
+func TestSendBatchFailsBackWhenFallbackDies(t *testing.T) {
+	primary, primaryDown := flakyBackend(t)
+	fallback, fallbackDown := flakyBackend(t)
+	c := newTestClient(primary.URL)
+	c.SetFallbackURLs([]string{fallback.URL})
+
+	primaryDown.Store(true)
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+
+	primaryDown.Store(false)
+	fallbackDown.Store(true)
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("SendBatch with the fallback down: %v", err)
+	}
+	if got := c.activeURL(); got != primary.URL {
+		t.Fatalf("active = %q, want the primary", got)
+	}
+}
+
+func TestSendBatchDoesNotFailOverOnRejection(t *testing.T) {
+	primary := newTestBackend(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	fallback := newTestBackend(t, nil)
+	c := newTestClient(primary.URL)
+	c.SetFallbackURLs([]string{fallback.URL})
+
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err == nil {
+		t.Fatal("rejected batch reported as sent")
+	}
+	if got := len(fallback.Requests()); got != 0 {
+		t.Fatalf("fallback got %d requests for a rejected batch, want 0", got)
+	}
+}
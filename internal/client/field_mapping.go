@@ -0,0 +1,172 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// Field name styles for NewFieldMapping
+const (
+	FieldCaseCamel = "camel" // deviceId, batchTimestamp (the default contract)
+	FieldCaseSnake = "snake" // device_id, batch_timestamp
+)
+
+// FieldMapping renames the JSON fields of batch requests and their events at
+// marshal time, for backends whose contract differs from the default
+// camelCase names. A nil mapping leaves the JSON unchanged.
+type FieldMapping struct {
+	snake  bool
+	rename map[string]string // default name -> backend name, applied instead of the case style
+}
+
+// NewFieldMapping builds a mapping from a case style ("" or camel keeps the
+// default names, snake converts them to snake_case) and explicit renames
+// keyed by the default camelCase name. It returns nil when nothing changes,
+// and an error when two fields of the same object would get the same name.
+func NewFieldMapping(fieldCase string, rename map[string]string) (*FieldMapping, error) {
+	mapping := &FieldMapping{}
+	switch fieldCase {
+	case "", FieldCaseCamel:
+	case FieldCaseSnake:
+		mapping.snake = true
+	default:
+		return nil, fmt.Errorf("unknown field case %q (use %s or %s)", fieldCase, FieldCaseCamel, FieldCaseSnake)
+	}
+
+	for from, to := range rename {
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("field rename %q -> %q has an empty name", from, to)
+		}
+		if mapping.rename == nil {
+			mapping.rename = make(map[string]string, len(rename))
+		}
+		mapping.rename[from] = to
+	}
+
+	if !mapping.snake && mapping.rename == nil {
+		return nil, nil
+	}
+	for _, object := range mappedObjects {
+		if err := mapping.checkCollisions(object); err != nil {
+			return nil, err
+		}
+	}
+	return mapping, nil
+}
+
+// mappedObjects are the JSON objects a mapping renames the fields of
+var mappedObjects = []reflect.Type{
+	reflect.TypeOf(models.BatchEventRequest{}),
+	reflect.TypeOf(models.BatchEventRequestV2{}),
+	reflect.TypeOf(models.TrackingEvent{}),
+}
+
+// checkCollisions returns an error if two of object's default field names
+// map to the same backend name, which would silently drop one of them
+func (m *FieldMapping) checkCollisions(object reflect.Type) error {
+	sources := make(map[string]string, object.NumField())
+	for i := 0; i < object.NumField(); i++ {
+		field := strings.Split(object.Field(i).Tag.Get("json"), ",")[0]
+		if field == "" || field == "-" {
+			continue
+		}
+		to := m.name(field)
+		if from, ok := sources[to]; ok {
+			return fmt.Errorf("fields %q and %q would both be sent as %q", from, field, to)
+		}
+		sources[to] = field
+	}
+	return nil
+}
+
+// SetFieldMapping sets the field mapping applied to batch requests; nil
+// sends the default field names. Must be called before use.
+func (c *APIClient) SetFieldMapping(mapping *FieldMapping) {
+	c.fieldMapping = mapping
+}
+
+// name returns the backend name for a default field name
+func (m *FieldMapping) name(field string) string {
+	if to, ok := m.rename[field]; ok {
+		return to
+	}
+	if m.snake {
+		return snakeCase(field)
+	}
+	return field
+}
+
+// marshalBody marshals a batch request body, renaming its fields and those
+// of each event under "events"
+func (m *FieldMapping) marshalBody(body interface{}) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil || m == nil {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if raw, ok := fields["events"]; ok {
+		var events []json.RawMessage
+		if err := json.Unmarshal(raw, &events); err != nil {
+			return nil, err
+		}
+		for i, event := range events {
+			if events[i], err = m.renameObject(event); err != nil {
+				return nil, err
+			}
+		}
+		if fields["events"], err = json.Marshal(events); err != nil {
+			return nil, err
+		}
+	}
+	return m.marshalRenamed(fields)
+}
+
+// marshalEvent marshals one event with its fields renamed
+func (m *FieldMapping) marshalEvent(event interface{}) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil || m == nil {
+		return data, err
+	}
+	return m.renameObject(data)
+}
+
+// renameObject renames the top-level fields of a JSON object
+func (m *FieldMapping) renameObject(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return m.marshalRenamed(fields)
+}
+
+func (m *FieldMapping) marshalRenamed(fields map[string]json.RawMessage) ([]byte, error) {
+	renamed := make(map[string]json.RawMessage, len(fields))
+	for field, value := range fields {
+		renamed[m.name(field)] = value
+	}
+	return json.Marshal(renamed)
+}
+
+// snakeCase converts a camelCase name to snake_case
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
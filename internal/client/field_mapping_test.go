@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// keys returns the sorted field names of a JSON object
+func keys(t *testing.T, data []byte) []string {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("%s is not an object: %v", data, err)
+	}
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// equalKeys compares sorted field names with want in any order
+func equalKeys(got, want []string) bool {
+	want = append([]string(nil), want...)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFieldMappingSerializesBatch(t *testing.T) {
+	events := testEvents(1)
+	body := batchBody(APIVersionV1, "device-1", events, time.UnixMilli(1700000000000))
+
+	tests := []struct {
+		name      string
+		fieldCase string
+		rename    map[string]string
+		wantBody  []string
+		wantEvent []string
+	}{
+		{
+			"default", "", nil,
+			[]string{"events", "deviceId", "batchTimestamp"},
+			[]string{"eventId", "deviceId", "timestamp", "status", "duration", "application"},
+		},
+		{
+			"snake case", FieldCaseSnake, nil,
+			[]string{"events", "device_id", "batch_timestamp"},
+			[]string{"event_id", "device_id", "timestamp", "status", "duration", "application"},
+		},
+		{
+			"renames over snake case", FieldCaseSnake, map[string]string{"deviceId": "machine", "timestamp": "ts"},
+			[]string{"events", "machine", "batch_timestamp"},
+			[]string{"event_id", "machine", "ts", "status", "duration", "application"},
+		},
+		{
+			"renames only", FieldCaseCamel, map[string]string{"eventId": "id"},
+			[]string{"events", "deviceId", "batchTimestamp"},
+			[]string{"id", "deviceId", "timestamp", "status", "duration", "application"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := NewFieldMapping(tt.fieldCase, tt.rename)
+			if err != nil {
+				t.Fatalf("NewFieldMapping: %v", err)
+			}
+			data, err := mapping.marshalBody(body)
+			if err != nil {
+				t.Fatalf("marshalBody: %v", err)
+			}
+			if got := keys(t, data); !equalKeys(got, tt.wantBody) {
+				t.Errorf("body fields = %v, want %v", got, tt.wantBody)
+			}
+
+			var sent struct {
+				Events []json.RawMessage `json:"events"`
+			}
+			if err := json.Unmarshal(data, &sent); err != nil || len(sent.Events) != 1 {
+				t.Fatalf("events in %s (%v), want 1", data, err)
+			}
+			if got := keys(t, sent.Events[0]); !equalKeys(got, tt.wantEvent) {
+				t.Errorf("event fields = %v, want %v", got, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestNewFieldMapping(t *testing.T) {
+	if m, err := NewFieldMapping("", nil); m != nil || err != nil {
+		t.Errorf("default mapping = %v, %v, want nil", m, err)
+	}
+	if m, err := NewFieldMapping(FieldCaseCamel, map[string]string{}); m != nil || err != nil {
+		t.Errorf("camel mapping = %v, %v, want nil", m, err)
+	}
+	if _, err := NewFieldMapping("kebab", nil); err == nil {
+		t.Error("unknown case accepted")
+	}
+	if _, err := NewFieldMapping("", map[string]string{"deviceId": ""}); err == nil {
+		t.Error("empty rename accepted")
+	}
+}
+
+func TestNewFieldMappingRejectsCollisions(t *testing.T) {
+	tests := []struct {
+		name      string
+		fieldCase string
+		rename    map[string]string
+		wantErr   bool
+	}{
+		{"rename onto another field", "", map[string]string{"title": "url"}, true},
+		{"two renames to one name", "", map[string]string{"title": "name", "application": "name"}, true},
+		{"rename onto a snake_case name", FieldCaseSnake, map[string]string{"title": "start_time"}, true},
+		{"rename onto a body field", "", map[string]string{"batchTimestamp": "deviceId"}, true},
+		{"rename onto a v2 body field", "", map[string]string{"sentAt": "events"}, true},
+		{"swap", "", map[string]string{"title": "url", "url": "title"}, false},
+		{"same name in another object", "", map[string]string{"batchTimestamp": "timestamp"}, false},
+		{"camel name kept under snake case", FieldCaseSnake, map[string]string{"title": "startTime"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFieldMapping(tt.fieldCase, tt.rename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFieldMapping error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendBatchAppliesFieldMapping(t *testing.T) {
+	backend := newTestBackend(t, nil)
+	c := newTestClient(backend.URL)
+	mapping, err := NewFieldMapping(FieldCaseSnake, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetFieldMapping(mapping)
+
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+	var sent struct {
+		DeviceID string                 `json:"device_id"`
+		Events   []models.TrackingEvent `json:"events"`
+	}
+	if err := json.Unmarshal(backend.Requests()[0].Body, &sent); err != nil || sent.DeviceID != "device-1" || len(sent.Events) != 1 {
+		t.Fatalf("sent %s (%v), want a snake_case batch", backend.Requests()[0].Body, err)
+	}
+}
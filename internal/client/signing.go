@@ -0,0 +1,45 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// signatureHeader carries "sha256=" and the hex HMAC-SHA256 of
+	// "<timestamp>.<body>" under the shared signing secret
+	signatureHeader = "X-Signature"
+	// signatureTimestampHeader carries the Unix time in seconds that was
+	// signed; the backend rejects stale values to prevent replay
+	signatureTimestampHeader = "X-Signature-Timestamp"
+)
+
+// SetSigningSecret enables HMAC signing of batch requests with secret; an
+// empty secret disables it. Must be called before use.
+func (c *APIClient) SetSigningSecret(secret string) {
+	c.signingSecret = []byte(secret)
+}
+
+// signRequest adds the signature headers for body, the bytes exactly as
+// sent (after compression), if signing is enabled
+func (c *APIClient) signRequest(req *http.Request, body []byte, now time.Time) {
+	if len(c.signingSecret) == 0 {
+		return
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(signatureTimestampHeader, timestamp)
+	req.Header.Set(signatureHeader, "sha256="+signature(c.signingSecret, timestamp, body))
+}
+
+// signature returns the hex HMAC-SHA256 of "<timestamp>.<body>"
+func signature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignatureMatchesReference(t *testing.T) {
+	// Computed with: printf '%s' '1700000000.{"events":[]}' | openssl dgst -sha256 -hmac s3cret
+	const want = "41b4d3f6c59a79e37d083fb3cbe77f82807c4369fd469fb70cfa75e02d2e2775"
+	if got := signature([]byte("s3cret"), "1700000000", []byte(`{"events":[]}`)); got != want {
+		t.Fatalf("signature = %s, want %s", got, want)
+	}
+
+	// The timestamp is part of what is signed, so a replayed body with a
+	// fresh timestamp doesn't verify
+	if signature([]byte("s3cret"), "1700000001", []byte(`{"events":[]}`)) == want {
+		t.Error("signature doesn't depend on the timestamp")
+	}
+	if signature([]byte("other"), "1700000000", []byte(`{"events":[]}`)) == want {
+		t.Error("signature doesn't depend on the secret")
+	}
+}
+
+// verify checks a signature the way a backend would
+func verify(secret, timestamp string, body []byte, header string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hmac.Equal([]byte(header), []byte("sha256="+hex.EncodeToString(mac.Sum(nil))))
+}
+
+func TestSendBatchSignsSentBytes(t *testing.T) {
+	for _, threshold := range []int{0, 64} {
+		backend := newTestBackend(t, nil)
+		c := newTestClient(backend.URL)
+		c.SetSigningSecret("s3cret")
+		c.SetCompressionThreshold(threshold)
+
+		before := time.Now().Unix()
+		if err := c.SendBatch(context.Background(), "device-1", testEvents(5)); err != nil {
+			t.Fatalf("SendBatch: %v", err)
+		}
+		req := backend.Requests()[0]
+		timestamp := req.Header.Get(signatureTimestampHeader)
+		if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || ts < before || ts > time.Now().Unix() {
+			t.Errorf("threshold %d: timestamp header %q isn't the send time", threshold, timestamp)
+		}
+		// Verified over the body as received, compressed or not
+		if !verify("s3cret", timestamp, req.Body, req.Header.Get(signatureHeader)) {
+			t.Errorf("threshold %d (Content-Encoding %q): signature %q doesn't verify", threshold, req.Header.Get("Content-Encoding"), req.Header.Get(signatureHeader))
+		}
+	}
+}
+
+func TestSendBatchUnsignedWithoutSecret(t *testing.T) {
+	backend := newTestBackend(t, nil)
+	c := newTestClient(backend.URL)
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+	req := backend.Requests()[0]
+	if req.Header.Get(signatureHeader) != "" || req.Header.Get(signatureTimestampHeader) != "" {
+		t.Errorf("signature headers sent without a secret: %v", req.Header)
+	}
+}
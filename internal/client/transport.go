@@ -0,0 +1,122 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// TLSOptions configures the TLS side of outbound connections to the backend.
+// Empty fields keep the defaults: system roots and no client certificate.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of extra root CAs trusted alongside the system roots
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key presented
+	// for mutual TLS; both or neither must be set
+	CertFile string
+	KeyFile  string
+}
+
+// ProxyOptions routes backend requests through an explicit proxy instead of
+// the one from the HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables
+type ProxyOptions struct {
+	// URL is the proxy address; the http, https, socks5 and socks5h schemes
+	// are supported. Empty keeps the environment proxy.
+	URL string
+	// Username and Password authenticate to the proxy, overriding any
+	// credentials in URL
+	Username string
+	Password string
+}
+
+// NewTransport builds an HTTP transport for backend requests, based on the
+// default transport so environment proxies keep working unless an explicit
+// proxy is configured
+func NewTransport(tlsOpts TLSOptions, proxyOpts ProxyOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	proxyURL, err := proxyOpts.url()
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := tlsOpts.config()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return transport, nil
+}
+
+// url returns the parsed proxy URL with credentials applied, or nil if no
+// proxy is configured
+func (o ProxyOptions) url() (*url.URL, error) {
+	if o.URL == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", proxyURL.Redacted())
+	}
+
+	if o.Username != "" {
+		proxyURL.User = url.UserPassword(o.Username, o.Password)
+	}
+
+	return proxyURL, nil
+}
+
+// config returns the TLS config for the options, or nil if none are set
+func (o TLSOptions) config() (*tls.Config, error) {
+	if o.CAFile == "" && o.CertFile == "" && o.KeyFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if o.CAFile != "" {
+		pemData, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil || roots == nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// APIVersion is the backend API version used for event batches
+type APIVersion int
+
+const (
+	APIVersionV1 APIVersion = 1
+	APIVersionV2 APIVersion = 2
+)
+
+// String returns the version as it appears in paths, e.g. "v1"
+func (v APIVersion) String() string {
+	return fmt.Sprintf("v%d", int(v))
+}
+
+// parseAPIVersion parses "v1" or "v2"; other versions are not supported
+func parseAPIVersion(s string) (APIVersion, bool) {
+	switch s {
+	case "v1":
+		return APIVersionV1, true
+	case "v2":
+		return APIVersionV2, true
+	}
+	return 0, false
+}
+
+// sentAtLayout has a fixed width so splitBatch can size the envelope once
+const sentAtLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// versionInfo is the body of GET /api/version
+type versionInfo struct {
+	Versions []string `json:"versions"` // e.g. ["v1", "v2"]
+}
+
+// Version returns the negotiated API version, v1 until NegotiateVersion
+// finds something newer
+func (c *APIClient) Version() APIVersion {
+	c.endpointsMu.RLock()
+	defer c.endpointsMu.RUnlock()
+	return c.version
+}
+
+// setVersion records the API version used for batches
+func (c *APIClient) setVersion(version APIVersion) {
+	c.endpointsMu.Lock()
+	defer c.endpointsMu.Unlock()
+	c.version = version
+}
+
+// NegotiateVersion asks the active backend which API versions it supports
+// (GET /api/version) and uses the newest one the agent also speaks. Backends
+// without the endpoint, or any failure, leave the client on v1; the error
+// is returned for logging only.
+func (c *APIClient) NegotiateVersion(ctx context.Context) (APIVersion, error) {
+	version, err := c.fetchVersion(ctx, c.activeURL())
+	if err != nil {
+		version = APIVersionV1
+	}
+	c.setVersion(version)
+	return version, err
+}
+
+// fetchVersion returns the newest supported version advertised at baseURL
+func (c *APIClient) fetchVersion(ctx context.Context, baseURL string) (APIVersion, error) {
+	endpoint, err := endpoint(baseURL, "api", "version")
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("version check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("version check returned status %d", resp.StatusCode)
+	}
+
+	var info versionInfo
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&info); err != nil {
+		return 0, fmt.Errorf("failed to decode version response: %w", err)
+	}
+
+	best := APIVersion(0)
+	for _, s := range info.Versions {
+		if v, ok := parseAPIVersion(s); ok && v > best {
+			best = v
+		}
+	}
+	if best == 0 {
+		return 0, fmt.Errorf("backend advertises no supported API version: %v", info.Versions)
+	}
+	return best, nil
+}
+
+// batchPath returns the path elements of the batch endpoint for version
+func batchPath(version APIVersion) []string {
+	return []string{"api", version.String(), "events", "batch"}
+}
+
+// batchBody builds the request body for version
+func batchBody(version APIVersion, deviceID string, events []models.TrackingEvent, now time.Time) interface{} {
+	if version == APIVersionV2 {
+		return models.BatchEventRequestV2{
+			DeviceID: deviceID,
+			SentAt:   now.UTC().Format(sentAtLayout),
+			Events:   events,
+		}
+	}
+	return models.BatchEventRequest{
+		Events:         events,
+		DeviceID:       deviceID,
+		BatchTimestamp: now.UnixMilli(),
+	}
+}
+
+// downgradeOn404 falls back to v1 when a newer batch endpoint is missing,
+// e.g. after failing over to a backend that hasn't been upgraded yet.
+// It reports whether the request should be retried.
+func (c *APIClient) downgradeOn404(err error, version APIVersion) bool {
+	var backendErr *BackendError
+	if version == APIVersionV1 || !errors.As(err, &backendErr) || backendErr.StatusCode != http.StatusNotFound {
+		return false
+	}
+	c.logger.Warn("Backend has no batch endpoint for the negotiated API version, falling back to v1",
+		zap.String("version", version.String()),
+	)
+	c.setVersion(APIVersionV1)
+	return true
+}
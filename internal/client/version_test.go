@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// versionBackend advertises versions at /api/version, or answers it with
+// status when versions is nil, and accepts batches on the paths in batches
+func versionBackend(t *testing.T, status int, versions []string, batches ...string) *testBackend {
+	t.Helper()
+	accepted := make(map[string]bool)
+	for _, path := range batches {
+		accepted[path] = true
+	}
+	return newTestBackend(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		switch {
+		case r.URL.Path == "/api/version" && versions != nil:
+			json.NewEncoder(w).Encode(versionInfo{Versions: versions})
+		case r.URL.Path == "/api/version":
+			w.WriteHeader(status)
+		case accepted[r.URL.Path]:
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		versions []string
+		want     APIVersion
+		wantErr  bool
+	}{
+		{"v2 advertised", 0, []string{"v1", "v2"}, APIVersionV2, false},
+		{"newest known wins", 0, []string{"v2", "v1", "v9"}, APIVersionV2, false},
+		{"v1 only", 0, []string{"v1"}, APIVersionV1, false},
+		{"nothing supported", 0, []string{"v9"}, APIVersionV1, true},
+		{"no endpoint", http.StatusNotFound, nil, APIVersionV1, true},
+		{"server error", http.StatusInternalServerError, nil, APIVersionV1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(versionBackend(t, tt.status, tt.versions).URL)
+			got, err := c.NegotiateVersion(context.Background())
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Fatalf("NegotiateVersion = %s, %v, want %s (error %v)", got, err, tt.want, tt.wantErr)
+			}
+			if c.Version() != tt.want {
+				t.Errorf("Version = %s after negotiating %s", c.Version(), tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionDefaultsToV1(t *testing.T) {
+	backend := versionBackend(t, 0, []string{"v1", "v2"}, "/api/v1/events/batch")
+	c := newTestClient(backend.URL)
+	if c.Version() != APIVersionV1 {
+		t.Fatalf("Version before negotiating = %s, want v1", c.Version())
+	}
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+	if got := backend.Requests()[0].Path; got != "/api/v1/events/batch" {
+		t.Errorf("batch sent to %s, want /api/v1/events/batch", got)
+	}
+}
+
+func TestSendBatchUsesNegotiatedVersion(t *testing.T) {
+	backend := versionBackend(t, 0, []string{"v1", "v2"}, "/api/v1/events/batch", "/api/v2/events/batch")
+	c := newTestClient(backend.URL)
+	if _, err := c.NegotiateVersion(context.Background()); err != nil {
+		t.Fatalf("NegotiateVersion: %v", err)
+	}
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+
+	requests := backend.Requests()
+	last := requests[len(requests)-1]
+	if last.Path != "/api/v2/events/batch" {
+		t.Fatalf("batch sent to %s, want /api/v2/events/batch", last.Path)
+	}
+	var sent models.BatchEventRequestV2
+	if err := json.Unmarshal(last.Body, &sent); err != nil || sent.SentAt == "" || len(sent.Events) != 1 {
+		t.Errorf("v2 body = %s (%v)", last.Body, err)
+	}
+}
+
+func TestSendBatchFallsBackToV1On404(t *testing.T) {
+	// Advertises v2 but only serves the v1 batch endpoint
+	backend := versionBackend(t, 0, []string{"v1", "v2"}, "/api/v1/events/batch")
+	c := newTestClient(backend.URL)
+	if _, err := c.NegotiateVersion(context.Background()); err != nil {
+		t.Fatalf("NegotiateVersion: %v", err)
+	}
+	if err := c.SendBatch(context.Background(), "device-1", testEvents(1)); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+	if c.Version() != APIVersionV1 {
+		t.Errorf("Version after a v2 404 = %s, want v1", c.Version())
+	}
+	requests := backend.Requests()
+	if last := requests[len(requests)-1].Path; last != "/api/v1/events/batch" {
+		t.Errorf("retried batch sent to %s, want /api/v1/events/batch", last)
+	}
+}
@@ -0,0 +1,52 @@
+package collector
+
+import "Mansoor88-6/time-tracking-agent/internal/models"
+
+// SetCoalesce enables merging an event into the previous pending event when
+// both describe the same activity (device, application, title, URL and
+// status). The merged event keeps the first event's start and takes the last
+// event's end, with durations summed. Each event is still staged on its own,
+// so events restored on Start are merged again.
+func (ec *EventCollector) SetCoalesce(enabled bool) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	ec.coalesce = enabled
+}
+
+// coalesceInto merges event into last if they describe the same activity,
+// reporting whether it did
+func coalesceInto(last *models.TrackingEvent, event *models.TrackingEvent) bool {
+	if last.DeviceID != event.DeviceID ||
+		last.Status != event.Status ||
+		!equalString(last.Application, event.Application) ||
+		!equalString(last.Title, event.Title) ||
+		!equalString(last.URL, event.URL) {
+		return false
+	}
+
+	if event.Duration != nil {
+		total := *event.Duration
+		if last.Duration != nil {
+			total += *last.Duration
+		}
+		last.Duration = &total
+	}
+
+	if last.StartTime == nil && event.StartTime != nil {
+		start := *event.StartTime
+		last.StartTime = &start
+	}
+	if event.EndTime != nil && (last.EndTime == nil || *event.EndTime > *last.EndTime) {
+		end := *event.EndTime
+		last.EndTime = &end
+	}
+
+	return true
+}
+
+func equalString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
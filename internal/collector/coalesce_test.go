@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+func strPtr(s string) *string { return &s }
+
+func int64Ptr(n int64) *int64 { return &n }
+
+// activity returns an event for app spanning start..end, in Unix ms
+func activity(app string, start, end int64) models.TrackingEvent {
+	return models.TrackingEvent{
+		DeviceID:    "device-1",
+		Status:      models.StatusActive,
+		Application: strPtr(app),
+		Title:       strPtr("main.go"),
+		URL:         strPtr("https://example.com"),
+		StartTime:   int64Ptr(start),
+		EndTime:     int64Ptr(end),
+		Duration:    int64Ptr(end - start),
+	}
+}
+
+func TestCoalesceIntoMerges(t *testing.T) {
+	last := activity("code", 1000, 2000)
+	event := activity("code", 2000, 3500)
+
+	if !coalesceInto(&last, &event) {
+		t.Fatal("same activity was not coalesced")
+	}
+	if *last.Duration != 2500 {
+		t.Errorf("duration %d, want the sum 2500", *last.Duration)
+	}
+	if *last.StartTime != 1000 {
+		t.Errorf("start %d, want the first event's 1000", *last.StartTime)
+	}
+	if *last.EndTime != 3500 {
+		t.Errorf("end %d, want the latest 3500", *last.EndTime)
+	}
+
+	// An event that ends earlier doesn't pull the end back
+	earlier := activity("code", 1500, 1800)
+	if !coalesceInto(&last, &earlier) {
+		t.Fatal("same activity was not coalesced")
+	}
+	if *last.EndTime != 3500 {
+		t.Errorf("end %d after an earlier-ending event, want 3500", *last.EndTime)
+	}
+}
+
+func TestCoalesceIntoRejectsDifferentActivity(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*models.TrackingEvent)
+	}{
+		{"device", func(e *models.TrackingEvent) { e.DeviceID = "device-2" }},
+		{"application", func(e *models.TrackingEvent) { e.Application = strPtr("browser") }},
+		{"title", func(e *models.TrackingEvent) { e.Title = strPtr("README.md") }},
+		{"url", func(e *models.TrackingEvent) { e.URL = strPtr("https://example.org") }},
+		{"status", func(e *models.TrackingEvent) { e.Status = models.StatusIdle }},
+		{"missing title", func(e *models.TrackingEvent) { e.Title = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last := activity("code", 1000, 2000)
+			event := activity("code", 2000, 3000)
+			tt.change(&event)
+
+			if coalesceInto(&last, &event) {
+				t.Fatalf("event with a different %s was coalesced", tt.name)
+			}
+			if *last.Duration != 1000 || *last.EndTime != 2000 {
+				t.Fatalf("rejected merge changed last: duration %d, end %d", *last.Duration, *last.EndTime)
+			}
+		})
+	}
+}
+
+func TestCoalescedEventsDontCountTowardBatchSize(t *testing.T) {
+	var rec batchRecorder
+	ec := NewEventCollector(2, time.Hour, zap.NewNop())
+	ec.SetCoalesce(true)
+	ec.Start(rec.handle)
+	defer ec.Stop()
+
+	ec.AddEvent(activity("code", 0, 1000))
+	ec.AddEvent(activity("code", 1000, 2000))
+	ec.AddEvent(activity("code", 2000, 3000))
+	if got := ec.GetPendingCount(); got != 1 {
+		t.Fatalf("pending count %d after three coalesced events, want 1", got)
+	}
+	if len(rec.batches) != 0 {
+		t.Fatalf("flushed %d batches before batch size was reached", len(rec.batches))
+	}
+
+	ec.AddEvent(activity("browser", 3000, 4000))
+	batch, _ := rec.batch(t, 0)
+	if len(batch) != 2 {
+		t.Fatalf("batch of %d events, want 2", len(batch))
+	}
+	if *batch[0].Duration != 3000 {
+		t.Errorf("coalesced duration %d, want 3000", *batch[0].Duration)
+	}
+}
+
+func TestStartCoalescesRestoredEvents(t *testing.T) {
+	stager := newFakeStager()
+
+	crashed := newTestCollector(stager)
+	crashed.SetCoalesce(true)
+	crashed.Start(func([]models.TrackingEvent, func(error)) {})
+	t.Cleanup(crashed.Stop)
+	crashed.AddEvent(activity("code", 0, 1000))
+	crashed.AddEvent(activity("code", 1000, 2000))
+	crashed.AddEvent(activity("browser", 2000, 3000))
+
+	var rec batchRecorder
+	restarted := newTestCollector(stager)
+	restarted.SetCoalesce(true)
+	restarted.Start(rec.handle)
+	defer restarted.Stop()
+
+	if got := restarted.GetPendingCount(); got != 2 {
+		t.Fatalf("pending count %d after restart, want the 2 coalesced events", got)
+	}
+
+	restarted.Flush()
+	batch, done := rec.batch(t, 0)
+	if *batch[0].Duration != 2000 || *batch[0].EndTime != 2000 {
+		t.Errorf("restored event duration %d, end %d, want 2000 and 2000", *batch[0].Duration, *batch[0].EndTime)
+	}
+
+	// Every staged row, coalesced or not, is released with the batch
+	done(nil)
+	if got := stager.stagedIDs(); len(got) != 0 {
+		t.Fatalf("still staged %v after hand-off", got)
+	}
+}
@@ -9,12 +9,27 @@ import (
 	"go.uber.org/zap"
 )
 
+// EventStager durably stores buffered events until they are handed off
+type EventStager interface {
+	Stage(event models.TrackingEvent) (int64, error)
+	Unstage(ids []int64) error
+	LoadStaged() ([]models.TrackingEvent, []int64, error)
+}
+
+// BatchHandler receives a ready batch. It must call done exactly once,
+// possibly later and from another goroutine: with nil once the events have
+// been sent or queued, or with an error if they could be neither.
+type BatchHandler func(events []models.TrackingEvent, done func(error))
+
 // EventCollector collects and batches tracking events
 type EventCollector struct {
 	events         []models.TrackingEvent
 	batchSize      int
 	flushInterval  time.Duration
-	onBatchReady   func([]models.TrackingEvent)
+	onBatchReady   BatchHandler
+	stager         EventStager
+	stagedIDs      []int64 // Staging IDs of the buffered events
+	coalesce       bool    // Merge consecutive events for the same activity
 	logger         *zap.Logger
 	mu             sync.Mutex
 	flushTicker    *time.Ticker
@@ -36,10 +51,74 @@ func NewEventCollector(
 	}
 }
 
-// Start begins the event collector with auto-flush
-func (ec *EventCollector) Start(onBatchReady func([]models.TrackingEvent)) {
+// SetStager makes the collector stage every event until onBatchReady has
+// handed it off, and restore leftover staged events on Start. Must be called
+// before Start.
+func (ec *EventCollector) SetStager(stager EventStager) {
+	ec.stager = stager
+}
+
+// SetBatchSize changes how many buffered events trigger a flush. Safe to
+// call while running; non-positive sizes are ignored.
+func (ec *EventCollector) SetBatchSize(size int) {
+	if size <= 0 {
+		return
+	}
+	ec.mu.Lock()
+	ec.batchSize = size
+	ec.mu.Unlock()
+}
+
+// SetFlushInterval changes how often buffered events are flushed. Safe to
+// call while running; the next flush is one full interval away.
+// Non-positive intervals are ignored.
+func (ec *EventCollector) SetFlushInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ec.mu.Lock()
+	ec.flushInterval = interval
+	ticker := ec.flushTicker
+	ec.mu.Unlock()
+
+	if ticker != nil {
+		ticker.Reset(interval)
+	}
+}
+
+// Start begins the event collector with auto-flush. Handed-off events stay
+// staged until onBatchReady reports them sent or queued, so a batch that
+// fails, or is still in flight when the process dies, is restored next run.
+func (ec *EventCollector) Start(onBatchReady BatchHandler) {
 	ec.onBatchReady = onBatchReady
+
+	// Restore events left over from a crash or forced exit
+	if ec.stager != nil {
+		events, ids, err := ec.stager.LoadStaged()
+		if err != nil {
+			ec.logger.Error("Failed to load staged events", zap.Error(err))
+		} else if len(events) > 0 {
+			ec.mu.Lock()
+			for _, event := range events {
+				// Coalesced events are staged one row each, so merge them again
+				if ec.coalesce && len(ec.events) > 0 && coalesceInto(&ec.events[len(ec.events)-1], &event) {
+					continue
+				}
+				ec.events = append(ec.events, event)
+			}
+			ec.stagedIDs = append(ec.stagedIDs, ids...)
+			pendingCount := len(ec.events)
+			ec.mu.Unlock()
+			ec.logger.Info("Restored staged events",
+				zap.Int("count", len(events)),
+				zap.Int("pending_count", pendingCount),
+			)
+		}
+	}
+
+	ec.mu.Lock()
 	ec.flushTicker = time.NewTicker(ec.flushInterval)
+	ec.mu.Unlock()
 
 	ec.wg.Add(1)
 	go ec.autoFlushLoop()
@@ -71,13 +150,9 @@ func (ec *EventCollector) Stop() {
 	// Flush any remaining events
 	ec.mu.Lock()
 	if len(ec.events) > 0 {
-		events := make([]models.TrackingEvent, len(ec.events))
-		copy(events, ec.events)
-		ec.events = ec.events[:0]
+		events, ids := ec.takeBatchLocked()
 		ec.mu.Unlock()
-		if ec.onBatchReady != nil {
-			ec.onBatchReady(events)
-		}
+		ec.handOff(events, ids)
 	} else {
 		ec.mu.Unlock()
 	}
@@ -88,13 +163,23 @@ func (ec *EventCollector) Stop() {
 // AddEvent adds a new event to the collection
 func (ec *EventCollector) AddEvent(event models.TrackingEvent) {
 	ec.mu.Lock()
+	ec.stageLocked(event)
+	if ec.coalesce && len(ec.events) > 0 && coalesceInto(&ec.events[len(ec.events)-1], &event) {
+		pendingCount := len(ec.events)
+		ec.mu.Unlock()
+
+		ec.logger.Debug("Event coalesced with previous event",
+			zap.String("application", getEventApplication(event)),
+			zap.Int("pending_count", pendingCount),
+		)
+		return
+	}
 	ec.events = append(ec.events, event)
 	shouldFlush := len(ec.events) >= ec.batchSize
 	events := make([]models.TrackingEvent, 0)
+	var ids []int64
 	if shouldFlush {
-		events = make([]models.TrackingEvent, len(ec.events))
-		copy(events, ec.events)
-		ec.events = ec.events[:0]
+		events, ids = ec.takeBatchLocked()
 	}
 	pendingCount := len(ec.events)
 	ec.mu.Unlock()
@@ -110,10 +195,56 @@ func (ec *EventCollector) AddEvent(event models.TrackingEvent) {
 		ec.logger.Info("Batch size reached, flushing events",
 			zap.Int("count", len(events)),
 		)
-		if ec.onBatchReady != nil {
-			ec.onBatchReady(events)
-		}
+		ec.handOff(events, ids)
+	}
+}
+
+// stageLocked durably stores event before it is buffered. Must hold ec.mu.
+func (ec *EventCollector) stageLocked(event models.TrackingEvent) {
+	if ec.stager == nil {
+		return
+	}
+	id, err := ec.stager.Stage(event)
+	if err != nil {
+		ec.logger.Warn("Failed to stage event", zap.Error(err))
+		return
+	}
+	ec.stagedIDs = append(ec.stagedIDs, id)
+}
+
+// takeBatchLocked removes and returns all buffered events and their staging
+// IDs. Must hold ec.mu.
+func (ec *EventCollector) takeBatchLocked() ([]models.TrackingEvent, []int64) {
+	events := make([]models.TrackingEvent, len(ec.events))
+	copy(events, ec.events)
+	ec.events = ec.events[:0]
+
+	ids := ec.stagedIDs
+	ec.stagedIDs = nil
+
+	return events, ids
+}
+
+// handOff passes a batch to onBatchReady and unstages it once it has been
+// sent or queued
+func (ec *EventCollector) handOff(events []models.TrackingEvent, ids []int64) {
+	if ec.onBatchReady == nil {
+		return
 	}
+	ec.onBatchReady(events, func(err error) {
+		if err != nil {
+			ec.logger.Error("Batch could not be handed off, keeping events staged",
+				zap.Error(err),
+				zap.Int("count", len(events)),
+			)
+			return
+		}
+		if ec.stager != nil {
+			if err := ec.stager.Unstage(ids); err != nil {
+				ec.logger.Warn("Failed to unstage events", zap.Error(err))
+			}
+		}
+	})
 }
 
 // Helper functions for logging
@@ -138,17 +269,13 @@ func (ec *EventCollector) Flush() {
 		ec.mu.Unlock()
 		return
 	}
-	events := make([]models.TrackingEvent, len(ec.events))
-	copy(events, ec.events)
-	ec.events = ec.events[:0]
+	events, ids := ec.takeBatchLocked()
 	ec.mu.Unlock()
 
 	ec.logger.Debug("Manual flush triggered",
 		zap.Int("count", len(events)),
 	)
-	if ec.onBatchReady != nil {
-		ec.onBatchReady(events)
-	}
+	ec.handOff(events, ids)
 }
 
 // GetPendingCount returns the number of pending events
@@ -166,15 +293,16 @@ func (ec *EventCollector) autoFlushLoop() {
 		case <-ec.flushTicker.C:
 			ec.mu.Lock()
 			pendingCount := len(ec.events)
+			flushInterval := ec.flushInterval
 			ec.mu.Unlock()
 			if pendingCount > 0 {
 				ec.logger.Info("Auto-flush triggered",
 					zap.Int("pending_count", pendingCount),
-					zap.Duration("flush_interval", ec.flushInterval),
+					zap.Duration("flush_interval", flushInterval),
 				)
 			} else {
 				ec.logger.Debug("Auto-flush triggered but no events pending",
-					zap.Duration("flush_interval", ec.flushInterval),
+					zap.Duration("flush_interval", flushInterval),
 				)
 			}
 			ec.Flush()
@@ -0,0 +1,201 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// fakeStager keeps staged events in memory and records what was unstaged
+type fakeStager struct {
+	mu       sync.Mutex
+	nextID   int64
+	staged   map[int64]models.TrackingEvent
+	unstaged []int64
+}
+
+func newFakeStager() *fakeStager {
+	return &fakeStager{staged: make(map[int64]models.TrackingEvent)}
+}
+
+func (s *fakeStager) Stage(event models.TrackingEvent) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.staged[s.nextID] = event
+	return s.nextID, nil
+}
+
+func (s *fakeStager) Unstage(ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.staged, id)
+	}
+	s.unstaged = append(s.unstaged, ids...)
+	return nil
+}
+
+func (s *fakeStager) LoadStaged() ([]models.TrackingEvent, []int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]int64, 0, len(s.staged))
+	for id := range s.staged {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	events := make([]models.TrackingEvent, len(ids))
+	for i, id := range ids {
+		events[i] = s.staged[id]
+	}
+	return events, ids, nil
+}
+
+func (s *fakeStager) stagedIDs() []int64 {
+	_, ids, _ := s.LoadStaged()
+	return ids
+}
+
+// batchRecorder is a BatchHandler that keeps each batch and its done callback
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]models.TrackingEvent
+	dones   []func(error)
+}
+
+func (r *batchRecorder) handle(events []models.TrackingEvent, done func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, events)
+	r.dones = append(r.dones, done)
+}
+
+func (r *batchRecorder) batch(t *testing.T, i int) ([]models.TrackingEvent, func(error)) {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i >= len(r.batches) {
+		t.Fatalf("got %d batches, want at least %d", len(r.batches), i+1)
+	}
+	return r.batches[i], r.dones[i]
+}
+
+// newTestCollector returns a collector that only flushes when asked to
+func newTestCollector(stager EventStager) *EventCollector {
+	ec := NewEventCollector(100, time.Hour, zap.NewNop())
+	ec.SetStager(stager)
+	return ec
+}
+
+// testEvents returns events named event-0 ... event-(n-1)
+func testEvents(n int) []models.TrackingEvent {
+	events := make([]models.TrackingEvent, n)
+	for i := range events {
+		events[i] = models.TrackingEvent{
+			EventID:  fmt.Sprintf("event-%d", i),
+			DeviceID: "device-1",
+			Status:   models.StatusActive,
+		}
+	}
+	return events
+}
+
+func eventIDs(events []models.TrackingEvent) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.EventID
+	}
+	return ids
+}
+
+func equalInts(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStartRestoresStagedEvents(t *testing.T) {
+	stager := newFakeStager()
+
+	// A collector that dies before handing its events off
+	crashed := newTestCollector(stager)
+	crashed.Start(func([]models.TrackingEvent, func(error)) {})
+	t.Cleanup(crashed.Stop)
+	for _, event := range testEvents(3) {
+		crashed.AddEvent(event)
+	}
+
+	var rec batchRecorder
+	restarted := newTestCollector(stager)
+	restarted.Start(rec.handle)
+	defer restarted.Stop()
+
+	if got := restarted.GetPendingCount(); got != 3 {
+		t.Fatalf("pending count %d after restart, want 3", got)
+	}
+	restarted.Flush()
+	batch, _ := rec.batch(t, 0)
+	if got, want := eventIDs(batch), []string{"event-0", "event-1", "event-2"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("restored batch %v, want %v", got, want)
+	}
+}
+
+func TestDoneUnstagesBatch(t *testing.T) {
+	stager := newFakeStager()
+	var rec batchRecorder
+	ec := newTestCollector(stager)
+	ec.Start(rec.handle)
+	defer ec.Stop()
+
+	for _, event := range testEvents(2) {
+		ec.AddEvent(event)
+	}
+	ec.Flush()
+	ec.AddEvent(testEvents(3)[2])
+
+	_, done := rec.batch(t, 0)
+	done(nil)
+
+	if want := []int64{1, 2}; !equalInts(stager.unstaged, want) {
+		t.Fatalf("unstaged %v, want %v", stager.unstaged, want)
+	}
+	if got := stager.stagedIDs(); !equalInts(got, []int64{3}) {
+		t.Fatalf("still staged %v, want only the unflushed event [3]", got)
+	}
+}
+
+func TestFailedHandOffKeepsEventsStaged(t *testing.T) {
+	stager := newFakeStager()
+	var rec batchRecorder
+	ec := newTestCollector(stager)
+	ec.Start(rec.handle)
+	defer ec.Stop()
+
+	for _, event := range testEvents(2) {
+		ec.AddEvent(event)
+	}
+	ec.Flush()
+
+	_, done := rec.batch(t, 0)
+	done(errors.New("queue unavailable"))
+
+	if len(stager.unstaged) != 0 {
+		t.Fatalf("unstaged %v after a failed hand-off, want nothing", stager.unstaged)
+	}
+	if got := stager.stagedIDs(); !equalInts(got, []int64{1, 2}) {
+		t.Fatalf("still staged %v, want [1 2]", got)
+	}
+}
@@ -0,0 +1,439 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// Config holds the agent configuration loaded from YAML and the environment
+type Config struct {
+	Env         string `yaml:"env" env:"ENV" env-default:"development"`
+	StoragePath string `yaml:"storage_path" env:"STORAGE_PATH" env-default:"storage/database.db"`
+	// StorageReadConns > 0 opens a separate read-only pool of that size for
+	// local analytics queries
+	StorageReadConns int `yaml:"storage_read_conns"`
+	// StorageMaintenanceInterval is how often the WAL is truncated (and the
+	// file occasionally vacuumed); negative disables
+	StorageMaintenanceInterval int `yaml:"storage_maintenance_interval" env-default:"60"` // minutes
+
+	HTTPServer HTTPServer `yaml:"http_server"`
+	Log        Log        `yaml:"log"`
+	Backend    Backend    `yaml:"backend"`
+	Tracking   Tracking   `yaml:"tracking"`
+	Device     Device     `yaml:"device"`
+	Auth       Auth       `yaml:"auth"`
+	Server     Server     `yaml:"server"`
+	Metrics    Metrics    `yaml:"metrics"`
+	Automation Automation `yaml:"automation"`
+	Privacy    Privacy    `yaml:"privacy"`
+	Categories Categories `yaml:"categories"`
+	Projects   Projects   `yaml:"projects"`
+	Queue      Queue      `yaml:"queue"`
+	EventLog   EventLog   `yaml:"event_log"`
+
+	// BaseDir is the agent root directory (the parent of the config directory).
+	// Relative paths such as StoragePath and the logs directory resolve against it.
+	BaseDir string `yaml:"-"`
+
+	// loaded is the config as it was loaded, so Save only writes changes
+	loaded *Config
+}
+
+// HTTPServer configures the local time entry API (/api/v1/time-entries)
+type HTTPServer struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address" env-default:"localhost:8082"`
+	// Overlap is what happens to a time entry overlapping another of the
+	// same user: reject (409 Conflict) or warn (saved and logged)
+	Overlap string `yaml:"overlap" env-default:"reject"`
+}
+
+type Log struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" env-default:"info"`
+	Format string `yaml:"format" env:"LOG_FORMAT" env-default:"json"`
+	// Redact hides window titles and URLs in log fields: off, hash or
+	// truncate. Application names are always logged.
+	Redact string `yaml:"redact" env:"LOG_REDACT" env-default:"off"`
+}
+
+type Backend struct {
+	BaseURL string `yaml:"base_url" env:"BACKEND_BASE_URL" env-required:"true"`
+	// FallbackURLs are mirrors tried in order when BaseURL is unreachable
+	// or returns a 5xx
+	FallbackURLs []string `yaml:"fallback_urls" env:"BACKEND_FALLBACK_URLS"`
+	APIKey       string   `yaml:"api_key" env:"BACKEND_API_KEY"`
+	// SigningSecret, when set, signs each batch with an HMAC-SHA256 of its
+	// body in the X-Signature header, plus X-Signature-Timestamp against replay
+	SigningSecret string `yaml:"signing_secret" env:"BACKEND_SIGNING_SECRET"`
+	Timeout       int    `yaml:"timeout" env-default:"30"` // seconds
+	// Batches larger than CompressionThreshold bytes are sent gzip-compressed
+	// unless DisableCompression is set (for backends without gzip support).
+	CompressionThreshold int  `yaml:"compression_threshold" env-default:"1024"`
+	DisableCompression   bool `yaml:"disable_compression"`
+	// ShutdownSendTimeout bounds the final flush's send on shutdown; events
+	// not delivered in time are queued for the next run.
+	ShutdownSendTimeout int `yaml:"shutdown_send_timeout" env-default:"3"` // seconds
+	// SendWorkers is how many batches are uploaded concurrently, off the
+	// collector's goroutine; negative sends inline on the collector
+	SendWorkers int `yaml:"send_workers" env-default:"2"`
+	// MaxPayloadBytes splits batches whose JSON body would be larger, for
+	// backends with a request size limit; negative disables splitting
+	MaxPayloadBytes int `yaml:"max_payload_bytes" env-default:"1048576"`
+	// FieldCase is the JSON field naming the backend expects: camel
+	// (deviceId) or snake (device_id)
+	FieldCase string `yaml:"field_case" env-default:"camel"`
+	// FieldNames renames individual fields, keyed by their camelCase name
+	// (e.g. deviceId: agent_id); takes precedence over FieldCase
+	FieldNames map[string]string `yaml:"field_names"`
+	TLS        TLS               `yaml:"tls"`
+	Proxy      Proxy             `yaml:"proxy"`
+}
+
+// Proxy routes all backend traffic, including the device token exchange,
+// through an HTTP(S) or SOCKS5 proxy. When URL is empty the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables apply.
+type Proxy struct {
+	URL      string `yaml:"url" env:"BACKEND_PROXY_URL"` // e.g. http://proxy:3128 or socks5://proxy:1080
+	Username string `yaml:"username" env:"BACKEND_PROXY_USERNAME"`
+	Password string `yaml:"password" env:"BACKEND_PROXY_PASSWORD"`
+}
+
+// TLS points to PEM files for backends behind a private CA or mutual TLS.
+// Relative paths are resolved against the agent root directory.
+type TLS struct {
+	CAFile   string `yaml:"ca_file" env:"BACKEND_CA_FILE"`     // extra root CAs, added to the system roots
+	CertFile string `yaml:"cert_file" env:"BACKEND_CERT_FILE"` // client certificate for mutual TLS
+	KeyFile  string `yaml:"key_file" env:"BACKEND_KEY_FILE"`   // client certificate's private key
+}
+
+type Tracking struct {
+	WindowPollInterval int `yaml:"window_poll_interval" env-default:"2"`  // seconds
+	IdleThreshold      int `yaml:"idle_threshold" env-default:"300"`      // seconds
+	AwayThreshold      int `yaml:"away_threshold" env-default:"900"`      // seconds
+	BatchSize          int `yaml:"batch_size" env-default:"100"`          // events
+	BatchFlushInterval int `yaml:"batch_flush_interval" env-default:"15"` // seconds
+	// CoalesceEvents merges consecutive events for the same activity before
+	// sending; disable for backends that want raw events
+	CoalesceEvents           bool `yaml:"coalesce_events"`
+	SessionInactivityTimeout int  `yaml:"session_inactivity_timeout" env-default:"60"` // seconds
+	// AccumulateSessions keeps a window's session open across idle periods
+	// and sends one event with its total active time when the window
+	// changes, instead of splitting it at each inactivity timeout
+	AccumulateSessions bool `yaml:"accumulate_sessions"`
+	// MouseMoveIntervalMs coalesces mouse movement to at most one activity
+	// event per interval on platforms with input hooks; negative reports
+	// every move
+	MouseMoveIntervalMs int `yaml:"mouse_move_interval_ms" env-default:"200"`
+	// MinDwellMs is how long a window must stay focused before it is
+	// reported; shorter visits (rapid alt-tabbing) are dropped. 0 disables.
+	MinDwellMs int `yaml:"min_dwell_ms"`
+	// LivenessInterval is how often the last-seen-alive heartbeat is written
+	// to disk; 0 disables offline gap detection.
+	LivenessInterval int `yaml:"liveness_interval" env-default:"30"` // seconds
+	// HeartbeatInterval reports the current session (or a status-only event)
+	// when nothing else has been sent for this long; 0 disables.
+	HeartbeatInterval int `yaml:"heartbeat_interval"` // seconds
+	// SplitAtMidnight splits events that straddle local midnight into per-day events
+	SplitAtMidnight bool `yaml:"split_at_midnight"`
+	// IncludePower stamps AC/battery state onto events when it changes
+	IncludePower bool `yaml:"include_power"`
+	// PresenceEnabled accepts presence signals on POST /api/v1/presence; a
+	// user reported present is not marked idle or away
+	PresenceEnabled bool `yaml:"presence_enabled"`
+	// FullscreenAction handles sessions in fullscreen windows: empty tracks
+	// them normally, drop discards them, leisure tags them with that category
+	FullscreenAction string `yaml:"fullscreen_action"`
+	// EmptyWindow handles foreground windows with no application or title
+	// (desktop, splash screens): empty ignores them so the previous window
+	// continues, report tracks them as "(no active window)"
+	EmptyWindow string `yaml:"empty_window"`
+}
+
+type Device struct {
+	ID   string `yaml:"id" env:"DEVICE_ID"`
+	Name string `yaml:"name" env:"DEVICE_NAME"`
+}
+
+type Auth struct {
+	DeviceToken string `yaml:"device_token" env:"DEVICE_TOKEN"`
+	// CallbackPort is the preferred port for the OAuth callback server. A
+	// negative value lets the OS pick a free port
+	CallbackPort int `yaml:"callback_port" env-default:"8080"`
+	// CallbackPortFixed fails authorization instead of falling back to
+	// another port when CallbackPort is busy, for pre-registered redirect URIs
+	CallbackPortFixed bool `yaml:"callback_port_fixed"`
+	// TokenStorage is where the device token is kept: config (plaintext in
+	// this file), keychain (OS secret store, falling back to an encrypted
+	// file) or file (encrypted file keyed to this machine)
+	TokenStorage string `yaml:"token_storage" env-default:"config"`
+}
+
+type Server struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port" env-default:"8765"`
+	// URLGranularity is how much of each extension URL is kept: full,
+	// path (drops query and fragment) or domain
+	URLGranularity string `yaml:"url_granularity" env-default:"full"`
+	// URLStripParams are query parameters (case-insensitive globs) removed
+	// from extension URLs; empty uses the built-in tracking/session list
+	URLStripParams []string `yaml:"url_strip_params"`
+	// URLKeepParams keeps every query parameter, for full fidelity
+	URLKeepParams bool `yaml:"url_keep_params"`
+	// URLDropFragment removes the #fragment from extension URLs
+	URLDropFragment bool `yaml:"url_drop_fragment"`
+	// Token is a shared secret the extension sends in X-Agent-Token; empty
+	// accepts requests from any local page. The time entry API requires it
+	// too. The admin endpoints always require it and are refused while it is
+	// empty.
+	Token string `yaml:"token" env:"SERVER_TOKEN"`
+	// RateLimit caps extension POSTs per second, allowing bursts of up to
+	// RateBurst requests (e.g. rapid tab switching). Negative disables.
+	RateLimit float64 `yaml:"rate_limit" env-default:"10"`
+	RateBurst int     `yaml:"rate_burst" env-default:"30"`
+	// ReadyMaxBacklog is how many queued events /api/v1/health/ready
+	// tolerates before reporting not ready. Negative ignores the backlog.
+	ReadyMaxBacklog int `yaml:"ready_max_backlog" env-default:"10000"`
+}
+
+// Queue bounds the local retry queue so a long-offline machine can't fill
+// the disk
+type Queue struct {
+	MaxSize  int    `yaml:"max_size" env-default:"100000"`      // events; negative for unbounded
+	Overflow string `yaml:"overflow" env-default:"drop_oldest"` // drop_oldest or reject_new
+	// DeadLetterAfter is how many failed attempts move an event to the
+	// dead-letter table, where it is kept for inspection or requeue
+	DeadLetterAfter int `yaml:"dead_letter_after" env-default:"10"`
+	// BatchSize is how many queued events are retried per request
+	BatchSize int `yaml:"batch_size" env-default:"100"`
+	// RetentionDays deletes locally stored events (queued, staged and
+	// dead-lettered) older than this many days even if they were never
+	// sent; 0 keeps them. Sent events are deleted as soon as they're sent.
+	RetentionDays int `yaml:"retention_days"`
+}
+
+// EventLog configures an optional local copy of every event as JSON lines,
+// for auditing or feeding other tools
+type EventLog struct {
+	Enabled bool `yaml:"enabled"`
+	// Path is relative to the agent root unless absolute
+	Path       string `yaml:"path" env-default:"logs/events.jsonl"`
+	MaxSizeMB  int    `yaml:"max_size_mb" env-default:"50"` // rotate above this size; negative never rotates
+	MaxBackups int    `yaml:"max_backups" env-default:"3"`  // rotated files kept; negative keeps none
+}
+
+// Metrics configures the Prometheus /metrics endpoint
+type Metrics struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address" env-default:"localhost:9464"`
+}
+
+// Privacy configures the blocklist of sensitive applications and domains.
+// Patterns are case-insensitive globs (*, ?) or plain substrings.
+type Privacy struct {
+	Action       string   `yaml:"action" env-default:"redact"` // redact or drop
+	Applications []string `yaml:"applications"`
+	Domains      []string `yaml:"domains"`
+}
+
+// Categories configures tagging events as productive/neutral/distracting
+type Categories struct {
+	Enabled bool `yaml:"enabled"`
+	// RulesFile is an optional YAML file of rules checked before the built-in
+	// defaults; relative paths resolve against the agent root
+	RulesFile string `yaml:"rules_file"`
+}
+
+// Projects configures attributing events to a project ID
+type Projects struct {
+	// Rules are checked in order and the first match sets the event's
+	// project; events matching no rule have no project
+	Rules []ProjectRule `yaml:"rules"`
+}
+
+// ProjectRule matches when every non-empty list has a matching pattern
+type ProjectRule struct {
+	Project      string   `yaml:"project"`
+	Applications []string `yaml:"applications"`
+	Titles       []string `yaml:"titles"`
+	Domains      []string `yaml:"domains"`
+}
+
+// Automation configures local rules that fire a webhook or command when an
+// event matches
+type Automation struct {
+	Rules []AutomationRule `yaml:"rules"`
+}
+
+type AutomationRule struct {
+	Name        string   `yaml:"name"`
+	Application string   `yaml:"application"`
+	Domain      string   `yaml:"domain"`
+	Status      string   `yaml:"status"`
+	Webhook     string   `yaml:"webhook"`
+	Command     []string `yaml:"command"`
+	MinInterval int      `yaml:"min_interval"` // seconds between firings, default 60
+}
+
+// ResolveConfigPath returns the config file to load. An explicit path wins;
+// otherwise CONFIG_PATH is consulted, then the well-known locations relative
+// to the working directory and the executable.
+func ResolveConfigPath(explicit string) (string, error) {
+	if explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			return "", fmt.Errorf("config file %q: %w", explicit, err)
+		}
+		return filepath.Abs(explicit)
+	}
+
+	if envPath := os.Getenv("CONFIG_PATH"); envPath != "" {
+		if _, err := os.Stat(envPath); err != nil {
+			return "", fmt.Errorf("CONFIG_PATH %q: %w", envPath, err)
+		}
+		return filepath.Abs(envPath)
+	}
+
+	candidates := []string{
+		filepath.Join("config", "local.yaml"),
+		filepath.Join("config", "config.yaml"),
+	}
+	if exe, err := os.Executable(); err == nil {
+		// Installed layout: <root>/bin/time-tracking.exe and <root>/config/config.yaml
+		exeDir := filepath.Dir(exe)
+		candidates = append(candidates,
+			filepath.Join(exeDir, "..", "config", "config.yaml"),
+			filepath.Join(exeDir, "config", "config.yaml"),
+		)
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return filepath.Abs(candidate)
+		}
+	}
+
+	return "", fmt.Errorf("no config file found (tried %s)", strings.Join(candidates, ", "))
+}
+
+// LoadConfig reads the config file at path, applies environment overrides
+// and validates the result.
+func LoadConfig(path string) (*Config, error) {
+	var cfg Config
+	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	cfg.BaseDir = baseDirFor(absPath)
+
+	storagePath, err := expandPath(cfg.StoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage_path %q: %w", cfg.StoragePath, err)
+	}
+	cfg.StoragePath = storagePath
+	if !filepath.IsAbs(cfg.StoragePath) {
+		cfg.StoragePath = filepath.Join(cfg.BaseDir, cfg.StoragePath)
+	}
+
+	if cfg.Categories.RulesFile != "" && !filepath.IsAbs(cfg.Categories.RulesFile) {
+		cfg.Categories.RulesFile = filepath.Join(cfg.BaseDir, cfg.Categories.RulesFile)
+	}
+	if !filepath.IsAbs(cfg.EventLog.Path) {
+		cfg.EventLog.Path = filepath.Join(cfg.BaseDir, cfg.EventLog.Path)
+	}
+	for _, path := range []*string{&cfg.Backend.TLS.CAFile, &cfg.Backend.TLS.CertFile, &cfg.Backend.TLS.KeyFile} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(cfg.BaseDir, *path)
+		}
+	}
+
+	baseURL, err := NormalizeBaseURL(cfg.Backend.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend.base_url %q: %w", cfg.Backend.BaseURL, err)
+	}
+	cfg.Backend.BaseURL = baseURL
+	for i, fallback := range cfg.Backend.FallbackURLs {
+		normalized, err := NormalizeBaseURL(fallback)
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend.fallback_urls entry %q: %w", fallback, err)
+		}
+		cfg.Backend.FallbackURLs[i] = normalized
+	}
+
+	loaded := cfg
+	cfg.loaded = &loaded
+
+	return &cfg, nil
+}
+
+// NormalizeBaseURL canonicalizes a backend base URL. A missing scheme defaults
+// to https, the host must be present, and trailing slashes are stripped so
+// endpoint paths can be joined onto the result.
+func NormalizeBaseURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("base URL is empty")
+	}
+
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse: %w", err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		u.Scheme = strings.ToLower(u.Scheme)
+	default:
+		return "", fmt.Errorf("unsupported scheme %q (must be http or https)", u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("missing host")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("must not contain a query or fragment")
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	return u.String(), nil
+}
+
+// expandPath expands environment variables and a leading ~ (the current
+// user's home directory) in path
+func expandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	return path, nil
+}
+
+// baseDirFor returns the agent root for a config file: the parent of the
+// config directory when the file lives in one, otherwise the file's directory.
+func baseDirFor(configPath string) string {
+	dir := filepath.Dir(configPath)
+	if strings.EqualFold(filepath.Base(dir), "config") {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
@@ -0,0 +1,42 @@
+package config
+
+import "testing"
+
+func TestNormalizeBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", raw: "https://api.example.com", want: "https://api.example.com"},
+		{name: "trailing slash", raw: "https://api.example.com/", want: "https://api.example.com"},
+		{name: "trailing slashes on path", raw: "https://api.example.com/v1//", want: "https://api.example.com/v1"},
+		{name: "missing scheme", raw: "api.example.com:4000", want: "https://api.example.com:4000"},
+		{name: "http kept", raw: "http://192.168.18.18:4000", want: "http://192.168.18.18:4000"},
+		{name: "case and whitespace", raw: "  HTTPS://API.Example.com/ ", want: "https://api.example.com"},
+		{name: "empty", raw: "  ", wantErr: true},
+		{name: "unsupported scheme", raw: "ftp://api.example.com", wantErr: true},
+		{name: "missing host", raw: "https:///v1", wantErr: true},
+		{name: "query", raw: "https://api.example.com/?env=prod", wantErr: true},
+		{name: "fragment", raw: "https://api.example.com/#top", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeBaseURL(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NormalizeBaseURL(%q) = %q, want error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeBaseURL(%q): %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Fatalf("NormalizeBaseURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Save writes cfg back to the YAML file at path. Only values changed since
+// LoadConfig are written, so environment overrides and resolved paths stay
+// out of the file; comments, key order and quoting of untouched entries are
+// kept. A Config not produced by LoadConfig is written in full.
+func Save(path string, cfg *Config) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	var updated yaml.Node
+	if err := updated.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	var base *yaml.Node
+	if cfg.loaded != nil {
+		base = &yaml.Node{}
+		if err := base.Encode(cfg.loaded); err != nil {
+			return fmt.Errorf("failed to encode loaded config: %w", err)
+		}
+	}
+	mergeChanged(doc.Content[0], &updated, base)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	encoder.Close()
+
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return err
+	}
+
+	// Later saves diff against what is now on disk
+	saved := *cfg
+	saved.loaded = nil
+	cfg.loaded = &saved
+	return nil
+}
+
+// mergeChanged copies the entries of the mapping src that differ from base
+// into the mapping dst
+func mergeChanged(dst, src, base *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i].Value, src.Content[i+1]
+		baseValue := mappingValue(base, key)
+		if baseValue != nil && nodesEqual(value, baseValue) {
+			continue
+		}
+
+		existing := mappingValue(dst, key)
+		switch {
+		case value.Kind == yaml.MappingNode:
+			if existing == nil || existing.Kind != yaml.MappingNode {
+				existing = setMappingValue(dst, key, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+			}
+			mergeChanged(existing, value, baseValue)
+		case existing != nil:
+			replaceValue(existing, value)
+		default:
+			setMappingValue(dst, key, value)
+		}
+	}
+}
+
+// replaceValue overwrites old with value in place, keeping old's comments
+// and, for strings and sequences, its quoting or flow style
+func replaceValue(old, value *yaml.Node) {
+	style := old.Style
+	head, line, foot := old.HeadComment, old.LineComment, old.FootComment
+	sameKind := old.Kind == value.Kind
+
+	*old = *value
+	old.HeadComment, old.LineComment, old.FootComment = head, line, foot
+	if sameKind && (value.Kind == yaml.SequenceNode || value.Kind == yaml.ScalarNode && value.Tag == "!!str") {
+		old.Style = style
+	}
+}
+
+// mappingValue returns the value for key in the mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key to value in the mapping node and returns the
+// stored value node
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return value
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+	return value
+}
+
+// nodesEqual reports whether two nodes serialize identically
+func nodesEqual(a, b *yaml.Node) bool {
+	encodedA, errA := yaml.Marshal(a)
+	encodedB, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// writeFileAtomic replaces path with data via a temp file, keeping the
+// existing file's permissions
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set config file permissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+	return nil
+}
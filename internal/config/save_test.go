@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const nestedConfig = `# Agent configuration
+storage_path: storage/agent.db
+backend:
+  base_url: https://api.example.com
+  timeout: 45 # seconds
+  tls:
+    ca_file: certs/ca.pem
+tracking:
+  min_dwell_ms: 500
+device:
+  name: laptop
+`
+
+func TestSaveKeepsNestedSections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(nestedConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	cfg.Device.ID = "device-1"
+	cfg.Backend.Proxy.URL = "http://proxy:3128"
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	for _, want := range []string{"# Agent configuration", "timeout: 45 # seconds", "ca_file: certs/ca.pem", "storage_path: storage/agent.db"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("saved file lost %q:\n%s", want, saved)
+		}
+	}
+	if strings.Contains(saved, "callback_port") {
+		t.Errorf("saved file gained unchanged defaults:\n%s", saved)
+	}
+
+	reloaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig after Save: %v", err)
+	}
+	if reloaded.Device.ID != "device-1" || reloaded.Device.Name != "laptop" {
+		t.Errorf("device = %+v, want the new ID and the original name", reloaded.Device)
+	}
+	if reloaded.Backend.Proxy.URL != "http://proxy:3128" {
+		t.Errorf("backend.proxy.url = %q, want the new proxy", reloaded.Backend.Proxy.URL)
+	}
+	if reloaded.Backend.Timeout != 45 || reloaded.Backend.BaseURL != "https://api.example.com" {
+		t.Errorf("backend = %+v, want the original timeout and base URL", reloaded.Backend)
+	}
+	if reloaded.Backend.TLS.CAFile != filepath.Join(reloaded.BaseDir, "certs/ca.pem") {
+		t.Errorf("backend.tls.ca_file = %q, want the original file", reloaded.Backend.TLS.CAFile)
+	}
+	if reloaded.Tracking.MinDwellMs != 500 {
+		t.Errorf("tracking.min_dwell_ms = %d, want 500", reloaded.Tracking.MinDwellMs)
+	}
+}
+
+func TestSaveWritesNewFileInFull(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := &Config{Backend: Backend{BaseURL: "https://api.example.com"}, Device: Device{ID: "device-1"}}
+
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	reloaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if reloaded.Device.ID != "device-1" || reloaded.Backend.BaseURL != "https://api.example.com" {
+		t.Errorf("reloaded %+v %+v", reloaded.Device, reloaded.Backend)
+	}
+}
@@ -3,18 +3,35 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"go.uber.org/zap"
 	_ "modernc.org/sqlite"
 )
 
+// busyTimeoutMs is how long a connection waits for another one's lock
+// (including one in a separate process) before failing with SQLITE_BUSY
+const busyTimeoutMs = 5000
+
 type DB struct {
 	*sql.DB
+	read   *sql.DB // Optional read-only pool for analytics queries
+	path   string
 	logger *zap.Logger
+
+	stopMaintenance chan struct{} // nil unless StartMaintenance was called
+	maintenanceDone chan struct{}
 }
 
 func New(storagePath string, logger *zap.Logger) (*DB, error) {
-	db, err := sql.Open("sqlite", storagePath+"?_foreign_keys=1&_journal_mode=WAL")
+	if err := ensureWritableDir(filepath.Dir(storagePath)); err != nil {
+		return nil, err
+	}
+
+	// WAL lets readers (including the optional read pool and other
+	// processes) proceed while the tracking path writes
+	db, err := sql.Open("sqlite", fmt.Sprintf("%s?_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)", storagePath, busyTimeoutMs))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -25,6 +42,7 @@ func New(storagePath string, logger *zap.Logger) (*DB, error) {
 
 	database := &DB{
 		DB:     db,
+		path:   storagePath,
 		logger: logger,
 	}
 
@@ -36,12 +54,35 @@ func New(storagePath string, logger *zap.Logger) (*DB, error) {
 	return database, nil
 }
 
-func (db *DB) migrate() error {
-	migrations := []string{
-		`CREATE TABLE IF NOT EXISTS schema_migrations (
-			version INTEGER PRIMARY KEY,
-			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
+// ensureWritableDir creates dir if it doesn't exist and checks that files can
+// be created in it, so a bad storage_path fails with a clear error instead of
+// SQLite's "unable to open database file"
+func ensureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory %q: %w", dir, err)
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return fmt.Errorf("storage directory %q is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return nil
+}
+
+// migration is one schema change. Versions must be unique and increasing;
+// append new migrations to the end of migrations and never edit applied ones.
+type migration struct {
+	version    int
+	statements []string
+}
+
+// migrations up to version 5 predate versioning and use IF NOT EXISTS, so
+// databases created before schema_migrations was tracked replay them safely
+var migrations = []migration{
+	{1, []string{
 		// Device info table
 		`CREATE TABLE IF NOT EXISTS device_info (
 			id INTEGER PRIMARY KEY,
@@ -63,19 +104,182 @@ func (db *DB) migrate() error {
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_pending_events_device ON pending_events(device_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_pending_events_created ON pending_events(created_at)`,
+	}},
+	{2, []string{
+		// Agent runtime state (key/value), e.g. the last-seen-alive heartbeat
+		`CREATE TABLE IF NOT EXISTS agent_state (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}},
+	{3, []string{
+		// Events buffered in the collector but not yet sent or queued
+		`CREATE TABLE IF NOT EXISTS staged_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_data TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}},
+	{4, []string{
+		// Events taken out of the retry queue, kept for inspection or requeue
+		`CREATE TABLE IF NOT EXISTS dead_letter_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_data TEXT NOT NULL,
+			device_id TEXT NOT NULL,
+			created_at TIMESTAMP,
+			retry_count INTEGER DEFAULT 0,
+			last_attempt TIMESTAMP,
+			reason TEXT,
+			dead_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}},
+	{5, []string{
+		// One queued copy per event idempotency key
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_pending_events_event_id
+			ON pending_events(json_extract(event_data, '$.eventId'))
+			WHERE json_extract(event_data, '$.eventId') IS NOT NULL`,
+	}},
+	{6, []string{
+		// Manually timed entries, e.g. from the local timer endpoints
+		`CREATE TABLE IF NOT EXISTS time_entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			project_id TEXT,
+			description TEXT,
+			start_time TIMESTAMP NOT NULL,
+			end_time TIMESTAMP,
+			duration_seconds INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_time_entries_user_start ON time_entries(user_id, start_time)`,
+	}},
+	{7, []string{
+		// Overlap checks look for entries still running or ending after a start
+		`CREATE INDEX IF NOT EXISTS idx_time_entries_user_end ON time_entries(user_id, end_time)`,
+	}},
+}
+
+// migrate applies migrations newer than the recorded schema version, each in
+// its own transaction together with its schema_migrations row
+func (db *DB) migrate() error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
 	}
 
-	for _, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
-			return fmt.Errorf("migration failed: %w", err)
+	applied := 0
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := db.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d failed: %w", m.version, err)
 		}
+		applied++
 	}
 
-	db.logger.Info("Database migrations completed")
+	db.logger.Info("Database migrations completed",
+		zap.Int("applied", applied),
+		zap.Int("schema_version", migrations[len(migrations)-1].version),
+	)
 	return nil
 }
 
+// applyMigration runs m and records it atomically
+func (db *DB) applyMigration(m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range m.statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, m.version); err != nil {
+		return fmt.Errorf("failed to record version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// OpenReadPool opens a separate read-only connection pool on the same file
+// so analytics queries don't compete with the tracking write path for
+// connections. Reads are served from the WAL snapshot and never block writers.
+func (db *DB) OpenReadPool(maxOpenConns int) error {
+	read, err := sql.Open("sqlite", readOnlyDSN(db.path))
+	if err != nil {
+		return fmt.Errorf("failed to open read pool: %w", err)
+	}
+	read.SetMaxOpenConns(maxOpenConns)
+	read.SetMaxIdleConns(maxOpenConns)
+
+	if err := read.Ping(); err != nil {
+		read.Close()
+		return fmt.Errorf("failed to ping read pool: %w", err)
+	}
+
+	db.read = read
+	db.logger.Info("Read-only database pool opened", zap.Int("max_open_conns", maxOpenConns))
+	return nil
+}
+
+// OpenReadOnly opens an existing database read-only, for tools such as the
+// report command that run alongside the agent. It never creates the file or
+// runs migrations. Thanks to WAL, reads see the last committed state and
+// neither block nor are blocked by the agent's writes; the busy timeout
+// covers the brief locks taken during checkpoints.
+func OpenReadOnly(storagePath string, logger *zap.Logger) (*DB, error) {
+	db, err := sql.Open("sqlite", readOnlyDSN(storagePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database read-only: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database read-only: %w", err)
+	}
+
+	return &DB{
+		DB:     db,
+		path:   storagePath,
+		logger: logger,
+	}, nil
+}
+
+// readOnlyDSN returns the connection string for a read-only connection to
+// the database at path
+func readOnlyDSN(path string) string {
+	return fmt.Sprintf("file:%s?mode=ro&_pragma=busy_timeout(%d)", filepath.ToSlash(path), busyTimeoutMs)
+}
+
+// Reader returns the read-only pool if one is open, otherwise the main connection
+func (db *DB) Reader() *sql.DB {
+	if db.read != nil {
+		return db.read
+	}
+	return db.DB
+}
+
 func (db *DB) Close() error {
+	db.stopMaintenanceLoop()
+	if db.read != nil {
+		if err := db.read.Close(); err != nil {
+			db.logger.Warn("Failed to close read pool", zap.Error(err))
+		}
+	}
 	if err := db.DB.Close(); err != nil {
 		return fmt.Errorf("failed to close database: %w", err)
 	}
@@ -0,0 +1,363 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestEnsureWritableDirCreatesMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+
+	if err := ensureWritableDir(dir); err != nil {
+		t.Fatalf("ensureWritableDir: %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		t.Fatalf("directory not created: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("write probe left behind: %v", entries)
+	}
+}
+
+func TestEnsureWritableDirUnderFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ensureWritableDir(filepath.Join(file, "storage"))
+	if err == nil || !strings.Contains(err.Error(), "failed to create storage directory") {
+		t.Fatalf("got %v, want a create error", err)
+	}
+}
+
+func TestEnsureWritableDirReadOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directory permissions don't restrict writes on Windows")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+
+	err := ensureWritableDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "is not writable") {
+		t.Fatalf("got %v, want a not writable error", err)
+	}
+}
+
+func TestNewFailsClearlyForBadStoragePath(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := New(filepath.Join(file, "agent.db"), zap.NewNop())
+	if err == nil || !strings.Contains(err.Error(), "storage directory") {
+		t.Fatalf("got %v, want a storage directory error", err)
+	}
+}
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "agent.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestReadPoolConcurrentWithWrites(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.OpenReadPool(4); err != nil {
+		t.Fatalf("OpenReadPool: %v", err)
+	}
+	if db.Reader() == db.DB {
+		t.Fatal("Reader returned the write connection with a read pool open")
+	}
+
+	const writes = 200
+	var wg sync.WaitGroup
+	errs := make(chan error, writes+100)
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < writes; i++ {
+			if _, err := db.Exec(`INSERT INTO agent_state (key, value) VALUES (?, ?)`, fmt.Sprintf("key-%d", i), "v"); err != nil {
+				errs <- fmt.Errorf("write %d: %w", i, err)
+				return
+			}
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := 0
+			for {
+				var count int
+				if err := db.Reader().QueryRow(`SELECT COUNT(*) FROM agent_state`).Scan(&count); err != nil {
+					errs <- fmt.Errorf("read: %w", err)
+					return
+				}
+				if count < last {
+					errs <- fmt.Errorf("read saw %d rows after %d", count, last)
+					return
+				}
+				last = count
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	var count int
+	if err := db.Reader().QueryRow(`SELECT COUNT(*) FROM agent_state`).Scan(&count); err != nil {
+		t.Fatalf("final read: %v", err)
+	}
+	if count != writes {
+		t.Errorf("read pool sees %d rows, want %d", count, writes)
+	}
+	if _, err := db.Reader().Exec(`INSERT INTO agent_state (key, value) VALUES ('x', 'y')`); err == nil {
+		t.Error("write through the read pool succeeded")
+	}
+}
+
+// schemaVersions returns the recorded migration versions in order
+func schemaVersions(t *testing.T, db *DB) []int {
+	t.Helper()
+	rows, err := db.Query(`SELECT version FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// schemaObjects returns the names of the tables and indexes in db
+func schemaObjects(t *testing.T, db *DB) map[string]bool {
+	t.Helper()
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type IN ('table', 'index') AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	objects := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		objects[name] = true
+	}
+	return objects
+}
+
+func allVersions() []int {
+	versions := make([]int, len(migrations))
+	for i, m := range migrations {
+		versions[i] = m.version
+	}
+	return versions
+}
+
+func TestMigrateEmptyDatabase(t *testing.T) {
+	db := newTestDB(t)
+
+	if got, want := schemaVersions(t, db), allVersions(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("schema versions = %v, want %v", got, want)
+	}
+	objects := schemaObjects(t, db)
+	for _, name := range []string{
+		"device_info", "pending_events", "agent_state", "staged_events", "dead_letter_events",
+		"time_entries", "idx_pending_events_event_id", "idx_time_entries_user_end",
+	} {
+		if !objects[name] {
+			t.Errorf("%s missing after migrating an empty database", name)
+		}
+	}
+
+	// Running again applies nothing
+	if err := db.migrate(); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if got := schemaVersions(t, db); len(got) != len(migrations) {
+		t.Fatalf("second migrate recorded %v", got)
+	}
+}
+
+func TestMigratePartiallyMigratedDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.db")
+
+	// Build a database that stopped at version 3, with data in it
+	saved := migrations
+	migrations = saved[:3]
+	db, err := New(path, zap.NewNop())
+	migrations = saved
+	if err != nil {
+		t.Fatalf("New at version 3: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO agent_state (key, value) VALUES ('last_seen', 'x')`); err != nil {
+		t.Fatal(err)
+	}
+	if objects := schemaObjects(t, db); objects["dead_letter_events"] || objects["time_entries"] {
+		t.Fatalf("version 3 database already has later tables: %v", objects)
+	}
+	db.Close()
+
+	db, err = New(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New upgrading from version 3: %v", err)
+	}
+	defer db.Close()
+
+	if got, want := schemaVersions(t, db), allVersions(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("schema versions = %v, want %v", got, want)
+	}
+	if objects := schemaObjects(t, db); !objects["dead_letter_events"] || !objects["time_entries"] {
+		t.Fatalf("later tables missing after upgrade: %v", objects)
+	}
+	var value string
+	if err := db.QueryRow(`SELECT value FROM agent_state WHERE key = 'last_seen'`).Scan(&value); err != nil || value != "x" {
+		t.Fatalf("existing data after upgrade = %q, %v", value, err)
+	}
+}
+
+func TestMigrateFailureRollsBackThatVersion(t *testing.T) {
+	db := newTestDB(t)
+
+	saved := migrations
+	t.Cleanup(func() { migrations = saved })
+	next := saved[len(saved)-1].version + 1
+	migrations = append(saved[:len(saved):len(saved)], migration{next, []string{
+		`CREATE TABLE half_done (id INTEGER)`,
+		`THIS IS NOT SQL`,
+	}})
+
+	if err := db.migrate(); err == nil {
+		t.Fatal("migrate succeeded with a broken migration")
+	}
+	if got := schemaVersions(t, db); got[len(got)-1] == next {
+		t.Fatalf("failed version %d recorded", next)
+	}
+	if schemaObjects(t, db)["half_done"] {
+		t.Fatal("failed migration left half_done behind")
+	}
+}
+
+func TestOpenReadOnlyReadsDuringWriteTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.db")
+	db, err := New(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO agent_state (key, value) VALUES ('committed', 'x')`); err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := OpenReadOnly(path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("OpenReadOnly: %v", err)
+	}
+	defer ro.Close()
+
+	// Hold a write transaction open with an uncommitted row
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO agent_state (key, value) VALUES ('pending', 'y')`); err != nil {
+		t.Fatal(err)
+	}
+
+	const readers = 8
+	counts := make(chan int, readers)
+	errs := make(chan error, readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			var count int
+			if err := ro.QueryRow(`SELECT COUNT(*) FROM agent_state`).Scan(&count); err != nil {
+				errs <- err
+				return
+			}
+			counts <- count
+		}()
+	}
+	// Reads must not wait for the transaction, which stays open throughout
+	deadline := time.After(time.Second)
+	for i := 0; i < readers; i++ {
+		select {
+		case count := <-counts:
+			if count != 1 {
+				t.Errorf("read-only reader saw %d rows, want only the committed one", count)
+			}
+		case err := <-errs:
+			t.Errorf("read during write transaction: %v", err)
+		case <-deadline:
+			t.Fatal("read-only reads blocked by the open write transaction")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	var count int
+	if err := ro.QueryRow(`SELECT COUNT(*) FROM agent_state`).Scan(&count); err != nil || count != 2 {
+		t.Errorf("after commit read %d rows (%v), want 2", count, err)
+	}
+	if _, err := ro.Exec(`INSERT INTO agent_state (key, value) VALUES ('ro', 'z')`); err == nil {
+		t.Error("write through a read-only connection succeeded")
+	}
+}
+
+func TestOpenReadOnlyMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.db")
+	if db, err := OpenReadOnly(path, zap.NewNop()); err == nil {
+		db.Close()
+		t.Fatal("OpenReadOnly succeeded without a database")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("OpenReadOnly created %s", path)
+	}
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// vacuumEvery is how many maintenance runs pass between VACUUM checks
+const vacuumEvery = 24
+
+// vacuumFreeRatio is the share of free pages above which VACUUM is worth
+// rewriting the file
+const vacuumFreeRatio = 0.25
+
+// StartMaintenance truncates the WAL every interval and, every vacuumEvery
+// runs, VACUUMs the file if enough of it is free pages. Close stops it.
+func (db *DB) StartMaintenance(interval time.Duration) {
+	if interval <= 0 || db.stopMaintenance != nil {
+		return
+	}
+	db.stopMaintenance = make(chan struct{})
+	db.maintenanceDone = make(chan struct{})
+
+	go func() {
+		defer close(db.maintenanceDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for run := 1; ; run++ {
+			select {
+			case <-ticker.C:
+				db.runMaintenance(run%vacuumEvery == 0)
+			case <-db.stopMaintenance:
+				return
+			}
+		}
+	}()
+}
+
+// stopMaintenanceLoop stops the maintenance goroutine and waits for an
+// in-progress run to finish
+func (db *DB) stopMaintenanceLoop() {
+	if db.stopMaintenance == nil {
+		return
+	}
+	close(db.stopMaintenance)
+	<-db.maintenanceDone
+	db.stopMaintenance = nil
+}
+
+// runMaintenance checkpoints the WAL and optionally VACUUMs
+func (db *DB) runMaintenance(vacuum bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	busy, err := db.checkpoint(ctx)
+	if err != nil {
+		db.logger.Warn("WAL checkpoint failed", zap.Error(err))
+		return
+	}
+	// A busy checkpoint means another connection is mid-transaction; VACUUM
+	// would have to wait for it (or fail), so leave it for the next round
+	if busy || !vacuum {
+		return
+	}
+
+	ratio, err := db.freeRatio(ctx)
+	if err != nil {
+		db.logger.Warn("Failed to read free page count", zap.Error(err))
+		return
+	}
+	if ratio < vacuumFreeRatio {
+		return
+	}
+
+	start := time.Now()
+	if _, err := db.ExecContext(ctx, `VACUUM`); err != nil {
+		db.logger.Warn("VACUUM failed", zap.Error(err))
+		return
+	}
+	// VACUUM writes the whole database through the WAL; truncate it again
+	if _, err := db.checkpoint(ctx); err != nil {
+		db.logger.Warn("WAL checkpoint failed", zap.Error(err))
+	}
+	db.logger.Info("Database vacuumed",
+		zap.Float64("free_ratio", ratio),
+		zap.Duration("took", time.Since(start)),
+	)
+}
+
+// checkpoint runs wal_checkpoint(TRUNCATE) and reports whether it was
+// blocked by another connection
+func (db *DB) checkpoint(ctx context.Context) (bool, error) {
+	var busy, logFrames, checkpointed int
+	err := db.QueryRowContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`).Scan(&busy, &logFrames, &checkpointed)
+	if err != nil {
+		return false, fmt.Errorf("wal_checkpoint: %w", err)
+	}
+	return busy != 0, nil
+}
+
+// freeRatio returns the share of database pages that are unused
+func (db *DB) freeRatio(ctx context.Context) (float64, error) {
+	var pages, free int
+	if err := db.QueryRowContext(ctx, `PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, fmt.Errorf("page_count: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, `PRAGMA freelist_count`).Scan(&free); err != nil {
+		return 0, fmt.Errorf("freelist_count: %w", err)
+	}
+	if pages == 0 {
+		return 0, nil
+	}
+	return float64(free) / float64(pages), nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 
@@ -18,21 +19,48 @@ func NewDeviceManager() *DeviceManager {
 	return &DeviceManager{}
 }
 
-// GetOrGenerateDeviceID gets the device ID from config or generates a new one
-func (dm *DeviceManager) GetOrGenerateDeviceID(existingID string) (string, error) {
+// deviceIDFileName is the file in the storage directory holding the device ID
+const deviceIDFileName = "device_id"
+
+// GetOrGenerateDeviceID gets the device ID from config, then from the device
+// ID file in storageDir, or generates a new one. A newly resolved ID is
+// written to storageDir so later runs reuse it even if the config can't be
+// updated or the UUID fallback was used.
+func (dm *DeviceManager) GetOrGenerateDeviceID(existingID string, storageDir string) (string, error) {
 	if existingID != "" {
 		return existingID, nil
 	}
 
+	idPath := filepath.Join(storageDir, deviceIDFileName)
+	if data, err := os.ReadFile(idPath); err == nil {
+		if storedID := strings.TrimSpace(string(data)); storedID != "" {
+			return storedID, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read device ID file: %w", err)
+	}
+
 	// Try to get platform-specific device ID
 	deviceID, err := dm.getPlatformDeviceID()
-	if err == nil && deviceID != "" {
-		return deviceID, nil
+	if err != nil || deviceID == "" {
+		// Fallback: generate UUID
+		deviceID = uuid.New().String()
 	}
 
-	// Fallback: generate UUID
-	newUUID := uuid.New()
-	return newUUID.String(), nil
+	if err := os.MkdirAll(storageDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	if err := os.WriteFile(idPath, []byte(deviceID+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to save device ID: %w", err)
+	}
+
+	return deviceID, nil
+}
+
+// MachineID returns the platform's machine identifier (MachineGuid,
+// Hardware UUID or machine-id), independent of any configured device ID
+func (dm *DeviceManager) MachineID() (string, error) {
+	return dm.getPlatformDeviceID()
 }
 
 // getPlatformDeviceID gets a platform-specific device identifier
@@ -51,7 +79,13 @@ func (dm *DeviceManager) getPlatformDeviceID() (string, error) {
 
 // getWindowsDeviceID gets Windows machine GUID
 func (dm *DeviceManager) getWindowsDeviceID() (string, error) {
-	// Try wmic csproduct get uuid
+	// Prefer the registry MachineGuid; wmic is deprecated and missing on
+	// recent Windows 11 builds
+	if guid, err := readMachineGUID(); err == nil {
+		return guid, nil
+	}
+
+	// Try wmic csproduct get uuid (older systems)
 	cmd := exec.Command("wmic", "csproduct", "get", "uuid")
 	output, err := cmd.Output()
 	if err == nil {
@@ -0,0 +1,80 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetOrGenerateDeviceIDPersists(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "storage")
+	dm := NewDeviceManager()
+
+	first, err := dm.GetOrGenerateDeviceID("", dir)
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if first == "" {
+		t.Fatal("first run returned an empty device ID")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, deviceIDFileName))
+	if err != nil {
+		t.Fatalf("device ID file not written: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != first {
+		t.Errorf("device ID file holds %q, want %q", got, first)
+	}
+
+	second, err := NewDeviceManager().GetOrGenerateDeviceID("", dir)
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if second != first {
+		t.Errorf("second run got %q, want the stored %q", second, first)
+	}
+}
+
+func TestGetOrGenerateDeviceIDPrefersStoredID(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, deviceIDFileName), []byte("  stored-id\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dm := NewDeviceManager()
+
+	got, err := dm.GetOrGenerateDeviceID("", dir)
+	if err != nil {
+		t.Fatalf("GetOrGenerateDeviceID: %v", err)
+	}
+	if got != "stored-id" {
+		t.Errorf("got %q, want the stored stored-id", got)
+	}
+
+	got, err = dm.GetOrGenerateDeviceID("configured-id", dir)
+	if err != nil {
+		t.Fatalf("GetOrGenerateDeviceID: %v", err)
+	}
+	if got != "configured-id" {
+		t.Errorf("got %q, want the configured ID", got)
+	}
+}
+
+func TestGetOrGenerateDeviceIDRegeneratesEmptyFile(t *testing.T) {
+	dir := t.TempDir()
+	idPath := filepath.Join(dir, deviceIDFileName)
+	if err := os.WriteFile(idPath, []byte("\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewDeviceManager().GetOrGenerateDeviceID("", dir)
+	if err != nil {
+		t.Fatalf("GetOrGenerateDeviceID: %v", err)
+	}
+	if got == "" {
+		t.Fatal("empty device ID file produced an empty ID")
+	}
+	data, _ := os.ReadFile(idPath)
+	if strings.TrimSpace(string(data)) != got {
+		t.Errorf("device ID file holds %q, want %q", data, got)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package device
+
+import "fmt"
+
+func readMachineGUID() (string, error) {
+	return "", fmt.Errorf("MachineGuid is only available on Windows")
+}
@@ -0,0 +1,30 @@
+//go:build windows
+// +build windows
+
+package device
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// readMachineGUID reads HKLM\SOFTWARE\Microsoft\Cryptography\MachineGuid,
+// which is set at OS install and stays stable across app reinstalls
+func readMachineGUID() (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Cryptography`,
+		registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return "", fmt.Errorf("failed to open Cryptography key: %w", err)
+	}
+	defer key.Close()
+
+	guid, _, err := key.GetStringValue("MachineGuid")
+	if err != nil {
+		return "", fmt.Errorf("failed to read MachineGuid: %w", err)
+	}
+	if guid == "" {
+		return "", fmt.Errorf("MachineGuid is empty")
+	}
+	return guid, nil
+}
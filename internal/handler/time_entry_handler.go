@@ -2,10 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"Mansoor88-6/time-tracking-agent/internal/apierror"
 	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/requestid"
 	"Mansoor88-6/time-tracking-agent/internal/service"
 
 	"go.uber.org/zap"
@@ -23,23 +28,36 @@ func NewTimeEntryHandler(service *service.TimeEntryService, logger *zap.Logger)
 	}
 }
 
+// log returns the handler's logger tagged with the request's ID
+func (h *TimeEntryHandler) log(r *http.Request) *zap.Logger {
+	return requestid.Logger(r.Context(), h.logger)
+}
+
 func (h *TimeEntryHandler) CreateTimeEntry(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var req models.CreateTimeEntryRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode request", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.log(r).Error("Failed to decode request", zap.Error(err))
+		apierror.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	entry, err := h.service.CreateTimeEntry(&req)
+	if errors.Is(err, service.ErrInvalidTimeEntry) {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidTimeEntry, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrOverlap) {
+		apierror.Write(w, http.StatusConflict, apierror.CodeOverlap, err.Error())
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to create time entry", zap.Error(err))
-		http.Error(w, "Failed to create time entry", http.StatusInternalServerError)
+		h.log(r).Error("Failed to create time entry", zap.Error(err))
+		apierror.Error(w, "Failed to create time entry", http.StatusInternalServerError)
 		return
 	}
 
@@ -48,28 +66,65 @@ func (h *TimeEntryHandler) CreateTimeEntry(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(entry)
 }
 
+// maxBulkEntries caps the rows accepted by one bulk import request
+const maxBulkEntries = 10000
+
+// BulkCreateTimeEntries imports an array of entries, reporting success or
+// failure per row. The response is 200 even when some rows fail.
+func (h *TimeEntryHandler) BulkCreateTimeEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqs []*models.CreateTimeEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		h.log(r).Error("Failed to decode request", zap.Error(err))
+		apierror.Error(w, "Invalid request body, expected an array of time entries", http.StatusBadRequest)
+		return
+	}
+	if len(reqs) > maxBulkEntries {
+		apierror.Error(w, "Too many entries, send at most "+strconv.Itoa(maxBulkEntries)+" per request", http.StatusRequestEntityTooLarge)
+		return
+	}
+	for i, req := range reqs {
+		if req == nil {
+			reqs[i] = &models.CreateTimeEntryRequest{}
+		}
+	}
+
+	resp := h.service.BulkCreateTimeEntries(reqs)
+	h.log(r).Info("Imported time entries",
+		zap.Int("created", resp.Created),
+		zap.Int("failed", resp.Failed),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 func (h *TimeEntryHandler) GetTimeEntry(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
-		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		apierror.Error(w, "Missing id parameter", http.StatusBadRequest)
 		return
 	}
 
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid id parameter", http.StatusBadRequest)
+		apierror.Error(w, "Invalid id parameter", http.StatusBadRequest)
 		return
 	}
 
 	entry, err := h.service.GetTimeEntry(id)
 	if err != nil {
-		h.logger.Error("Failed to get time entry", zap.Error(err))
-		http.Error(w, "Time entry not found", http.StatusNotFound)
+		h.log(r).Error("Failed to get time entry", zap.Error(err))
+		apierror.Error(w, "Time entry not found", http.StatusNotFound)
 		return
 	}
 
@@ -79,13 +134,13 @@ func (h *TimeEntryHandler) GetTimeEntry(w http.ResponseWriter, r *http.Request)
 
 func (h *TimeEntryHandler) GetTimeEntriesByUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "Missing user_id parameter", http.StatusBadRequest)
+	filter, err := parseTimeEntryFilter(r)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -102,46 +157,130 @@ func (h *TimeEntryHandler) GetTimeEntriesByUser(w http.ResponseWriter, r *http.R
 		}
 	}
 
-	entries, err := h.service.GetTimeEntriesByUser(userID, limit, offset)
+	page, err := h.service.ListTimeEntries(filter, limit, offset)
+	if errors.Is(err, service.ErrInvalidRange) {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRange, err.Error())
+		return
+	}
+	if err != nil {
+		h.log(r).Error("Failed to get time entries", zap.Error(err))
+		apierror.Error(w, "Failed to get time entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// format=array returns the bare list, for clients written before the
+	// paginated envelope
+	if r.URL.Query().Get("format") == "array" {
+		json.NewEncoder(w).Encode(page.Items)
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetTimeEntrySummary returns total durations for a user's entries, grouped
+// by group_by (project, day or both, comma separated). running=now counts
+// entries still running up to now; by default they are left out.
+func (h *TimeEntryHandler) GetTimeEntrySummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseTimeEntryFilter(r)
 	if err != nil {
-		h.logger.Error("Failed to get time entries", zap.Error(err))
-		http.Error(w, "Failed to get time entries", http.StatusInternalServerError)
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var groupBy []string
+	if value := r.URL.Query().Get("group_by"); value != "" {
+		groupBy = strings.Split(value, ",")
+	}
+
+	summary, err := h.service.SummarizeTimeEntries(filter, groupBy, r.URL.Query().Get("running"))
+	if errors.Is(err, service.ErrInvalidRange) {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRange, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrInvalidSummary) {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidSummary, err.Error())
+		return
+	}
+	if err != nil {
+		h.log(r).Error("Failed to summarize time entries", zap.Error(err))
+		apierror.Error(w, "Failed to summarize time entries", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(entries)
+	json.NewEncoder(w).Encode(summary)
+}
+
+// parseTimeEntryFilter reads user_id, the optional start and end (RFC 3339)
+// and project_id query parameters
+func parseTimeEntryFilter(r *http.Request) (*models.TimeEntryFilter, error) {
+	query := r.URL.Query()
+	userID := query.Get("user_id")
+	if userID == "" {
+		return nil, errors.New("Missing user_id parameter")
+	}
+
+	filter := &models.TimeEntryFilter{UserID: userID}
+	for param, dst := range map[string]**time.Time{"start": &filter.From, "end": &filter.To} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New("Invalid " + param + " parameter, expected RFC 3339")
+		}
+		*dst = &t
+	}
+	if projectID := query.Get("project_id"); projectID != "" {
+		filter.ProjectID = &projectID
+	}
+	return filter, nil
 }
 
 func (h *TimeEntryHandler) UpdateTimeEntry(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
-		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		apierror.Error(w, "Missing id parameter", http.StatusBadRequest)
 		return
 	}
 
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid id parameter", http.StatusBadRequest)
+		apierror.Error(w, "Invalid id parameter", http.StatusBadRequest)
 		return
 	}
 
 	var req models.UpdateTimeEntryRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode request", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.log(r).Error("Failed to decode request", zap.Error(err))
+		apierror.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	entry, err := h.service.UpdateTimeEntry(id, &req)
+	if errors.Is(err, service.ErrInvalidTimeEntry) {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidTimeEntry, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrOverlap) {
+		apierror.Write(w, http.StatusConflict, apierror.CodeOverlap, err.Error())
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to update time entry", zap.Error(err))
-		http.Error(w, "Failed to update time entry", http.StatusInternalServerError)
+		h.log(r).Error("Failed to update time entry", zap.Error(err))
+		apierror.Error(w, "Failed to update time entry", http.StatusInternalServerError)
 		return
 	}
 
@@ -151,25 +290,25 @@ func (h *TimeEntryHandler) UpdateTimeEntry(w http.ResponseWriter, r *http.Reques
 
 func (h *TimeEntryHandler) DeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
-		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		apierror.Error(w, "Missing id parameter", http.StatusBadRequest)
 		return
 	}
 
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid id parameter", http.StatusBadRequest)
+		apierror.Error(w, "Invalid id parameter", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.service.DeleteTimeEntry(id); err != nil {
-		h.logger.Error("Failed to delete time entry", zap.Error(err))
-		http.Error(w, "Failed to delete time entry", http.StatusInternalServerError)
+		h.log(r).Error("Failed to delete time entry", zap.Error(err))
+		apierror.Error(w, "Failed to delete time entry", http.StatusInternalServerError)
 		return
 	}
 
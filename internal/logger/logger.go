@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -12,9 +13,13 @@ type Logger struct {
 	*zap.Logger
 }
 
+// level is shared by every logger built by this package so SetLevel can
+// change verbosity without rebuilding them
+var level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
 // New creates a logger that writes to stderr (for development / console runs).
-func New(level, format string) (*Logger, error) {
-	zapLevel := parseLevel(level)
+func New(logLevel, format string) (*Logger, error) {
+	level.SetLevel(parseLevel(logLevel))
 
 	var config zap.Config
 	if format == "json" {
@@ -23,7 +28,7 @@ func New(level, format string) (*Logger, error) {
 		config = zap.NewDevelopmentConfig()
 	}
 
-	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Level = level
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
@@ -37,13 +42,13 @@ func New(level, format string) (*Logger, error) {
 
 // NewWithFile creates a logger that writes to both stderr and a log file.
 // This is used in production when the agent runs as a GUI process (no console).
-func NewWithFile(level, format, logDir string) (*Logger, error) {
-	zapLevel := parseLevel(level)
+func NewWithFile(logLevel, format, logDir string) (*Logger, error) {
+	level.SetLevel(parseLevel(logLevel))
 
 	// Ensure log directory exists
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		// Fall back to console-only logger
-		return New(level, format)
+		return New(logLevel, format)
 	}
 
 	logFilePath := filepath.Join(logDir, "agent.log")
@@ -52,7 +57,7 @@ func NewWithFile(level, format, logDir string) (*Logger, error) {
 	logFile, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		// Fall back to console-only logger
-		return New(level, format)
+		return New(logLevel, format)
 	}
 
 	// Create encoder config
@@ -69,8 +74,8 @@ func NewWithFile(level, format, logDir string) (*Logger, error) {
 
 	// Write to both file and stderr
 	core := zapcore.NewTee(
-		zapcore.NewCore(encoder, zapcore.AddSync(logFile), zapLevel),
-		zapcore.NewCore(encoder, zapcore.AddSync(os.Stderr), zapLevel),
+		zapcore.NewCore(encoder, zapcore.AddSync(logFile), level),
+		zapcore.NewCore(encoder, zapcore.AddSync(os.Stderr), level),
 	)
 
 	logger := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
@@ -78,6 +83,23 @@ func NewWithFile(level, format, logDir string) (*Logger, error) {
 	return &Logger{Logger: logger}, nil
 }
 
+// SetLevel changes the minimum level of all loggers at runtime. Safe to call
+// at any time; an empty level is info.
+func SetLevel(logLevel string) error {
+	switch logLevel {
+	case "", "debug", "info", "warn", "error":
+		level.SetLevel(parseLevel(logLevel))
+		return nil
+	default:
+		return fmt.Errorf("unknown log level %q (want debug, info, warn or error)", logLevel)
+	}
+}
+
+// Level returns the current minimum level
+func Level() string {
+	return level.Level().String()
+}
+
 func parseLevel(level string) zapcore.Level {
 	switch level {
 	case "debug":
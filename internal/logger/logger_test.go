@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSetLevelAffectsNextLogCalls(t *testing.T) {
+	previous := Level()
+	t.Cleanup(func() { SetLevel(previous) })
+
+	built, err := New("info", "json")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	// An observed logger on the shared level, to see which calls get through
+	core, logs := observer.New(level)
+	observed := zap.New(core)
+
+	observed.Debug("dropped at info")
+	observed.Info("kept at info")
+	if built.Core().Enabled(zapcore.DebugLevel) {
+		t.Fatal("built logger has debug enabled at info")
+	}
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel(debug): %v", err)
+	}
+	observed.Debug("kept at debug")
+	if !built.Core().Enabled(zapcore.DebugLevel) {
+		t.Fatal("built logger still has debug disabled after SetLevel(debug)")
+	}
+
+	if err := SetLevel("error"); err != nil {
+		t.Fatalf("SetLevel(error): %v", err)
+	}
+	observed.Warn("dropped at error")
+	observed.Error("kept at error")
+
+	var got []string
+	for _, entry := range logs.All() {
+		got = append(got, entry.Message)
+	}
+	want := []string{"kept at info", "kept at debug", "kept at error"}
+	if len(got) != len(want) {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("logged %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSetLevelRejectsUnknownLevel(t *testing.T) {
+	previous := Level()
+	t.Cleanup(func() { SetLevel(previous) })
+
+	if err := SetLevel("warn"); err != nil {
+		t.Fatalf("SetLevel(warn): %v", err)
+	}
+	if err := SetLevel("verbose"); err == nil {
+		t.Fatal("unknown level accepted")
+	}
+	if got := Level(); got != "warn" {
+		t.Fatalf("level = %s after a rejected change, want warn", got)
+	}
+	if err := SetLevel(""); err != nil || Level() != "info" {
+		t.Fatalf("empty level = %s, %v, want info", Level(), err)
+	}
+}
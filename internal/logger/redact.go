@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// RedactMode controls how window titles and URLs appear in log fields
+type RedactMode string
+
+const (
+	// RedactOff logs titles and URLs as they are
+	RedactOff RedactMode = "off"
+	// RedactHash replaces them with a short hash, so repeats of the same
+	// value can still be correlated
+	RedactHash RedactMode = "hash"
+	// RedactTruncate keeps the start of titles and only the host of URLs
+	RedactTruncate RedactMode = "truncate"
+)
+
+// truncatedTitleLength is how many characters of a title RedactTruncate keeps
+const truncatedTitleLength = 12
+
+var redactMode atomic.Value // RedactMode
+
+// SetRedaction sets how Title and URL render their values. Safe to call at
+// any time; an empty mode is RedactOff.
+func SetRedaction(mode string) error {
+	switch RedactMode(mode) {
+	case "", RedactOff:
+		redactMode.Store(RedactOff)
+	case RedactHash, RedactTruncate:
+		redactMode.Store(RedactMode(mode))
+	default:
+		return fmt.Errorf("unknown log redaction mode %q (want off, hash or truncate)", mode)
+	}
+	return nil
+}
+
+func currentRedaction() RedactMode {
+	mode, _ := redactMode.Load().(RedactMode)
+	return mode
+}
+
+// Title returns a log field for a window or tab title, redacted according to
+// SetRedaction. Application names are not sensitive and should be logged as
+// plain strings.
+func Title(key, title string) zap.Field {
+	if title == "" {
+		return zap.String(key, title)
+	}
+	switch currentRedaction() {
+	case RedactHash:
+		return zap.String(key, hashValue(title))
+	case RedactTruncate:
+		runes := []rune(title)
+		if len(runes) > truncatedTitleLength {
+			return zap.String(key, string(runes[:truncatedTitleLength])+"…")
+		}
+	}
+	return zap.String(key, title)
+}
+
+// URL returns a log field for a page URL, redacted according to
+// SetRedaction. Truncation keeps the scheme and host.
+func URL(key, rawURL string) zap.Field {
+	if rawURL == "" {
+		return zap.String(key, rawURL)
+	}
+	switch currentRedaction() {
+	case RedactHash:
+		return zap.String(key, hashValue(rawURL))
+	case RedactTruncate:
+		parsed, err := url.Parse(rawURL)
+		if err != nil || parsed.Host == "" {
+			return zap.String(key, hashValue(rawURL))
+		}
+		return zap.String(key, parsed.Scheme+"://"+parsed.Host+"/…")
+	}
+	return zap.String(key, rawURL)
+}
+
+// hashValue returns a short, stable digest of value
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc increments the counter by 1
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add increments the counter by n
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Histogram counts observations into cumulative buckets
+type Histogram struct {
+	buckets []float64 // Upper bounds, ascending
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds
+func NewHistogram(buckets []float64) *Histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{
+		buckets: sorted,
+		counts:  make([]uint64, len(sorted)),
+	}
+}
+
+// Observe records a single value
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// ObserveDuration records d in seconds
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// Metrics holds the agent's in-memory counters
+type Metrics struct {
+	EventsCollected  Counter
+	EventsRejected   Counter
+	BatchesSent      Counter
+	BatchesFailed    Counter
+	SendBatchLatency *Histogram
+}
+
+// New creates an empty metrics set
+func New() *Metrics {
+	return &Metrics{
+		SendBatchLatency: NewHistogram([]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}),
+	}
+}
+
+// Gauge is a point-in-time value supplied at scrape time
+type Gauge struct {
+	Name  string
+	Help  string
+	Value float64
+}
+
+// WritePrometheus writes the metrics and the given gauges in the Prometheus
+// text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer, gauges ...Gauge) error {
+	ew := &errWriter{w: w}
+
+	writeCounter(ew, "events_collected_total", "Tracking events handed to the collector.", m.EventsCollected.Value())
+	writeCounter(ew, "events_rejected_total", "Tracking events dropped by validation before sending.", m.EventsRejected.Value())
+	writeCounter(ew, "batches_sent_total", "Event batches successfully sent to the backend.", m.BatchesSent.Value())
+	writeCounter(ew, "batches_failed_total", "Event batches that failed to send.", m.BatchesFailed.Value())
+
+	for _, g := range gauges {
+		ew.printf("# HELP %s %s\n", g.Name, g.Help)
+		ew.printf("# TYPE %s gauge\n", g.Name)
+		ew.printf("%s %s\n", g.Name, formatFloat(g.Value))
+	}
+
+	m.SendBatchLatency.write(ew, "send_batch_duration_seconds", "Latency of SendBatch requests to the backend.")
+
+	return ew.err
+}
+
+func writeCounter(ew *errWriter, name, help string, value uint64) {
+	ew.printf("# HELP %s %s\n", name, help)
+	ew.printf("# TYPE %s counter\n", name)
+	ew.printf("%s %d\n", name, value)
+}
+
+func (h *Histogram) write(ew *errWriter, name, help string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ew.printf("# HELP %s %s\n", name, help)
+	ew.printf("# TYPE %s histogram\n", name)
+	for i, upper := range h.buckets {
+		ew.printf("%s_bucket{le=\"%s\"} %d\n", name, formatFloat(upper), h.counts[i])
+	}
+	ew.printf("%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	ew.printf("%s_sum %s\n", name, formatFloat(h.sum))
+	ew.printf("%s_count %d\n", name, h.count)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// errWriter keeps the first write error so callers can check once at the end
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
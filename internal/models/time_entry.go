@@ -22,6 +22,56 @@ type CreateTimeEntryRequest struct {
 	EndTime     *time.Time `json:"end_time,omitempty"`
 }
 
+// TimeEntryFilter selects a user's time entries. From is inclusive and To is
+// exclusive, both compared with the entry's start time; nil fields don't
+// filter.
+type TimeEntryFilter struct {
+	UserID    string
+	From      *time.Time
+	To        *time.Time
+	ProjectID *string
+}
+
+// TimeEntryPage is one page of time entries. Total counts every entry
+// matching the filter, not just this page.
+type TimeEntryPage struct {
+	Items  []*TimeEntry `json:"items"`
+	Total  int          `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+// TimeEntrySummary totals time entry durations per group
+type TimeEntrySummary struct {
+	Groups       []*TimeEntryTotal `json:"groups"`
+	TotalSeconds int64             `json:"total_seconds"`
+}
+
+// TimeEntryTotal is the total duration of one group of entries. ProjectID
+// and Day are only set when grouping by them; Day is the UTC date the
+// entries started on.
+type TimeEntryTotal struct {
+	ProjectID    *string `json:"project_id,omitempty"`
+	Day          string  `json:"day,omitempty"`
+	TotalSeconds int64   `json:"total_seconds"`
+	Entries      int     `json:"entries"`
+}
+
+// BulkCreateResult is the outcome of one row of a bulk import: the created
+// entry, or why the row was rejected
+type BulkCreateResult struct {
+	Index int        `json:"index"`
+	Entry *TimeEntry `json:"entry,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// BulkCreateResponse reports a bulk import row by row, in input order
+type BulkCreateResponse struct {
+	Results []*BulkCreateResult `json:"results"`
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
+}
+
 type UpdateTimeEntryRequest struct {
 	ProjectID       *string    `json:"project_id,omitempty"`
 	Description     *string    `json:"description,omitempty"`
@@ -29,3 +79,16 @@ type UpdateTimeEntryRequest struct {
 	EndTime         *time.Time `json:"end_time,omitempty"`
 	DurationSeconds *int64     `json:"duration_seconds,omitempty"`
 }
+
+// StartTimerRequest starts the manual timer
+type StartTimerRequest struct {
+	Description *string `json:"description,omitempty"`
+	ProjectID   *string `json:"project_id,omitempty"`
+}
+
+// RunningTimer is the manual timer while it runs
+type RunningTimer struct {
+	Description *string   `json:"description,omitempty"`
+	ProjectID   *string   `json:"project_id,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+}
@@ -12,18 +12,25 @@ type BrowserEvent struct {
 	Sequence  int    `json:"sequence"`
 }
 
+// PresenceEvent is a human-presence signal from an external sensor service
+type PresenceEvent struct {
+	Present *bool `json:"present"`
+}
+
 // AppFocusEvent represents an application focus event from OS
 type AppFocusEvent struct {
 	Type        string `json:"type"`        // "APP_FOCUS"
 	Application string `json:"application"`
 	PID         int    `json:"pid"`
 	Title       string `json:"title"`
+	Fullscreen  bool   `json:"fullscreen,omitempty"`
 	Timestamp   int64  `json:"timestamp"`   // Unix ms
 	Sequence    int    `json:"sequence"`
 }
 
 // TrackingEvent represents a single tracking event matching the backend EventDto structure
 type TrackingEvent struct {
+	EventID     string  `json:"eventId,omitempty"` // Client-generated idempotency key (UUID)
 	DeviceID    string  `json:"deviceId"`
 	Timestamp   int64   `json:"timestamp"` // Unix timestamp in milliseconds (session start)
 	Status       string  `json:"status"`    // active, idle, away, offline
@@ -38,6 +45,10 @@ type TrackingEvent struct {
 	Sequence     *int    `json:"sequence,omitempty"`
 	StartTime    *int64  `json:"startTime,omitempty"`    // Unix ms
 	EndTime      *int64  `json:"endTime,omitempty"`      // Unix ms
+	Category     *string `json:"category,omitempty"`     // productive, neutral, distracting, uncategorized or a custom label
+	Intensity    *string `json:"intensity,omitempty"`    // low, medium, high input rate over the event
+	OnBattery    *bool   `json:"onBattery,omitempty"`    // Set when power state is included
+	BatteryLevel *int    `json:"batteryLevel,omitempty"` // Percent, when known
 }
 
 // BatchEventRequest represents a batch of events to send to the backend
@@ -47,6 +58,13 @@ type BatchEventRequest struct {
 	BatchTimestamp int64          `json:"batchTimestamp"` // Unix timestamp in milliseconds
 }
 
+// BatchEventRequestV2 is the batch body for backends speaking API v2
+type BatchEventRequestV2 struct {
+	DeviceID string          `json:"deviceId"`
+	SentAt   string          `json:"sentAt"` // RFC 3339 UTC with milliseconds
+	Events   []TrackingEvent `json:"events"`
+}
+
 // EventStatus constants matching backend EventStatus enum
 const (
 	StatusActive  = "active"
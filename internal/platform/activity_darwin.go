@@ -0,0 +1,53 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package platform
+
+/*
+#cgo LDFLAGS: -framework ApplicationServices
+#include <ApplicationServices/ApplicationServices.h>
+
+static double seconds_since(CGEventType type) {
+	return CGEventSourceSecondsSinceLastEventType(kCGEventSourceStateHIDSystemState, type);
+}
+
+// listen_access reports whether Input Monitoring is granted, prompting the
+// user to grant it if not
+static int listen_access(void) {
+	if (CGPreflightListenEventAccess()) {
+		return 1;
+	}
+	CGRequestListenEventAccess();
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+)
+
+// inputEventTypes maps the activity types reported by the Windows hooks to
+// their Quartz event types
+var inputEventTypes = map[ActivityType]C.CGEventType{
+	ActivityMouseMove:  C.kCGEventMouseMoved,
+	ActivityMouseClick: C.kCGEventLeftMouseDown,
+	ActivityKeyPress:   C.kCGEventKeyDown,
+	ActivityScroll:     C.kCGEventScrollWheel,
+	ActivityMouseDrag:  C.kCGEventLeftMouseDragged,
+}
+
+func checkInputMonitoringAccess() error {
+	if C.listen_access() == 0 {
+		return fmt.Errorf("activity monitoring requires Input Monitoring permission: allow the agent in " +
+			"System Settings > Privacy & Security > Input Monitoring, then restart it")
+	}
+	return nil
+}
+
+// sinceLastInput returns how long ago the last event of type t occurred
+func sinceLastInput(t ActivityType) time.Duration {
+	seconds := C.seconds_since(inputEventTypes[t])
+	return time.Duration(float64(seconds) * float64(time.Second))
+}
@@ -0,0 +1,17 @@
+//go:build darwin && !cgo
+// +build darwin,!cgo
+
+package platform
+
+import (
+	"fmt"
+	"time"
+)
+
+func checkInputMonitoringAccess() error {
+	return fmt.Errorf("activity monitoring on macOS requires a cgo build")
+}
+
+func sinceLastInput(t ActivityType) time.Duration {
+	return 0
+}
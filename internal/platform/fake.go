@@ -0,0 +1,252 @@
+package platform
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FakePlatform is a scriptable Platform for tests. It has no OS hooks:
+// tests set what GetActiveWindow returns and inject activity, lock and sleep
+// events through the registered callbacks. Safe for concurrent use.
+type FakePlatform struct {
+	mu sync.Mutex
+
+	window      *WindowInfo
+	windowErr   error
+	windowFunc  func() (*WindowInfo, error)
+	activity    func(ActivityEvent)
+	session     func(locked bool)
+	sleep       func(asleep bool, at time.Time)
+	openedURLs  []string
+	deviceID    string
+	systemInfo  SystemInfo
+	powerStatus PowerStatus
+	moves       mouseMoveSampler
+}
+
+// NewFakePlatform creates a fake with no active window, a fixed device ID
+// and mains power
+func NewFakePlatform() *FakePlatform {
+	return &FakePlatform{
+		deviceID: "fake-device",
+		systemInfo: SystemInfo{
+			OS:        "fake",
+			OSVersion: "1.0",
+			Arch:      "amd64",
+			Hostname:  "fake-host",
+		},
+		powerStatus: PowerStatus{OnACPower: true, BatteryPercent: -1},
+	}
+}
+
+// SetActiveWindow sets what GetActiveWindow returns. A copy of window is
+// kept, with Timestamp filled in on each call if it is zero.
+func (f *FakePlatform) SetActiveWindow(window *WindowInfo, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if window != nil {
+		copied := *window
+		window = &copied
+	}
+	f.window, f.windowErr, f.windowFunc = window, err, nil
+}
+
+// SetActiveWindowFunc makes GetActiveWindow call fn, e.g. to block or to
+// return a sequence of windows. fn runs without the fake's lock held.
+func (f *FakePlatform) SetActiveWindowFunc(fn func() (*WindowInfo, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.windowFunc = fn
+}
+
+// GetActiveWindow returns the scripted window
+func (f *FakePlatform) GetActiveWindow() (*WindowInfo, error) {
+	f.mu.Lock()
+	fn := f.windowFunc
+	window, err := f.window, f.windowErr
+	f.mu.Unlock()
+
+	if fn != nil {
+		return fn()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if window == nil {
+		return nil, fmt.Errorf("no active window")
+	}
+	copied := *window
+	if copied.Timestamp.IsZero() {
+		copied.Timestamp = time.Now()
+	}
+	return &copied, nil
+}
+
+// StartActivityMonitoring registers callback for EmitActivity
+func (f *FakePlatform) StartActivityMonitoring(callback func(ActivityEvent)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.activity != nil {
+		return fmt.Errorf("activity monitoring already started")
+	}
+	f.activity = callback
+	return nil
+}
+
+// StopActivityMonitoring unregisters the activity callback
+func (f *FakePlatform) StopActivityMonitoring() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.activity = nil
+	return nil
+}
+
+// EmitActivity delivers event to the activity callback as a hook would.
+// It reports false if monitoring isn't running or a mouse move was dropped
+// by SetMouseMoveInterval. A zero Timestamp is set to now.
+func (f *FakePlatform) EmitActivity(event ActivityEvent) bool {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	f.mu.Lock()
+	callback := f.activity
+	sampled := true
+	if callback != nil && (event.Type == ActivityMouseMove || event.Type == ActivityMouseDrag) {
+		sampled = f.moves.sample(event.Timestamp)
+	}
+	f.mu.Unlock()
+
+	if callback == nil || !sampled {
+		return false
+	}
+	callback(event)
+	return true
+}
+
+// SetMouseMoveInterval implements MouseMoveSampler, throttling moves and
+// drags by their timestamps. Unlike the Windows hooks the fake reports every
+// move by default.
+func (f *FakePlatform) SetMouseMoveInterval(interval time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.moves.setInterval(interval)
+}
+
+// StartSessionMonitoring registers callback for EmitLock
+func (f *FakePlatform) StartSessionMonitoring(callback func(locked bool)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session = callback
+	return nil
+}
+
+// StopSessionMonitoring unregisters the session callback
+func (f *FakePlatform) StopSessionMonitoring() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session = nil
+	return nil
+}
+
+// EmitLock reports a session lock or unlock. It reports false if session
+// monitoring isn't running.
+func (f *FakePlatform) EmitLock(locked bool) bool {
+	f.mu.Lock()
+	callback := f.session
+	f.mu.Unlock()
+
+	if callback == nil {
+		return false
+	}
+	callback(locked)
+	return true
+}
+
+// StartSleepMonitoring registers callback for EmitSleep
+func (f *FakePlatform) StartSleepMonitoring(callback func(asleep bool, at time.Time)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sleep = callback
+	return nil
+}
+
+// StopSleepMonitoring unregisters the sleep callback
+func (f *FakePlatform) StopSleepMonitoring() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sleep = nil
+	return nil
+}
+
+// EmitSleep reports a system sleep or resume at the given time. It reports
+// false if sleep monitoring isn't running.
+func (f *FakePlatform) EmitSleep(asleep bool, at time.Time) bool {
+	f.mu.Lock()
+	callback := f.sleep
+	f.mu.Unlock()
+
+	if callback == nil {
+		return false
+	}
+	callback(asleep, at)
+	return true
+}
+
+// SetDeviceID sets what GetDeviceID returns
+func (f *FakePlatform) SetDeviceID(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deviceID = id
+}
+
+// GetDeviceID returns the configured device ID
+func (f *FakePlatform) GetDeviceID() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deviceID, nil
+}
+
+// GetSystemInfo returns fixed system information
+func (f *FakePlatform) GetSystemInfo() (*SystemInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info := f.systemInfo
+	return &info, nil
+}
+
+// OpenBrowser records url instead of opening a browser
+func (f *FakePlatform) OpenBrowser(url string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.openedURLs = append(f.openedURLs, url)
+	return nil
+}
+
+// OpenedURLs returns the URLs passed to OpenBrowser, oldest first
+func (f *FakePlatform) OpenedURLs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.openedURLs...)
+}
+
+// SetPowerStatus sets what GetPowerStatus returns
+func (f *FakePlatform) SetPowerStatus(status PowerStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.powerStatus = status
+}
+
+// GetPowerStatus returns the configured power status
+func (f *FakePlatform) GetPowerStatus() (*PowerStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := f.powerStatus
+	return &status, nil
+}
+
+var (
+	_ Platform       = (*FakePlatform)(nil)
+	_ SessionMonitor = (*FakePlatform)(nil)
+	_ SleepMonitor   = (*FakePlatform)(nil)
+)
@@ -0,0 +1,63 @@
+//go:build linux && cgo
+// +build linux,cgo
+
+package platform
+
+/*
+#cgo LDFLAGS: -lX11 -lXss
+#include <X11/Xlib.h>
+#include <X11/extensions/scrnsaver.h>
+
+// query_idle stores the X server's idle time in milliseconds, returning 0 on failure
+static int query_idle(Display *dpy, unsigned long *idle_ms) {
+	XScreenSaverInfo *info = XScreenSaverAllocInfo();
+	if (info == NULL) {
+		return 0;
+	}
+	Status ok = XScreenSaverQueryInfo(dpy, DefaultRootWindow(dpy), info);
+	if (ok) {
+		*idle_ms = info->idle;
+	}
+	XFree(info);
+	return ok;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+)
+
+// xIdleSource reads the system idle time from the XScreenSaver extension.
+// Xlib is not thread-safe, so a source must only be used from one goroutine.
+type xIdleSource struct {
+	display *C.Display
+}
+
+func newIdleSource() (idleSource, error) {
+	display := C.XOpenDisplay(nil)
+	if display == nil {
+		return nil, fmt.Errorf("cannot open X display (is DISPLAY set?)")
+	}
+
+	var eventBase, errorBase C.int
+	if C.XScreenSaverQueryExtension(display, &eventBase, &errorBase) == 0 {
+		C.XCloseDisplay(display)
+		return nil, fmt.Errorf("X server does not support the XScreenSaver extension")
+	}
+
+	return &xIdleSource{display: display}, nil
+}
+
+func (s *xIdleSource) IdleTime() (time.Duration, error) {
+	var idleMs C.ulong
+	if C.query_idle(s.display, &idleMs) == 0 {
+		return 0, fmt.Errorf("XScreenSaverQueryInfo failed")
+	}
+	return time.Duration(idleMs) * time.Millisecond, nil
+}
+
+func (s *xIdleSource) Close() {
+	C.XCloseDisplay(s.display)
+}
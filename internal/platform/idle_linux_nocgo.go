@@ -0,0 +1,10 @@
+//go:build linux && !cgo
+// +build linux,!cgo
+
+package platform
+
+import "fmt"
+
+func newIdleSource() (idleSource, error) {
+	return nil, fmt.Errorf("idle detection requires a cgo build (libX11 and libXss)")
+}
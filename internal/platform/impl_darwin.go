@@ -8,26 +8,108 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"sync"
+	"time"
 )
 
-type darwinImpl struct{}
+// inputPollInterval is how often the time since the last input is sampled
+const inputPollInterval = time.Second
+
+// inputMonitoringAccess checks the Input Monitoring permission; replaced in
+// tests
+var inputMonitoringAccess = checkInputMonitoringAccess
+
+type darwinImpl struct {
+	mu                  sync.Mutex
+	accessibilityWarned bool
+	stopChan            chan struct{}
+	done                chan struct{}
+	sleepStop           chan struct{}
+	sleepDone           chan struct{}
+}
 
 func newDarwinPlatform() (Platform, error) {
-	return nil, fmt.Errorf("macOS implementation not yet available")
+	return &darwinImpl{}, nil
 }
 
 func (p *darwinImpl) GetActiveWindow() (*WindowInfo, error) {
-	return nil, fmt.Errorf("not implemented")
+	return p.frontmostWindow()
+}
+
+// warnAccessibility reports whether the missing Accessibility permission
+// still needs to be surfaced. It is reported once as an error so the tracker
+// logs it; after that windows are returned without titles.
+func (p *darwinImpl) warnAccessibility() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.accessibilityWarned {
+		return false
+	}
+	p.accessibilityWarned = true
+	return true
 }
 
+// StartActivityMonitoring polls the HID system's time since the last mouse
+// and keyboard events, emitting the same activity types as the Windows hooks
 func (p *darwinImpl) StartActivityMonitoring(callback func(ActivityEvent)) error {
-	return fmt.Errorf("not implemented")
+	if err := inputMonitoringAccess(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopChan != nil {
+		return fmt.Errorf("activity monitoring already started")
+	}
+
+	p.stopChan = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.pollInput(callback, p.stopChan, p.done)
+
+	return nil
 }
 
 func (p *darwinImpl) StopActivityMonitoring() error {
+	p.mu.Lock()
+	stopChan, done := p.stopChan, p.done
+	p.stopChan, p.done = nil, nil
+	p.mu.Unlock()
+
+	if stopChan != nil {
+		close(stopChan)
+		<-done
+	}
 	return nil
 }
 
+// pollInput emits an activity event for each input type seen since the
+// previous sample
+func (p *darwinImpl) pollInput(callback func(ActivityEvent), stopChan, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(inputPollInterval)
+	defer ticker.Stop()
+
+	types := []ActivityType{ActivityMouseMove, ActivityMouseClick, ActivityKeyPress, ActivityScroll, ActivityMouseDrag}
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			for _, t := range types {
+				if since := sinceLastInput(t); since < inputPollInterval {
+					callback(ActivityEvent{
+						Type:      t,
+						Timestamp: now.Add(-since),
+					})
+				}
+			}
+		case <-stopChan:
+			return
+		}
+	}
+}
+
 func (p *darwinImpl) GetDeviceID() (string, error) {
 	hostname, _ := os.Hostname()
 	if hostname != "" {
@@ -46,6 +128,10 @@ func (p *darwinImpl) GetSystemInfo() (*SystemInfo, error) {
 	}, nil
 }
 
+func (p *darwinImpl) GetPowerStatus() (*PowerStatus, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (p *darwinImpl) OpenBrowser(url string) error {
 	// Use macOS open command
 	cmd := exec.Command("open", url)
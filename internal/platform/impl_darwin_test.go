@@ -0,0 +1,49 @@
+//go:build darwin
+// +build darwin
+
+package platform
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDarwinActivityMonitoringWithoutPermission(t *testing.T) {
+	denied := errors.New("activity monitoring requires Input Monitoring permission")
+	saved := inputMonitoringAccess
+	inputMonitoringAccess = func() error { return denied }
+	t.Cleanup(func() { inputMonitoringAccess = saved })
+
+	p := &darwinImpl{}
+	called := false
+	err := p.StartActivityMonitoring(func(ActivityEvent) { called = true })
+	if !errors.Is(err, denied) {
+		t.Fatalf("StartActivityMonitoring error = %v, want the permission error", err)
+	}
+	if p.stopChan != nil {
+		t.Fatal("polling started without permission")
+	}
+	if err := p.StopActivityMonitoring(); err != nil {
+		t.Fatalf("StopActivityMonitoring: %v", err)
+	}
+	if called {
+		t.Fatal("activity reported without permission")
+	}
+}
+
+func TestDarwinActivityMonitoringStartsWithPermission(t *testing.T) {
+	saved := inputMonitoringAccess
+	inputMonitoringAccess = func() error { return nil }
+	t.Cleanup(func() { inputMonitoringAccess = saved })
+
+	p := &darwinImpl{}
+	if err := p.StartActivityMonitoring(func(ActivityEvent) {}); err != nil {
+		t.Fatalf("StartActivityMonitoring: %v", err)
+	}
+	if err := p.StartActivityMonitoring(func(ActivityEvent) {}); err == nil {
+		t.Fatal("second StartActivityMonitoring succeeded")
+	}
+	if err := p.StopActivityMonitoring(); err != nil {
+		t.Fatalf("StopActivityMonitoring: %v", err)
+	}
+}
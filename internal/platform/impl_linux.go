@@ -7,27 +7,108 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-type linuxImpl struct{}
+// idlePollInterval is how often the X server idle time is sampled
+var idlePollInterval = time.Second
 
+// idleSource reports how long the user has been idle
+type idleSource interface {
+	IdleTime() (time.Duration, error)
+	Close()
+}
+
+type linuxImpl struct {
+	mu       sync.Mutex
+	openIdle func() (idleSource, error) // Opens the idle source when monitoring starts
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// newLinuxPlatform returns the Linux platform. Activity is detected from the
+// X server idle time; active window tracking is not implemented yet, so
+// GetActiveWindow returns ErrActiveWindowUnsupported and only activity
+// states are reported.
 func newLinuxPlatform() (Platform, error) {
-	return nil, fmt.Errorf("Linux implementation not yet available")
+	return &linuxImpl{openIdle: newIdleSource}, nil
 }
 
 func (p *linuxImpl) GetActiveWindow() (*WindowInfo, error) {
-	return nil, fmt.Errorf("not implemented")
+	return nil, ErrActiveWindowUnsupported
 }
 
+// StartActivityMonitoring polls the X server idle time instead of installing
+// global input hooks, reporting activity whenever the idle time resets
 func (p *linuxImpl) StartActivityMonitoring(callback func(ActivityEvent)) error {
-	return fmt.Errorf("not implemented")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopChan != nil {
+		return fmt.Errorf("activity monitoring already started")
+	}
+
+	source, err := p.openIdle()
+	if err != nil {
+		return fmt.Errorf("activity monitoring unavailable: %w", err)
+	}
+
+	p.stopChan = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.pollIdle(source, callback, p.stopChan, p.done)
+
+	return nil
 }
 
 func (p *linuxImpl) StopActivityMonitoring() error {
+	p.mu.Lock()
+	stopChan, done := p.stopChan, p.done
+	p.stopChan, p.done = nil, nil
+	p.mu.Unlock()
+
+	if stopChan != nil {
+		close(stopChan)
+		<-done
+	}
 	return nil
 }
 
+// pollIdle samples the idle time and synthesizes an activity event when it
+// drops, i.e. the user provided input since the previous sample. The source
+// is only touched from this goroutine.
+func (p *linuxImpl) pollIdle(source idleSource, callback func(ActivityEvent), stopChan, done chan struct{}) {
+	defer close(done)
+	defer source.Close()
+
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+
+	var lastIdle time.Duration
+	for {
+		select {
+		case <-ticker.C:
+			idle, err := source.IdleTime()
+			if err != nil {
+				continue
+			}
+			if idle < lastIdle || idle < idlePollInterval {
+				callback(ActivityEvent{
+					Type:      ActivityInput,
+					Timestamp: time.Now().Add(-idle),
+				})
+			}
+			lastIdle = idle
+		case <-stopChan:
+			return
+		}
+	}
+}
+
 func (p *linuxImpl) GetDeviceID() (string, error) {
 	hostname, _ := os.Hostname()
 	if hostname != "" {
@@ -57,3 +138,42 @@ func (p *linuxImpl) OpenBrowser(url string) error {
 	}
 	return fmt.Errorf("no browser found")
 }
+
+// GetPowerStatus reads the power supplies exposed under /sys/class/power_supply
+func (p *linuxImpl) GetPowerStatus() (*PowerStatus, error) {
+	supplies, err := filepath.Glob("/sys/class/power_supply/*")
+	if err != nil {
+		return nil, err
+	}
+
+	status := &PowerStatus{BatteryPercent: -1}
+	sawMains := false
+	for _, dir := range supplies {
+		switch readSysfs(dir, "type") {
+		case "Mains":
+			sawMains = true
+			if readSysfs(dir, "online") == "1" {
+				status.OnACPower = true
+			}
+		case "Battery":
+			status.HasBattery = true
+			if capacity, err := strconv.Atoi(readSysfs(dir, "capacity")); err == nil {
+				status.BatteryPercent = capacity
+			}
+		}
+	}
+
+	// Desktops without a mains entry are always on AC
+	if !sawMains && !status.HasBattery {
+		status.OnACPower = true
+	}
+	return status, nil
+}
+
+func readSysfs(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,95 @@
+//go:build linux
+// +build linux
+
+package platform
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedIdle returns a fixed sequence of idle times, then repeats the last
+type scriptedIdle struct {
+	mu     sync.Mutex
+	idle   []time.Duration
+	closed bool
+}
+
+func (s *scriptedIdle) IdleTime() (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idle := s.idle[0]
+	if len(s.idle) > 1 {
+		s.idle = s.idle[1:]
+	}
+	return idle, nil
+}
+
+func (s *scriptedIdle) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+func TestNewLinuxPlatform(t *testing.T) {
+	p, err := newLinuxPlatform()
+	if err != nil {
+		t.Fatalf("newLinuxPlatform: %v", err)
+	}
+	if _, err := p.GetActiveWindow(); !errors.Is(err, ErrActiveWindowUnsupported) {
+		t.Fatalf("GetActiveWindow error = %v, want ErrActiveWindowUnsupported", err)
+	}
+}
+
+func TestLinuxActivityFromIdleTime(t *testing.T) {
+	saved := idlePollInterval
+	idlePollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { idlePollInterval = saved })
+
+	// Idle grows, resets (input), then grows without further input
+	source := &scriptedIdle{idle: []time.Duration{
+		time.Minute, 2 * time.Minute, 3 * time.Second, time.Hour,
+	}}
+	p := &linuxImpl{openIdle: func() (idleSource, error) { return source, nil }}
+
+	events := make(chan ActivityEvent, 16)
+	if err := p.StartActivityMonitoring(func(e ActivityEvent) { events <- e }); err != nil {
+		t.Fatalf("StartActivityMonitoring: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Type != ActivityInput {
+			t.Fatalf("event type = %s, want %s", event.Type, ActivityInput)
+		}
+		if since := time.Since(event.Timestamp); since < 3*time.Second || since > 4*time.Second {
+			t.Fatalf("event timestamp %v ago, want the input time about 3s ago", since)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no activity reported after idle time reset")
+	}
+
+	time.Sleep(5 * idlePollInterval)
+	if err := p.StopActivityMonitoring(); err != nil {
+		t.Fatalf("StopActivityMonitoring: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("%d extra events while idle kept growing", len(events))
+	}
+	if !source.closed {
+		t.Fatal("idle source not closed on stop")
+	}
+}
+
+func TestLinuxActivityMonitoringUnavailable(t *testing.T) {
+	p := &linuxImpl{openIdle: func() (idleSource, error) { return nil, errors.New("cannot open X display") }}
+	if err := p.StartActivityMonitoring(func(ActivityEvent) {}); err == nil {
+		t.Fatal("StartActivityMonitoring succeeded without an idle source")
+	}
+	// A failed start leaves nothing to stop
+	if err := p.StopActivityMonitoring(); err != nil {
+		t.Fatalf("StopActivityMonitoring: %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build darwin
+// +build darwin
+
+package platform
+
+// Stubs for non-macOS platforms when building for macOS
+func newWindowsPlatform() (Platform, error) {
+	return nil, &UnsupportedPlatformError{OS: "windows (building for darwin)"}
+}
+
+func newLinuxPlatform() (Platform, error) {
+	return nil, &UnsupportedPlatformError{OS: "linux (building for darwin)"}
+}
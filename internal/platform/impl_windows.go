@@ -10,18 +10,23 @@ import (
 	"runtime"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 )
 
 type windowsImpl struct {
-	mouseHook       windows.Handle
-	keyboardHook    windows.Handle
+	input           *inputHooks // thread running the mouse and keyboard hooks
+	processPaths    processPathCache
 	activityCallback func(ActivityEvent)
+	buttonsDown     int // mouse buttons currently held, to report drags
+	moves           mouseMoveSampler
 	stopped         bool
+	foreground      *foregroundMonitor
+	session         *messageWindow
+	sleep           *messageWindow
 	mu              sync.Mutex
 }
 
@@ -35,6 +40,11 @@ var (
 	procGetWindowTextLength = user32.NewProc("GetWindowTextLengthW")
 	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
 	procIsWindowVisible     = user32.NewProc("IsWindowVisible")
+	procGetWindowRect       = user32.NewProc("GetWindowRect")
+	procMonitorFromWindow   = user32.NewProc("MonitorFromWindow")
+	procGetMonitorInfoW     = user32.NewProc("GetMonitorInfoW")
+	procGetShellWindow      = user32.NewProc("GetShellWindow")
+	procGetDesktopWindow    = user32.NewProc("GetDesktopWindow")
 	procSetWindowsHookEx    = user32.NewProc("SetWindowsHookExW")
 	procUnhookWindowsHookEx = user32.NewProc("UnhookWindowsHookEx")
 	procCallNextHookEx      = user32.NewProc("CallNextHookEx")
@@ -42,6 +52,7 @@ var (
 	procGetModuleFileNameEx = psapi.NewProc("GetModuleFileNameExW")
 	procOpenProcess        = kernel32.NewProc("OpenProcess")
 	procCloseHandle        = kernel32.NewProc("CloseHandle")
+	procGetSystemPowerStatus = kernel32.NewProc("GetSystemPowerStatus")
 )
 
 const (
@@ -49,15 +60,43 @@ const (
 	WH_KEYBOARD_LL = 13
 	WM_MOUSEMOVE   = 0x0200
 	WM_LBUTTONDOWN = 0x0201
+	WM_LBUTTONUP   = 0x0202
 	WM_RBUTTONDOWN = 0x0204
+	WM_RBUTTONUP   = 0x0205
 	WM_MOUSEWHEEL  = 0x020A
+	WM_MOUSEHWHEEL = 0x020E
 	WM_KEYDOWN     = 0x0100
 	PROCESS_QUERY_INFORMATION = 0x0400
 	PROCESS_VM_READ            = 0x0010
+	MONITOR_DEFAULTTONEAREST   = 0x00000002
 )
 
+// winRect mirrors the Win32 RECT structure
+type winRect struct {
+	left, top, right, bottom int32
+}
+
+// monitorInfo mirrors the Win32 MONITORINFO structure
+type monitorInfo struct {
+	size    uint32
+	monitor winRect
+	work    winRect
+	flags   uint32
+}
+
+// defaultMouseMoveInterval coalesces mouse moves, which the low-level hook
+// delivers hundreds of times a second while the cursor is in use
+const defaultMouseMoveInterval = 200 * time.Millisecond
+
 func newWindowsPlatform() (Platform, error) {
-	return &windowsImpl{}, nil
+	return &windowsImpl{moves: mouseMoveSampler{interval: defaultMouseMoveInterval}}, nil
+}
+
+// SetMouseMoveInterval implements MouseMoveSampler
+func (p *windowsImpl) SetMouseMoveInterval(interval time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.moves.setInterval(interval)
 }
 
 func (p *windowsImpl) GetActiveWindow() (*WindowInfo, error) {
@@ -88,8 +127,8 @@ func (p *windowsImpl) GetActiveWindow() (*WindowInfo, error) {
 	var processID uint32
 	procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&processID)))
 
-	// Get process path
-	processPath := p.getProcessPath(int(processID))
+	// Get process path, reusing the last lookup while the window is unchanged
+	processPath := p.processPaths.lookup(hwnd, processID, p.getProcessPath)
 
 	// Get application name from process path
 	application := p.getApplicationName(processPath)
@@ -104,10 +143,41 @@ func (p *windowsImpl) GetActiveWindow() (*WindowInfo, error) {
 		ProcessID:   int(processID),
 		ProcessPath: processPath,
 		IsVisible:   isVisible,
+		IsFullscreen: isFullscreen(hwnd),
 		Timestamp:   time.Now(),
 	}, nil
 }
 
+// isFullscreen reports whether hwnd covers the whole monitor it is on. The
+// desktop and shell windows always do, so they are excluded.
+func isFullscreen(hwnd uintptr) bool {
+	shell, _, _ := procGetShellWindow.Call()
+	desktop, _, _ := procGetDesktopWindow.Call()
+	if hwnd == shell || hwnd == desktop {
+		return false
+	}
+
+	var window winRect
+	if ok, _, _ := procGetWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&window))); ok == 0 {
+		return false
+	}
+
+	monitor, _, _ := procMonitorFromWindow.Call(hwnd, MONITOR_DEFAULTTONEAREST)
+	if monitor == 0 {
+		return false
+	}
+	info := monitorInfo{}
+	info.size = uint32(unsafe.Sizeof(info))
+	if ok, _, _ := procGetMonitorInfoW.Call(monitor, uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return false
+	}
+
+	return window.left <= info.monitor.left &&
+		window.top <= info.monitor.top &&
+		window.right >= info.monitor.right &&
+		window.bottom >= info.monitor.bottom
+}
+
 func (p *windowsImpl) getProcessPath(processID int) string {
 	if processID == 0 {
 		return ""
@@ -154,76 +224,33 @@ func (p *windowsImpl) getApplicationName(processPath string) string {
 	return ""
 }
 
-func (p *windowsImpl) StartActivityMonitoring(callback func(ActivityEvent)) error {
-	p.mu.Lock()
-	p.activityCallback = callback
-	p.stopped = false
-	p.mu.Unlock()
-
-	// Set up low-level mouse hook
-	mouseHookProc := syscall.NewCallback(p.mouseHookProc)
-	mouseHook, _, _ := procSetWindowsHookEx.Call(
-		WH_MOUSE_LL,
-		mouseHookProc,
-		0,
-		0,
-	)
-	if mouseHook == 0 {
-		return fmt.Errorf("failed to set mouse hook")
-	}
-	p.mouseHook = windows.Handle(mouseHook)
-
-	// Set up low-level keyboard hook
-	keyboardHookProc := syscall.NewCallback(p.keyboardHookProc)
-	keyboardHook, _, _ := procSetWindowsHookEx.Call(
-		WH_KEYBOARD_LL,
-		keyboardHookProc,
-		0,
-		0,
-	)
-	if keyboardHook == 0 {
-		procUnhookWindowsHookEx.Call(uintptr(p.mouseHook))
-		p.mouseHook = 0
-		return fmt.Errorf("failed to set keyboard hook")
-	}
-	p.keyboardHook = windows.Handle(keyboardHook)
-
-	return nil
-}
-
-func (p *windowsImpl) StopActivityMonitoring() error {
-	p.mu.Lock()
-	p.stopped = true
-	p.activityCallback = nil
-	
-	// Remove hooks immediately - this is critical for process exit
-	if p.mouseHook != 0 {
-		procUnhookWindowsHookEx.Call(uintptr(p.mouseHook))
-		p.mouseHook = 0
-	}
-	if p.keyboardHook != 0 {
-		procUnhookWindowsHookEx.Call(uintptr(p.keyboardHook))
-		p.keyboardHook = 0
-	}
-	p.mu.Unlock()
-	
-	// Give Windows time to process hook removal
-	time.Sleep(100 * time.Millisecond)
-	
-	return nil
-}
-
 func (p *windowsImpl) mouseHookProc(nCode int, wParam uintptr, lParam uintptr) uintptr {
 	p.mu.Lock()
 	stopped := p.stopped
 	callback := p.activityCallback
+	switch wParam {
+	case WM_LBUTTONDOWN, WM_RBUTTONDOWN:
+		p.buttonsDown++
+	case WM_LBUTTONUP, WM_RBUTTONUP:
+		if p.buttonsDown > 0 {
+			p.buttonsDown--
+		}
+	}
+	dragging := p.buttonsDown > 0
+	// Drop moves inside the sampling interval; idle detection only needs
+	// to know the cursor moved recently
+	sampled := wParam != WM_MOUSEMOVE || p.moves.sample(time.Now())
 	p.mu.Unlock()
 	
-	if nCode >= 0 && !stopped && callback != nil {
+	if nCode >= 0 && !stopped && callback != nil && sampled {
 		switch wParam {
 		case WM_MOUSEMOVE:
+			activityType := ActivityMouseMove
+			if dragging {
+				activityType = ActivityMouseDrag
+			}
 			callback(ActivityEvent{
-				Type:      ActivityMouseMove,
+				Type:      activityType,
 				Timestamp: time.Now(),
 			})
 		case WM_LBUTTONDOWN, WM_RBUTTONDOWN:
@@ -231,10 +258,9 @@ func (p *windowsImpl) mouseHookProc(nCode int, wParam uintptr, lParam uintptr) u
 				Type:      ActivityMouseClick,
 				Timestamp: time.Now(),
 			})
-		case WM_MOUSEWHEEL:
-			// Scroll wheel activity also indicates user is active
+		case WM_MOUSEWHEEL, WM_MOUSEHWHEEL:
 			callback(ActivityEvent{
-				Type:      ActivityMouseMove,
+				Type:      ActivityScroll,
 				Timestamp: time.Now(),
 			})
 		}
@@ -260,7 +286,17 @@ func (p *windowsImpl) keyboardHookProc(nCode int, wParam uintptr, lParam uintptr
 }
 
 func (p *windowsImpl) GetDeviceID() (string, error) {
-	// Try to get machine GUID from Windows
+	// Try to get machine GUID from the registry
+	if key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Cryptography`,
+		registry.QUERY_VALUE|registry.WOW64_64KEY); err == nil {
+		guid, _, err := key.GetStringValue("MachineGuid")
+		key.Close()
+		if err == nil && guid != "" {
+			return guid, nil
+		}
+	}
+
+	// Fall back to wmic on older systems
 	cmd := exec.Command("wmic", "csproduct", "get", "uuid")
 	output, err := cmd.Output()
 	if err == nil {
@@ -293,6 +329,34 @@ func (p *windowsImpl) GetSystemInfo() (*SystemInfo, error) {
 	}, nil
 }
 
+// systemPowerStatus mirrors the Win32 SYSTEM_POWER_STATUS structure
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+func (p *windowsImpl) GetPowerStatus() (*PowerStatus, error) {
+	var sps systemPowerStatus
+	ret, _, err := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&sps)))
+	if ret == 0 {
+		return nil, fmt.Errorf("GetSystemPowerStatus failed: %w", err)
+	}
+
+	status := &PowerStatus{
+		OnACPower:      sps.ACLineStatus == 1,
+		HasBattery:     sps.BatteryFlag != 128 && sps.BatteryFlag != 255, // 128 = no battery, 255 = unknown
+		BatteryPercent: -1,
+	}
+	if status.HasBattery && sps.BatteryLifePercent <= 100 {
+		status.BatteryPercent = int(sps.BatteryLifePercent)
+	}
+	return status, nil
+}
+
 func (p *windowsImpl) OpenBrowser(url string) error {
 	// Use Windows start command to open default browser
 	// The empty string after "start" is required for Windows cmd.exe
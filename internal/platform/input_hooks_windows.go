@@ -0,0 +1,109 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// inputHooks owns the thread running the low-level mouse and keyboard hooks
+type inputHooks struct {
+	threadID uint32
+	done     chan struct{}
+}
+
+// StartActivityMonitoring installs low-level mouse and keyboard hooks.
+//
+// Threading model: Windows calls a low-level hook on the thread that
+// installed it, and only while that thread is waiting in GetMessage. The
+// hooks are therefore installed on a dedicated goroutine locked to its OS
+// thread, which then pumps messages until StopActivityMonitoring posts
+// WM_QUIT to it. mouseHookProc and keyboardHookProc (and so callback) run on
+// that thread, one at a time; they must return quickly, or Windows skips the
+// hook and eventually removes it. The hooks are removed by the same thread
+// when its loop ends, so once Stop returns no further callbacks arrive.
+func (p *windowsImpl) StartActivityMonitoring(callback func(ActivityEvent)) error {
+	p.mu.Lock()
+	if p.input != nil {
+		p.mu.Unlock()
+		return fmt.Errorf("activity monitoring already started")
+	}
+	p.activityCallback = callback
+	p.stopped = false
+	p.mu.Unlock()
+
+	mouseHookProc := syscall.NewCallback(p.mouseHookProc)
+	keyboardHookProc := syscall.NewCallback(p.keyboardHookProc)
+
+	started := make(chan error, 1)
+	hooks := &inputHooks{done: make(chan struct{})}
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(hooks.done)
+
+		mouseHook, _, _ := procSetWindowsHookEx.Call(WH_MOUSE_LL, mouseHookProc, 0, 0)
+		if mouseHook == 0 {
+			started <- fmt.Errorf("failed to set mouse hook")
+			return
+		}
+		defer procUnhookWindowsHookEx.Call(mouseHook)
+
+		keyboardHook, _, _ := procSetWindowsHookEx.Call(WH_KEYBOARD_LL, keyboardHookProc, 0, 0)
+		if keyboardHook == 0 {
+			started <- fmt.Errorf("failed to set keyboard hook")
+			return
+		}
+		defer procUnhookWindowsHookEx.Call(keyboardHook)
+
+		hooks.threadID = windows.GetCurrentThreadId()
+		started <- nil
+
+		// Pump messages until WM_QUIT (GetMessage returns 0) or an error (-1).
+		// The hooks are called from inside GetMessage.
+		var msg winMsg
+		for {
+			ret, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(ret) <= 0 {
+				return
+			}
+		}
+	}()
+
+	if err := <-started; err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.input = hooks
+	p.mu.Unlock()
+	return nil
+}
+
+// StopActivityMonitoring ends the hook thread's message loop, which removes
+// the hooks, and waits for it to exit
+func (p *windowsImpl) StopActivityMonitoring() error {
+	p.mu.Lock()
+	p.stopped = true
+	p.activityCallback = nil
+	hooks := p.input
+	p.input = nil
+	p.mu.Unlock()
+
+	if hooks == nil {
+		return nil
+	}
+
+	// Not under p.mu: a hook proc running on the hook thread may be waiting
+	// for it
+	procPostThreadMessageW.Call(uintptr(hooks.threadID), WM_QUIT, 0, 0)
+	<-hooks.done
+	return nil
+}
@@ -0,0 +1,128 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procRegisterClassExW = user32.NewProc("RegisterClassExW")
+	procUnregisterClassW = user32.NewProc("UnregisterClassW")
+	procCreateWindowExW  = user32.NewProc("CreateWindowExW")
+	procDestroyWindow    = user32.NewProc("DestroyWindow")
+	procDefWindowProcW   = user32.NewProc("DefWindowProcW")
+	procDispatchMessageW = user32.NewProc("DispatchMessageW")
+	procGetModuleHandleW = kernel32.NewProc("GetModuleHandleW")
+)
+
+const (
+	HWND_MESSAGE = ^uintptr(2) // (HWND)-3
+)
+
+// wndClassEx mirrors the Win32 WNDCLASSEXW structure
+type wndClassEx struct {
+	size       uint32
+	style      uint32
+	wndProc    uintptr
+	clsExtra   int32
+	wndExtra   int32
+	instance   uintptr
+	icon       uintptr
+	cursor     uintptr
+	background uintptr
+	menuName   *uint16
+	className  *uint16
+	iconSm     uintptr
+}
+
+// messageWindow owns the thread running a hidden notification window
+type messageWindow struct {
+	threadID uint32
+	done     chan struct{}
+}
+
+// startMessageWindow creates a hidden window of its own class on a
+// dedicated OS thread and pumps its messages until stop. parent is
+// HWND_MESSAGE for a message-only window, or 0 for a hidden top-level window,
+// which unlike a message-only one also receives broadcasts. register, if
+// set, runs once the window exists and returns its cleanup.
+func startMessageWindow(className string, parent uintptr, wndProc uintptr, register func(hwnd uintptr) (func(), error)) (*messageWindow, error) {
+	started := make(chan error, 1)
+	window := &messageWindow{done: make(chan struct{})}
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(window.done)
+
+		instance, _, _ := procGetModuleHandleW.Call(0)
+		classNamePtr, _ := windows.UTF16PtrFromString(className)
+		class := wndClassEx{
+			wndProc:   wndProc,
+			instance:  instance,
+			className: classNamePtr,
+		}
+		class.size = uint32(unsafe.Sizeof(class))
+		if atom, _, err := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&class))); atom == 0 {
+			started <- fmt.Errorf("failed to register window class %s: %w", className, err)
+			return
+		}
+		defer procUnregisterClassW.Call(uintptr(unsafe.Pointer(classNamePtr)), instance)
+
+		hwnd, _, err := procCreateWindowExW.Call(
+			0,
+			uintptr(unsafe.Pointer(classNamePtr)),
+			0,
+			0,
+			0, 0, 0, 0,
+			parent,
+			0,
+			instance,
+			0,
+		)
+		if hwnd == 0 {
+			started <- fmt.Errorf("failed to create window %s: %w", className, err)
+			return
+		}
+		defer procDestroyWindow.Call(hwnd)
+
+		if register != nil {
+			unregister, err := register(hwnd)
+			if err != nil {
+				started <- err
+				return
+			}
+			defer unregister()
+		}
+
+		window.threadID = windows.GetCurrentThreadId()
+		started <- nil
+
+		// Pump messages until WM_QUIT (GetMessage returns 0) or an error (-1)
+		var msg winMsg
+		for {
+			ret, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(ret) <= 0 {
+				return
+			}
+			procDispatchMessageW.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+	}()
+
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// stop ends the message loop, which runs the cleanup and destroys the window
+func (w *messageWindow) stop() {
+	procPostThreadMessageW.Call(uintptr(w.threadID), WM_QUIT, 0, 0)
+	<-w.done
+}
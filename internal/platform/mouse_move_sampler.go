@@ -0,0 +1,28 @@
+package platform
+
+import "time"
+
+// mouseMoveSampler coalesces mouse moves to at most one per interval for
+// MouseMoveSampler implementations. Not safe for concurrent use.
+type mouseMoveSampler struct {
+	interval time.Duration // minimum gap between reported mouse moves
+	last     time.Time     // when the last mouse move was reported
+}
+
+// setInterval changes the interval; 0 or less reports every move
+func (s *mouseMoveSampler) setInterval(interval time.Duration) {
+	s.interval = max(interval, 0)
+}
+
+// sample reports whether a move at at should be reported, and if so
+// starts a new interval from it
+func (s *mouseMoveSampler) sample(at time.Time) bool {
+	if s.interval <= 0 {
+		return true
+	}
+	if at.Sub(s.last) < s.interval {
+		return false
+	}
+	s.last = at
+	return true
+}
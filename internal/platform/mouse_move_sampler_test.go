@@ -0,0 +1,47 @@
+package platform
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMouseMoveSamplerBurst(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		interval time.Duration
+		want     int
+	}{
+		{0, 1000},
+		{-time.Second, 1000},
+		{time.Millisecond, 1000},
+		{200 * time.Millisecond, 5},
+		{time.Second, 1},
+	}
+	for _, tt := range tests {
+		var s mouseMoveSampler
+		s.setInterval(tt.interval)
+		// 1000 moves a millisecond apart
+		reported := 0
+		for i := 0; i < 1000; i++ {
+			if s.sample(start.Add(time.Duration(i) * time.Millisecond)) {
+				reported++
+			}
+		}
+		if reported != tt.want {
+			t.Errorf("interval %s: reported %d of 1000 moves, want %d", tt.interval, reported, tt.want)
+		}
+	}
+}
+
+func TestMouseMoveSamplerIntervalChange(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	var s mouseMoveSampler
+	s.setInterval(time.Second)
+	if !s.sample(start) || s.sample(start.Add(500*time.Millisecond)) {
+		t.Fatal("1s interval didn't report only the first move")
+	}
+	s.setInterval(0)
+	if !s.sample(start.Add(600 * time.Millisecond)) {
+		t.Error("move dropped after turning sampling off")
+	}
+}
@@ -1,9 +1,14 @@
 package platform
 
 import (
+	"errors"
 	"runtime"
 )
 
+// ErrActiveWindowUnsupported is returned by GetActiveWindow on platforms
+// that can't report the foreground window. Activity tracking still works.
+var ErrActiveWindowUnsupported = errors.New("active window tracking is not supported on this platform")
+
 // NewPlatform creates a platform-specific implementation based on the current OS
 func NewPlatform() (Platform, error) {
 	switch runtime.GOOS {
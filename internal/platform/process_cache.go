@@ -0,0 +1,36 @@
+package platform
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// processPathCache remembers the process path of the last foreground window
+// so polls of an unchanged window skip OpenProcess and GetModuleFileNameEx.
+// An HWND belongs to one process for its lifetime, so the entry is only
+// replaced when the HWND or PID changes.
+type processPathCache struct {
+	mu   sync.Mutex
+	hwnd uintptr
+	pid  uint32
+	path string
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// lookup returns the cached path for hwnd and pid, or calls resolve and
+// caches its result
+func (c *processPathCache) lookup(hwnd uintptr, pid uint32, resolve func(processID int) string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if hwnd != 0 && hwnd == c.hwnd && pid == c.pid {
+		c.hits.Add(1)
+		return c.path
+	}
+
+	c.misses.Add(1)
+	c.hwnd, c.pid, c.path = hwnd, pid, resolve(int(pid))
+	return c.path
+}
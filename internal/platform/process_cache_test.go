@@ -0,0 +1,48 @@
+package platform
+
+import "testing"
+
+// countingResolver resolves processes to fixed paths and counts the calls
+type countingResolver struct {
+	paths map[int]string
+	calls int
+}
+
+func (r *countingResolver) resolve(processID int) string {
+	r.calls++
+	return r.paths[processID]
+}
+
+func TestProcessPathCache(t *testing.T) {
+	r := &countingResolver{paths: map[int]string{100: `C:\code.exe`, 200: `C:\chrome.exe`}}
+	var c processPathCache
+
+	steps := []struct {
+		name      string
+		hwnd      uintptr
+		pid       uint32
+		want      string
+		wantCalls int
+	}{
+		{"first lookup", 1, 100, `C:\code.exe`, 1},
+		{"same window", 1, 100, `C:\code.exe`, 1},
+		{"same window again", 1, 100, `C:\code.exe`, 1},
+		{"window change", 2, 200, `C:\chrome.exe`, 2},
+		{"back to the first window", 1, 100, `C:\code.exe`, 3},
+		{"same window, new process", 1, 200, `C:\chrome.exe`, 4},
+		{"no window", 0, 200, `C:\chrome.exe`, 5},
+		{"no window again", 0, 200, `C:\chrome.exe`, 6},
+	}
+	for _, step := range steps {
+		if got := c.lookup(step.hwnd, step.pid, r.resolve); got != step.want {
+			t.Errorf("%s: path = %q, want %q", step.name, got, step.want)
+		}
+		if r.calls != step.wantCalls {
+			t.Errorf("%s: %d process lookups, want %d", step.name, r.calls, step.wantCalls)
+		}
+	}
+
+	if hits, misses := c.hits.Load(), c.misses.Load(); hits != 2 || misses != 6 {
+		t.Errorf("hits %d, misses %d, want 2 and 6", hits, misses)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package platform
+
+// ProcessCacheStats implements ProcessCache
+func (p *windowsImpl) ProcessCacheStats() (hits, misses uint64) {
+	return p.processPaths.hits.Load(), p.processPaths.misses.Load()
+}
@@ -0,0 +1,84 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	wtsapi32 = windows.NewLazyDLL("wtsapi32.dll")
+
+	procWTSRegisterSessionNotification   = wtsapi32.NewProc("WTSRegisterSessionNotification")
+	procWTSUnRegisterSessionNotification = wtsapi32.NewProc("WTSUnRegisterSessionNotification")
+)
+
+const (
+	WM_WTSSESSION_CHANGE    = 0x02B1
+	WTS_SESSION_LOCK        = 0x7
+	WTS_SESSION_UNLOCK      = 0x8
+	NOTIFY_FOR_THIS_SESSION = 0
+)
+
+// sessionWindowClass is the class of the message-only window that receives
+// session change notifications
+const sessionWindowClass = "TimeTrackingAgentSessionMonitor"
+
+// StartSessionMonitoring registers for WTS session notifications. They are
+// delivered as window messages, so a message-only window is created on a
+// dedicated OS thread that pumps its messages.
+func (p *windowsImpl) StartSessionMonitoring(callback func(locked bool)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.session != nil {
+		return fmt.Errorf("session monitoring already started")
+	}
+
+	wndProc := syscall.NewCallback(func(hwnd, msg, wParam, lParam uintptr) uintptr {
+		if msg == WM_WTSSESSION_CHANGE {
+			switch wParam {
+			case WTS_SESSION_LOCK:
+				callback(true)
+			case WTS_SESSION_UNLOCK:
+				callback(false)
+			}
+			return 0
+		}
+		ret, _, _ := procDefWindowProcW.Call(hwnd, msg, wParam, lParam)
+		return ret
+	})
+
+	window, err := startMessageWindow(sessionWindowClass, HWND_MESSAGE, wndProc, func(hwnd uintptr) (func(), error) {
+		if ok, _, err := procWTSRegisterSessionNotification.Call(hwnd, NOTIFY_FOR_THIS_SESSION); ok == 0 {
+			return nil, fmt.Errorf("failed to register for session notifications: %w", err)
+		}
+		return func() { procWTSUnRegisterSessionNotification.Call(hwnd) }, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	p.session = window
+	return nil
+}
+
+// StopSessionMonitoring ends the message loop, which unregisters the
+// notifications and destroys the window
+func (p *windowsImpl) StopSessionMonitoring() error {
+	p.mu.Lock()
+	window := p.session
+	p.session = nil
+	p.mu.Unlock()
+
+	if window == nil {
+		return nil
+	}
+
+	window.stop()
+	return nil
+}
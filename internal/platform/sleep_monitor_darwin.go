@@ -0,0 +1,76 @@
+//go:build darwin
+// +build darwin
+
+package platform
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// sleepPollInterval is how often the clocks are compared
+	sleepPollInterval = 5 * time.Second
+	// sleepMinGap is how far the wall clock must run ahead of the monotonic
+	// clock between two polls to count as a sleep
+	sleepMinGap = 30 * time.Second
+)
+
+// StartSleepMonitoring detects sleep from the clocks: the monotonic clock
+// stops while the machine sleeps but the wall clock doesn't, so a poll that
+// finds the wall clock far ahead reports a sleep starting at the previous
+// poll and a resume now. This avoids an Objective-C bridge for NSWorkspace
+// notifications, at the cost of learning about the sleep only on resume.
+func (p *darwinImpl) StartSleepMonitoring(callback func(asleep bool, at time.Time)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.sleepStop != nil {
+		return fmt.Errorf("sleep monitoring already started")
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	p.sleepStop, p.sleepDone = stop, done
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(sleepPollInterval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				wall := now.Round(0).Sub(last.Round(0))
+				if wall-now.Sub(last) >= sleepMinGap {
+					callback(true, last)
+					callback(false, now)
+				}
+				last = now
+			}
+		}
+	}()
+
+	return nil
+}
+
+// StopSleepMonitoring stops the clock polling
+func (p *darwinImpl) StopSleepMonitoring() error {
+	p.mu.Lock()
+	stop, done := p.sleepStop, p.sleepDone
+	p.sleepStop, p.sleepDone = nil, nil
+	p.mu.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+
+	close(stop)
+	<-done
+	return nil
+}
@@ -0,0 +1,80 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+const (
+	WM_POWERBROADCAST      = 0x0218
+	PBT_APMSUSPEND         = 0x4
+	PBT_APMRESUMESUSPEND   = 0x7
+	PBT_APMRESUMEAUTOMATIC = 0x12
+)
+
+// sleepWindowClass is the class of the hidden window that receives power
+// broadcasts
+const sleepWindowClass = "TimeTrackingAgentSleepMonitor"
+
+// StartSleepMonitoring listens for WM_POWERBROADCAST. It is only broadcast
+// to top-level windows, so a hidden top-level window is used rather than a
+// message-only one.
+func (p *windowsImpl) StartSleepMonitoring(callback func(asleep bool, at time.Time)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.sleep != nil {
+		return fmt.Errorf("sleep monitoring already started")
+	}
+
+	// Resume is announced by PBT_APMRESUMEAUTOMATIC and, when a user woke
+	// the machine, also PBT_APMRESUMESUSPEND; report it once. Only the
+	// window's thread touches asleep.
+	asleep := false
+	wndProc := syscall.NewCallback(func(hwnd, msg, wParam, lParam uintptr) uintptr {
+		if msg == WM_POWERBROADCAST {
+			switch wParam {
+			case PBT_APMSUSPEND:
+				if !asleep {
+					asleep = true
+					callback(true, time.Now())
+				}
+			case PBT_APMRESUMEAUTOMATIC, PBT_APMRESUMESUSPEND:
+				if asleep {
+					asleep = false
+					callback(false, time.Now())
+				}
+			}
+			return 1 // TRUE
+		}
+		ret, _, _ := procDefWindowProcW.Call(hwnd, msg, wParam, lParam)
+		return ret
+	})
+
+	window, err := startMessageWindow(sleepWindowClass, 0, wndProc, nil)
+	if err != nil {
+		return err
+	}
+
+	p.sleep = window
+	return nil
+}
+
+// StopSleepMonitoring ends the message loop and destroys the window
+func (p *windowsImpl) StopSleepMonitoring() error {
+	p.mu.Lock()
+	window := p.sleep
+	p.sleep = nil
+	p.mu.Unlock()
+
+	if window == nil {
+		return nil
+	}
+
+	window.stop()
+	return nil
+}
@@ -22,6 +22,57 @@ type Platform interface {
 	
 	// OpenBrowser opens the default browser with the given URL
 	OpenBrowser(url string) error
+
+	// GetPowerStatus returns the AC/battery state of the device
+	GetPowerStatus() (*PowerStatus, error)
+}
+
+// WindowMonitor is implemented by platforms that can report foreground
+// window changes as they happen instead of being polled
+type WindowMonitor interface {
+	// StartWindowMonitoring calls callback whenever the foreground window changes
+	StartWindowMonitoring(callback func()) error
+
+	// StopWindowMonitoring removes the foreground window hook
+	StopWindowMonitoring() error
+}
+
+// SessionMonitor is implemented by platforms that can report when the user
+// session is locked or unlocked
+type SessionMonitor interface {
+	// StartSessionMonitoring calls callback with true on lock and false on unlock
+	StartSessionMonitoring(callback func(locked bool)) error
+
+	// StopSessionMonitoring stops the session notifications
+	StopSessionMonitoring() error
+}
+
+// SleepMonitor is implemented by platforms that can report when the system
+// sleeps and resumes
+type SleepMonitor interface {
+	// StartSleepMonitoring calls callback with true when the system is about
+	// to sleep and false when it resumes, along with when that happened
+	StartSleepMonitoring(callback func(asleep bool, at time.Time)) error
+
+	// StopSleepMonitoring stops the sleep notifications
+	StopSleepMonitoring() error
+}
+
+// MouseMoveSampler is implemented by platforms whose input hooks see every
+// mouse movement and can coalesce them before reporting
+type MouseMoveSampler interface {
+	// SetMouseMoveInterval reports at most one mouse move or drag per
+	// interval; 0 reports every move. Clicks, scrolls and keys are never
+	// throttled.
+	SetMouseMoveInterval(interval time.Duration)
+}
+
+// ProcessCache is implemented by platforms that cache the process lookup
+// behind GetActiveWindow
+type ProcessCache interface {
+	// ProcessCacheStats returns how many lookups were served from the cache
+	// and how many had to query the process
+	ProcessCacheStats() (hits, misses uint64)
 }
 
 // WindowInfo contains information about a window
@@ -30,7 +81,9 @@ type WindowInfo struct {
 	Application string
 	ProcessID   int
 	ProcessPath string
+	BundleID    string // macOS bundle identifier (e.g. com.apple.Safari), empty elsewhere
 	IsVisible   bool
+	IsFullscreen bool // Window covers its whole monitor (video, games, presentations)
 	Timestamp   time.Time
 }
 
@@ -47,6 +100,9 @@ const (
 	ActivityMouseMove  ActivityType = "mouse_move"
 	ActivityMouseClick ActivityType = "mouse_click"
 	ActivityKeyPress   ActivityType = "key_press"
+	ActivityScroll     ActivityType = "scroll"     // Vertical or horizontal wheel
+	ActivityMouseDrag  ActivityType = "mouse_drag" // Mouse moved with a button held
+	ActivityInput      ActivityType = "input"      // Input of unknown kind, e.g. from an idle-time source
 )
 
 // SystemInfo contains system information
@@ -56,3 +112,10 @@ type SystemInfo struct {
 	Arch     string
 	Hostname string
 }
+
+// PowerStatus contains the device's power source and battery level
+type PowerStatus struct {
+	OnACPower      bool
+	HasBattery     bool
+	BatteryPercent int // 0-100, or -1 if unknown
+}
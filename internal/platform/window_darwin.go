@@ -0,0 +1,133 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package platform
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework AppKit -framework ApplicationServices
+#import <AppKit/AppKit.h>
+#import <ApplicationServices/ApplicationServices.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef struct {
+	int   pid;
+	char *name;
+	char *bundle_id;
+	char *title;
+	int   trusted;
+} frontmost_info;
+
+static char *copy_nsstring(NSString *s) {
+	if (s == nil) {
+		return NULL;
+	}
+	const char *utf8 = [s UTF8String];
+	return utf8 ? strdup(utf8) : NULL;
+}
+
+// focused_window_title reads the focused window's title through the
+// Accessibility API, which requires the process to be trusted
+static char *focused_window_title(pid_t pid) {
+	char *result = NULL;
+	AXUIElementRef app = AXUIElementCreateApplication(pid);
+	if (app == NULL) {
+		return NULL;
+	}
+	CFTypeRef window = NULL;
+	if (AXUIElementCopyAttributeValue(app, kAXFocusedWindowAttribute, &window) == kAXErrorSuccess && window != NULL) {
+		CFTypeRef title = NULL;
+		if (AXUIElementCopyAttributeValue((AXUIElementRef)window, kAXTitleAttribute, &title) == kAXErrorSuccess && title != NULL) {
+			if (CFGetTypeID(title) == CFStringGetTypeID()) {
+				result = copy_nsstring((NSString *)title);
+			}
+			CFRelease(title);
+		}
+		CFRelease(window);
+	}
+	CFRelease(app);
+	return result;
+}
+
+// get_frontmost fills out with the owner of the frontmost on-screen window.
+// The window list is used rather than NSWorkspace.frontmostApplication
+// because the latter only updates on a running main run loop.
+static int get_frontmost(frontmost_info *out) {
+	@autoreleasepool {
+		memset(out, 0, sizeof(*out));
+
+		CFArrayRef windows = CGWindowListCopyWindowInfo(
+			kCGWindowListOptionOnScreenOnly | kCGWindowListExcludeDesktopElements, kCGNullWindowID);
+		if (windows == NULL) {
+			return 0;
+		}
+
+		NSString *ownerName = nil;
+		int found = 0;
+		for (NSDictionary *w in (NSArray *)windows) {
+			if ([w[(id)kCGWindowLayer] intValue] != 0) {
+				continue;
+			}
+			out->pid = [w[(id)kCGWindowOwnerPID] intValue];
+			ownerName = w[(id)kCGWindowOwnerName];
+			found = 1;
+			break;
+		}
+		if (found) {
+			out->name = copy_nsstring(ownerName);
+		}
+		CFRelease(windows);
+		if (!found) {
+			return 0;
+		}
+
+		NSRunningApplication *app = [NSRunningApplication runningApplicationWithProcessIdentifier:out->pid];
+		if (app != nil) {
+			if ([app localizedName] != nil) {
+				free(out->name);
+				out->name = copy_nsstring([app localizedName]);
+			}
+			out->bundle_id = copy_nsstring([app bundleIdentifier]);
+		}
+
+		out->trusted = AXIsProcessTrusted() ? 1 : 0;
+		if (out->trusted) {
+			out->title = focused_window_title(out->pid);
+		}
+		return 1;
+	}
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// frontmostWindow returns the frontmost application and its focused window
+func (p *darwinImpl) frontmostWindow() (*WindowInfo, error) {
+	var info C.frontmost_info
+	if C.get_frontmost(&info) == 0 {
+		return nil, fmt.Errorf("no frontmost window")
+	}
+	defer C.free(unsafe.Pointer(info.name))
+	defer C.free(unsafe.Pointer(info.bundle_id))
+	defer C.free(unsafe.Pointer(info.title))
+
+	if info.trusted == 0 && p.warnAccessibility() {
+		return nil, fmt.Errorf("window titles unavailable: grant Accessibility permission to the agent in " +
+			"System Settings > Privacy & Security > Accessibility (windows are tracked without titles until then)")
+	}
+
+	return &WindowInfo{
+		Title:       C.GoString(info.title),
+		Application: C.GoString(info.name),
+		BundleID:    C.GoString(info.bundle_id),
+		ProcessID:   int(info.pid),
+		IsVisible:   true,
+		Timestamp:   time.Now(),
+	}, nil
+}
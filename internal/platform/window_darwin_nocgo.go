@@ -0,0 +1,10 @@
+//go:build darwin && !cgo
+// +build darwin,!cgo
+
+package platform
+
+import "fmt"
+
+func (p *darwinImpl) frontmostWindow() (*WindowInfo, error) {
+	return nil, fmt.Errorf("window tracking on macOS requires a cgo build")
+}
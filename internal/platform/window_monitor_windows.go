@@ -0,0 +1,121 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procSetWinEventHook    = user32.NewProc("SetWinEventHook")
+	procUnhookWinEvent     = user32.NewProc("UnhookWinEvent")
+	procGetMessageW        = user32.NewProc("GetMessageW")
+	procPostThreadMessageW = user32.NewProc("PostThreadMessageW")
+)
+
+const (
+	EVENT_SYSTEM_FOREGROUND = 0x0003
+	WINEVENT_OUTOFCONTEXT   = 0x0000
+	WINEVENT_SKIPOWNPROCESS = 0x0002
+	WM_QUIT                 = 0x0012
+)
+
+// winMsg mirrors the Win32 MSG structure
+type winMsg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      struct{ x, y int32 }
+}
+
+// foregroundMonitor owns the thread running the SetWinEventHook message loop
+type foregroundMonitor struct {
+	threadID uint32
+	done     chan struct{}
+}
+
+// StartWindowMonitoring installs an EVENT_SYSTEM_FOREGROUND hook. Out-of-context
+// WinEvent hooks are delivered through the message queue of the thread that
+// installed them, so the hook lives on a dedicated OS thread pumping messages.
+func (p *windowsImpl) StartWindowMonitoring(callback func()) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.foreground != nil {
+		return fmt.Errorf("window monitoring already started")
+	}
+
+	hookProc := syscall.NewCallback(func(hook, event, hwnd, idObject, idChild, eventThread, eventTime uintptr) uintptr {
+		if event == EVENT_SYSTEM_FOREGROUND {
+			callback()
+		}
+		return 0
+	})
+
+	started := make(chan error, 1)
+	monitor := &foregroundMonitor{done: make(chan struct{})}
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(monitor.done)
+
+		hook, _, _ := procSetWinEventHook.Call(
+			EVENT_SYSTEM_FOREGROUND,
+			EVENT_SYSTEM_FOREGROUND,
+			0,
+			hookProc,
+			0,
+			0,
+			WINEVENT_OUTOFCONTEXT|WINEVENT_SKIPOWNPROCESS,
+		)
+		if hook == 0 {
+			started <- fmt.Errorf("failed to set foreground window hook")
+			return
+		}
+		defer procUnhookWinEvent.Call(hook)
+
+		monitor.threadID = windows.GetCurrentThreadId()
+		started <- nil
+
+		// Pump messages until WM_QUIT (GetMessage returns 0) or an error (-1)
+		var msg winMsg
+		for {
+			ret, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(ret) <= 0 {
+				return
+			}
+		}
+	}()
+
+	if err := <-started; err != nil {
+		return err
+	}
+
+	p.foreground = monitor
+	return nil
+}
+
+// StopWindowMonitoring ends the message loop, which removes the hook
+func (p *windowsImpl) StopWindowMonitoring() error {
+	p.mu.Lock()
+	monitor := p.foreground
+	p.foreground = nil
+	p.mu.Unlock()
+
+	if monitor == nil {
+		return nil
+	}
+
+	procPostThreadMessageW.Call(uintptr(monitor.threadID), WM_QUIT, 0, 0)
+	<-monitor.done
+	return nil
+}
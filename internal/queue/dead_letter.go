@@ -0,0 +1,183 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// defaultDeadLetterAfter is how many failed attempts move an event to the
+// dead-letter table
+const defaultDeadLetterAfter = 10
+
+// deadLetterRetention is how long dead-lettered events are kept for inspection
+const deadLetterRetention = 30 * 24 * time.Hour
+
+// DeadLetter is an event that was taken out of the retry queue
+type DeadLetter struct {
+	ID         int64
+	DeviceID   string
+	Event      models.TrackingEvent
+	RetryCount int
+	Reason     string
+	CreatedAt  time.Time
+	DeadAt     time.Time
+}
+
+// SetDeadLetterAfter sets how many failed attempts move an event out of the
+// retry queue into the dead-letter table
+func (eq *EventQueue) SetDeadLetterAfter(retries int) {
+	if retries <= 0 {
+		retries = defaultDeadLetterAfter
+	}
+	eq.deadLetterAfter = retries
+}
+
+// DeadLetter moves events out of the retry queue, recording why
+func (eq *EventQueue) DeadLetter(ids []int64, reason string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders, args := idPlaceholders(ids)
+	moved, err := eq.moveToDeadLetter("id IN ("+placeholders+")", args, reason)
+	if err != nil {
+		return err
+	}
+	if moved > 0 {
+		eq.logger.Warn("Moved events to dead-letter table",
+			zap.Int64("count", moved),
+			zap.String("reason", reason),
+		)
+	}
+	return nil
+}
+
+// deadLetterExhausted moves events that have used up their retries
+func (eq *EventQueue) deadLetterExhausted() error {
+	moved, err := eq.moveToDeadLetter("retry_count >= ?", []interface{}{eq.deadLetterAfter}, "max retries exceeded")
+	if err != nil {
+		return err
+	}
+	if moved > 0 {
+		eq.logger.Warn("Moved events that exceeded max retries to dead-letter table",
+			zap.Int64("count", moved),
+			zap.Int("max_retries", eq.deadLetterAfter),
+		)
+	}
+	return nil
+}
+
+// moveToDeadLetter moves the pending events matching where in one transaction
+func (eq *EventQueue) moveToDeadLetter(where string, args []interface{}, reason string) (int64, error) {
+	tx, err := eq.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertArgs := append([]interface{}{reason, time.Now()}, args...)
+	_, err = tx.Exec(`
+		INSERT INTO dead_letter_events (event_data, device_id, created_at, retry_count, last_attempt, reason, dead_at)
+		SELECT event_data, device_id, created_at, retry_count, last_attempt, ?, ?
+		FROM pending_events
+		WHERE `+where, insertArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy events to dead-letter table: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM pending_events WHERE `+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove dead-lettered events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	moved, _ := result.RowsAffected()
+	return moved, nil
+}
+
+// ListDeadLetters returns up to limit dead-lettered events, newest first
+func (eq *EventQueue) ListDeadLetters(limit int) ([]DeadLetter, error) {
+	rows, err := eq.db.Query(`
+		SELECT id, device_id, event_data, retry_count, reason, created_at, dead_at
+		FROM dead_letter_events
+		ORDER BY dead_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead-letter events: %w", err)
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var letter DeadLetter
+		var eventData string
+		if err := rows.Scan(&letter.ID, &letter.DeviceID, &eventData, &letter.RetryCount,
+			&letter.Reason, &letter.CreatedAt, &letter.DeadAt); err != nil {
+			eq.logger.Error("Failed to scan row", zap.Error(err))
+			continue
+		}
+		if err := json.Unmarshal([]byte(eventData), &letter.Event); err != nil {
+			eq.logger.Error("Failed to unmarshal event", zap.Error(err), zap.Int64("id", letter.ID))
+			continue
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, rows.Err()
+}
+
+// RequeueDeadLetters moves dead-lettered events back into the retry queue
+// with a fresh retry count. Events whose eventId is already queued are
+// dropped rather than queued twice.
+func (eq *EventQueue) RequeueDeadLetters(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders, args := idPlaceholders(ids)
+
+	tx, err := eq.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO pending_events (event_data, device_id, created_at, retry_count)
+		SELECT event_data, device_id, created_at, 0
+		FROM dead_letter_events
+		WHERE id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to requeue dead-letter events: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM dead_letter_events WHERE id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to remove requeued dead-letter events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	requeued, _ := result.RowsAffected()
+	eq.logger.Info("Requeued dead-letter events", zap.Int64("count", requeued))
+	return nil
+}
+
+// idPlaceholders returns "?,?,..." and the matching args for ids
+func idPlaceholders(ids []int64) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","), args
+}
@@ -0,0 +1,136 @@
+package queue
+
+import (
+	"database/sql"
+	"sort"
+	"testing"
+)
+
+// retryCounts returns the queued events' retry counts keyed by event ID
+func retryCounts(t *testing.T, db *sql.DB) map[string]int {
+	t.Helper()
+	rows, err := db.Query(`SELECT json_extract(event_data, '$.eventId'), retry_count FROM pending_events`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var eventID string
+		var count int
+		if err := rows.Scan(&eventID, &count); err != nil {
+			t.Fatal(err)
+		}
+		counts[eventID] = count
+	}
+	return counts
+}
+
+// deadLetterIDs returns the IDs and event IDs of every dead letter
+func deadLetterIDs(t *testing.T, eq *EventQueue) ([]int64, []string) {
+	t.Helper()
+	letters, err := eq.ListDeadLetters(100)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	var ids []int64
+	var eventIDs []string
+	for _, letter := range letters {
+		ids = append(ids, letter.ID)
+		eventIDs = append(eventIDs, letter.Event.EventID)
+	}
+	sort.Strings(eventIDs)
+	return ids, eventIDs
+}
+
+func TestIncrementRetryDeadLettersExhaustedEvents(t *testing.T) {
+	eq, db := newTestQueue(t)
+	eq.SetDeadLetterAfter(2)
+	if err := eq.Enqueue(testDevice, testEvents("event", 3)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	ids := queuedIDs(t, db)
+	failing := []int64{ids["event-0"], ids["event-1"]}
+
+	if err := eq.IncrementRetry(failing); err != nil {
+		t.Fatalf("IncrementRetry: %v", err)
+	}
+	if _, dead := deadLetterIDs(t, eq); len(dead) != 0 {
+		t.Fatalf("dead-lettered %v after one failure, want none", dead)
+	}
+
+	if err := eq.IncrementRetry(failing); err != nil {
+		t.Fatalf("IncrementRetry: %v", err)
+	}
+	if got := queuedEventIDs(t, db); !equalIDs(got, []string{"event-2"}) {
+		t.Fatalf("queued %v, want only event-2", got)
+	}
+
+	letters, err := eq.ListDeadLetters(100)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("got %d dead letters, want 2", len(letters))
+	}
+	for _, letter := range letters {
+		if letter.Reason != "max retries exceeded" || letter.RetryCount != 2 || letter.DeviceID != testDevice {
+			t.Errorf("dead letter %s = reason %q, retries %d, device %q", letter.Event.EventID, letter.Reason, letter.RetryCount, letter.DeviceID)
+		}
+	}
+}
+
+func TestListDeadLettersKeepsReason(t *testing.T) {
+	eq, db := newTestQueue(t)
+	if err := eq.Enqueue(testDevice, testEvents("event", 2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := eq.DeadLetter([]int64{queuedIDs(t, db)["event-1"]}, "rejected by backend"); err != nil {
+		t.Fatalf("DeadLetter: %v", err)
+	}
+
+	letters, err := eq.ListDeadLetters(100)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Event.EventID != "event-1" || letters[0].Reason != "rejected by backend" {
+		t.Fatalf("dead letters = %+v, want event-1 rejected by backend", letters)
+	}
+}
+
+func TestRequeueDeadLetters(t *testing.T) {
+	eq, db := newTestQueue(t)
+	eq.SetDeadLetterAfter(1)
+	events := testEvents("event", 2)
+	if err := eq.Enqueue(testDevice, events); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	ids := queuedIDs(t, db)
+	if err := eq.IncrementRetry([]int64{ids["event-0"], ids["event-1"]}); err != nil {
+		t.Fatalf("IncrementRetry: %v", err)
+	}
+	letterIDs, dead := deadLetterIDs(t, eq)
+	if !equalIDs(dead, []string{"event-0", "event-1"}) {
+		t.Fatalf("dead-lettered %v, want both events", dead)
+	}
+
+	// event-0 was queued again in the meantime
+	if err := eq.Enqueue(testDevice, events[:1]); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if err := eq.RequeueDeadLetters(letterIDs); err != nil {
+		t.Fatalf("RequeueDeadLetters: %v", err)
+	}
+	got := queuedEventIDs(t, db)
+	sort.Strings(got)
+	if !equalIDs(got, []string{"event-0", "event-1"}) {
+		t.Fatalf("queued %v, want event-0 and event-1 once each", got)
+	}
+	if counts := retryCounts(t, db); counts["event-0"] != 0 || counts["event-1"] != 0 {
+		t.Fatalf("retry counts = %v, want 0 for both", counts)
+	}
+	if _, dead := deadLetterIDs(t, eq); len(dead) != 0 {
+		t.Fatalf("still dead-lettered %v after requeue", dead)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"Mansoor88-6/time-tracking-agent/internal/models"
@@ -11,21 +12,57 @@ import (
 	"go.uber.org/zap"
 )
 
+// OverflowPolicy decides what Enqueue does when the queue is full
+type OverflowPolicy string
+
+const (
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // evict the oldest queued events
+	OverflowRejectNew  OverflowPolicy = "reject_new"  // keep the queue, drop the new events
+)
+
 // EventQueue manages a local queue of pending events
 type EventQueue struct {
-	db     *sql.DB
-	logger *zap.Logger
+	db       *sql.DB
+	logger   *zap.Logger
+	maxSize  int // 0 means unbounded
+	overflow OverflowPolicy
+
+	deadLetterAfter int // failed attempts before an event is dead-lettered
+
+	stopRetention chan struct{} // nil unless StartRetention was called
+	retentionDone chan struct{}
 }
 
 // NewEventQueue creates a new event queue
 func NewEventQueue(db *sql.DB, logger *zap.Logger) *EventQueue {
 	return &EventQueue{
-		db:     db,
-		logger: logger,
+		db:       db,
+		logger:   logger,
+		overflow: OverflowDropOldest,
+
+		deadLetterAfter: defaultDeadLetterAfter,
+	}
+}
+
+// SetMaxSize bounds the queue to maxSize events across all devices (0 for
+// unbounded), applying policy when an Enqueue would exceed it
+func (eq *EventQueue) SetMaxSize(maxSize int, policy OverflowPolicy) error {
+	switch policy {
+	case OverflowDropOldest, OverflowRejectNew:
+	default:
+		return fmt.Errorf("unknown overflow policy %q (must be drop_oldest or reject_new)", policy)
+	}
+	if maxSize < 0 {
+		maxSize = 0
 	}
+	eq.maxSize = maxSize
+	eq.overflow = policy
+	return nil
 }
 
-// Enqueue adds events to the queue
+// Enqueue adds events to the queue. Events whose eventId is already queued
+// are skipped. If a max size is set, the size check and any eviction happen
+// in the same transaction as the insert.
 func (eq *EventQueue) Enqueue(deviceID string, events []models.TrackingEvent) error {
 	tx, err := eq.db.Begin()
 	if err != nil {
@@ -33,8 +70,28 @@ func (eq *EventQueue) Enqueue(deviceID string, events []models.TrackingEvent) er
 	}
 	defer tx.Rollback()
 
+	var queued int
+	if eq.maxSize > 0 {
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM pending_events`).Scan(&queued); err != nil {
+			return fmt.Errorf("failed to count pending events: %w", err)
+		}
+	}
+
+	// With reject_new, only what still fits is inserted
+	rejected := 0
+	if eq.maxSize > 0 && eq.overflow == OverflowRejectNew {
+		space := eq.maxSize - queued
+		if space < 0 {
+			space = 0
+		}
+		if len(events) > space {
+			rejected = len(events) - space
+			events = events[:space]
+		}
+	}
+
 	stmt, err := tx.Prepare(`
-		INSERT INTO pending_events (event_data, device_id, created_at, retry_count)
+		INSERT OR IGNORE INTO pending_events (event_data, device_id, created_at, retry_count)
 		VALUES (?, ?, ?, 0)
 	`)
 	if err != nil {
@@ -42,6 +99,7 @@ func (eq *EventQueue) Enqueue(deviceID string, events []models.TrackingEvent) er
 	}
 	defer stmt.Close()
 
+	inserted, duplicates := 0, 0
 	for _, event := range events {
 		eventData, err := json.Marshal(event)
 		if err != nil {
@@ -49,54 +107,135 @@ func (eq *EventQueue) Enqueue(deviceID string, events []models.TrackingEvent) er
 			continue
 		}
 
-		_, err = stmt.Exec(string(eventData), deviceID, time.Now())
+		result, err := stmt.Exec(string(eventData), deviceID, time.Now())
 		if err != nil {
 			eq.logger.Error("Failed to enqueue event", zap.Error(err))
 			continue
 		}
+		// An event whose eventId is already queued is skipped
+		if n, _ := result.RowsAffected(); n == 0 {
+			duplicates++
+			continue
+		}
+		inserted++
+	}
+
+	// With drop_oldest, evict from the head of the queue to make room
+	var evicted int64
+	if eq.maxSize > 0 && eq.overflow == OverflowDropOldest && queued+inserted > eq.maxSize {
+		result, err := tx.Exec(`
+			DELETE FROM pending_events WHERE id IN (
+				SELECT id FROM pending_events ORDER BY created_at ASC, id ASC LIMIT ?
+			)
+		`, queued+inserted-eq.maxSize)
+		if err != nil {
+			return fmt.Errorf("failed to evict oldest events: %w", err)
+		}
+		evicted, _ = result.RowsAffected()
 	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	if rejected > 0 {
+		eq.logger.Warn("Event queue full, dropped new events",
+			zap.Int("dropped", rejected),
+			zap.Int("max_size", eq.maxSize),
+		)
+	}
+	if evicted > 0 {
+		eq.logger.Warn("Event queue full, dropped oldest events",
+			zap.Int64("dropped", evicted),
+			zap.Int("max_size", eq.maxSize),
+		)
+	}
+
 	eq.logger.Debug("Events enqueued",
-		zap.Int("count", len(events)),
+		zap.Int("count", inserted),
+		zap.Int("duplicates", duplicates),
 		zap.String("device_id", deviceID),
 	)
 
 	return nil
 }
 
-// Dequeue retrieves a batch of events from the queue
+// Retry backoff: an event that has failed n times is not retried until
+// last_attempt + retryBackoffBase*2^(n-1), capped at retryBackoffMax
+const (
+	retryBackoffBase = 30 * time.Second
+	retryBackoffMax  = time.Hour
+)
+
+// retryBackoff returns how long after its last attempt an event that has
+// failed retryCount times becomes eligible again
+func retryBackoff(retryCount int) time.Duration {
+	if retryCount <= 0 {
+		return 0
+	}
+	backoff := retryBackoffBase
+	for i := 1; i < retryCount && backoff < retryBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > retryBackoffMax {
+		backoff = retryBackoffMax
+	}
+	return backoff
+}
+
+// Dequeue retrieves a batch of events that are due for sending. Events still
+// in their retry backoff window are skipped so a repeatedly failing batch
+// doesn't hold back newer events. Never-attempted events come first in
+// queue order, followed by retried events in the order they became due.
 func (eq *EventQueue) Dequeue(deviceID string, limit int) ([]models.TrackingEvent, []int64, error) {
-	rows, err := eq.db.Query(`
-		SELECT id, event_data, retry_count
-		FROM pending_events
-		WHERE device_id = ?
-		ORDER BY created_at ASC
-		LIMIT ?
-	`, deviceID, limit)
+	ids, err := eq.dueIDs(deviceID, limit, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	query := "SELECT id, event_data FROM pending_events WHERE id IN ("
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		if i > 0 {
+			query += ","
+		}
+		query += "?"
+		args[i] = id
+	}
+	query += ")"
+
+	rows, err := eq.db.Query(query, args...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to query pending events: %w", err)
 	}
 	defer rows.Close()
 
-	var events []models.TrackingEvent
-	var ids []int64
-
+	eventData := make(map[int64]string, len(ids))
 	for rows.Next() {
 		var id int64
-		var eventData string
-		var retryCount int
-
-		if err := rows.Scan(&id, &eventData, &retryCount); err != nil {
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
 			eq.logger.Error("Failed to scan row", zap.Error(err))
 			continue
 		}
+		eventData[id] = data
+	}
+	rows.Close()
+
+	var events []models.TrackingEvent
+	var dequeued []int64
+
+	for _, id := range ids {
+		data, ok := eventData[id]
+		if !ok {
+			continue
+		}
 
 		var event models.TrackingEvent
-		if err := json.Unmarshal([]byte(eventData), &event); err != nil {
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
 			eq.logger.Error("Failed to unmarshal event", zap.Error(err), zap.Int64("id", id))
 			// Remove corrupted event
 			eq.db.Exec("DELETE FROM pending_events WHERE id = ?", id)
@@ -104,10 +243,68 @@ func (eq *EventQueue) Dequeue(deviceID string, limit int) ([]models.TrackingEven
 		}
 
 		events = append(events, event)
-		ids = append(ids, id)
+		dequeued = append(dequeued, id)
+	}
+
+	return events, dequeued, nil
+}
+
+// dueIDs returns up to limit IDs of events that are out of their backoff
+// window at now, in send order
+func (eq *EventQueue) dueIDs(deviceID string, limit int, now time.Time) ([]int64, error) {
+	rows, err := eq.db.Query(`
+		SELECT id, retry_count, last_attempt
+		FROM pending_events
+		WHERE device_id = ?
+		ORDER BY created_at ASC, id ASC
+	`, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending events: %w", err)
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		id  int64
+		due time.Time // zero for never-attempted events
+	}
+	var candidates []candidate
+
+	for rows.Next() {
+		var id int64
+		var retryCount int
+		var lastAttempt sql.NullTime
+
+		if err := rows.Scan(&id, &retryCount, &lastAttempt); err != nil {
+			eq.logger.Error("Failed to scan row", zap.Error(err))
+			continue
+		}
+
+		var due time.Time
+		if retryCount > 0 && lastAttempt.Valid {
+			due = lastAttempt.Time.Add(retryBackoff(retryCount))
+			if due.After(now) {
+				continue
+			}
+		}
+		candidates = append(candidates, candidate{id: id, due: due})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pending events: %w", err)
 	}
 
-	return events, ids, nil
+	// Stable, so events due at the same time keep queue order
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].due.Before(candidates[j].due)
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	ids := make([]int64, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.id
+	}
+	return ids, nil
 }
 
 // Remove removes events from the queue by their IDs
@@ -164,7 +361,8 @@ func (eq *EventQueue) IncrementRetry(ids []int64) error {
 		return fmt.Errorf("failed to increment retry: %w", err)
 	}
 
-	return nil
+	// Stop retrying events that keep failing; keep them for inspection
+	return eq.deadLetterExhausted()
 }
 
 // GetPendingCount returns the number of pending events for a device
@@ -179,20 +377,94 @@ func (eq *EventQueue) GetPendingCount(deviceID string) (int, error) {
 	return count, nil
 }
 
-// CleanupOldEvents removes events older than the specified duration
+// QueueStats summarizes the retry queue for diagnostics
+type QueueStats struct {
+	Pending       int     `json:"pending"`
+	OldestAgeSecs float64 `json:"oldest_age_seconds"` // 0 when empty
+	MaxRetryCount int     `json:"max_retry_count"`
+	InBackoff     int     `json:"in_backoff"` // events waiting out a retry backoff
+	DeadLetters   int     `json:"dead_letters"`
+}
+
+// GetStats returns queue statistics for a device
+func (eq *EventQueue) GetStats(deviceID string) (QueueStats, error) {
+	var stats QueueStats
+	now := time.Now()
+
+	err := eq.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(MAX(retry_count), 0)
+		FROM pending_events WHERE device_id = ?
+	`, deviceID).Scan(&stats.Pending, &stats.MaxRetryCount)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+
+	if stats.Pending > 0 {
+		var oldest time.Time
+		err := eq.db.QueryRow(`
+			SELECT created_at FROM pending_events WHERE device_id = ?
+			ORDER BY created_at ASC, id ASC LIMIT 1
+		`, deviceID).Scan(&oldest)
+		if err != nil {
+			return stats, fmt.Errorf("failed to get oldest queued event: %w", err)
+		}
+		stats.OldestAgeSecs = now.Sub(oldest).Seconds()
+	}
+
+	rows, err := eq.db.Query(`
+		SELECT retry_count, last_attempt FROM pending_events
+		WHERE device_id = ? AND retry_count > 0
+	`, deviceID)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get retried events: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var retryCount int
+		var lastAttempt sql.NullTime
+		if err := rows.Scan(&retryCount, &lastAttempt); err != nil {
+			continue
+		}
+		if lastAttempt.Valid && lastAttempt.Time.Add(retryBackoff(retryCount)).After(now) {
+			stats.InBackoff++
+		}
+	}
+	rows.Close()
+
+	err = eq.db.QueryRow(`
+		SELECT COUNT(*) FROM dead_letter_events WHERE device_id = ?
+	`, deviceID).Scan(&stats.DeadLetters)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count dead-letter events: %w", err)
+	}
+
+	return stats, nil
+}
+
+// CleanupOldEvents moves events older than olderThan that have exhausted
+// their retries to the dead-letter table, and prunes old dead letters
 func (eq *EventQueue) CleanupOldEvents(olderThan time.Duration) error {
 	cutoff := time.Now().Add(-olderThan)
-	result, err := eq.db.Exec(`
-		DELETE FROM pending_events
-		WHERE created_at < ? AND retry_count > 10
-	`, cutoff)
+	moved, err := eq.moveToDeadLetter("created_at < ? AND retry_count >= ?",
+		[]interface{}{cutoff, eq.deadLetterAfter}, "max retries exceeded")
 	if err != nil {
 		return fmt.Errorf("failed to cleanup old events: %w", err)
 	}
+	if moved > 0 {
+		eq.logger.Info("Moved old failed events to dead-letter table",
+			zap.Int64("count", moved),
+		)
+	}
+
+	result, err := eq.db.Exec(`DELETE FROM dead_letter_events WHERE dead_at < ?`,
+		time.Now().Add(-deadLetterRetention))
+	if err != nil {
+		return fmt.Errorf("failed to prune dead-letter events: %w", err)
+	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected > 0 {
-		eq.logger.Info("Cleaned up old events",
+		eq.logger.Info("Pruned old dead-letter events",
 			zap.Int64("count", rowsAffected),
 		)
 	}
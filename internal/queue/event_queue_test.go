@@ -0,0 +1,273 @@
+package queue
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/database"
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const testDevice = "device-1"
+
+func newTestQueue(t *testing.T) (*EventQueue, *sql.DB) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "agent.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewEventQueue(db.DB, zap.NewNop()), db.DB
+}
+
+// testEvents returns events named prefix-0 ... prefix-(n-1)
+func testEvents(prefix string, n int) []models.TrackingEvent {
+	events := make([]models.TrackingEvent, n)
+	for i := range events {
+		events[i] = models.TrackingEvent{
+			EventID:   fmt.Sprintf("%s-%d", prefix, i),
+			DeviceID:  testDevice,
+			Timestamp: time.Now().UnixMilli(),
+			Status:    models.StatusActive,
+		}
+	}
+	return events
+}
+
+// queuedIDs returns the queue's row IDs keyed by event ID
+func queuedIDs(t *testing.T, db *sql.DB) map[string]int64 {
+	t.Helper()
+	rows, err := db.Query(`SELECT id, json_extract(event_data, '$.eventId') FROM pending_events`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	ids := make(map[string]int64)
+	for rows.Next() {
+		var id int64
+		var eventID string
+		if err := rows.Scan(&id, &eventID); err != nil {
+			t.Fatal(err)
+		}
+		ids[eventID] = id
+	}
+	return ids
+}
+
+// eventIDs returns the event IDs of events in order
+func eventIDs(events []models.TrackingEvent) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.EventID
+	}
+	return ids
+}
+
+func equalIDs(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		retries int
+		want    time.Duration
+	}{
+		{0, 0},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.retries); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", tt.retries, got, tt.want)
+		}
+	}
+}
+
+func TestDequeueSkipsEventsInBackoff(t *testing.T) {
+	eq, db := newTestQueue(t)
+	if err := eq.Enqueue(testDevice, testEvents("e", 3)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	ids := queuedIDs(t, db)
+
+	// e-0 failed twice (1m backoff), e-1 failed once (30s backoff)
+	lastAttempt := time.Now().Add(-45 * time.Second)
+	if _, err := db.Exec(`UPDATE pending_events SET retry_count = 2, last_attempt = ? WHERE id = ?`, lastAttempt, ids["e-0"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE pending_events SET retry_count = 1, last_attempt = ? WHERE id = ?`, lastAttempt, ids["e-1"]); err != nil {
+		t.Fatal(err)
+	}
+
+	events, _, err := eq.Dequeue(testDevice, 10)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	// e-0 is still backing off; never-attempted e-2 goes before retried e-1
+	if got, want := eventIDs(events), []string{"e-2", "e-1"}; !equalIDs(got, want) {
+		t.Fatalf("Dequeue = %v, want %v", got, want)
+	}
+
+	// Once e-0's backoff ends it is due again, after e-1 which was due first
+	due, err := eq.dueIDs(testDevice, 10, lastAttempt.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("dueIDs: %v", err)
+	}
+	if want := []int64{ids["e-2"], ids["e-1"], ids["e-0"]}; fmt.Sprint(due) != fmt.Sprint(want) {
+		t.Fatalf("dueIDs after backoff = %v, want %v", due, want)
+	}
+}
+
+func TestDequeueLimit(t *testing.T) {
+	eq, _ := newTestQueue(t)
+	if err := eq.Enqueue(testDevice, testEvents("e", 5)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	events, ids, err := eq.Dequeue(testDevice, 2)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if got, want := eventIDs(events), []string{"e-0", "e-1"}; !equalIDs(got, want) || len(ids) != 2 {
+		t.Fatalf("Dequeue = %v (ids %v), want %v", got, ids, want)
+	}
+}
+
+func TestIncrementRetryStartsBackoff(t *testing.T) {
+	eq, _ := newTestQueue(t)
+	if err := eq.Enqueue(testDevice, testEvents("e", 2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	_, ids, err := eq.Dequeue(testDevice, 1)
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("Dequeue: %v, %v", ids, err)
+	}
+	if err := eq.IncrementRetry(ids); err != nil {
+		t.Fatalf("IncrementRetry: %v", err)
+	}
+
+	events, _, err := eq.Dequeue(testDevice, 10)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if got, want := eventIDs(events), []string{"e-1"}; !equalIDs(got, want) {
+		t.Fatalf("Dequeue after a failure = %v, want %v", got, want)
+	}
+}
+
+// queuedEventIDs returns the event IDs in the queue in queue order
+func queuedEventIDs(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT json_extract(event_data, '$.eventId') FROM pending_events ORDER BY created_at, id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	eq, db := newTestQueue(t)
+	if err := eq.SetMaxSize(3, OverflowDropOldest); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := eq.Enqueue(testDevice, testEvents("old", 2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := eq.Enqueue(testDevice, testEvents("new", 2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if got, want := queuedEventIDs(t, db), []string{"old-1", "new-0", "new-1"}; !equalIDs(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+
+	// A batch larger than the queue keeps its newest events
+	if err := eq.Enqueue(testDevice, testEvents("big", 5)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if got, want := queuedEventIDs(t, db), []string{"big-2", "big-3", "big-4"}; !equalIDs(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+}
+
+func TestOverflowRejectNew(t *testing.T) {
+	eq, db := newTestQueue(t)
+	if err := eq.SetMaxSize(3, OverflowRejectNew); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := eq.Enqueue(testDevice, testEvents("old", 2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := eq.Enqueue(testDevice, testEvents("new", 2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if got, want := queuedEventIDs(t, db), []string{"old-0", "old-1", "new-0"}; !equalIDs(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+
+	if err := eq.Enqueue(testDevice, testEvents("later", 1)); err != nil {
+		t.Fatalf("Enqueue into a full queue: %v", err)
+	}
+	if got := queuedEventIDs(t, db); len(got) != 3 {
+		t.Fatalf("full queue grew to %v", got)
+	}
+}
+
+func TestSetMaxSizeRejectsUnknownPolicy(t *testing.T) {
+	eq, _ := newTestQueue(t)
+	if err := eq.SetMaxSize(10, "drop_newest"); err == nil {
+		t.Fatal("unknown overflow policy accepted")
+	}
+}
+
+func TestEnqueueSameEventTwiceStoresOnce(t *testing.T) {
+	eq, db := newTestQueue(t)
+	events := testEvents("e", 2)
+
+	if err := eq.Enqueue(testDevice, events); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	// A resend of the same batch, plus a duplicate within one batch
+	if err := eq.Enqueue(testDevice, append(events, events[0])); err != nil {
+		t.Fatalf("Enqueue again: %v", err)
+	}
+	if got, want := queuedEventIDs(t, db), []string{"e-0", "e-1"}; !equalIDs(got, want) {
+		t.Fatalf("queue = %v, want each event once", got)
+	}
+
+	// Events without an ID aren't deduplicated
+	anonymous := models.TrackingEvent{DeviceID: testDevice, Status: models.StatusActive}
+	if err := eq.Enqueue(testDevice, []models.TrackingEvent{anonymous, anonymous}); err != nil {
+		t.Fatalf("Enqueue anonymous: %v", err)
+	}
+	if count, err := eq.GetPendingCount(testDevice); err != nil || count != 4 {
+		t.Fatalf("pending = %d, %v, want 4", count, err)
+	}
+}
@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// localEventTables hold events that have not been delivered to the backend:
+// the retry queue, the collector's staging area and the dead-letter table
+var localEventTables = []string{"pending_events", "staged_events", "dead_letter_events"}
+
+// LocalEvents returns every event still stored locally whose start time
+// falls in [from, to). Events already delivered to the backend are gone from
+// the database and not included.
+func (eq *EventQueue) LocalEvents(from, to time.Time) ([]models.TrackingEvent, error) {
+	var events []models.TrackingEvent
+	for _, table := range localEventTables {
+		rows, err := eq.db.Query(`SELECT event_data FROM ` + table + ` ORDER BY id ASC`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", table, err)
+		}
+
+		for rows.Next() {
+			var eventData string
+			if err := rows.Scan(&eventData); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s: %w", table, err)
+			}
+
+			var event models.TrackingEvent
+			if err := json.Unmarshal([]byte(eventData), &event); err != nil {
+				eq.logger.Debug("Skipping unreadable local event", zap.String("table", table), zap.Error(err))
+				continue
+			}
+
+			start := event.Timestamp
+			if event.StartTime != nil {
+				start = *event.StartTime
+			}
+			if start >= from.UnixMilli() && start < to.UnixMilli() {
+				events = append(events, event)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+	}
+	return events, nil
+}
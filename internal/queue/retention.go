@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// retentionInterval is how often the retention purge runs
+const retentionInterval = time.Hour
+
+// PurgeOlderThan deletes events stored locally for longer than maxAge from
+// the retry queue, the staging area and the dead-letter table, whether or
+// not they were ever delivered. It returns how many rows were deleted.
+// Delivered events need no purge: they are removed as soon as the backend
+// accepts them.
+func (eq *EventQueue) PurgeOlderThan(maxAge time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-maxAge)
+
+	var total int64
+	for _, table := range localEventTables {
+		result, err := eq.db.Exec(`DELETE FROM `+table+` WHERE created_at < ?`, cutoff)
+		if err != nil {
+			return total, fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+		n, _ := result.RowsAffected()
+		if n > 0 {
+			eq.logger.Info("Purged events past the retention period",
+				zap.String("table", table),
+				zap.Int64("count", n),
+				zap.Duration("retention", maxAge),
+			)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// StartRetention purges events older than maxAge now and then every
+// retentionInterval until StopRetention. A maxAge of 0 or less disables it.
+func (eq *EventQueue) StartRetention(maxAge time.Duration) {
+	if maxAge <= 0 || eq.stopRetention != nil {
+		return
+	}
+	eq.stopRetention = make(chan struct{})
+	eq.retentionDone = make(chan struct{})
+
+	go func() {
+		defer close(eq.retentionDone)
+
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := eq.PurgeOlderThan(maxAge); err != nil {
+				eq.logger.Warn("Retention purge failed", zap.Error(err))
+			}
+			select {
+			case <-ticker.C:
+			case <-eq.stopRetention:
+				return
+			}
+		}
+	}()
+}
+
+// StopRetention stops the retention goroutine and waits for an in-progress
+// purge to finish
+func (eq *EventQueue) StopRetention() {
+	if eq.stopRetention == nil {
+		return
+	}
+	close(eq.stopRetention)
+	<-eq.retentionDone
+	eq.stopRetention = nil
+}
@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// insertAged adds a row named name to table, created at createdAt
+func insertAged(t *testing.T, db *sql.DB, table, name string, createdAt time.Time) {
+	t.Helper()
+	var err error
+	data := `{"eventId":"` + name + `"}`
+	switch table {
+	case "staged_events":
+		_, err = db.Exec(`INSERT INTO staged_events (event_data, created_at) VALUES (?, ?)`, data, createdAt)
+	default:
+		_, err = db.Exec(`INSERT INTO `+table+` (event_data, device_id, created_at) VALUES (?, ?, ?)`, data, testDevice, createdAt)
+	}
+	if err != nil {
+		t.Fatalf("insert into %s: %v", table, err)
+	}
+}
+
+// remaining returns the event IDs left in table
+func remaining(t *testing.T, db *sql.DB, table string) map[string]bool {
+	t.Helper()
+	rows, err := db.Query(`SELECT json_extract(event_data, '$.eventId') FROM ` + table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	names := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names[name] = true
+	}
+	return names
+}
+
+func TestPurgeOlderThanBoundary(t *testing.T) {
+	eq, db := newTestQueue(t)
+	const maxAge = 7 * 24 * time.Hour
+	now := time.Now()
+
+	for _, table := range localEventTables {
+		insertAged(t, db, table, "long-gone", now.Add(-30*24*time.Hour))
+		insertAged(t, db, table, "just-outside", now.Add(-maxAge-time.Second))
+		insertAged(t, db, table, "just-inside", now.Add(-maxAge+time.Second))
+		insertAged(t, db, table, "fresh", now)
+	}
+
+	purged, err := eq.PurgeOlderThan(maxAge)
+	if err != nil {
+		t.Fatalf("PurgeOlderThan: %v", err)
+	}
+	if want := int64(2 * len(localEventTables)); purged != want {
+		t.Errorf("purged %d rows, want %d", purged, want)
+	}
+	for _, table := range localEventTables {
+		left := remaining(t, db, table)
+		if len(left) != 2 || !left["just-inside"] || !left["fresh"] {
+			t.Errorf("%s kept %v, want just-inside and fresh", table, left)
+		}
+	}
+
+	// Nothing left to purge
+	if purged, err := eq.PurgeOlderThan(maxAge); err != nil || purged != 0 {
+		t.Errorf("second purge = %d, %v, want 0", purged, err)
+	}
+}
+
+func TestPurgeOlderThanKeepsEventsFromEnqueue(t *testing.T) {
+	eq, db := newTestQueue(t)
+	if err := eq.Enqueue(testDevice, testEvents("e", 3)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if purged, err := eq.PurgeOlderThan(time.Hour); err != nil || purged != 0 {
+		t.Fatalf("purge of new events = %d, %v, want 0", purged, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if purged, err := eq.PurgeOlderThan(time.Millisecond); err != nil || purged != 3 {
+		t.Fatalf("purge past their age = %d, %v, want 3", purged, err)
+	}
+	if left := queuedEventIDs(t, db); len(left) != 0 {
+		t.Errorf("queue after purge = %v", left)
+	}
+}
@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// StagingArea durably holds events the collector has buffered but not yet
+// handed off, so a crash or forced exit doesn't lose them
+type StagingArea struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewStagingArea creates a new staging area
+func NewStagingArea(db *sql.DB, logger *zap.Logger) *StagingArea {
+	return &StagingArea{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Stage stores an event and returns its staging ID
+func (sa *StagingArea) Stage(event models.TrackingEvent) (int64, error) {
+	eventData, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	result, err := sa.db.Exec(`
+		INSERT INTO staged_events (event_data, created_at)
+		VALUES (?, ?)
+	`, string(eventData), time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to stage event: %w", err)
+	}
+
+	return result.LastInsertId()
+}
+
+// Unstage removes events that have been handed off
+func (sa *StagingArea) Unstage(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// Build query with placeholders
+	query := "DELETE FROM staged_events WHERE id IN ("
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		if i > 0 {
+			query += ","
+		}
+		query += "?"
+		args[i] = id
+	}
+	query += ")"
+
+	if _, err := sa.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to unstage events: %w", err)
+	}
+	return nil
+}
+
+// LoadStaged returns all staged events in the order they were staged
+func (sa *StagingArea) LoadStaged() ([]models.TrackingEvent, []int64, error) {
+	rows, err := sa.db.Query(`
+		SELECT id, event_data
+		FROM staged_events
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query staged events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.TrackingEvent
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		var eventData string
+
+		if err := rows.Scan(&id, &eventData); err != nil {
+			sa.logger.Error("Failed to scan row", zap.Error(err))
+			continue
+		}
+
+		var event models.TrackingEvent
+		if err := json.Unmarshal([]byte(eventData), &event); err != nil {
+			sa.logger.Error("Failed to unmarshal staged event", zap.Error(err), zap.Int64("id", id))
+			// Remove corrupted event
+			if _, err := sa.db.Exec("DELETE FROM staged_events WHERE id = ?", id); err != nil {
+				sa.logger.Warn("Failed to remove corrupt staged event", zap.Error(err), zap.Int64("id", id))
+			}
+			continue
+		}
+
+		events = append(events, event)
+		ids = append(ids, id)
+	}
+
+	return events, ids, rows.Err()
+}
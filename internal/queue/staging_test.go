@@ -0,0 +1,75 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestStagingRoundTrip(t *testing.T) {
+	_, db := newTestQueue(t)
+	sa := NewStagingArea(db, zap.NewNop())
+
+	events := testEvents("staged", 3)
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		id, err := sa.Stage(event)
+		if err != nil {
+			t.Fatalf("Stage: %v", err)
+		}
+		ids[i] = id
+	}
+
+	loaded, loadedIDs, err := sa.LoadStaged()
+	if err != nil {
+		t.Fatalf("LoadStaged: %v", err)
+	}
+	if want := eventIDs(events); !equalIDs(eventIDs(loaded), want) {
+		t.Fatalf("loaded %v, want %v in staging order", eventIDs(loaded), want)
+	}
+	for i := range ids {
+		if loadedIDs[i] != ids[i] {
+			t.Fatalf("loaded IDs %v, want %v", loadedIDs, ids)
+		}
+	}
+
+	if err := sa.Unstage(ids[:2]); err != nil {
+		t.Fatalf("Unstage: %v", err)
+	}
+	loaded, loadedIDs, err = sa.LoadStaged()
+	if err != nil {
+		t.Fatalf("LoadStaged: %v", err)
+	}
+	if got := eventIDs(loaded); !equalIDs(got, []string{"staged-2"}) || len(loadedIDs) != 1 || loadedIDs[0] != ids[2] {
+		t.Fatalf("after unstaging the first two, loaded %v (IDs %v), want [staged-2]", got, loadedIDs)
+	}
+}
+
+func TestLoadStagedDropsCorruptRows(t *testing.T) {
+	_, db := newTestQueue(t)
+	sa := NewStagingArea(db, zap.NewNop())
+
+	if _, err := db.Exec(`INSERT INTO staged_events (event_data, created_at) VALUES (?, ?)`, "{not json", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sa.Stage(testEvents("good", 1)[0]); err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+
+	loaded, _, err := sa.LoadStaged()
+	if err != nil {
+		t.Fatalf("LoadStaged: %v", err)
+	}
+	if got := eventIDs(loaded); !equalIDs(got, []string{"good-0"}) {
+		t.Fatalf("loaded %v, want [good-0]", got)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM staged_events`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("%d staged rows left, want the corrupt one removed", count)
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const lastSeenKey = "last_seen_at"
+
+// AgentStateRepository persists small pieces of agent runtime state
+type AgentStateRepository struct {
+	db *sql.DB
+}
+
+func NewAgentStateRepository(db *sql.DB) *AgentStateRepository {
+	return &AgentStateRepository{db: db}
+}
+
+// GetLastSeen returns the last time the agent recorded itself as alive.
+// The boolean is false when no heartbeat has been recorded yet.
+func (r *AgentStateRepository) GetLastSeen() (time.Time, bool, error) {
+	var value string
+	err := r.db.QueryRow("SELECT value FROM agent_state WHERE key = ?", lastSeenKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get last seen: %w", err)
+	}
+
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid last seen value %q: %w", value, err)
+	}
+
+	return time.UnixMilli(ms), true, nil
+}
+
+// SetLastSeen records t as the last time the agent was alive
+func (r *AgentStateRepository) SetLastSeen(t time.Time) error {
+	_, err := r.db.Exec(`
+		INSERT INTO agent_state (key, value, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP
+	`, lastSeenKey, strconv.FormatInt(t.UnixMilli(), 10))
+	if err != nil {
+		return fmt.Errorf("failed to set last seen: %w", err)
+	}
+	return nil
+}
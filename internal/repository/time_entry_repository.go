@@ -3,20 +3,101 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"Mansoor88-6/time-tracking-agent/internal/models"
 )
 
+// storedTimeLayout is how entry times are written: fixed-width UTC, so
+// they compare correctly as text and still scan back into time.Time
+const storedTimeLayout = "2006-01-02 15:04:05.000000000-07:00"
+
+func storedTime(t time.Time) string {
+	return t.UTC().Format(storedTimeLayout)
+}
+
+func storedTimePtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return storedTime(*t)
+}
+
 type TimeEntryRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	readDB *sql.DB
 }
 
 func NewTimeEntryRepository(db *sql.DB) *TimeEntryRepository {
-	return &TimeEntryRepository{db: db}
+	return &TimeEntryRepository{db: db, readDB: db}
+}
+
+// SetReadDB routes read-only queries to a separate (e.g. read-only) pool
+func (r *TimeEntryRepository) SetReadDB(readDB *sql.DB) {
+	r.readDB = readDB
+}
+
+// bulkInsertBatchSize is how many entries BulkCreate inserts per transaction
+const bulkInsertBatchSize = 500
+
+// queryRower is satisfied by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 func (r *TimeEntryRepository) Create(entry *models.CreateTimeEntryRequest) (*models.TimeEntry, error) {
+	return insertTimeEntry(r.db, entry)
+}
+
+// BulkCreate inserts entries in batched transactions. It returns the created
+// entry or the error for each input row, by index; a failed row doesn't stop
+// the others.
+func (r *TimeEntryRepository) BulkCreate(entries []*models.CreateTimeEntryRequest) ([]*models.TimeEntry, []error) {
+	created := make([]*models.TimeEntry, len(entries))
+	errs := make([]error, len(entries))
+
+	for start := 0; start < len(entries); start += bulkInsertBatchSize {
+		end := start + bulkInsertBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		if err := r.bulkInsertBatch(entries[start:end], created[start:end], errs[start:end]); err != nil {
+			for i := start; i < end; i++ {
+				created[i] = nil
+				errs[i] = err
+			}
+		}
+	}
+
+	return created, errs
+}
+
+// bulkInsertBatch inserts one batch in a transaction, recording per-row
+// results in created and errs. A returned error means the whole batch was
+// rolled back.
+func (r *TimeEntryRepository) bulkInsertBatch(entries []*models.CreateTimeEntryRequest, created []*models.TimeEntry, errs []error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, entry := range entries {
+		// SQLite rolls back only the failing statement, so the rest of the
+		// batch can still commit
+		created[i], errs[i] = insertTimeEntry(tx, entry)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit time entries: %w", err)
+	}
+	return nil
+}
+
+// insertTimeEntry inserts entry, computing its duration from its times
+func insertTimeEntry(q queryRower, entry *models.CreateTimeEntryRequest) (*models.TimeEntry, error) {
 	var durationSeconds *int64
 	if entry.EndTime != nil {
 		duration := int64(entry.EndTime.Sub(entry.StartTime).Seconds())
@@ -31,13 +112,13 @@ func (r *TimeEntryRepository) Create(entry *models.CreateTimeEntryRequest) (*mod
 
 	var id int64
 	var createdAt, updatedAt time.Time
-	err := r.db.QueryRow(
+	err := q.QueryRow(
 		query,
 		entry.UserID,
 		entry.ProjectID,
 		entry.Description,
-		entry.StartTime,
-		entry.EndTime,
+		storedTime(entry.StartTime),
+		storedTimePtr(entry.EndTime),
 		durationSeconds,
 	).Scan(&id, &createdAt, &updatedAt)
 
@@ -66,7 +147,7 @@ func (r *TimeEntryRepository) GetByID(id int64) (*models.TimeEntry, error) {
 	`
 
 	var entry models.TimeEntry
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.readDB.QueryRow(query, id).Scan(
 		&entry.ID,
 		&entry.UserID,
 		&entry.ProjectID,
@@ -89,15 +170,21 @@ func (r *TimeEntryRepository) GetByID(id int64) (*models.TimeEntry, error) {
 }
 
 func (r *TimeEntryRepository) GetByUserID(userID string, limit, offset int) ([]*models.TimeEntry, error) {
+	return r.List(&models.TimeEntryFilter{UserID: userID}, limit, offset)
+}
+
+// List returns a page of the entries matching filter, newest first
+func (r *TimeEntryRepository) List(filter *models.TimeEntryFilter, limit, offset int) ([]*models.TimeEntry, error) {
+	where, args := timeEntryWhere(filter)
 	query := `
 		SELECT id, user_id, project_id, description, start_time, end_time, duration_seconds, created_at, updated_at
 		FROM time_entries
-		WHERE user_id = ?
+		WHERE ` + where + `
 		ORDER BY start_time DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.Query(query, userID, limit, offset)
+	rows, err := r.readDB.Query(query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query time entries: %w", err)
 	}
@@ -130,6 +217,133 @@ func (r *TimeEntryRepository) GetByUserID(userID string, limit, offset int) ([]*
 	return entries, nil
 }
 
+// Count returns how many entries match filter
+func (r *TimeEntryRepository) Count(filter *models.TimeEntryFilter) (int, error) {
+	where, args := timeEntryWhere(filter)
+	var count int
+	err := r.readDB.QueryRow("SELECT COUNT(*) FROM time_entries WHERE "+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count time entries: %w", err)
+	}
+	return count, nil
+}
+
+// Summarize totals the durations of the entries matching filter, grouped by
+// project and/or UTC start day. Entries without an end are counted up to
+// runningUntil, or left out when it is nil.
+func (r *TimeEntryRepository) Summarize(filter *models.TimeEntryFilter, byProject, byDay bool, runningUntil *time.Time) ([]*models.TimeEntryTotal, error) {
+	var columns, groups []string
+	if byProject {
+		columns = append(columns, "project_id")
+		groups = append(groups, "project_id")
+	}
+	if byDay {
+		// Stored times are UTC text, so the first 10 characters are the date
+		columns = append(columns, "substr(start_time, 1, 10) AS day")
+		groups = append(groups, "day")
+	}
+
+	where, whereArgs := timeEntryWhere(filter)
+	var args []interface{}
+	duration := "duration_seconds"
+	if runningUntil != nil {
+		duration = "CASE WHEN end_time IS NULL" +
+			" THEN CAST((julianday(?) - julianday(start_time)) * 86400 AS INTEGER)" +
+			" ELSE duration_seconds END"
+		args = append(args, storedTime(*runningUntil))
+	} else {
+		where += " AND end_time IS NOT NULL"
+	}
+	args = append(args, whereArgs...)
+
+	query := "SELECT " + strings.Join(append(columns, "COALESCE(SUM("+duration+"), 0)", "COUNT(*)"), ", ") +
+		" FROM time_entries WHERE " + where
+	if len(groups) > 0 {
+		query += " GROUP BY " + strings.Join(groups, ", ") + " ORDER BY " + strings.Join(groups, ", ")
+	}
+
+	rows, err := r.readDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize time entries: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []*models.TimeEntryTotal
+	for rows.Next() {
+		var total models.TimeEntryTotal
+		var dest []interface{}
+		if byProject {
+			dest = append(dest, &total.ProjectID)
+		}
+		if byDay {
+			dest = append(dest, &total.Day)
+		}
+		dest = append(dest, &total.TotalSeconds, &total.Entries)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan time entry total: %w", err)
+		}
+		if total.Entries > 0 {
+			totals = append(totals, &total)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return totals, nil
+}
+
+// timeEntryWhere builds the WHERE clause for filter
+func timeEntryWhere(filter *models.TimeEntryFilter) (string, []interface{}) {
+	conditions := []string{"user_id = ?"}
+	args := []interface{}{filter.UserID}
+
+	if filter.From != nil {
+		conditions = append(conditions, "start_time >= ?")
+		args = append(args, storedTime(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "start_time < ?")
+		args = append(args, storedTime(*filter.To))
+	}
+	if filter.ProjectID != nil {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, *filter.ProjectID)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// FindOverlapping returns an entry of userID whose [start, end) interval
+// overlaps [start, end), or nil if none does. A nil end, on either side,
+// means the interval is still open. excludeID skips that entry, for
+// checking an update against the other entries.
+func (r *TimeEntryRepository) FindOverlapping(userID string, start time.Time, end *time.Time, excludeID int64) (*models.TimeEntry, error) {
+	query := `
+		SELECT id
+		FROM time_entries
+		WHERE user_id = ? AND id != ?
+			AND (end_time IS NULL OR end_time > ?)
+	`
+	args := []interface{}{userID, excludeID, storedTime(start)}
+	if end != nil {
+		query += ` AND start_time < ?`
+		args = append(args, storedTime(*end))
+	}
+	query += ` ORDER BY start_time LIMIT 1`
+
+	var id int64
+	err := r.readDB.QueryRow(query, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for overlapping time entries: %w", err)
+	}
+	return r.GetByID(id)
+}
+
 func (r *TimeEntryRepository) Update(id int64, update *models.UpdateTimeEntryRequest) (*models.TimeEntry, error) {
 	// Get current entry first
 	current, err := r.GetByID(id)
@@ -154,12 +368,12 @@ func (r *TimeEntryRepository) Update(id int64, update *models.UpdateTimeEntryReq
 	}
 	if update.StartTime != nil {
 		setParts = append(setParts, "start_time = ?")
-		args = append(args, *update.StartTime)
+		args = append(args, storedTime(*update.StartTime))
 		startTime = *update.StartTime
 	}
 	if update.EndTime != nil {
 		setParts = append(setParts, "end_time = ?")
-		args = append(args, *update.EndTime)
+		args = append(args, storedTime(*update.EndTime))
 		endTime = update.EndTime
 	}
 
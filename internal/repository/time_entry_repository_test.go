@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/database"
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+func newTestRepository(t *testing.T) *TimeEntryRepository {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "agent.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewTimeEntryRepository(db.DB)
+}
+
+// createEntry stores an entry of userID from start lasting duration; a zero
+// duration leaves it running
+func createEntry(t *testing.T, r *TimeEntryRepository, userID string, start time.Time, duration time.Duration) *models.TimeEntry {
+	t.Helper()
+	req := &models.CreateTimeEntryRequest{UserID: userID, StartTime: start}
+	if duration > 0 {
+		end := start.Add(duration)
+		req.EndTime = &end
+	}
+	entry, err := r.Create(req)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return entry
+}
+
+func TestListRangeBoundaries(t *testing.T) {
+	r := newTestRepository(t)
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	before := createEntry(t, r, "user-1", day.Add(-time.Nanosecond), time.Hour)
+	atFrom := createEntry(t, r, "user-1", day, time.Hour)
+	inside := createEntry(t, r, "user-1", day.Add(12*time.Hour), time.Hour)
+	lastInside := createEntry(t, r, "user-1", day.Add(24*time.Hour-time.Microsecond), time.Hour)
+	atTo := createEntry(t, r, "user-1", day.Add(24*time.Hour), time.Hour)
+
+	// Other time zones name the same instants
+	from := day.In(time.FixedZone("UTC+5", 5*3600))
+	to := day.Add(24 * time.Hour).In(time.FixedZone("UTC-8", -8*3600))
+	filter := &models.TimeEntryFilter{UserID: "user-1", From: &from, To: &to}
+
+	entries, err := r.List(filter, 50, 0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	got := make(map[int64]bool)
+	for _, entry := range entries {
+		got[entry.ID] = true
+	}
+	for _, entry := range []*models.TimeEntry{atFrom, inside, lastInside} {
+		if !got[entry.ID] {
+			t.Errorf("entry starting %v missing from [from, to)", entry.StartTime)
+		}
+	}
+	for _, entry := range []*models.TimeEntry{before, atTo} {
+		if got[entry.ID] {
+			t.Errorf("entry starting %v outside [from, to) listed", entry.StartTime)
+		}
+	}
+	if count, err := r.Count(filter); err != nil || count != 3 {
+		t.Errorf("Count = %d, %v, want 3", count, err)
+	}
+
+	// An empty range holds nothing, even an entry starting at its bound
+	empty := &models.TimeEntryFilter{UserID: "user-1", From: &from, To: &from}
+	if count, err := r.Count(empty); err != nil || count != 0 {
+		t.Errorf("Count of [from, from) = %d, %v, want 0", count, err)
+	}
+}
+
+func TestFindOverlapping(t *testing.T) {
+	r := newTestRepository(t)
+	nine := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	existing := createEntry(t, r, "user-1", nine, time.Hour) // [09:00, 10:00)
+	running := createEntry(t, r, "user-2", nine, 0)          // [09:00, ...)
+
+	at := func(hour, minute int) *time.Time {
+		tm := time.Date(2026, 3, 2, hour, minute, 0, 0, time.UTC)
+		return &tm
+	}
+	tests := []struct {
+		name        string
+		userID      string
+		start       *time.Time
+		end         *time.Time
+		excludeID   int64
+		wantOverlap *models.TimeEntry
+	}{
+		{"ends where existing starts", "user-1", at(8, 0), at(9, 0), 0, nil},
+		{"starts where existing ends", "user-1", at(10, 0), at(11, 0), 0, nil},
+		{"open, starts where existing ends", "user-1", at(10, 0), nil, 0, nil},
+		{"overlaps the start", "user-1", at(8, 30), at(9, 1), 0, existing},
+		{"overlaps the end", "user-1", at(9, 59), at(11, 0), 0, existing},
+		{"inside", "user-1", at(9, 15), at(9, 45), 0, existing},
+		{"covers", "user-1", at(8, 0), at(11, 0), 0, existing},
+		{"same interval", "user-1", at(9, 0), at(10, 0), 0, existing},
+		{"open, starts inside", "user-1", at(9, 30), nil, 0, existing},
+		{"excluded itself", "user-1", at(9, 0), at(10, 0), existing.ID, nil},
+		{"other user", "user-3", at(9, 0), at(10, 0), 0, nil},
+		{"ends where running starts", "user-2", at(8, 0), at(9, 0), 0, nil},
+		{"after running start", "user-2", at(15, 0), at(16, 0), 0, running},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.FindOverlapping(tt.userID, *tt.start, tt.end, tt.excludeID)
+			if err != nil {
+				t.Fatalf("FindOverlapping: %v", err)
+			}
+			switch {
+			case tt.wantOverlap == nil && got != nil:
+				t.Errorf("found overlap with entry %d, want none", got.ID)
+			case tt.wantOverlap != nil && (got == nil || got.ID != tt.wantOverlap.ID):
+				t.Errorf("got %v, want entry %d", got, tt.wantOverlap.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Header carries the request ID on both the request and the response
+const Header = "X-Request-ID"
+
+// maxLength bounds caller-supplied IDs so they can't bloat the logs
+const maxLength = 128
+
+type contextKey struct{}
+
+// Middleware accepts the caller's X-Request-ID if it looks sane, otherwise
+// generates one, then stores it in the request context and echoes it in the
+// response
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, id)))
+	})
+}
+
+// FromContext returns the request ID, or "" outside a request
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Logger returns base tagged with the request ID from ctx
+func Logger(ctx context.Context, base *zap.Logger) *zap.Logger {
+	id := FromContext(ctx)
+	if id == "" {
+		return base
+	}
+	return base.With(zap.String("request_id", id))
+}
+
+// valid allows IDs made of letters, digits, '.', '_' and '-', which covers
+// UUIDs and the usual proxy formats without letting log injection through
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
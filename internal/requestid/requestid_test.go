@@ -0,0 +1,73 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// serve runs a request with the X-Request-ID header set to id, unless it is
+// empty, and returns the response's ID and the one the handler saw
+func serve(t *testing.T, id string) (echoed, seen string) {
+	t.Helper()
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if id != "" {
+		req.Header.Set(Header, id)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Header().Get(Header), seen
+}
+
+func TestMiddlewareEchoesProvidedID(t *testing.T) {
+	for _, id := range []string{"abc-123", "3f2b8c1e-7d1a-4e7b-9a55-0c8e2f6d9b41", "req_1.2"} {
+		echoed, seen := serve(t, id)
+		if echoed != id || seen != id {
+			t.Errorf("provided %q: echoed %q, handler saw %q", id, echoed, seen)
+		}
+	}
+}
+
+func TestMiddlewareGeneratesMissingOrInvalidID(t *testing.T) {
+	for _, id := range []string{"", "has space", "line\nbreak", "quote\"", strings.Repeat("a", maxLength+1)} {
+		echoed, seen := serve(t, id)
+		if echoed == id || echoed != seen {
+			t.Errorf("provided %q: echoed %q, handler saw %q, want the same generated ID", id, echoed, seen)
+		}
+		if _, err := uuid.Parse(echoed); err != nil {
+			t.Errorf("provided %q: generated ID %q is not a UUID", id, echoed)
+		}
+	}
+
+	first, _ := serve(t, "")
+	second, _ := serve(t, "")
+	if first == second {
+		t.Errorf("two requests got the same generated ID %q", first)
+	}
+}
+
+func TestLoggerTagsRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	base := zap.New(core)
+
+	Logger(context.Background(), base).Info("outside")
+	Logger(context.WithValue(context.Background(), contextKey{}, "abc-123"), base).Info("inside")
+
+	entries := logs.All()
+	if _, ok := entries[0].ContextMap()["request_id"]; ok {
+		t.Error("request_id logged outside a request")
+	}
+	if got := entries[1].ContextMap()["request_id"]; got != "abc-123" {
+		t.Errorf("request_id = %v, want abc-123", got)
+	}
+}
@@ -1,20 +1,31 @@
 package router
 
 import (
+	"crypto/subtle"
+	"mime"
 	"net/http"
 
+	"Mansoor88-6/time-tracking-agent/internal/apierror"
 	"Mansoor88-6/time-tracking-agent/internal/handler"
+	"Mansoor88-6/time-tracking-agent/internal/requestid"
 
 	"go.uber.org/zap"
 )
 
-func New(timeEntryHandler *handler.TimeEntryHandler, logger *zap.Logger) http.Handler {
+// tokenHeader carries the shared secret (server.token), as on the browser
+// event server
+const tokenHeader = "X-Agent-Token"
+
+// New returns the time entry API. Every endpoint but /health requires token
+// in X-Agent-Token when it is set, and POST bodies must be JSON either way, so
+// a web page can't write entries with a plain cross-site form.
+func New(timeEntryHandler *handler.TimeEntryHandler, token string, logger *zap.Logger) http.Handler {
 	mux := http.NewServeMux()
 
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -35,20 +46,50 @@ func New(timeEntryHandler *handler.TimeEntryHandler, logger *zap.Logger) http.Ha
 				timeEntryHandler.GetTimeEntriesByUser(w, r)
 			}
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 
+	mux.HandleFunc("/api/v1/time-entries/bulk", timeEntryHandler.BulkCreateTimeEntries)
+	mux.HandleFunc("/api/v1/time-entries/summary", timeEntryHandler.GetTimeEntrySummary)
 	mux.HandleFunc("/api/v1/time-entries/update", timeEntryHandler.UpdateTimeEntry)
 	mux.HandleFunc("/api/v1/time-entries/delete", timeEntryHandler.DeleteTimeEntry)
 
-	// Logging middleware
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("HTTP request",
+	// Logging middleware, inside the request ID middleware so the ID is
+	// already in the context
+	return requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestid.Logger(r.Context(), logger).Info("HTTP request",
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
 			zap.String("remote_addr", r.RemoteAddr),
 		)
+		if r.URL.Path != "/health" {
+			if !authorized(r, token) {
+				requestid.Logger(r.Context(), logger).Warn("Rejected request without a valid token", zap.String("path", r.URL.Path))
+				apierror.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if r.Method == http.MethodPost && !isJSON(r) {
+				apierror.Write(w, http.StatusUnsupportedMediaType, apierror.CodeBadRequest, "Content-Type must be application/json")
+				return
+			}
+		}
 		mux.ServeHTTP(w, r)
-	})
+	}))
+}
+
+// authorized reports whether r carries token; an empty token disables the check
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	presented := r.Header.Get(tokenHeader)
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// isJSON reports whether r declares a JSON body. Browsers only send that
+// cross-origin after a CORS preflight, which this API never answers.
+func isJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
 }
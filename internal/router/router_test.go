@@ -0,0 +1,148 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Mansoor88-6/time-tracking-agent/internal/apierror"
+	"Mansoor88-6/time-tracking-agent/internal/database"
+	"Mansoor88-6/time-tracking-agent/internal/handler"
+	"Mansoor88-6/time-tracking-agent/internal/repository"
+	"Mansoor88-6/time-tracking-agent/internal/service"
+
+	"go.uber.org/zap"
+)
+
+const testToken = "test-token"
+
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "agent.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := service.NewTimeEntryService(repository.NewTimeEntryRepository(db.DB), zap.NewNop())
+	return New(handler.NewTimeEntryHandler(s, zap.NewNop()), testToken, zap.NewNop())
+}
+
+// serve sends a JSON request carrying the test token
+func serve(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	return serveWith(h, method, target, body, testToken, "application/json")
+}
+
+func serveWith(h http.Handler, method, target, body, token, contentType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set(tokenHeader, token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+const entry = `{"user_id":"user-1","start_time":"2026-03-02T09:00:00Z","end_time":"2026-03-02T10:00:00Z"}`
+
+func TestErrorPathsWriteEnvelope(t *testing.T) {
+	h := newTestRouter(t)
+	if rec := serve(h, http.MethodPost, "/api/v1/time-entries", entry); rec.Code != http.StatusCreated {
+		t.Fatalf("creating the first entry: %d %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"bad json", http.MethodPost, "/api/v1/time-entries", "{", http.StatusBadRequest, apierror.CodeBadRequest},
+		{"invalid entry", http.MethodPost, "/api/v1/time-entries", `{"start_time":"2026-03-02T09:00:00Z"}`, http.StatusBadRequest, apierror.CodeInvalidTimeEntry},
+		{"overlap", http.MethodPost, "/api/v1/time-entries", entry, http.StatusConflict, apierror.CodeOverlap},
+		{"not found", http.MethodGet, "/api/v1/time-entries?id=999", "", http.StatusNotFound, apierror.CodeNotFound},
+		{"bad id", http.MethodGet, "/api/v1/time-entries?id=x", "", http.StatusBadRequest, apierror.CodeBadRequest},
+		{"method", http.MethodPatch, "/api/v1/time-entries", "", http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed},
+		{"missing user", http.MethodGet, "/api/v1/time-entries", "", http.StatusBadRequest, apierror.CodeBadRequest},
+		{
+			"inverted range", http.MethodGet,
+			"/api/v1/time-entries?user_id=user-1&start=2026-03-03T00:00:00Z&end=2026-03-02T00:00:00Z", "",
+			http.StatusBadRequest, apierror.CodeInvalidRange,
+		},
+		{"bad group", http.MethodGet, "/api/v1/time-entries/summary?user_id=user-1&group_by=week", "", http.StatusBadRequest, apierror.CodeInvalidSummary},
+		{"health method", http.MethodPost, "/health", "", http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h, tt.method, tt.target, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body apierror.Body
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q is not an envelope: %v", rec.Body.String(), err)
+			}
+			if body.Error.Code != tt.wantCode || body.Error.Message == "" {
+				t.Errorf("error = %+v, want code %s with a message", body.Error, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRequiresToken(t *testing.T) {
+	h := newTestRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		token  string
+	}{
+		{"create without token", http.MethodPost, "/api/v1/time-entries", entry, ""},
+		{"create with wrong token", http.MethodPost, "/api/v1/time-entries", entry, "wrong"},
+		{"list without token", http.MethodGet, "/api/v1/time-entries?user_id=user-1", "", ""},
+		{"delete without token", http.MethodDelete, "/api/v1/time-entries/delete?id=1", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveWith(h, tt.method, tt.target, tt.body, tt.token, "application/json")
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+
+	if rec := serve(h, http.MethodGet, "/api/v1/time-entries?user_id=user-1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("list with token: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serveWith(h, http.MethodGet, "/health", "", "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("health without token: %d, want it open", rec.Code)
+	}
+}
+
+func TestRejectsNonJSONPost(t *testing.T) {
+	h := newTestRouter(t)
+
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		rec := serveWith(h, http.MethodPost, "/api/v1/time-entries", entry, testToken, contentType)
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("Content-Type %q: status = %d, want %d", contentType, rec.Code, http.StatusUnsupportedMediaType)
+		}
+	}
+
+	rec := serveWith(h, http.MethodPost, "/api/v1/time-entries", entry, testToken, "application/json; charset=utf-8")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("JSON with charset: %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,95 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// tokenFileName is the encrypted token file in the storage directory
+const tokenFileName = "device_token.enc"
+
+// FileStore keeps the secret AES-GCM encrypted with a key derived from the
+// machine ID, so a copied file (e.g. from a backup) is useless elsewhere
+type FileStore struct {
+	path string
+	key  [32]byte
+}
+
+// NewFileStore creates a file store in dir
+func NewFileStore(dir, machineID string) *FileStore {
+	return &FileStore{
+		path: filepath.Join(dir, tokenFileName),
+		key:  sha256.Sum256([]byte(service + "/" + account + "/" + machineID)),
+	}
+}
+
+func (s *FileStore) Load() (string, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	gcm, err := s.aead()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("token file is corrupt")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt token file (was it copied from another machine?): %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (s *FileStore) Save(secret string) error {
+	gcm, err := s.aead()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	data := gcm.Seal(nonce, nonce, []byte(secret), nil)
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+	// Write then rename so a crash never leaves a half-written file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace token file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Delete() error {
+	err := os.Remove(s.path)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *FileStore) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
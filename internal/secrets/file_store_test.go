@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileStore(dir, "machine-1")
+
+	if _, err := s.Load(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load before Save = %v, want ErrNotFound", err)
+	}
+	if err := s.Save("token-1"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := s.Save("token-2"); err != nil {
+		t.Fatalf("Save over an existing token: %v", err)
+	}
+
+	got, err := NewFileStore(dir, "machine-1").Load()
+	if err != nil || got != "token-2" {
+		t.Fatalf("Load = %q, %v, want token-2", got, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, tokenFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "token-2") {
+		t.Error("token stored in plaintext")
+	}
+
+	if err := s.Delete(); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Load(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load after Delete = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreOtherMachineCannotDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewFileStore(dir, "machine-1").Save("token"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	_, err := NewFileStore(dir, "machine-2").Load()
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load with another machine ID = %v, want a decrypt error", err)
+	}
+}
+
+func TestFileStoreCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, tokenFileName), []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileStore(dir, "machine-1").Load(); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Fatalf("Load = %v, want a corrupt file error", err)
+	}
+}
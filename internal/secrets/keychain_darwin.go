@@ -0,0 +1,72 @@
+//go:build darwin
+// +build darwin
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainStore uses the login keychain through the security tool
+type keychainStore struct{}
+
+func newKeychainStore() Store {
+	return keychainStore{}
+}
+
+// errItemNotFound is security's exit status for a missing item
+const errItemNotFound = 44
+
+func (keychainStore) Load() (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == errItemNotFound {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("security find-generic-password: %w", err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func (keychainStore) Save(secret string) error {
+	if strings.ContainsAny(secret, "\r\n") {
+		return errors.New("secret must not contain line breaks")
+	}
+
+	// add-generic-password only takes the secret as an argument, so run it
+	// through security's interactive mode on stdin rather than putting it on
+	// our command line, where it would appear in the process list. -U updates
+	// the item if it already exists.
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		quote(service), quote(account), quote(secret)))
+	out, err := cmd.CombinedOutput()
+	msg := strings.TrimSpace(strings.ReplaceAll(string(out), "security> ", ""))
+	if err != nil {
+		return fmt.Errorf("security add-generic-password: %w: %s", err, msg)
+	}
+	// Interactive mode can exit 0 when the command fails, and prints nothing
+	// but the prompt when it succeeds
+	if msg != "" {
+		return fmt.Errorf("security add-generic-password: %s", msg)
+	}
+	return nil
+}
+
+// quote quotes s for security's interactive command parser
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (keychainStore) Delete() error {
+	err := exec.Command("security", "delete-generic-password", "-s", service, "-a", account).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == errItemNotFound {
+		return ErrNotFound
+	}
+	return err
+}
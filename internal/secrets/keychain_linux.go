@@ -0,0 +1,50 @@
+//go:build linux
+// +build linux
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainStore uses the Secret Service (GNOME Keyring, KWallet) through
+// libsecret's secret-tool
+type keychainStore struct{}
+
+func newKeychainStore() Store {
+	return keychainStore{}
+}
+
+func (keychainStore) Load() (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+	if err != nil {
+		// secret-tool exits 1 with no output when nothing matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("secret-tool lookup: %w", err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func (keychainStore) Save(secret string) error {
+	// The secret is read from stdin so it never appears in the process list
+	cmd := exec.Command("secret-tool", "store", "--label=Time Tracking Agent device token",
+		"service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool store: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (keychainStore) Delete() error {
+	if err := exec.Command("secret-tool", "clear", "service", service, "account", account).Run(); err != nil {
+		return fmt.Errorf("secret-tool clear: %w", err)
+	}
+	return nil
+}
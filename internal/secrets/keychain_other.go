@@ -0,0 +1,25 @@
+//go:build !windows && !darwin && !linux
+// +build !windows,!darwin,!linux
+
+package secrets
+
+import "fmt"
+
+// keychainStore is unavailable here; the fallback file store is used
+type keychainStore struct{}
+
+func newKeychainStore() Store {
+	return keychainStore{}
+}
+
+func (keychainStore) Load() (string, error) {
+	return "", fmt.Errorf("no OS secret store on this platform")
+}
+
+func (keychainStore) Save(string) error {
+	return fmt.Errorf("no OS secret store on this platform")
+}
+
+func (keychainStore) Delete() error {
+	return fmt.Errorf("no OS secret store on this platform")
+}
@@ -0,0 +1,100 @@
+//go:build windows
+// +build windows
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	advapi32       = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	CRED_TYPE_GENERIC          = 1
+	CRED_PERSIST_LOCAL_MACHINE = 2
+)
+
+// credential mirrors CREDENTIALW
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// keychainStore uses Windows Credential Manager
+type keychainStore struct{}
+
+func newKeychainStore() Store {
+	return keychainStore{}
+}
+
+func target() *uint16 {
+	name, _ := windows.UTF16PtrFromString(service + "/" + account)
+	return name
+}
+
+func (keychainStore) Load() (string, error) {
+	var cred *credential
+	ret, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target())), CRED_TYPE_GENERIC, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		if errors.Is(err, windows.ERROR_NOT_FOUND) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("CredReadW: %w", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return string(blob), nil
+}
+
+func (keychainStore) Save(secret string) error {
+	if secret == "" {
+		return fmt.Errorf("refusing to store an empty secret")
+	}
+	blob := []byte(secret)
+	userName, _ := windows.UTF16PtrFromString(account)
+	cred := credential{
+		Type:               CRED_TYPE_GENERIC,
+		TargetName:         target(),
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            CRED_PERSIST_LOCAL_MACHINE,
+		UserName:           userName,
+	}
+	ret, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return fmt.Errorf("CredWriteW: %w", err)
+	}
+	return nil
+}
+
+func (keychainStore) Delete() error {
+	ret, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target())), CRED_TYPE_GENERIC, 0)
+	if ret == 0 {
+		if errors.Is(err, windows.ERROR_NOT_FOUND) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("CredDeleteW: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Package secrets stores the device token outside the plaintext config, in
+// the OS secret store or an encrypted file
+package secrets
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// service and account identify the agent's entry in the OS secret store
+const (
+	service = "time-tracking-agent"
+	account = "device_token"
+)
+
+// ErrNotFound is returned by Load when no token is stored
+var ErrNotFound = errors.New("secret not found")
+
+// Store persists a single secret
+type Store interface {
+	Load() (string, error)
+	Save(secret string) error
+	Delete() error
+}
+
+// Storage backends selectable in config
+const (
+	BackendKeychain = "keychain" // OS secret store, falling back to an encrypted file
+	BackendFile     = "file"     // Encrypted file only
+)
+
+// NewStore returns the store for backend. storageDir holds the encrypted
+// file and machineID keys its encryption.
+func NewStore(backend, storageDir, machineID string, logger *zap.Logger) (Store, error) {
+	file := NewFileStore(storageDir, machineID)
+	switch backend {
+	case BackendFile:
+		return file, nil
+	case BackendKeychain:
+		return &fallbackStore{primary: newKeychainStore(), secondary: file, logger: logger}, nil
+	default:
+		return nil, fmt.Errorf("unknown token storage %q (must be config, keychain or file)", backend)
+	}
+}
+
+// fallbackStore uses primary and falls back to secondary when primary is
+// unavailable (e.g. no Secret Service on a headless Linux box)
+type fallbackStore struct {
+	primary   Store
+	secondary Store
+	logger    *zap.Logger
+}
+
+func (s *fallbackStore) Load() (string, error) {
+	secret, err := s.primary.Load()
+	if err == nil {
+		return secret, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		s.logger.Warn("OS secret store unavailable, using encrypted file", zap.Error(err))
+	}
+	return s.secondary.Load()
+}
+
+func (s *fallbackStore) Save(secret string) error {
+	if err := s.primary.Save(secret); err != nil {
+		s.logger.Warn("Failed to save to OS secret store, using encrypted file", zap.Error(err))
+		return s.secondary.Save(secret)
+	}
+	// Don't leave a stale copy behind in the fallback
+	if err := s.secondary.Delete(); err != nil && !errors.Is(err, ErrNotFound) {
+		s.logger.Debug("Failed to remove encrypted token file", zap.Error(err))
+	}
+	return nil
+}
+
+func (s *fallbackStore) Delete() error {
+	errPrimary := s.primary.Delete()
+	errSecondary := s.secondary.Delete()
+	if errPrimary != nil && !errors.Is(errPrimary, ErrNotFound) {
+		return errPrimary
+	}
+	if errSecondary != nil && !errors.Is(errSecondary, ErrNotFound) {
+		return errSecondary
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// memoryStore is an in-memory Store, optionally failing every call
+type memoryStore struct {
+	secret string
+	set    bool
+	err    error
+}
+
+func (s *memoryStore) Load() (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	if !s.set {
+		return "", ErrNotFound
+	}
+	return s.secret, nil
+}
+
+func (s *memoryStore) Save(secret string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.secret, s.set = secret, true
+	return nil
+}
+
+func (s *memoryStore) Delete() error {
+	if s.err != nil {
+		return s.err
+	}
+	if !s.set {
+		return ErrNotFound
+	}
+	s.secret, s.set = "", false
+	return nil
+}
+
+func TestFallbackStoreUsesPrimary(t *testing.T) {
+	primary := &memoryStore{}
+	secondary := &memoryStore{secret: "stale", set: true}
+	s := &fallbackStore{primary: primary, secondary: secondary, logger: zap.NewNop()}
+
+	if err := s.Save("token"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got, err := s.Load(); err != nil || got != "token" {
+		t.Fatalf("Load = %q, %v, want token", got, err)
+	}
+	if !primary.set || primary.secret != "token" {
+		t.Error("token not saved to the primary store")
+	}
+	if secondary.set {
+		t.Error("stale copy left in the secondary store")
+	}
+
+	if err := s.Delete(); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Load(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFallbackStoreFallsBackToEncryptedFile(t *testing.T) {
+	dir := t.TempDir()
+	unavailable := &memoryStore{err: errors.New("no secret service")}
+	s := &fallbackStore{primary: unavailable, secondary: NewFileStore(dir, "machine-1"), logger: zap.NewNop()}
+
+	if err := s.Save("token"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got, err := s.Load(); err != nil || got != "token" {
+		t.Fatalf("Load = %q, %v, want token", got, err)
+	}
+	// The file alone holds the token
+	if got, err := NewFileStore(dir, "machine-1").Load(); err != nil || got != "token" {
+		t.Fatalf("encrypted file holds %q, %v, want token", got, err)
+	}
+
+	if err := s.Delete(); err == nil {
+		t.Fatal("Delete hid the primary store's error")
+	}
+}
+
+func TestFallbackStoreLoadsFileWhenPrimaryEmpty(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewFileStore(dir, "machine-1").Save("token"); err != nil {
+		t.Fatal(err)
+	}
+	s := &fallbackStore{primary: &memoryStore{}, secondary: NewFileStore(dir, "machine-1"), logger: zap.NewNop()}
+
+	if got, err := s.Load(); err != nil || got != "token" {
+		t.Fatalf("Load = %q, %v, want the file's token", got, err)
+	}
+}
+
+func TestNewStoreRejectsUnknownBackend(t *testing.T) {
+	if _, err := NewStore("vault", t.TempDir(), "machine-1", zap.NewNop()); err == nil {
+		t.Fatal("unknown backend accepted")
+	}
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/apierror"
+	"Mansoor88-6/time-tracking-agent/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// adminPath is the prefix of the runtime control endpoints. They change what
+// the agent tracks, so they always need the server token: with no token
+// configured they are refused rather than left open to local pages.
+const adminPath = "/api/v1/admin"
+
+// urlServerPath reports (GET) or sets (POST) whether browser URL events from
+// the extension are accepted
+const urlServerPath = adminPath + "/url-server"
+
+// reloadPath re-reads the config file (POST), like SIGHUP does, for
+// platforms without signals
+const reloadPath = adminPath + "/reload"
+
+// thresholdsPath sets (POST) the idle and away thresholds, which otherwise
+// only change on a config reload
+const thresholdsPath = adminPath + "/thresholds"
+
+// logLevelPath reports (GET) or sets (POST) the agent's log level. It sits
+// outside adminPath but is guarded the same way.
+const logLevelPath = "/api/v1/loglevel"
+
+// urlServerRequest is the body of POST /api/v1/admin/url-server
+type urlServerRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// thresholdsRequest is the body of POST /api/v1/admin/thresholds. Values
+// are in seconds, like the config; an omitted field is left unchanged.
+type thresholdsRequest struct {
+	IdleThreshold *int `json:"idle_threshold,omitempty"`
+	AwayThreshold *int `json:"away_threshold,omitempty"`
+}
+
+// logLevelRequest is the body of POST /api/v1/loglevel and the response to
+// both methods
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// isAdminPath reports whether path is a runtime control endpoint
+func isAdminPath(path string) bool {
+	return strings.HasPrefix(path, adminPath+"/") || path == logLevelPath
+}
+
+// adminAuthorized reports whether r carries the configured token. Unlike
+// authorized it fails when no token is set.
+func (s *BrowserEventServer) adminAuthorized(r *http.Request) bool {
+	s.mu.RLock()
+	token := s.token
+	s.mu.RUnlock()
+	if token == "" {
+		return false
+	}
+	presented := r.Header.Get(tokenHeader)
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// SetEventsEnabled sets whether browser events are accepted. While disabled
+// the server keeps running, so health, status and this setting stay
+// reachable, but browser events and streams get 503 and browsers are
+// tracked as regular applications. Safe to call while running.
+func (s *BrowserEventServer) SetEventsEnabled(enabled bool) {
+	s.mu.Lock()
+	s.eventsDisabled = !enabled
+	s.mu.Unlock()
+
+	s.sessionManager.SetBrowserEventsEnabled(enabled)
+	if !enabled {
+		s.closeStreams()
+	}
+}
+
+// SetReloadHandler enables POST /api/v1/admin/reload, which calls fn and
+// reports its error. Must be called before Start.
+func (s *BrowserEventServer) SetReloadHandler(fn func() error) {
+	s.onReload = fn
+}
+
+// SetThresholdsHandler enables POST /api/v1/admin/thresholds, which calls
+// fn with the new idle and away thresholds; a zero threshold is unchanged.
+// An error from fn, e.g. an idle threshold past the current away threshold,
+// is reported as 400. A later config reload sets them back to the configured
+// values. Must be called before Start.
+func (s *BrowserEventServer) SetThresholdsHandler(fn func(idle, away time.Duration) error) {
+	s.onThresholds = fn
+}
+
+// EventsEnabled reports whether browser events are accepted
+func (s *BrowserEventServer) EventsEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.eventsDisabled
+}
+
+// handleAdmin checks the token and routes the runtime control endpoints
+func (s *BrowserEventServer) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	if !s.adminAuthorized(r) {
+		s.log(r).Warn("Rejected admin request without a valid token", zap.String("path", r.URL.Path))
+		apierror.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case urlServerPath:
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]bool{"enabled": s.EventsEnabled()})
+		case http.MethodPost:
+			s.handleURLServer(w, r)
+		default:
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case reloadPath:
+		if s.onReload == nil {
+			apierror.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleReload(w, r)
+	case logLevelPath:
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, logLevelRequest{Level: logger.Level()})
+		case http.MethodPost:
+			s.handleLogLevel(w, r)
+		default:
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case thresholdsPath:
+		if s.onThresholds == nil {
+			apierror.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleThresholds(w, r)
+	default:
+		apierror.NotFound(w, r)
+	}
+}
+
+// handleURLServer turns acceptance of browser events on or off
+func (s *BrowserEventServer) handleURLServer(w http.ResponseWriter, r *http.Request) {
+	var req urlServerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Enabled == nil {
+		apierror.Error(w, "Missing enabled field", http.StatusBadRequest)
+		return
+	}
+
+	if *req.Enabled != s.EventsEnabled() {
+		s.SetEventsEnabled(*req.Enabled)
+		s.log(r).Info("Browser events toggled through the admin API", zap.Bool("enabled", *req.Enabled))
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": *req.Enabled})
+}
+
+// handleReload reloads the configuration through the reload handler
+func (s *BrowserEventServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	s.log(r).Info("Reloading configuration through the admin API")
+	if err := s.onReload(); err != nil {
+		apierror.Error(w, "Failed to reload configuration: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"reloaded": true})
+}
+
+// handleThresholds changes the idle and away thresholds through the
+// thresholds handler
+func (s *BrowserEventServer) handleThresholds(w http.ResponseWriter, r *http.Request) {
+	var req thresholdsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.IdleThreshold == nil && req.AwayThreshold == nil {
+		apierror.Error(w, "Missing idle_threshold or away_threshold field", http.StatusBadRequest)
+		return
+	}
+	if (req.IdleThreshold != nil && *req.IdleThreshold <= 0) || (req.AwayThreshold != nil && *req.AwayThreshold <= 0) {
+		apierror.Error(w, "Thresholds must be positive", http.StatusBadRequest)
+		return
+	}
+	if req.IdleThreshold != nil && req.AwayThreshold != nil && *req.AwayThreshold <= *req.IdleThreshold {
+		apierror.Error(w, "away_threshold must be greater than idle_threshold", http.StatusBadRequest)
+		return
+	}
+
+	var idle, away time.Duration
+	if req.IdleThreshold != nil {
+		idle = time.Duration(*req.IdleThreshold) * time.Second
+	}
+	if req.AwayThreshold != nil {
+		away = time.Duration(*req.AwayThreshold) * time.Second
+	}
+	if err := s.onThresholds(idle, away); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.log(r).Info("Activity thresholds changed through the admin API",
+		zap.Duration("idle_threshold", idle),
+		zap.Duration("away_threshold", away),
+	)
+	writeJSON(w, http.StatusOK, req)
+}
+
+// handleLogLevel changes the log level of every logger. A later config
+// reload sets it back to log.level.
+func (s *BrowserEventServer) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Level == "" {
+		apierror.Error(w, "Missing level field", http.StatusBadRequest)
+		return
+	}
+	if err := logger.SetLevel(req.Level); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.log(r).Info("Log level changed through the admin API", zap.String("level", req.Level))
+	writeJSON(w, http.StatusOK, logLevelRequest{Level: logger.Level()})
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/logger"
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+	"Mansoor88-6/time-tracking-agent/internal/tracker"
+
+	"go.uber.org/zap"
+)
+
+func TestURLServerToggle(t *testing.T) {
+	s, sessions := newTestServer(t)
+
+	// Enabled by default: browser events are accepted
+	if rec := serve(s, http.MethodPost, "/api/v1/browser-event", testToken, browserEvent("https://example.com/a")); rec.Code != http.StatusOK {
+		t.Fatalf("browser event while enabled: %d %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(s, http.MethodPost, urlServerPath, testToken, map[string]bool{"enabled": false})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("disable: %d %s", rec.Code, rec.Body)
+	}
+	var state map[string]bool
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil || state["enabled"] {
+		t.Fatalf("disable response = %s", rec.Body)
+	}
+	if s.EventsEnabled() {
+		t.Fatal("EventsEnabled after disabling")
+	}
+
+	before := sessions.GetCurrentSession()
+	rec = serve(s, http.MethodPost, "/api/v1/browser-event", testToken, browserEvent("https://example.com/b"))
+	if rec.Code != http.StatusServiceUnavailable || errorCode(t, rec) != "unavailable" {
+		t.Fatalf("browser event while disabled: %d %s", rec.Code, rec.Body)
+	}
+	if after := sessions.GetCurrentSession(); after == nil || after.URL != before.URL {
+		t.Fatalf("session changed by a rejected event: %+v", after)
+	}
+	if rec := serve(s, http.MethodGet, streamPath, testToken, nil); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("stream while disabled: %d", rec.Code)
+	}
+
+	// Health and the admin endpoint itself stay reachable
+	if rec := serve(s, http.MethodGet, "/api/v1/health", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("health while disabled: %d", rec.Code)
+	}
+	if rec := serve(s, http.MethodGet, urlServerPath, testToken, nil); rec.Code != http.StatusOK || rec.Body.String() != "{\"enabled\":false}\n" {
+		t.Fatalf("GET url-server: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(s, http.MethodPost, urlServerPath, testToken, map[string]bool{"enabled": true}); rec.Code != http.StatusOK {
+		t.Fatalf("enable: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodPost, "/api/v1/browser-event", testToken, browserEvent("https://example.com/c")); rec.Code != http.StatusOK {
+		t.Fatalf("browser event after re-enabling: %d %s", rec.Code, rec.Body)
+	}
+	if session := sessions.GetCurrentSession(); session == nil || session.URL != "https://example.com/c" {
+		t.Fatalf("session after re-enabling = %+v", session)
+	}
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	s, _ := newTestServer(t)
+	enable := map[string]bool{"enabled": false}
+
+	if rec := serve(s, http.MethodPost, urlServerPath, "", enable); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without token: %d", rec.Code)
+	}
+	if rec := serve(s, http.MethodPost, urlServerPath, "wrong", enable); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: %d", rec.Code)
+	}
+
+	// With no token configured the extension endpoints are open, but admin
+	// endpoints are refused
+	s.SetToken("")
+	if rec := serve(s, http.MethodPost, urlServerPath, "", enable); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("no token configured: %d", rec.Code)
+	}
+	if !s.EventsEnabled() {
+		t.Fatal("rejected requests changed the setting")
+	}
+}
+
+func TestURLServerRejectsBadBody(t *testing.T) {
+	s, _ := newTestServer(t)
+	if rec := serve(s, http.MethodPost, urlServerPath, testToken, map[string]string{}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing enabled: %d", rec.Code)
+	}
+	if rec := serve(s, http.MethodPut, urlServerPath, testToken, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("PUT: %d", rec.Code)
+	}
+	if rec := serve(s, http.MethodGet, adminPath+"/unknown", testToken, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown admin path: %d", rec.Code)
+	}
+}
+
+func TestReloadEndpoint(t *testing.T) {
+	s, _ := newTestServer(t)
+	if rec := serve(s, http.MethodPost, reloadPath, testToken, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("reload without a handler: %d", rec.Code)
+	}
+
+	reloads := 0
+	var reloadErr error
+	s.SetReloadHandler(func() error {
+		reloads++
+		return reloadErr
+	})
+
+	if rec := serve(s, http.MethodPost, reloadPath, "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("reload without token: %d", rec.Code)
+	}
+	if rec := serve(s, http.MethodGet, reloadPath, testToken, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET reload: %d", rec.Code)
+	}
+	if reloads != 0 {
+		t.Fatalf("rejected requests reloaded %d times", reloads)
+	}
+
+	if rec := serve(s, http.MethodPost, reloadPath, testToken, nil); rec.Code != http.StatusOK {
+		t.Fatalf("reload: %d %s", rec.Code, rec.Body)
+	}
+	if reloads != 1 {
+		t.Fatalf("reloaded %d times, want 1", reloads)
+	}
+
+	reloadErr = errors.New("yaml: line 3: bad indentation")
+	rec := serve(s, http.MethodPost, reloadPath, testToken, nil)
+	if rec.Code != http.StatusInternalServerError || errorCode(t, rec) != "internal_error" {
+		t.Fatalf("failed reload: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestThresholdsEndpoint(t *testing.T) {
+	s, _ := newTestServer(t)
+	if rec := serve(s, http.MethodPost, thresholdsPath, testToken, map[string]int{"idle_threshold": 60}); rec.Code != http.StatusNotFound {
+		t.Fatalf("thresholds without a handler: %d", rec.Code)
+	}
+
+	// Checked against the current thresholds, as the activity tracker does
+	at := tracker.NewActivityTracker(platform.NewFakePlatform(), time.Minute, 5*time.Minute, zap.NewNop())
+	var calls [][2]time.Duration
+	s.SetThresholdsHandler(func(idle, away time.Duration) error {
+		if err := at.SetThresholds(idle, away); err != nil {
+			return err
+		}
+		calls = append(calls, [2]time.Duration{idle, away})
+		return nil
+	})
+
+	for _, tt := range []struct {
+		name  string
+		token string
+		body  interface{}
+		want  int
+	}{
+		{"without token", "", map[string]int{"idle_threshold": 60}, http.StatusUnauthorized},
+		{"empty body", testToken, map[string]int{}, http.StatusBadRequest},
+		{"not positive", testToken, map[string]int{"idle_threshold": 0}, http.StatusBadRequest},
+		{"away not after idle", testToken, map[string]int{"idle_threshold": 600, "away_threshold": 300}, http.StatusBadRequest},
+		{"idle past current away", testToken, map[string]int{"idle_threshold": 600}, http.StatusBadRequest},
+		{"away before current idle", testToken, map[string]int{"away_threshold": 30}, http.StatusBadRequest},
+		{"not JSON", testToken, "sixty", http.StatusBadRequest},
+	} {
+		if rec := serve(s, http.MethodPost, thresholdsPath, tt.token, tt.body); rec.Code != tt.want {
+			t.Errorf("%s: %d %s, want %d", tt.name, rec.Code, rec.Body, tt.want)
+		}
+	}
+	if rec := serve(s, http.MethodGet, thresholdsPath, testToken, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET thresholds: %d", rec.Code)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("rejected requests changed thresholds: %v", calls)
+	}
+
+	rec := serve(s, http.MethodPost, thresholdsPath, testToken, map[string]int{"idle_threshold": 60})
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"idle_threshold\":60}\n" {
+		t.Fatalf("idle only: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodPost, thresholdsPath, testToken, map[string]int{"idle_threshold": 120, "away_threshold": 600}); rec.Code != http.StatusOK {
+		t.Fatalf("both: %d %s", rec.Code, rec.Body)
+	}
+	want := [][2]time.Duration{{time.Minute, 0}, {2 * time.Minute, 10 * time.Minute}}
+	if len(calls) != len(want) || calls[0] != want[0] || calls[1] != want[1] {
+		t.Fatalf("handler calls = %v, want %v", calls, want)
+	}
+}
+
+func TestLogLevelEndpoint(t *testing.T) {
+	s, _ := newTestServer(t)
+	previous := logger.Level()
+	t.Cleanup(func() { logger.SetLevel(previous) })
+
+	if rec := serve(s, http.MethodPost, logLevelPath, "", map[string]string{"level": "debug"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without token: %d", rec.Code)
+	}
+	for _, body := range []interface{}{map[string]string{}, map[string]string{"level": "verbose"}, "debug"} {
+		if rec := serve(s, http.MethodPost, logLevelPath, testToken, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("body %v: %d %s, want 400", body, rec.Code, rec.Body)
+		}
+	}
+	if logger.Level() != previous {
+		t.Fatalf("rejected requests changed the level to %s", logger.Level())
+	}
+
+	if rec := serve(s, http.MethodPost, logLevelPath, testToken, map[string]string{"level": "debug"}); rec.Code != http.StatusOK || rec.Body.String() != "{\"level\":\"debug\"}\n" {
+		t.Fatalf("set debug: %d %s", rec.Code, rec.Body)
+	}
+	if logger.Level() != "debug" {
+		t.Fatalf("level = %s, want debug", logger.Level())
+	}
+	if rec := serve(s, http.MethodGet, logLevelPath, testToken, nil); rec.Code != http.StatusOK || rec.Body.String() != "{\"level\":\"debug\"}\n" {
+		t.Fatalf("GET loglevel: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodPut, logLevelPath, testToken, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("PUT loglevel: %d", rec.Code)
+	}
+}
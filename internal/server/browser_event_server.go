@@ -1,21 +1,49 @@
 package server
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"Mansoor88-6/time-tracking-agent/internal/apierror"
+	"Mansoor88-6/time-tracking-agent/internal/logger"
 	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/requestid"
 	"Mansoor88-6/time-tracking-agent/internal/service"
 
+	"github.com/coder/websocket"
 	"go.uber.org/zap"
 )
 
+// tokenHeader carries the shared secret the extension must send when a
+// server token is configured
+const tokenHeader = "X-Agent-Token"
+
 // BrowserEventServer handles HTTP requests from the browser extension
 type BrowserEventServer struct {
 	sessionManager *service.SessionManager
+	onPresence     func(present bool)                   // nil unless the presence endpoint is enabled
+	status         func() map[string]interface{}        // nil unless the status endpoint is enabled
+	ready          func(ctx context.Context) error      // nil reports ready whenever the server is up
+	timer          *service.ManualTimer                 // nil unless the timer endpoints are enabled
+	onReload       func() error                         // nil unless the reload endpoint is enabled
+	onThresholds   func(idle, away time.Duration) error // nil unless the thresholds endpoint is enabled
 	logger         *zap.Logger
+
+	mu             sync.RWMutex
+	urlGranularity URLGranularity
+	urlNormalizer  urlNormalizer
+	token          string // empty disables the token check
+	limiter        *rateLimiter
+	eventsDisabled bool // browser events turned off through the admin API
+
+	streamsMu sync.Mutex
+	streams   map[*websocket.Conn]struct{}
 }
 
 // NewBrowserEventServer creates a new browser event server
@@ -23,11 +51,102 @@ func NewBrowserEventServer(sessionManager *service.SessionManager, logger *zap.L
 	return &BrowserEventServer{
 		sessionManager: sessionManager,
 		logger:         logger,
+		urlGranularity: URLGranularityFull,
+		urlNormalizer:  newURLNormalizer(DefaultStripParams, false),
+		limiter:        newRateLimiter(0, 0),
+		streams:        make(map[*websocket.Conn]struct{}),
 	}
 }
 
-// ServeHTTP implements http.Handler
+// SetRateLimit limits POST requests to rate per second with bursts of up to
+// burst requests; rate <= 0 disables limiting. Safe to call while running.
+func (s *BrowserEventServer) SetRateLimit(rate float64, burst int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limiter = newRateLimiter(rate, burst)
+}
+
+// SetURLGranularity sets how much of each extension URL is kept. Safe to
+// call while the server is running.
+func (s *BrowserEventServer) SetURLGranularity(granularity URLGranularity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.urlGranularity = granularity
+}
+
+// SetURLNormalization sets the query parameters (case-insensitive globs)
+// removed from extension URLs and whether the #fragment is dropped. Nil
+// stripParams keeps every parameter. Safe to call while running.
+func (s *BrowserEventServer) SetURLNormalization(stripParams []string, dropFragment bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.urlNormalizer = newURLNormalizer(stripParams, dropFragment)
+}
+
+// SetPresenceHandler enables POST /api/v1/presence, passing each reported
+// presence state to fn
+func (s *BrowserEventServer) SetPresenceHandler(fn func(present bool)) {
+	s.onPresence = fn
+}
+
+// SetStatusProvider enables GET /api/v1/status, which serves the map
+// returned by fn as JSON
+func (s *BrowserEventServer) SetStatusProvider(fn func() map[string]interface{}) {
+	s.status = fn
+}
+
+// SetReadinessCheck sets the check behind GET /api/v1/health/ready, which
+// returns 503 with the error while fn fails
+func (s *BrowserEventServer) SetReadinessCheck(fn func(ctx context.Context) error) {
+	s.ready = fn
+}
+
+// SetToken sets the shared secret required on POST requests; empty disables
+// the check. Safe to call while the server is running.
+func (s *BrowserEventServer) SetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// authorized reports whether r carries the configured token. Browsers cannot
+// set headers on WebSocket handshakes, so the stream may pass it as ?token=.
+func (s *BrowserEventServer) authorized(r *http.Request) bool {
+	s.mu.RLock()
+	token := s.token
+	s.mu.RUnlock()
+	if token == "" {
+		return true
+	}
+
+	presented := r.Header.Get(tokenHeader)
+	if presented == "" && r.URL.Path == streamPath {
+		presented = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// allow applies the rate limit
+func (s *BrowserEventServer) allow() bool {
+	s.mu.RLock()
+	limiter := s.limiter
+	s.mu.RUnlock()
+	return limiter.allow()
+}
+
+// ServeHTTP implements http.Handler. Every request gets an X-Request-ID,
+// taken from the caller or generated, before routing.
 func (s *BrowserEventServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestid.Middleware(http.HandlerFunc(s.route)).ServeHTTP(w, r)
+}
+
+// log returns the server's logger tagged with the request's ID
+func (s *BrowserEventServer) log(r *http.Request) *zap.Logger {
+	return requestid.Logger(r.Context(), s.logger)
+}
+
+// route handles CORS, rate limiting and auth, then dispatches by path
+func (s *BrowserEventServer) route(w http.ResponseWriter, r *http.Request) {
 	// Enable CORS for extension
 	s.setCORSHeaders(w)
 
@@ -37,22 +156,78 @@ func (s *BrowserEventServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Method != http.MethodGet {
+		if !s.allow() {
+			s.log(r).Debug("Rate limited extension request", zap.String("path", r.URL.Path))
+			apierror.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	// Admin endpoints do their own, stricter token check
+	if isAdminPath(r.URL.Path) {
+		s.handleAdmin(w, r)
+		return
+	}
+
+	// Only the health checks are open; everything that feeds tracking needs the token
+	if (r.Method != http.MethodGet || r.URL.Path == streamPath || r.URL.Path == "/api/v1/status" || r.URL.Path == timerPath) && !s.authorized(r) {
+		s.log(r).Warn("Rejected request without a valid token", zap.String("path", r.URL.Path))
+		apierror.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if (r.URL.Path == "/api/v1/browser-event" || r.URL.Path == streamPath) && !s.EventsEnabled() {
+		apierror.Write(w, http.StatusServiceUnavailable, apierror.CodeUnavailable, "Browser events are disabled")
+		return
+	}
+
 	// Route requests
 	switch r.URL.Path {
 	case "/api/v1/browser-event":
 		if r.Method == http.MethodPost {
 			s.handleBrowserEvent(w, r)
 		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case streamPath:
+		if r.Method == http.MethodGet {
+			s.handleBrowserStream(w, r)
+		} else {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case "/api/v1/presence":
+		if s.onPresence == nil {
+			apierror.NotFound(w, r)
+		} else if r.Method == http.MethodPost {
+			s.handlePresence(w, r)
+		} else {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case "/api/v1/status":
+		if s.status == nil {
+			apierror.NotFound(w, r)
+		} else if r.Method == http.MethodGet {
+			s.handleStatus(w, r)
+		} else {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	case "/api/v1/health":
+	case timerPath, timerPath + "/start", timerPath + "/stop":
+		s.handleTimer(w, r)
+	case "/api/v1/health", "/api/v1/health/live":
 		if r.Method == http.MethodGet {
 			s.handleHealth(w, r)
 		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case "/api/v1/health/ready":
+		if r.Method == http.MethodGet {
+			s.handleReady(w, r)
+		} else {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	default:
-		http.NotFound(w, r)
+		apierror.NotFound(w, r)
 	}
 }
 
@@ -60,7 +235,8 @@ func (s *BrowserEventServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (s *BrowserEventServer) setCORSHeaders(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*") // Extension origin
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+tokenHeader+", "+requestid.Header)
+	w.Header().Set("Access-Control-Expose-Headers", requestid.Header)
 	w.Header().Set("Access-Control-Max-Age", "3600")
 }
 
@@ -70,64 +246,105 @@ func (s *BrowserEventServer) handleBrowserEvent(w http.ResponseWriter, r *http.R
 
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&event); err != nil {
-		s.logger.Warn("Failed to decode browser event request", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		s.log(r).Warn("Failed to decode browser event request", zap.Error(err))
+		apierror.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	if err := s.acceptBrowserEvent(s.log(r), &event); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Return success
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "ok",
+	})
+}
+
+// acceptBrowserEvent validates a browser event and passes it to the session
+// manager. The returned error is safe to send back to the extension.
+func (s *BrowserEventServer) acceptBrowserEvent(log *zap.Logger, event *models.BrowserEvent) error {
 	// Validate request
 	if event.Source != "browser" {
-		http.Error(w, "Invalid source, must be 'browser'", http.StatusBadRequest)
-		return
+		return errors.New("Invalid source, must be 'browser'")
 	}
 
 	if event.Browser == "" {
-		http.Error(w, "Missing browser field", http.StatusBadRequest)
-		return
+		return errors.New("Missing browser field")
 	}
 
 	if event.URL == "" {
-		http.Error(w, "Missing URL field", http.StatusBadRequest)
-		return
+		return errors.New("Missing URL field")
 	}
 
 	// Validate URL format
 	if !strings.HasPrefix(event.URL, "http://") && !strings.HasPrefix(event.URL, "https://") {
-		s.logger.Warn("Rejected invalid URL format",
-			zap.String("url", event.URL),
+		log.Warn("Rejected invalid URL format",
+			logger.URL("url", event.URL),
 		)
-		http.Error(w, "Invalid URL format", http.StatusBadRequest)
-		return
+		return errors.New("Invalid URL format")
 	}
 
 	// Validate sequence number
 	if event.Sequence < 0 {
-		http.Error(w, "Invalid sequence number", http.StatusBadRequest)
-		return
+		return errors.New("Invalid sequence number")
 	}
 
 	// Validate that browser is a known type (security check)
 	if !s.isValidBrowser(event.Browser) {
-		s.logger.Warn("Rejected browser event from unknown browser",
+		log.Warn("Rejected browser event from unknown browser",
 			zap.String("browser", event.Browser),
 		)
-		http.Error(w, "Invalid browser type", http.StatusBadRequest)
-		return
+		return errors.New("Invalid browser type")
+	}
+
+	// Strip tracking parameters and path/query before the URL is logged or
+	// tracked
+	s.mu.RLock()
+	granularity := s.urlGranularity
+	normalizer := s.urlNormalizer
+	s.mu.RUnlock()
+	event.URL = reduceURL(normalizer.normalize(event.URL), granularity)
+	if event.URL == "" {
+		return errors.New("Invalid URL format")
 	}
 
-	s.logger.Info("Browser event received",
+	log.Info("Browser event received",
 		zap.String("browser", event.Browser),
-		zap.String("url", event.URL),
-		zap.String("title", event.Title),
+		logger.URL("url", event.URL),
+		logger.Title("title", event.Title),
 		zap.Int("tabId", event.TabID),
 		zap.Int("windowId", event.WindowID),
 		zap.Int("sequence", event.Sequence),
 	)
 
 	// Process event through session manager
-	s.sessionManager.ProcessBrowserEvent(&event)
+	s.sessionManager.ProcessBrowserEvent(event)
+	return nil
+}
+
+// handlePresence accepts a human-presence signal (e.g. from an OEM presence
+// sensor service)
+func (s *BrowserEventServer) handlePresence(w http.ResponseWriter, r *http.Request) {
+	var event models.PresenceEvent
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&event); err != nil {
+		s.log(r).Warn("Failed to decode presence request", zap.Error(err))
+		apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if event.Present == nil {
+		apierror.Error(w, "Missing present field", http.StatusBadRequest)
+		return
+	}
+
+	s.log(r).Debug("Presence signal received", zap.Bool("present", *event.Present))
+	s.onPresence(*event.Present)
 
-	// Return success
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -135,7 +352,8 @@ func (s *BrowserEventServer) handleBrowserEvent(w http.ResponseWriter, r *http.R
 	})
 }
 
-// handleHealth provides a health check endpoint
+// handleHealth provides a liveness check: it only reports that the process
+// is up and serving
 func (s *BrowserEventServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -145,6 +363,39 @@ func (s *BrowserEventServer) handleHealth(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// handleReady reports whether the agent is healthy enough to track and sync,
+// with 503 and an error envelope when it is not
+func (s *BrowserEventServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	var err error
+	if s.ready != nil {
+		err = s.ready(r.Context())
+	}
+
+	if err != nil {
+		s.log(r).Debug("Readiness check failed", zap.Error(err))
+		code := apierror.CodeUnavailable
+		if _, backendCode, ok := apierror.Classify(err); ok {
+			code = backendCode
+		}
+		apierror.Write(w, http.StatusServiceUnavailable, code, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "ok",
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// handleStatus reports the agent's tracking state, backlog and last sync
+func (s *BrowserEventServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.status())
+}
+
 // isValidBrowser checks if the browser is a known browser type
 func (s *BrowserEventServer) isValidBrowser(browser string) bool {
 	browserLower := strings.ToLower(browser)
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/apierror"
+	"Mansoor88-6/time-tracking-agent/internal/service"
+
+	"github.com/coder/websocket"
+	"go.uber.org/zap"
+)
+
+const testToken = "test-token"
+
+func newTestServer(t *testing.T) (*BrowserEventServer, *service.SessionManager) {
+	t.Helper()
+	sessions := service.NewSessionManager(func(*service.ActiveSession) {}, zap.NewNop(), 0)
+	t.Cleanup(sessions.Stop)
+	s := NewBrowserEventServer(sessions, zap.NewNop())
+	s.SetToken(testToken)
+	return s, sessions
+}
+
+// serve sends a request to s with the given token (none if empty) and JSON
+// body (none if nil)
+func serve(s *BrowserEventServer, method, target, token string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if token != "" {
+		req.Header.Set(tokenHeader, token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+// browserEvent returns a valid extension event for url
+func browserEvent(url string) map[string]interface{} {
+	return map[string]interface{}{
+		"source":    "browser",
+		"browser":   "chrome",
+		"url":       url,
+		"title":     "Example",
+		"tabId":     1,
+		"windowId":  1,
+		"timestamp": 1700000000000,
+		"sequence":  1,
+	}
+}
+
+// errorCode decodes the error envelope of rec
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body apierror.Body
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not an error envelope: %q", rec.Body.String())
+	}
+	return body.Error.Code
+}
+
+func TestAuthorized(t *testing.T) {
+	s, _ := newTestServer(t)
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   bool
+	}{
+		{"header", "/api/v1/browser-event", testToken, true},
+		{"wrong header", "/api/v1/browser-event", "wrong", false},
+		{"missing", "/api/v1/browser-event", "", false},
+		{"token prefix", "/api/v1/browser-event", testToken[:4], false},
+		{"stream query", streamPath + "?token=" + testToken, "", true},
+		{"stream wrong query", streamPath + "?token=wrong", "", false},
+		{"stream header wins over query", streamPath + "?token=" + testToken, "wrong", false},
+		{"query only on the stream", "/api/v1/browser-event?token=" + testToken, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set(tokenHeader, tt.header)
+			}
+			if got := s.authorized(r); got != tt.want {
+				t.Errorf("authorized = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	s.SetToken("")
+	if !s.authorized(httptest.NewRequest(http.MethodPost, "/api/v1/browser-event", nil)) {
+		t.Error("request rejected with no token configured")
+	}
+}
+
+func TestRouteRequiresToken(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.SetStatusProvider(func() map[string]interface{} { return map[string]interface{}{} })
+
+	if rec := serve(s, http.MethodPost, "/api/v1/browser-event", "", browserEvent("https://example.com")); rec.Code != http.StatusUnauthorized || errorCode(t, rec) != "unauthorized" {
+		t.Fatalf("event without token: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodPost, "/api/v1/browser-event", testToken, browserEvent("https://example.com")); rec.Code != http.StatusOK {
+		t.Fatalf("event with token: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodGet, "/api/v1/status", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status without token: %d", rec.Code)
+	}
+	if rec := serve(s, http.MethodGet, "/api/v1/health", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("health without token: %d", rec.Code)
+	}
+}
+
+func TestStreamTokenInQuery(t *testing.T) {
+	s, sessions := newTestServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	defer s.closeStreams()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	streamURL := "ws" + strings.TrimPrefix(ts.URL, "http") + streamPath
+	opts := &websocket.DialOptions{HTTPHeader: http.Header{"Origin": {"chrome-extension://abcdef"}}}
+
+	_, resp, err := websocket.Dial(ctx, streamURL+"?token=wrong", opts)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong query token: err=%v resp=%v, want 401", err, resp)
+	}
+
+	conn, _, err := websocket.Dial(ctx, streamURL+"?token="+testToken, opts)
+	if err != nil {
+		t.Fatalf("query token rejected: %v", err)
+	}
+	defer conn.CloseNow()
+
+	frame, _ := json.Marshal(browserEvent("https://example.com/streamed"))
+	if err := conn.Write(ctx, websocket.MessageText, frame); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	var reply streamReply
+	if err := json.Unmarshal(data, &reply); err != nil || reply.Status != "ok" {
+		t.Fatalf("reply = %s, want ok", data)
+	}
+	if session := sessions.GetCurrentSession(); session == nil || session.URL != "https://example.com/streamed" {
+		t.Fatalf("session = %+v, want the streamed URL", session)
+	}
+}
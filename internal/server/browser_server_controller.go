@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// BrowserServerController owns the browser event server's listener so the
+// server can be started and stopped while the agent is running
+type BrowserServerController struct {
+	handler        *BrowserEventServer
+	sessionManager *service.SessionManager
+	preferredPort  int
+	logger         *zap.Logger
+
+	mu         sync.Mutex
+	httpServer *http.Server
+	port       int
+}
+
+// NewBrowserServerController creates a controller for the browser event server
+func NewBrowserServerController(sessionManager *service.SessionManager, preferredPort int, logger *zap.Logger) *BrowserServerController {
+	return &BrowserServerController{
+		handler:        NewBrowserEventServer(sessionManager, logger),
+		sessionManager: sessionManager,
+		preferredPort:  preferredPort,
+		logger:         logger,
+	}
+}
+
+// SetPresenceHandler enables the presence endpoint. Must be called before Start.
+func (c *BrowserServerController) SetPresenceHandler(fn func(present bool)) {
+	c.handler.SetPresenceHandler(fn)
+}
+
+// SetStatusProvider enables the status endpoint. Must be called before Start.
+func (c *BrowserServerController) SetStatusProvider(fn func() map[string]interface{}) {
+	c.handler.SetStatusProvider(fn)
+}
+
+// SetReadinessCheck sets the check behind the readiness endpoint. Must be
+// called before Start.
+func (c *BrowserServerController) SetReadinessCheck(fn func(ctx context.Context) error) {
+	c.handler.SetReadinessCheck(fn)
+}
+
+// SetTimer enables the manual timer endpoints. Must be called before Start.
+func (c *BrowserServerController) SetTimer(timer *service.ManualTimer) {
+	c.handler.SetTimer(timer)
+}
+
+// SetReloadHandler enables the config reload endpoint. Must be called before
+// Start.
+func (c *BrowserServerController) SetReloadHandler(fn func() error) {
+	c.handler.SetReloadHandler(fn)
+}
+
+// SetThresholdsHandler enables the activity thresholds endpoint. Must be
+// called before Start.
+func (c *BrowserServerController) SetThresholdsHandler(fn func(idle, away time.Duration) error) {
+	c.handler.SetThresholdsHandler(fn)
+}
+
+// SetURLGranularity sets how much of each extension URL is kept
+func (c *BrowserServerController) SetURLGranularity(granularity URLGranularity) {
+	c.handler.SetURLGranularity(granularity)
+}
+
+// SetURLNormalization sets the query parameters removed from extension URLs
+// and whether fragments are dropped; nil stripParams keeps every parameter
+func (c *BrowserServerController) SetURLNormalization(stripParams []string, dropFragment bool) {
+	c.handler.SetURLNormalization(stripParams, dropFragment)
+}
+
+// SetToken sets the shared secret the extension must send
+func (c *BrowserServerController) SetToken(token string) {
+	c.handler.SetToken(token)
+}
+
+// SetRateLimit limits extension POSTs to rate per second with the given burst
+func (c *BrowserServerController) SetRateLimit(rate float64, burst int) {
+	c.handler.SetRateLimit(rate, burst)
+}
+
+// SetEnabled starts or stops the server to match enabled
+func (c *BrowserServerController) SetEnabled(enabled bool) error {
+	if enabled {
+		return c.Start()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return c.Stop(ctx)
+}
+
+// Start binds the listener and serves browser events. It is a no-op if the
+// server is already running.
+func (c *BrowserServerController) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.httpServer != nil {
+		return nil
+	}
+
+	// Try the configured port; if busy, try nearby ports
+	listener, port, err := listenWithFallback(c.preferredPort, c.logger)
+	if err != nil {
+		return fmt.Errorf("failed to start browser event server: %w", err)
+	}
+
+	httpServer := &http.Server{
+		Handler:      c.handler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	c.httpServer = httpServer
+	c.port = port
+
+	go func() {
+		c.logger.Info("Browser event server started for extension",
+			zap.Int("configured_port", c.preferredPort),
+			zap.Int("actual_port", port),
+		)
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			c.logger.Error("Browser event server error", zap.Error(err))
+		}
+	}()
+
+	if port != c.preferredPort {
+		c.logger.Warn("Browser extension server is on a different port than configured!",
+			zap.Int("configured", c.preferredPort),
+			zap.Int("actual", port),
+			zap.String("note", "Make sure the browser extension is configured to use the correct port"),
+		)
+	}
+
+	// Browser time now comes from the extension, unless turned off through
+	// the admin API
+	c.sessionManager.SetBrowserEventsEnabled(c.handler.EventsEnabled())
+	return nil
+}
+
+// Stop shuts the server down. It is a no-op if the server is not running.
+func (c *BrowserServerController) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.httpServer == nil {
+		return nil
+	}
+
+	// Fall back to tracking browsers as plain applications
+	c.sessionManager.SetBrowserEventsEnabled(false)
+
+	// Shutdown does not wait for hijacked WebSocket connections
+	c.handler.closeStreams()
+	err := c.httpServer.Shutdown(ctx)
+	c.httpServer = nil
+	c.port = 0
+	if err != nil {
+		return fmt.Errorf("browser event server shutdown: %w", err)
+	}
+
+	c.logger.Info("Browser event server stopped")
+	return nil
+}
+
+// IsRunning reports whether the server is currently serving
+func (c *BrowserServerController) IsRunning() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.httpServer != nil
+}
+
+// Port returns the port the server is bound to, or 0 when stopped
+func (c *BrowserServerController) Port() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.port
+}
+
+// listenWithFallback tries to bind to the preferred port, then nearby ports,
+// then lets the OS pick a free port. Returns the listener and actual port.
+func listenWithFallback(preferredPort int, logger *zap.Logger) (net.Listener, int, error) {
+	// Try preferred port
+	addr := fmt.Sprintf("localhost:%d", preferredPort)
+	listener, err := net.Listen("tcp", addr)
+	if err == nil {
+		return listener, preferredPort, nil
+	}
+	logger.Warn("Preferred browser server port unavailable",
+		zap.Int("port", preferredPort),
+		zap.Error(err),
+	)
+
+	// Try nearby ports
+	for offset := 1; offset <= 10; offset++ {
+		altPort := preferredPort + offset
+		altAddr := fmt.Sprintf("localhost:%d", altPort)
+		listener, err = net.Listen("tcp", altAddr)
+		if err == nil {
+			return listener, altPort, nil
+		}
+	}
+
+	// Last resort: OS-assigned port
+	listener, err = net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not bind to any port: %w", err)
+	}
+	actualPort := listener.Addr().(*net.TCPAddr).Port
+	return listener, actualPort, nil
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"github.com/coder/websocket"
+	"go.uber.org/zap"
+)
+
+// streamPath is the WebSocket endpoint over which the extension streams
+// browser events as JSON text frames
+const streamPath = "/api/v1/browser-stream"
+
+// streamOrigins are the extension origins allowed to open a stream; web
+// pages are rejected by the handshake's origin check
+var streamOrigins = []string{
+	"chrome-extension://*",
+	"moz-extension://*",
+	"safari-web-extension://*",
+}
+
+// streamReply is sent back for each frame
+type streamReply struct {
+	Status   string `json:"status"`
+	Sequence int    `json:"sequence"`
+	Error    string `json:"error,omitempty"`
+}
+
+// handleBrowserStream upgrades to a WebSocket and processes each frame like a
+// POST to /api/v1/browser-event until the extension disconnects or the
+// server stops
+func (s *BrowserEventServer) handleBrowserStream(w http.ResponseWriter, r *http.Request) {
+	// The server's read/write timeouts are meant for single requests; clear
+	// them before hijacking so they don't cut the stream off
+	log := s.log(r)
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: streamOrigins})
+	if err != nil {
+		log.Warn("Rejected browser stream", zap.Error(err))
+		return
+	}
+
+	s.streamsMu.Lock()
+	s.streams[conn] = struct{}{}
+	s.streamsMu.Unlock()
+	defer func() {
+		s.streamsMu.Lock()
+		delete(s.streams, conn)
+		s.streamsMu.Unlock()
+	}()
+
+	log.Info("Browser stream connected", zap.String("remote", r.RemoteAddr))
+
+	// Hijacked connections outlive the request context
+	ctx := context.Background()
+	for {
+		reply, err := s.readStreamFrame(ctx, conn, log)
+		if err != nil {
+			if status := websocket.CloseStatus(err); status == websocket.StatusNormalClosure || status == websocket.StatusGoingAway {
+				log.Info("Browser stream closed", zap.String("remote", r.RemoteAddr))
+			} else {
+				log.Info("Browser stream dropped", zap.String("remote", r.RemoteAddr), zap.Error(err))
+			}
+			conn.CloseNow()
+			return
+		}
+
+		data, _ := json.Marshal(reply)
+		writeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err = conn.Write(writeCtx, websocket.MessageText, data)
+		cancel()
+		if err != nil {
+			log.Info("Browser stream dropped", zap.String("remote", r.RemoteAddr), zap.Error(err))
+			conn.CloseNow()
+			return
+		}
+	}
+}
+
+// readStreamFrame reads and processes one frame. Only connection errors are
+// returned; a bad frame is reported in the reply. Frames are logged with the
+// stream's request ID.
+func (s *BrowserEventServer) readStreamFrame(ctx context.Context, conn *websocket.Conn, log *zap.Logger) (streamReply, error) {
+	msgType, data, err := conn.Read(ctx)
+	if err != nil {
+		return streamReply{}, err
+	}
+	if msgType != websocket.MessageText {
+		return streamReply{Status: "error", Error: "Expected a text frame"}, nil
+	}
+
+	var event models.BrowserEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Warn("Failed to decode browser stream frame", zap.Error(err))
+		return streamReply{Status: "error", Error: "Invalid request body"}, nil
+	}
+	if !s.allow() {
+		return streamReply{Status: "error", Sequence: event.Sequence, Error: "Too many requests"}, nil
+	}
+	if err := s.acceptBrowserEvent(log, &event); err != nil {
+		return streamReply{Status: "error", Sequence: event.Sequence, Error: err.Error()}, nil
+	}
+	return streamReply{Status: "ok", Sequence: event.Sequence}, nil
+}
+
+// closeStreams closes open browser streams. http.Server.Shutdown does not
+// track hijacked connections, so the controller calls this when stopping.
+func (s *BrowserEventServer) closeStreams() {
+	s.streamsMu.Lock()
+	conns := make([]*websocket.Conn, 0, len(s.streams))
+	for conn := range s.streams {
+		conns = append(conns, conn)
+	}
+	s.streamsMu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *websocket.Conn) {
+			defer wg.Done()
+			if err := conn.Close(websocket.StatusGoingAway, "agent stopping"); err != nil && !errors.Is(err, net.ErrClosed) {
+				s.logger.Debug("Browser stream close", zap.Error(err))
+			}
+		}(conn)
+	}
+	wg.Wait()
+}
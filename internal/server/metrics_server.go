@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// MetricsServer exposes the tracking service's metrics for Prometheus scrapers
+type MetricsServer struct {
+	trackingService *service.TrackingService
+	httpServer      *http.Server
+	logger          *zap.Logger
+}
+
+// NewMetricsServer creates a metrics server listening on address
+func NewMetricsServer(trackingService *service.TrackingService, address string, logger *zap.Logger) *MetricsServer {
+	s := &MetricsServer{
+		trackingService: trackingService,
+		logger:          logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+
+	s.httpServer = &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// Start serves metrics in the background
+func (s *MetricsServer) Start() {
+	go func() {
+		s.logger.Info("Starting metrics server", zap.String("address", s.httpServer.Addr))
+		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Metrics server error", zap.Error(err))
+		}
+	}()
+}
+
+// Stop shuts the server down
+func (s *MetricsServer) Stop(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// handleMetrics writes the metrics in the Prometheus text format
+func (s *MetricsServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.trackingService.WriteMetrics(w); err != nil {
+		s.logger.Debug("Failed to write metrics response", zap.Error(err))
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRejectsBeyondBurst(t *testing.T) {
+	const burst = 5
+	l := newRateLimiter(0.001, burst) // effectively no refill during the test
+
+	for i := 0; i < burst; i++ {
+		if !l.allow() {
+			t.Fatalf("request %d of %d rejected", i+1, burst)
+		}
+	}
+	if l.allow() {
+		t.Fatalf("request %d allowed past a burst of %d", burst+1, burst)
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	l := newRateLimiter(10, 1)
+	if !l.allow() {
+		t.Fatal("first request rejected")
+	}
+	if l.allow() {
+		t.Fatal("second request allowed with an empty bucket")
+	}
+
+	l.mu.Lock()
+	l.last = l.last.Add(-150 * time.Millisecond) // 1.5 tokens at 10/s, capped at the burst of 1
+	l.mu.Unlock()
+
+	if !l.allow() {
+		t.Fatal("request rejected after refilling")
+	}
+	if l.allow() {
+		t.Fatal("refill exceeded the burst")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	l := newRateLimiter(0, 0)
+	for i := 0; i < 1000; i++ {
+		if !l.allow() {
+			t.Fatalf("request %d rejected with limiting disabled", i+1)
+		}
+	}
+}
+
+func TestServerRateLimitsPosts(t *testing.T) {
+	const burst = 3
+	s, _ := newTestServer(t)
+	s.SetRateLimit(0.001, burst)
+
+	for i := 0; i < burst; i++ {
+		if rec := serve(s, http.MethodPost, "/api/v1/browser-event", testToken, browserEvent("https://example.com")); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: %d %s", i+1, rec.Code, rec.Body)
+		}
+	}
+	rec := serve(s, http.MethodPost, "/api/v1/browser-event", testToken, browserEvent("https://example.com"))
+	if rec.Code != http.StatusTooManyRequests || errorCode(t, rec) != "rate_limited" {
+		t.Fatalf("request %d: %d %s, want 429", burst+1, rec.Code, rec.Body)
+	}
+
+	// Reads aren't limited
+	if rec := serve(s, http.MethodGet, "/api/v1/health", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("health while limited: %d", rec.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/apierror"
+	"Mansoor88-6/time-tracking-agent/internal/client"
+	"Mansoor88-6/time-tracking-agent/internal/collector"
+	"Mansoor88-6/time-tracking-agent/internal/database"
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+	"Mansoor88-6/time-tracking-agent/internal/queue"
+	"Mansoor88-6/time-tracking-agent/internal/service"
+	"Mansoor88-6/time-tracking-agent/internal/tracker"
+
+	"go.uber.org/zap"
+)
+
+// newReadyServer returns a server whose readiness check is a tracking
+// service talking to backendURL, and that service's queue
+func newReadyServer(t *testing.T, backendURL string) (*BrowserEventServer, *service.TrackingService, *queue.EventQueue) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "agent.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	logger := zap.NewNop()
+	fake := platform.NewFakePlatform()
+	eventQueue := queue.NewEventQueue(db.DB, logger)
+	s, sessions := newTestServer(t)
+	ts := service.NewTrackingService(
+		fake,
+		tracker.NewWindowTracker(fake, time.Hour, logger),
+		tracker.NewActivityTracker(fake, time.Minute, 5*time.Minute, logger),
+		collector.NewEventCollector(100, time.Hour, logger),
+		client.NewAPIClient(backendURL, "", 5*time.Second, logger),
+		eventQueue,
+		sessions,
+		"device-1",
+		logger,
+	)
+	s.SetReadinessCheck(ts.Ready)
+	return s, ts, eventQueue
+}
+
+func TestReadyUnavailableWhenBackendUnreachable(t *testing.T) {
+	backend := httptest.NewServer(http.NotFoundHandler())
+	backend.Close()
+	s, _, _ := newReadyServer(t, backend.URL)
+
+	rec := serve(s, http.MethodGet, "/api/v1/health/ready", "", nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("ready with the backend down: %d %s, want 503", rec.Code, rec.Body)
+	}
+}
+
+func TestReadyUnavailableWhenBacklogTooLarge(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	s, ts, eventQueue := newReadyServer(t, backend.URL)
+	ts.SetReadyMaxBacklog(2)
+
+	events := make([]models.TrackingEvent, 3)
+	for i := range events {
+		events[i] = models.TrackingEvent{EventID: fmt.Sprintf("event-%d", i), DeviceID: "device-1", Status: models.StatusActive}
+	}
+	if err := eventQueue.Enqueue("device-1", events[:2]); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if rec := serve(s, http.MethodGet, "/api/v1/health/ready", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("ready at the backlog limit: %d %s, want 200", rec.Code, rec.Body)
+	}
+
+	if err := eventQueue.Enqueue("device-1", events[2:]); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	rec := serve(s, http.MethodGet, "/api/v1/health/ready", "", nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("ready over the backlog limit: %d %s, want 503", rec.Code, rec.Body)
+	}
+	if code := errorCode(t, rec); code != apierror.CodeUnavailable {
+		t.Fatalf("error code %q, want unavailable", code)
+	}
+}
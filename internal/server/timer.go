@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/apierror"
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// timerPath is the prefix of the manual timer endpoints
+const timerPath = "/api/v1/timer"
+
+// SetTimer enables the manual timer endpoints: GET /api/v1/timer reports the
+// running timer, DELETE /api/v1/timer discards it without recording an
+// entry, POST /api/v1/timer/start and /api/v1/timer/stop control it
+func (s *BrowserEventServer) SetTimer(timer *service.ManualTimer) {
+	s.timer = timer
+}
+
+// handleTimer routes the manual timer endpoints
+func (s *BrowserEventServer) handleTimer(w http.ResponseWriter, r *http.Request) {
+	if s.timer == nil {
+		apierror.NotFound(w, r)
+		return
+	}
+
+	switch r.URL.Path {
+	case timerPath:
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"running": s.timer.Current(),
+			})
+		case http.MethodDelete:
+			s.handleTimerDiscard(w, r)
+		default:
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case timerPath + "/start":
+		if r.Method != http.MethodPost {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleTimerStart(w, r)
+	case timerPath + "/stop":
+		if r.Method != http.MethodPost {
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleTimerStop(w, r)
+	default:
+		apierror.NotFound(w, r)
+	}
+}
+
+// handleTimerStart starts the timer with an optional description and project
+func (s *BrowserEventServer) handleTimerStart(w http.ResponseWriter, r *http.Request) {
+	var req models.StartTimerRequest
+	// An empty body starts a timer without a description or project
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.log(r).Warn("Failed to decode timer start request", zap.Error(err))
+		apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Description = trimmedOrNil(req.Description)
+	req.ProjectID = trimmedOrNil(req.ProjectID)
+
+	running, err := s.timer.Start(&req, time.Now())
+	if errors.Is(err, service.ErrTimerRunning) {
+		apierror.Write(w, http.StatusConflict, apierror.CodeTimerRunning, err.Error())
+		return
+	}
+	if err != nil {
+		s.log(r).Error("Failed to start timer", zap.Error(err))
+		apierror.Error(w, "Failed to start timer", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, running)
+}
+
+// handleTimerStop stops the running timer and returns the recorded entry
+func (s *BrowserEventServer) handleTimerStop(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.timer.Stop(time.Now())
+	if errors.Is(err, service.ErrNoTimerRunning) {
+		apierror.Write(w, http.StatusConflict, apierror.CodeTimerNotRunning, err.Error())
+		return
+	}
+	// The timer keeps running after a failed stop; say why, and how to get
+	// rid of it if the entry can't be saved
+	if errors.Is(err, service.ErrOverlap) || errors.Is(err, service.ErrInvalidTimeEntry) {
+		code := apierror.CodeOverlap
+		status := http.StatusConflict
+		if errors.Is(err, service.ErrInvalidTimeEntry) {
+			code, status = apierror.CodeInvalidTimeEntry, http.StatusBadRequest
+		}
+		apierror.Write(w, status, code, "Timer still running: "+err.Error()+"; discard it with DELETE "+timerPath)
+		return
+	}
+	if err != nil {
+		s.log(r).Error("Failed to stop timer", zap.Error(err))
+		apierror.Error(w, "Failed to stop timer: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// handleTimerDiscard clears the running timer without recording an entry
+func (s *BrowserEventServer) handleTimerDiscard(w http.ResponseWriter, r *http.Request) {
+	discarded, err := s.timer.Discard()
+	if errors.Is(err, service.ErrNoTimerRunning) {
+		apierror.Write(w, http.StatusConflict, apierror.CodeTimerNotRunning, err.Error())
+		return
+	}
+	if err != nil {
+		s.log(r).Error("Failed to discard timer", zap.Error(err))
+		apierror.Error(w, "Failed to discard timer: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"discarded": discarded,
+	})
+}
+
+// trimmedOrNil returns v trimmed, or nil if it is nil or blank
+func trimmedOrNil(v *string) *string {
+	if v == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*v)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// writeJSON writes body as a JSON response with status code
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
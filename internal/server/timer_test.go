@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/database"
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/repository"
+	"Mansoor88-6/time-tracking-agent/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// newTimerServer returns a test server with the timer endpoints enabled and
+// the time entry service the timer records into
+func newTimerServer(t *testing.T) (*BrowserEventServer, *service.TimeEntryService) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "agent.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	entries := service.NewTimeEntryService(repository.NewTimeEntryRepository(db.DB), zap.NewNop())
+
+	s, _ := newTestServer(t)
+	s.SetTimer(service.NewManualTimer(entries, "device-1", zap.NewNop()))
+	return s, entries
+}
+
+func TestTimerStartStop(t *testing.T) {
+	s, entries := newTimerServer(t)
+
+	rec := serve(s, http.MethodPost, timerPath+"/start", testToken, map[string]string{"description": " Writing docs "})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("start: %d %s", rec.Code, rec.Body)
+	}
+	var running models.RunningTimer
+	if err := json.Unmarshal(rec.Body.Bytes(), &running); err != nil || running.Description == nil || *running.Description != "Writing docs" {
+		t.Fatalf("start response = %s", rec.Body)
+	}
+
+	rec = serve(s, http.MethodPost, timerPath+"/start", testToken, nil)
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != "timer_running" {
+		t.Fatalf("second start: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(s, http.MethodPost, timerPath+"/stop", testToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stop: %d %s", rec.Code, rec.Body)
+	}
+	var entry models.TimeEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil || entry.ID == 0 {
+		t.Fatalf("stop response = %s", rec.Body)
+	}
+	stored, err := entries.GetTimeEntry(entry.ID)
+	if err != nil {
+		t.Fatalf("entry not persisted: %v", err)
+	}
+	if stored.UserID != "device-1" || stored.EndTime == nil || stored.Description == nil || *stored.Description != "Writing docs" {
+		t.Errorf("stored entry = %+v", stored)
+	}
+
+	rec = serve(s, http.MethodPost, timerPath+"/stop", testToken, nil)
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != "timer_not_running" {
+		t.Fatalf("stop with nothing running: %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(s, http.MethodGet, timerPath, testToken, nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"running\":null}\n" {
+		t.Fatalf("GET after stop: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestTimerRequiresToken(t *testing.T) {
+	s, _ := newTimerServer(t)
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, timerPath},
+		{http.MethodDelete, timerPath},
+		{http.MethodPost, timerPath + "/start"},
+		{http.MethodPost, timerPath + "/stop"},
+	} {
+		for _, token := range []string{"", "wrong"} {
+			if rec := serve(s, req.method, req.path, token, nil); rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s with token %q: %d, want 401", req.method, req.path, token, rec.Code)
+			}
+		}
+	}
+	if rec := serve(s, http.MethodGet, timerPath, testToken, nil); rec.Code != http.StatusOK {
+		t.Fatalf("GET with token: %d", rec.Code)
+	}
+}
+
+func TestTimerDiscardAfterOverlap(t *testing.T) {
+	s, entries := newTimerServer(t)
+	if rec := serve(s, http.MethodPost, timerPath+"/start", testToken, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("start: %d %s", rec.Code, rec.Body)
+	}
+
+	// An entry created through the time entry API while the timer runs
+	now := time.Now()
+	end := now.Add(30 * time.Second)
+	if _, err := entries.CreateTimeEntry(&models.CreateTimeEntryRequest{UserID: "device-1", StartTime: now.Add(-time.Hour), EndTime: &end}); err != nil {
+		t.Fatalf("CreateTimeEntry: %v", err)
+	}
+
+	rec := serve(s, http.MethodPost, timerPath+"/stop", testToken, nil)
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != "overlap" {
+		t.Fatalf("stop over an overlapping entry: %d %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), "overlaps an existing entry") || !strings.Contains(rec.Body.String(), "DELETE") {
+		t.Errorf("stop error doesn't say why or how to recover: %s", rec.Body)
+	}
+
+	rec = serve(s, http.MethodDelete, timerPath, testToken, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "discarded") {
+		t.Fatalf("discard: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodDelete, timerPath, testToken, nil); rec.Code != http.StatusConflict || errorCode(t, rec) != "timer_not_running" {
+		t.Fatalf("second discard: %d %s", rec.Code, rec.Body)
+	}
+	// The timer can be used again
+	if rec := serve(s, http.MethodPost, timerPath+"/start", testToken, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("start after discard: %d %s", rec.Code, rec.Body)
+	}
+}
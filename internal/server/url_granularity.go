@@ -0,0 +1,47 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// URLGranularity controls how much of an extension-reported URL is kept
+type URLGranularity string
+
+const (
+	URLGranularityFull   URLGranularity = "full"   // scheme, host, path, query and fragment
+	URLGranularityPath   URLGranularity = "path"   // scheme, host and path
+	URLGranularityDomain URLGranularity = "domain" // scheme and host
+)
+
+// ParseURLGranularity validates a configured granularity; empty means full
+func ParseURLGranularity(s string) (URLGranularity, error) {
+	switch g := URLGranularity(s); g {
+	case "":
+		return URLGranularityFull, nil
+	case URLGranularityFull, URLGranularityPath, URLGranularityDomain:
+		return g, nil
+	default:
+		return "", fmt.Errorf("unknown URL granularity %q (must be full, path or domain)", s)
+	}
+}
+
+// reduceURL strips raw down to granularity. URLs that cannot be parsed are
+// reduced to nothing rather than passed through unstripped.
+func reduceURL(raw string, granularity URLGranularity) string {
+	if granularity == URLGranularityFull || granularity == "" {
+		return raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	reduced := url.URL{Scheme: u.Scheme, Host: u.Host}
+	if granularity == URLGranularityPath {
+		reduced.Path = u.Path
+		reduced.RawPath = u.RawPath
+	}
+	return reduced.String()
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReduceURL(t *testing.T) {
+	const raw = "https://user@docs.example.com:8443/a/b%2Fc?q=1&utm_source=x#section"
+	tests := []struct {
+		granularity URLGranularity
+		raw         string
+		want        string
+	}{
+		{URLGranularityFull, raw, raw},
+		{"", raw, raw},
+		{URLGranularityPath, raw, "https://docs.example.com:8443/a/b%2Fc"},
+		{URLGranularityDomain, raw, "https://docs.example.com:8443"},
+		{URLGranularityPath, "https://example.com", "https://example.com"},
+		{URLGranularityDomain, "https://example.com/", "https://example.com"},
+		{URLGranularityDomain, "http://[::1]:9000/x", "http://[::1]:9000"},
+		{URLGranularityPath, "https://example.com/%zz", ""},
+		{URLGranularityDomain, "://missing-scheme", ""},
+		{URLGranularityFull, "://missing-scheme", "://missing-scheme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.granularity)+" "+tt.raw, func(t *testing.T) {
+			if got := reduceURL(tt.raw, tt.granularity); got != tt.want {
+				t.Errorf("reduceURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseURLGranularity(t *testing.T) {
+	for input, want := range map[string]URLGranularity{
+		"":       URLGranularityFull,
+		"full":   URLGranularityFull,
+		"path":   URLGranularityPath,
+		"domain": URLGranularityDomain,
+	} {
+		if got, err := ParseURLGranularity(input); err != nil || got != want {
+			t.Errorf("ParseURLGranularity(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseURLGranularity("host"); err == nil {
+		t.Error("unknown granularity accepted")
+	}
+}
+
+func TestBrowserEventURLReducedToGranularity(t *testing.T) {
+	s, sessions := newTestServer(t)
+	s.SetURLGranularity(URLGranularityDomain)
+
+	if rec := serve(s, http.MethodPost, "/api/v1/browser-event", testToken, browserEvent("https://mail.example.com/inbox/123?id=9")); rec.Code != http.StatusOK {
+		t.Fatalf("browser event: %d %s", rec.Code, rec.Body)
+	}
+	if session := sessions.GetCurrentSession(); session == nil || session.URL != "https://mail.example.com" {
+		t.Fatalf("session URL = %+v, want the domain only", session)
+	}
+}
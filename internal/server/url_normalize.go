@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultStripParams are query parameters removed from extension URLs by
+// default: campaign tracking and click IDs, and common session IDs. Patterns
+// are case-insensitive globs.
+var DefaultStripParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_eid", "igshid", "_ga", "_gl",
+	"sessionid", "session_id", "sid", "phpsessid", "jsessionid", "aspsessionid*",
+}
+
+// urlNormalizer removes tracking and session parameters from URLs so the
+// same page isn't recorded as many different URLs
+type urlNormalizer struct {
+	stripParams  []string // lowercase glob patterns
+	dropFragment bool
+}
+
+// newURLNormalizer creates a normalizer removing query parameters matching
+// stripParams and, if dropFragment is set, the #fragment
+func newURLNormalizer(stripParams []string, dropFragment bool) urlNormalizer {
+	patterns := make([]string, 0, len(stripParams))
+	for _, p := range stripParams {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return urlNormalizer{stripParams: patterns, dropFragment: dropFragment}
+}
+
+// normalize returns raw without the stripped parameters and fragment. The
+// query is only re-encoded if a parameter was removed; URLs that cannot be
+// parsed are returned unchanged for reduceURL to reject.
+func (n urlNormalizer) normalize(raw string) string {
+	if len(n.stripParams) == 0 && !n.dropFragment {
+		return raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	changed := false
+	if n.dropFragment && (u.Fragment != "" || u.RawFragment != "") {
+		u.Fragment = ""
+		u.RawFragment = ""
+		changed = true
+	}
+
+	if u.RawQuery != "" && len(n.stripParams) > 0 {
+		query := u.Query()
+		for key := range query {
+			if n.stripped(key) {
+				query.Del(key)
+				changed = true
+			}
+		}
+		if changed {
+			u.RawQuery = query.Encode()
+		}
+	}
+
+	if !changed {
+		return raw
+	}
+	return u.String()
+}
+
+// stripped reports whether the query parameter key should be removed
+func (n urlNormalizer) stripped(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range n.stripParams {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNormalizeStripsTrackingParams(t *testing.T) {
+	n := newURLNormalizer(DefaultStripParams, false)
+
+	same := []string{
+		"https://example.com/docs?id=7",
+		"https://example.com/docs?id=7&utm_source=newsletter",
+		"https://example.com/docs?UTM_Medium=email&id=7&utm_campaign=spring",
+		"https://example.com/docs?fbclid=abc&id=7&gclid=def",
+	}
+	for _, raw := range same {
+		if got := n.normalize(raw); got != "https://example.com/docs?id=7" {
+			t.Errorf("normalize(%q) = %q, want https://example.com/docs?id=7", raw, got)
+		}
+	}
+
+	if got := n.normalize("https://example.com/docs?utm_source=x"); got != "https://example.com/docs" {
+		t.Errorf("only tracking params: got %q, want no query", got)
+	}
+	if got, want := n.normalize("https://example.com/docs?id=8&utm_source=x"), n.normalize("https://example.com/docs?id=7&utm_source=x"); got == want {
+		t.Errorf("different pages normalized to the same %q", got)
+	}
+}
+
+func TestNormalizeLeavesOtherURLsUntouched(t *testing.T) {
+	n := newURLNormalizer(DefaultStripParams, false)
+
+	for _, raw := range []string{
+		"https://example.com/search?q=a+b&z=1&a=2",
+		"https://example.com/page#section",
+		"https://example.com/%zz?utm_source=x",
+		"chrome://extensions",
+	} {
+		if got := n.normalize(raw); got != raw {
+			t.Errorf("normalize(%q) = %q, want it unchanged", raw, got)
+		}
+	}
+}
+
+func TestNormalizeDropFragment(t *testing.T) {
+	n := newURLNormalizer(nil, true)
+
+	if got := n.normalize("https://example.com/page?utm_source=x#section"); got != "https://example.com/page?utm_source=x" {
+		t.Errorf("got %q, want the fragment dropped and the query kept", got)
+	}
+	if got := newURLNormalizer(nil, false).normalize("https://example.com/?utm_source=x#a"); got != "https://example.com/?utm_source=x#a" {
+		t.Errorf("disabled normalizer changed the URL to %q", got)
+	}
+}
+
+func TestBrowserEventURLNormalized(t *testing.T) {
+	s, sessions := newTestServer(t)
+	s.SetURLNormalization([]string{"utm_*", "ref"}, true)
+
+	if rec := serve(s, http.MethodPost, "/api/v1/browser-event", testToken, browserEvent("https://example.com/a?ref=hn&id=1&utm_source=x#top")); rec.Code != http.StatusOK {
+		t.Fatalf("browser event: %d %s", rec.Code, rec.Body)
+	}
+	if session := sessions.GetCurrentSession(); session == nil || session.URL != "https://example.com/a?id=1" {
+		t.Fatalf("session URL = %+v, want https://example.com/a?id=1", session)
+	}
+}
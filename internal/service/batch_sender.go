@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSendWorkers is how many batches are uploaded concurrently
+	defaultSendWorkers = 2
+
+	// sendQueueSize is how many ready batches may wait for a worker before
+	// further batches go straight to the local queue
+	sendQueueSize = 16
+)
+
+// EventSink receives a copy of every batch, e.g. a local audit log.
+// Implementations must be safe for concurrent use.
+type EventSink interface {
+	WriteEvents(events []models.TrackingEvent) error
+}
+
+// SetDryRun makes every batch go to printer instead of the backend. Nothing
+// is sent or queued; the rest of the pipeline runs as usual. Must be called
+// before Start.
+func (ts *TrackingService) SetDryRun(printer EventSink) {
+	ts.dryRun = printer
+}
+
+// pendingBatch is a batch waiting for a sender worker
+type pendingBatch struct {
+	events []models.TrackingEvent
+	done   func(error)
+}
+
+// SetSendWorkers sets how many batches are uploaded concurrently. Zero or
+// negative sends each batch inline on the collector's goroutine. Must be
+// called before Start.
+func (ts *TrackingService) SetSendWorkers(workers int) {
+	ts.sendWorkers = workers
+}
+
+// startSender starts the worker pool that uploads batches handed over by
+// the collector, so a slow upload doesn't stall event collection
+func (ts *TrackingService) startSender() {
+	ts.sendCtx, ts.cancelSends = context.WithCancel(context.Background())
+	if ts.sendWorkers <= 0 {
+		return
+	}
+
+	ch := make(chan pendingBatch, sendQueueSize)
+	ts.mu.Lock()
+	ts.sendCh = ch
+	ts.mu.Unlock()
+
+	for i := 0; i < ts.sendWorkers; i++ {
+		ts.senderWG.Add(1)
+		go ts.sendWorker(ch)
+	}
+}
+
+// sendWorker delivers batches until ch is closed
+func (ts *TrackingService) sendWorker(ch <-chan pendingBatch) {
+	defer ts.senderWG.Done()
+	for batch := range ch {
+		batch.done(ts.deliverBatch(ts.batchContext(), batch.events))
+	}
+}
+
+// closeSender stops accepting batches and waits for the workers to deliver
+// the ones already handed over. Later batches are delivered inline.
+func (ts *TrackingService) closeSender() {
+	ts.mu.Lock()
+	ch := ts.sendCh
+	ts.sendCh = nil
+	ts.mu.Unlock()
+
+	if ch != nil {
+		close(ch)
+		ts.senderWG.Wait()
+	}
+}
+
+// batchContext returns the context for delivering a batch. During shutdown
+// the queue processor is gone and the stop budget is short, so sends are
+// bounded by the shutdown deadline and fall back to the queue.
+func (ts *TrackingService) batchContext() context.Context {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	if ts.stopped && ts.shutdownCtx != nil {
+		return ts.shutdownCtx
+	}
+	if ts.sendCtx != nil {
+		return ts.sendCtx
+	}
+	return context.Background()
+}
+
+// onBatchReady hands a ready batch to the sender workers. If they are all
+// busy and the backlog is full, the batch is queued locally for the queue
+// processor rather than blocking the collector.
+func (ts *TrackingService) onBatchReady(events []models.TrackingEvent, done func(error)) {
+	if len(events) == 0 {
+		ts.logger.Debug("Batch ready but empty, skipping")
+		done(nil)
+		return
+	}
+
+	ts.logger.Info("Batch ready to send",
+		zap.Int("event_count", len(events)),
+	)
+
+	// The local copy is best effort and never holds up delivery
+	if ts.eventSink != nil {
+		if err := ts.eventSink.WriteEvents(events); err != nil {
+			ts.logger.Warn("Failed to write events to sink", zap.Error(err))
+		}
+	}
+
+	if ts.dryRun != nil {
+		if err := ts.dryRun.WriteEvents(events); err != nil {
+			ts.logger.Warn("Failed to print dry-run batch", zap.Error(err))
+		}
+		done(nil)
+		return
+	}
+
+	// Hold the lock across the non-blocking send so closeSender can't close
+	// the channel underneath it
+	ts.mu.RLock()
+	ch := ts.sendCh
+	handed := false
+	if ch != nil {
+		select {
+		case ch <- pendingBatch{events: events, done: done}:
+			handed = true
+		default:
+		}
+	}
+	ts.mu.RUnlock()
+
+	switch {
+	case handed:
+	case ch == nil:
+		done(ts.deliverBatch(ts.batchContext(), events))
+	default:
+		ts.logger.Warn("Sender backlog full, queuing batch locally",
+			zap.Int("event_count", len(events)),
+		)
+		done(ts.eventQueue.Enqueue(ts.deviceID, events))
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingBackend accepts batches, counting them. Each request waits for
+// release to be closed, if it is set.
+func countingBackend(t *testing.T, release <-chan struct{}) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var batches atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		batches.Add(1)
+		if release != nil {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(backend.Close)
+	return backend, &batches
+}
+
+func pendingCount(t *testing.T, ts *testService) int {
+	t.Helper()
+	stats, err := ts.queue.GetStats(ts.deviceID)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	return stats.Pending
+}
+
+func TestOnBatchReadyQueuesWhenSenderBacklogFull(t *testing.T) {
+	backend, batches := countingBackend(t, nil)
+	ts := newTestService(t, backend.URL)
+	// No worker ever receives, so every hand-over finds the backlog full
+	ts.sendCh = make(chan pendingBatch)
+
+	var doneErr error
+	called := false
+	ts.onBatchReady(testEvents(2), func(err error) {
+		doneErr, called = err, true
+	})
+
+	if !called || doneErr != nil {
+		t.Fatalf("done called %v with %v, want nil before onBatchReady returns", called, doneErr)
+	}
+	if got := batches.Load(); got != 0 {
+		t.Fatalf("%d batches sent, want the batch queued instead", got)
+	}
+	if got := pendingCount(t, ts); got != 2 {
+		t.Fatalf("%d events queued, want 2", got)
+	}
+}
+
+func TestCloseSenderDrainsHandedOverBatches(t *testing.T) {
+	release := make(chan struct{})
+	backend, batches := countingBackend(t, release)
+	ts := newTestService(t, backend.URL)
+	ts.SetSendWorkers(1)
+	ts.startSender()
+	t.Cleanup(ts.cancelSends)
+
+	// One batch in flight, two waiting in the channel
+	dones := make(chan error, 3)
+	events := testEvents(3)
+	for i := range events {
+		ts.onBatchReady(events[i:i+1], func(err error) { dones <- err })
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		ts.closeSender()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("closeSender returned while a batch was still in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("closeSender did not return after the backend answered")
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-dones:
+			if err != nil {
+				t.Fatalf("batch done with %v, want nil", err)
+			}
+		default:
+			t.Fatalf("%d of 3 batches done after closeSender", i)
+		}
+	}
+	if got := batches.Load(); got != 3 {
+		t.Fatalf("%d batches sent, want 3", got)
+	}
+	if got := pendingCount(t, ts); got != 0 {
+		t.Fatalf("%d events queued, want all sent", got)
+	}
+}
+
+func TestOnBatchReadyDeliversInlineAfterClose(t *testing.T) {
+	backend, batches := countingBackend(t, nil)
+	ts := newTestService(t, backend.URL)
+	ts.SetSendWorkers(1)
+	ts.startSender()
+	t.Cleanup(ts.cancelSends)
+	ts.closeSender()
+
+	called := false
+	ts.onBatchReady(testEvents(2), func(err error) {
+		if err != nil {
+			t.Errorf("done with %v, want nil", err)
+		}
+		called = true
+	})
+
+	if !called {
+		t.Fatal("done not called before onBatchReady returned")
+	}
+	if got := batches.Load(); got != 1 {
+		t.Fatalf("%d batches sent, want the batch delivered inline", got)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// Category labels
+const (
+	CategoryProductive    = "productive"
+	CategoryNeutral       = "neutral"
+	CategoryDistracting   = "distracting"
+	CategoryUncategorized = "uncategorized"
+)
+
+// CategoryRule assigns Category to events whose application or URL host
+// matches one of its patterns (same pattern syntax as the privacy blocklist)
+type CategoryRule struct {
+	Category     string   `yaml:"category"`
+	Applications []string `yaml:"applications"`
+	Domains      []string `yaml:"domains"`
+}
+
+// categoryRulesFile is the layout of a categories rules file
+type categoryRulesFile struct {
+	Rules []CategoryRule `yaml:"rules"`
+}
+
+// DefaultCategoryRules are applied after any user rules
+var DefaultCategoryRules = []CategoryRule{
+	{
+		Category: CategoryProductive,
+		Applications: []string{
+			"code*", "devenv*", "idea*", "goland*", "pycharm*", "webstorm*", "rider*",
+			"sublime_text*", "xcode*", "*terminal*", "powershell*", "cmd.exe", "iterm*",
+		},
+		Domains: []string{
+			"github.com", "*.github.com", "gitlab.com", "bitbucket.org",
+			"stackoverflow.com", "*.stackexchange.com", "docs.google.com", "*.atlassian.net",
+		},
+	},
+	{
+		Category:     CategoryNeutral,
+		Applications: []string{"outlook*", "slack*", "teams*", "ms-teams*", "zoom*", "thunderbird*"},
+		Domains:      []string{"mail.google.com", "calendar.google.com", "outlook.office.com", "*.slack.com"},
+	},
+	{
+		Category:     CategoryDistracting,
+		Applications: []string{"steam*", "discord*", "spotify*"},
+		Domains: []string{
+			"facebook.com", "*.facebook.com", "instagram.com", "*.instagram.com",
+			"twitter.com", "x.com", "tiktok.com", "*.tiktok.com", "reddit.com", "*.reddit.com",
+			"youtube.com", "*.youtube.com", "netflix.com", "*.netflix.com", "twitch.tv", "*.twitch.tv",
+		},
+	},
+}
+
+// Categorizer maps applications and domains to a category label
+type Categorizer struct {
+	rules []CategoryRule
+}
+
+// NewCategorizer creates a categorizer from rules, evaluated in order
+func NewCategorizer(rules []CategoryRule) (*Categorizer, error) {
+	c := &Categorizer{}
+	for _, rule := range rules {
+		if rule.Category == "" {
+			return nil, fmt.Errorf("category rule without a category")
+		}
+		applications, err := normalizePatterns(rule.Applications)
+		if err != nil {
+			return nil, fmt.Errorf("category %q: %w", rule.Category, err)
+		}
+		domains, err := normalizePatterns(rule.Domains)
+		if err != nil {
+			return nil, fmt.Errorf("category %q: %w", rule.Category, err)
+		}
+		c.rules = append(c.rules, CategoryRule{
+			Category:     rule.Category,
+			Applications: applications,
+			Domains:      domains,
+		})
+	}
+	return c, nil
+}
+
+// LoadCategorizer creates a categorizer from the rules file at path (if
+// any) followed by DefaultCategoryRules
+func LoadCategorizer(path string) (*Categorizer, error) {
+	var rules []CategoryRule
+	if path != "" {
+		var file categoryRulesFile
+		if err := cleanenv.ReadConfig(path, &file); err != nil {
+			return nil, fmt.Errorf("failed to read category rules: %w", err)
+		}
+		rules = append(rules, file.Rules...)
+	}
+	rules = append(rules, DefaultCategoryRules...)
+	return NewCategorizer(rules)
+}
+
+// Categorize returns the category of the first matching rule, or
+// CategoryUncategorized
+func (c *Categorizer) Categorize(event *models.TrackingEvent) string {
+	var application, host string
+	if event.Application != nil {
+		application = strings.ToLower(*event.Application)
+	}
+	if event.URL != nil {
+		if u, err := url.Parse(*event.URL); err == nil {
+			host = strings.ToLower(u.Hostname())
+		}
+	}
+
+	for _, rule := range c.rules {
+		if matchAny(rule.Domains, host) || matchAny(rule.Applications, application) {
+			return rule.Category
+		}
+	}
+	return CategoryUncategorized
+}
+
+// SetCategorizer enables stamping a category onto events; nil disables it
+func (ts *TrackingService) SetCategorizer(categorizer *Categorizer) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.categorizer = categorizer
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// clockJumpTolerance is how far the wall clock may drift from the monotonic
+// clock over one session before it counts as a jump (NTP step, manual
+// change). Slewing corrections stay well below it.
+const clockJumpTolerance = 2 * time.Second
+
+// wallClockJump returns how far the wall clock moved relative to the
+// monotonic clock between since and now, both taken from time.Now(). A zero
+// since has no monotonic reading and returns 0.
+func wallClockJump(since, now time.Time) time.Duration {
+	if since.IsZero() {
+		return 0
+	}
+	return now.Round(0).Sub(since.Round(0)) - now.Sub(since)
+}
+
+// correctForClockJump returns the end time to use for a session. Session
+// times are wall-clock, so a step during the session stretches, shrinks or
+// even inverts its span; when that happens the jump is taken back out of the
+// span, keeping it between zero and the monotonic time actually elapsed.
+func (sm *SessionManager) correctForClockJump(session *ActiveSession, endTime time.Time) time.Time {
+	now := time.Now()
+	return sm.correctSpan(session, endTime, wallClockJump(session.started, now), now.Sub(session.started))
+}
+
+// correctSpan applies a wall clock jump, measured over elapsed monotonic
+// time since the session started, to the session's end time
+func (sm *SessionManager) correctSpan(session *ActiveSession, endTime time.Time, jump, elapsed time.Duration) time.Time {
+	if jump.Abs() <= clockJumpTolerance {
+		return endTime
+	}
+
+	span := max(0, min(endTime.Sub(session.StartTime)-jump, elapsed))
+	corrected := session.StartTime.Add(span)
+
+	sm.logger.Warn("Wall clock jumped during session, using monotonic duration",
+		zap.String("application", session.Application),
+		zap.Duration("jump", jump),
+		zap.Duration("wall_duration", endTime.Sub(session.StartTime)),
+		zap.Duration("corrected_duration", span),
+	)
+	return corrected
+}
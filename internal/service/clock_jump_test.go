@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestWallClockJumpWithoutChange(t *testing.T) {
+	started := time.Now().Add(-10 * time.Minute)
+	if jump := wallClockJump(started, time.Now()); jump.Abs() > time.Millisecond {
+		t.Errorf("jump = %s with a steady clock", jump)
+	}
+	if jump := wallClockJump(time.Time{}, time.Now()); jump != 0 {
+		t.Errorf("jump = %s for a session without a monotonic start", jump)
+	}
+	// Without a monotonic reading only the wall clock is left to compare
+	if jump := wallClockJump(started.Round(0).Add(-time.Hour), time.Now()); jump != 0 {
+		t.Errorf("jump = %s for a start without a monotonic reading", jump)
+	}
+}
+
+func TestCorrectSpanForClockJump(t *testing.T) {
+	sm := &SessionManager{logger: zap.NewNop()}
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		wall    time.Duration // end minus start on the wall clock
+		jump    time.Duration
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{"no jump", 10 * time.Minute, 0, 10 * time.Minute, 10 * time.Minute},
+		{"within tolerance", 10*time.Minute - clockJumpTolerance, -clockJumpTolerance, 10 * time.Minute, 10*time.Minute - clockJumpTolerance},
+		// Set back an hour ten minutes in: the wall span is 20m, but 1h20m passed
+		{"backward", 20 * time.Minute, -time.Hour, 80 * time.Minute, 80 * time.Minute},
+		// Set back an hour three minutes in: the end is before the start
+		{"backward past the start", -50 * time.Minute, -time.Hour, 10 * time.Minute, 10 * time.Minute},
+		{"forward", 70 * time.Minute, time.Hour, 10 * time.Minute, 10 * time.Minute},
+		{"forward, never above elapsed", 3 * time.Hour, time.Hour, 30 * time.Minute, 30 * time.Minute},
+		{"forward, never below zero", 30 * time.Minute, time.Hour, 30 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &ActiveSession{Application: "code.exe", StartTime: start}
+			got := sm.correctSpan(session, start.Add(tt.wall), tt.jump, tt.elapsed)
+			if span := got.Sub(start); span != tt.want {
+				t.Errorf("span = %s, want %s", span, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloseSessionKeepsSpanWithoutJump(t *testing.T) {
+	sm, ended := newTestSessionManager(t)
+	start := time.Now().Add(-10 * time.Minute)
+	session := &ActiveSession{Source: "app", Application: "code.exe", StartTime: start.Round(0), started: start}
+
+	sm.closeSession(session, start.Round(0).Add(10*time.Minute))
+	sessions := ended.All()
+	if len(sessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(sessions))
+	}
+	if span := sessions[0].LastEventTime.Sub(sessions[0].StartTime); span != 10*time.Minute {
+		t.Errorf("span = %s, want 10m", span)
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// maxEventDuration is the longest duration an activity event may report;
+// anything longer comes from a clock jump or a missed sleep and is clamped.
+// Offline gaps are exempt since the machine may really be off for days.
+const maxEventDuration = 24 * time.Hour
+
+// maxEventClockSkew is how far past now an event may start or end before it
+// is treated as a clock error
+const maxEventClockSkew = 5 * time.Minute
+
+// validEventStatuses are the statuses the backend accepts
+var validEventStatuses = map[string]bool{
+	models.StatusActive:  true,
+	models.StatusIdle:    true,
+	models.StatusAway:    true,
+	models.StatusOffline: true,
+}
+
+// validateEvent checks an event before it is collected. Events that can't be
+// repaired are rejected with a reason; a duration that is too long, or that
+// runs past now, is clamped in place and the reason returned with ok set.
+func validateEvent(event *models.TrackingEvent, now time.Time) (reason string, ok bool) {
+	switch {
+	case event.DeviceID == "":
+		return "missing device ID", false
+	case event.Timestamp <= 0:
+		return "missing timestamp", false
+	case !validEventStatuses[event.Status]:
+		return fmt.Sprintf("unknown status %q", event.Status), false
+	}
+
+	latest := now.Add(maxEventClockSkew).UnixMilli()
+	if event.Timestamp > latest {
+		return "timestamp in the future", false
+	}
+
+	if event.Duration == nil {
+		return "", true
+	}
+	duration := *event.Duration
+	if duration < 0 {
+		return "negative duration", false
+	}
+
+	limit := latest - event.Timestamp
+	if event.Status != models.StatusOffline {
+		limit = min(limit, maxEventDuration.Milliseconds())
+	}
+	if duration <= limit {
+		return "", true
+	}
+	event.Duration = &limit
+	if event.EndTime != nil {
+		end := event.Timestamp + limit
+		event.EndTime = &end
+	}
+	return fmt.Sprintf("duration of %s clamped to %s", time.Duration(duration)*time.Millisecond, time.Duration(limit)*time.Millisecond), true
+}
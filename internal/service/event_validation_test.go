@@ -0,0 +1,85 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+func TestValidateEvent(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	ms := func(d time.Duration) int64 { return d.Milliseconds() }
+	event := func(status string, start time.Time, duration *time.Duration) models.TrackingEvent {
+		e := models.TrackingEvent{DeviceID: "device-1", Timestamp: start.UnixMilli(), Status: status}
+		if duration != nil {
+			d := ms(*duration)
+			end := e.Timestamp + d
+			e.Duration = &d
+			e.EndTime = &end
+		}
+		return e
+	}
+	dur := func(d time.Duration) *time.Duration { return &d }
+	hourAgo := now.Add(-time.Hour)
+	weekAgo := now.Add(-7 * 24 * time.Hour)
+
+	tests := []struct {
+		name         string
+		event        models.TrackingEvent
+		wantOK       bool
+		wantReason   string
+		wantDuration time.Duration // after clamping, when the event has one
+	}{
+		{"valid", event(models.StatusActive, hourAgo, dur(time.Minute)), true, "", time.Minute},
+		{"no duration", event(models.StatusActive, hourAgo, nil), true, "", 0},
+		{"missing device", models.TrackingEvent{Timestamp: hourAgo.UnixMilli(), Status: models.StatusActive}, false, "missing device ID", 0},
+		{"missing timestamp", models.TrackingEvent{DeviceID: "device-1", Status: models.StatusActive}, false, "missing timestamp", 0},
+		{"unknown status", event("busy", hourAgo, nil), false, "unknown status", 0},
+		{"within skew", event(models.StatusActive, now.Add(maxEventClockSkew), nil), true, "", 0},
+		{"timestamp in the future", event(models.StatusActive, now.Add(maxEventClockSkew+time.Millisecond), nil), false, "timestamp in the future", 0},
+		{"far future", event(models.StatusActive, now.Add(365*24*time.Hour), dur(time.Minute)), false, "timestamp in the future", 0},
+		{"negative duration", event(models.StatusActive, hourAgo, dur(-time.Second)), false, "negative duration", 0},
+		{"huge duration", event(models.StatusActive, weekAgo, dur(100*24*time.Hour)), true, "clamped", maxEventDuration},
+		{"just over the cap", event(models.StatusIdle, weekAgo, dur(maxEventDuration+time.Millisecond)), true, "clamped", maxEventDuration},
+		{"at the cap", event(models.StatusAway, weekAgo, dur(maxEventDuration)), true, "", maxEventDuration},
+		{"runs past now", event(models.StatusActive, hourAgo, dur(2*time.Hour)), true, "clamped", time.Hour + maxEventClockSkew},
+		{"offline gap", event(models.StatusOffline, weekAgo, dur(5*24*time.Hour)), true, "", 5 * 24 * time.Hour},
+		{"offline past now", event(models.StatusOffline, weekAgo, dur(100*24*time.Hour)), true, "clamped", 7*24*time.Hour + maxEventClockSkew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.event
+			reason, ok := validateEvent(&e, now)
+			if ok != tt.wantOK || (tt.wantReason == "") != (reason == "") || !strings.Contains(reason, tt.wantReason) {
+				t.Fatalf("validateEvent = %q, %v, want %q, %v", reason, ok, tt.wantReason, tt.wantOK)
+			}
+			if !ok || e.Duration == nil {
+				return
+			}
+			if got := time.Duration(*e.Duration) * time.Millisecond; got != tt.wantDuration {
+				t.Errorf("duration = %s, want %s", got, tt.wantDuration)
+			}
+			if *e.EndTime != e.Timestamp+*e.Duration {
+				t.Errorf("end time %d doesn't match the clamped duration", *e.EndTime)
+			}
+		})
+	}
+}
+
+func TestFutureSessionDropped(t *testing.T) {
+	ts := newTestService(t, "http://unused")
+	now := time.Now()
+
+	events := endSessions(t, ts,
+		appSession("code.exe", "main.go", now),
+		appSession("code.exe", "later.go", now.Add(time.Hour)),
+	)
+	if len(events) != 1 || *events[0].Title != "main.go" {
+		t.Fatalf("got %d events, want only the current session", len(events))
+	}
+	if got := ts.metrics.EventsRejected.Value(); got != 1 {
+		t.Errorf("EventsRejected = %d, want 1", got)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// CategoryLeisure tags fullscreen sessions when FullscreenLeisure is set
+const CategoryLeisure = "leisure"
+
+// FullscreenAction is what happens to sessions in fullscreen windows
+type FullscreenAction string
+
+const (
+	// FullscreenTrack treats fullscreen sessions like any other
+	FullscreenTrack FullscreenAction = ""
+	// FullscreenDrop discards fullscreen sessions
+	FullscreenDrop FullscreenAction = "drop"
+	// FullscreenLeisure keeps fullscreen sessions tagged with CategoryLeisure
+	FullscreenLeisure FullscreenAction = "leisure"
+)
+
+// SetFullscreenAction sets how fullscreen sessions are handled. An unknown
+// action returns an error and leaves fullscreen sessions tracked normally.
+func (ts *TrackingService) SetFullscreenAction(action string) error {
+	parsed := FullscreenAction(strings.ToLower(strings.TrimSpace(action)))
+	var err error
+	switch parsed {
+	case FullscreenTrack, FullscreenDrop, FullscreenLeisure:
+	default:
+		err = fmt.Errorf("unknown fullscreen action %q (must be empty, drop or leisure)", action)
+		parsed = FullscreenTrack
+	}
+
+	ts.mu.Lock()
+	ts.fullscreenAction = parsed
+	ts.mu.Unlock()
+	return err
+}
+
+// applyFullscreen applies the fullscreen action to an event from a
+// fullscreen session. It returns false if the event should be dropped.
+func (ts *TrackingService) applyFullscreen(event *models.TrackingEvent) bool {
+	ts.mu.RLock()
+	action := ts.fullscreenAction
+	ts.mu.RUnlock()
+
+	switch action {
+	case FullscreenDrop:
+		return false
+	case FullscreenLeisure:
+		category := CategoryLeisure
+		event.Category = &category
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// maxAccumulatedSpan is how long an accumulated session may run before a
+// heartbeat reports it anyway
+const maxAccumulatedSpan = 12 * time.Hour
+
+// SetHeartbeatInterval enables heartbeat events. When no event has been
+// produced for interval, the current session is reported up to now (and
+// continues from there), or, with no session, a status-only event carrying
+// the current activity state is sent. 0 disables. Must be called before Start.
+func (ts *TrackingService) SetHeartbeatInterval(interval time.Duration) {
+	ts.heartbeatInterval = interval
+}
+
+// startHeartbeat starts the heartbeat loop if enabled
+func (ts *TrackingService) startHeartbeat() {
+	if ts.heartbeatInterval <= 0 {
+		return
+	}
+	ts.lastEventAt.Store(time.Now().UnixNano())
+
+	ts.wg.Add(1)
+	go ts.heartbeatLoop()
+}
+
+// heartbeatLoop checks for silence often enough that a heartbeat is at most
+// a few seconds late
+func (ts *TrackingService) heartbeatLoop() {
+	defer ts.wg.Done()
+
+	check := ts.heartbeatInterval / 4
+	if check > 15*time.Second {
+		check = 15 * time.Second
+	}
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ts.heartbeat(time.Now())
+		case <-ts.stopChan:
+			return
+		}
+	}
+}
+
+// heartbeat emits a heartbeat if no event was produced for an interval.
+// Real events reset the timer through addEvent.
+func (ts *TrackingService) heartbeat(now time.Time) {
+	if now.Sub(time.Unix(0, ts.lastEventAt.Load())) < ts.heartbeatInterval {
+		return
+	}
+
+	ts.mu.RLock()
+	paused := ts.isPaused
+	state := ts.currentState
+	ts.mu.RUnlock()
+	if paused {
+		return
+	}
+
+	// Report the ongoing session so far; it goes through OnSessionEnd like
+	// any other session, so the time isn't counted twice. Accumulated
+	// sessions are only reported once they get long, so the backend still
+	// accepts their start time.
+	if ts.sessionManager.Accumulating() {
+		if session := ts.sessionManager.GetCurrentSession(); session != nil {
+			if now.Sub(session.StartTime) < maxAccumulatedSpan {
+				return
+			}
+		}
+	}
+	if ts.sessionManager.Checkpoint(now) {
+		ts.logger.Debug("Heartbeat reported the current session")
+		return
+	}
+
+	timestamp := now.UnixMilli()
+	ts.logger.Debug("Heartbeat with no current session", zap.String("state", string(state)))
+	ts.addEvent(models.TrackingEvent{
+		DeviceID:  ts.deviceID,
+		Timestamp: timestamp,
+		Status:    string(state),
+		StartTime: &timestamp,
+		EndTime:   &timestamp,
+	})
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// SetLivenessStore enables the last-seen-alive heartbeat. The agent records
+// a timestamp every interval; on the next start the gap since that timestamp
+// is reported as an offline event so the backend can tell "agent not
+// running" apart from idle time. Must be called before Start.
+func (ts *TrackingService) SetLivenessStore(store *repository.AgentStateRepository, interval time.Duration) {
+	ts.livenessStore = store
+	ts.livenessInterval = interval
+}
+
+// startLiveness emits the offline gap event (if any) and starts the heartbeat loop
+func (ts *TrackingService) startLiveness() {
+	if ts.livenessStore == nil || ts.livenessInterval <= 0 {
+		return
+	}
+
+	now := time.Now()
+	lastSeen, ok, err := ts.livenessStore.GetLastSeen()
+	if err != nil {
+		ts.logger.Warn("Failed to read last seen heartbeat", zap.Error(err))
+	} else if ok {
+		if event := ts.offlineGapEvent(lastSeen, now); event != nil {
+			ts.logger.Info("Agent was not running, emitting offline gap event",
+				zap.Time("last_seen", lastSeen),
+				zap.Duration("gap", now.Sub(lastSeen)),
+			)
+			ts.addEvent(*event)
+		}
+	}
+
+	ts.recordLastSeen(now)
+
+	ts.wg.Add(1)
+	go ts.livenessLoop()
+}
+
+// offlineGapEvent builds an offline event spanning lastSeen..now, or returns
+// nil when the gap is within the heartbeat tolerance
+func (ts *TrackingService) offlineGapEvent(lastSeen, now time.Time) *models.TrackingEvent {
+	// Allow one missed heartbeat before calling it a gap
+	if now.Sub(lastSeen) <= 2*ts.livenessInterval {
+		return nil
+	}
+
+	duration := now.Sub(lastSeen).Milliseconds()
+	startTime := lastSeen.UnixMilli()
+	endTime := now.UnixMilli()
+
+	return &models.TrackingEvent{
+		DeviceID:  ts.deviceID,
+		Timestamp: startTime,
+		Status:    models.StatusOffline,
+		Duration:  &duration,
+		StartTime: &startTime,
+		EndTime:   &endTime,
+	}
+}
+
+// livenessLoop periodically records that the agent is alive
+func (ts *TrackingService) livenessLoop() {
+	defer ts.wg.Done()
+
+	ticker := time.NewTicker(ts.livenessInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ts.recordLastSeen(time.Now())
+		case <-ts.stopChan:
+			// Record a final heartbeat so the next start measures the gap from shutdown
+			ts.recordLastSeen(time.Now())
+			return
+		}
+	}
+}
+
+func (ts *TrackingService) recordLastSeen(t time.Time) {
+	if err := ts.livenessStore.SetLastSeen(t); err != nil {
+		ts.logger.Warn("Failed to record last seen heartbeat", zap.Error(err))
+	}
+}
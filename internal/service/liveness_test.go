@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/repository"
+)
+
+func TestStartEmitsOfflineGapSinceLastSeen(t *testing.T) {
+	ts := newTestService(t, "http://127.0.0.1:1")
+	store := repository.NewAgentStateRepository(ts.db.DB)
+	lastSeen := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	if err := store.SetLastSeen(lastSeen); err != nil {
+		t.Fatalf("SetLastSeen: %v", err)
+	}
+
+	sink := &recordingSink{}
+	ts.SetDryRun(sink)
+	ts.SetLivenessStore(store, time.Minute)
+	if err := ts.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	ts.Stop()
+
+	offline := eventsWithStatus(sink.Events(), models.StatusOffline)
+	if len(offline) != 1 {
+		t.Fatalf("got %d offline events, want 1: %+v", len(offline), sink.Events())
+	}
+	event := offline[0]
+	if event.Timestamp != lastSeen.UnixMilli() || *event.StartTime != lastSeen.UnixMilli() {
+		t.Fatalf("gap starts at %d, want last seen %d", event.Timestamp, lastSeen.UnixMilli())
+	}
+	if gap := time.Duration(*event.Duration) * time.Millisecond; gap < time.Hour || gap > time.Hour+time.Minute {
+		t.Fatalf("gap duration = %v, want about 1h", gap)
+	}
+
+	// Stop records a final heartbeat for the next start
+	seen, ok, err := store.GetLastSeen()
+	if err != nil || !ok || !seen.After(lastSeen) {
+		t.Fatalf("last seen after stop = %v, %v, %v", seen, ok, err)
+	}
+}
+
+func TestStartWithinHeartbeatToleranceEmitsNoGap(t *testing.T) {
+	ts := newTestService(t, "http://127.0.0.1:1")
+	store := repository.NewAgentStateRepository(ts.db.DB)
+	if err := store.SetLastSeen(time.Now().Add(-90 * time.Second)); err != nil {
+		t.Fatalf("SetLastSeen: %v", err)
+	}
+
+	sink := &recordingSink{}
+	ts.SetDryRun(sink)
+	ts.SetLivenessStore(store, time.Minute)
+	if err := ts.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	ts.Stop()
+
+	if offline := eventsWithStatus(sink.Events(), models.StatusOffline); len(offline) != 0 {
+		t.Fatalf("got offline events within one missed heartbeat: %+v", offline)
+	}
+}
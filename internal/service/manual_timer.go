@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrTimerRunning is returned when starting a timer while one runs
+	ErrTimerRunning = errors.New("a timer is already running")
+	// ErrNoTimerRunning is returned when stopping without a running timer
+	ErrNoTimerRunning = errors.New("no timer is running")
+)
+
+// ManualTimer lets the user explicitly time a task alongside automatic
+// tracking. Stopping the timer records a time entry.
+type ManualTimer struct {
+	entries *TimeEntryService
+	userID  string
+	logger  *zap.Logger
+
+	mu      sync.Mutex
+	running *models.RunningTimer
+}
+
+// NewManualTimer creates a timer that records entries for userID
+func NewManualTimer(entries *TimeEntryService, userID string, logger *zap.Logger) *ManualTimer {
+	return &ManualTimer{
+		entries: entries,
+		userID:  userID,
+		logger:  logger,
+	}
+}
+
+// Start starts the timer at at. It returns ErrTimerRunning if a timer is
+// already running.
+func (t *ManualTimer) Start(req *models.StartTimerRequest, at time.Time) (*models.RunningTimer, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running != nil {
+		return nil, ErrTimerRunning
+	}
+	t.running = &models.RunningTimer{
+		Description: req.Description,
+		ProjectID:   req.ProjectID,
+		StartTime:   at,
+	}
+
+	t.logger.Info("Manual timer started", zap.Time("start_time", at))
+	running := *t.running
+	return &running, nil
+}
+
+// Stop stops the timer at at and records the time entry. It returns
+// ErrNoTimerRunning if no timer is running. If the entry can't be saved the
+// timer keeps running so the time isn't lost; Discard clears it if the
+// entry can never be saved, e.g. because another entry now overlaps it.
+func (t *ManualTimer) Stop(at time.Time) (*models.TimeEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running == nil {
+		return nil, ErrNoTimerRunning
+	}
+	if at.Before(t.running.StartTime) {
+		at = t.running.StartTime
+	}
+
+	entry, err := t.entries.CreateTimeEntry(&models.CreateTimeEntryRequest{
+		UserID:      t.userID,
+		ProjectID:   t.running.ProjectID,
+		Description: t.running.Description,
+		StartTime:   t.running.StartTime,
+		EndTime:     &at,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record timer entry: %w", err)
+	}
+	t.running = nil
+
+	t.logger.Info("Manual timer stopped",
+		zap.Int64("entry_id", entry.ID),
+		zap.Duration("duration", at.Sub(entry.StartTime)),
+	)
+	return entry, nil
+}
+
+// Discard stops the timer without recording an entry and returns what was
+// running. It returns ErrNoTimerRunning if no timer is running.
+func (t *ManualTimer) Discard() (*models.RunningTimer, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running == nil {
+		return nil, ErrNoTimerRunning
+	}
+	discarded := *t.running
+	t.running = nil
+
+	t.logger.Info("Manual timer discarded", zap.Time("start_time", discarded.StartTime))
+	return &discarded, nil
+}
+
+// Current returns the running timer, or nil
+func (t *ManualTimer) Current() *models.RunningTimer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running == nil {
+		return nil
+	}
+	running := *t.running
+	return &running
+}
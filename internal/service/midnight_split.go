@@ -0,0 +1,68 @@
+package service
+
+import (
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// SetSplitAtMidnight controls whether events spanning device-local midnight
+// are split into one event per day
+func (ts *TrackingService) SetSplitAtMidnight(enabled bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.splitAtMidnight = enabled
+}
+
+// splitEventAtMidnight splits an event whose [StartTime, EndTime) straddles
+// one or more local midnights into consecutive per-day events. Events that
+// fit within a single day (or lack start/end times) are returned unchanged.
+// If the event's duration is shorter than its span (idle time was left out),
+// the duration is shared out in proportion to each day's span.
+func splitEventAtMidnight(event models.TrackingEvent, loc *time.Location) []models.TrackingEvent {
+	if event.StartTime == nil || event.EndTime == nil {
+		return []models.TrackingEvent{event}
+	}
+
+	start := time.UnixMilli(*event.StartTime).In(loc)
+	end := time.UnixMilli(*event.EndTime).In(loc)
+	if !end.After(start) {
+		return []models.TrackingEvent{event}
+	}
+
+	span := end.Sub(start).Milliseconds()
+	total := span
+	if event.Duration != nil && *event.Duration < span {
+		total = *event.Duration
+	}
+
+	var parts []models.TrackingEvent
+	allotted := int64(0)
+	for segStart := start; segStart.Before(end); {
+		year, month, day := segStart.Date()
+		nextMidnight := time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+
+		segEnd := end
+		if nextMidnight.Before(end) {
+			segEnd = nextMidnight
+		}
+
+		part := event
+		startMs := segStart.UnixMilli()
+		endMs := segEnd.UnixMilli()
+		duration := total * (endMs - startMs) / span
+		if segEnd.Equal(end) {
+			duration = total - allotted // rounding remainder goes to the last day
+		}
+		allotted += duration
+		part.Timestamp = startMs
+		part.StartTime = &startMs
+		part.EndTime = &endMs
+		part.Duration = &duration
+		parts = append(parts, part)
+
+		segStart = segEnd
+	}
+
+	return parts
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// spanEvent returns an active event from start to end with the given
+// duration
+func spanEvent(start, end time.Time, duration time.Duration) models.TrackingEvent {
+	startMs, endMs := start.UnixMilli(), end.UnixMilli()
+	durationMs := duration.Milliseconds()
+	return models.TrackingEvent{
+		DeviceID:  "test-device",
+		Timestamp: startMs,
+		Status:    models.StatusActive,
+		StartTime: &startMs,
+		EndTime:   &endMs,
+		Duration:  &durationMs,
+	}
+}
+
+// wantPart is the expected span and duration of one split part
+type wantPart struct {
+	start, end time.Time
+	duration   time.Duration
+}
+
+func checkParts(t *testing.T, parts []models.TrackingEvent, want []wantPart) {
+	t.Helper()
+	if len(parts) != len(want) {
+		t.Fatalf("got %d parts, want %d", len(parts), len(want))
+	}
+	for i, part := range parts {
+		w := want[i]
+		if *part.StartTime != w.start.UnixMilli() || part.Timestamp != w.start.UnixMilli() {
+			t.Errorf("part %d starts at %v, want %v", i, time.UnixMilli(*part.StartTime), w.start)
+		}
+		if *part.EndTime != w.end.UnixMilli() {
+			t.Errorf("part %d ends at %v, want %v", i, time.UnixMilli(*part.EndTime), w.end)
+		}
+		if got := time.Duration(*part.Duration) * time.Millisecond; got != w.duration {
+			t.Errorf("part %d duration = %v, want %v", i, got, w.duration)
+		}
+	}
+}
+
+func TestSplitEventAtMidnight(t *testing.T) {
+	loc := time.FixedZone("UTC+5", 5*60*60)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, loc)
+	}
+
+	t.Run("same day", func(t *testing.T) {
+		parts := splitEventAtMidnight(spanEvent(at(1, 9, 0), at(1, 17, 0), 8*time.Hour), loc)
+		checkParts(t, parts, []wantPart{{at(1, 9, 0), at(1, 17, 0), 8 * time.Hour}})
+	})
+
+	t.Run("ends at midnight", func(t *testing.T) {
+		parts := splitEventAtMidnight(spanEvent(at(1, 23, 0), at(2, 0, 0), time.Hour), loc)
+		checkParts(t, parts, []wantPart{{at(1, 23, 0), at(2, 0, 0), time.Hour}})
+	})
+
+	t.Run("one midnight", func(t *testing.T) {
+		parts := splitEventAtMidnight(spanEvent(at(1, 23, 0), at(2, 1, 30), 150*time.Minute), loc)
+		checkParts(t, parts, []wantPart{
+			{at(1, 23, 0), at(2, 0, 0), time.Hour},
+			{at(2, 0, 0), at(2, 1, 30), 90 * time.Minute},
+		})
+	})
+
+	t.Run("several midnights", func(t *testing.T) {
+		parts := splitEventAtMidnight(spanEvent(at(1, 22, 0), at(4, 2, 0), 52*time.Hour), loc)
+		checkParts(t, parts, []wantPart{
+			{at(1, 22, 0), at(2, 0, 0), 2 * time.Hour},
+			{at(2, 0, 0), at(3, 0, 0), 24 * time.Hour},
+			{at(3, 0, 0), at(4, 0, 0), 24 * time.Hour},
+			{at(4, 0, 0), at(4, 2, 0), 2 * time.Hour},
+		})
+	})
+
+	t.Run("idle time shared in proportion", func(t *testing.T) {
+		// 90 minute span with 30 minutes idle: 60 active minutes split 1:2
+		parts := splitEventAtMidnight(spanEvent(at(1, 23, 30), at(2, 1, 0), time.Hour), loc)
+		checkParts(t, parts, []wantPart{
+			{at(1, 23, 30), at(2, 0, 0), 20 * time.Minute},
+			{at(2, 0, 0), at(2, 1, 0), 40 * time.Minute},
+		})
+	})
+
+	t.Run("rounding remainder goes to the last day", func(t *testing.T) {
+		event := spanEvent(at(1, 23, 0), at(3, 1, 0), 0)
+		odd := int64(1001)
+		event.Duration = &odd
+		parts := splitEventAtMidnight(event, loc)
+		total := int64(0)
+		for _, part := range parts {
+			total += *part.Duration
+		}
+		if total != odd {
+			t.Fatalf("split durations sum to %d, want %d", total, odd)
+		}
+	})
+
+	t.Run("missing times", func(t *testing.T) {
+		event := models.TrackingEvent{Timestamp: at(1, 23, 0).UnixMilli(), Status: models.StatusActive}
+		if parts := splitEventAtMidnight(event, loc); len(parts) != 1 {
+			t.Fatalf("got %d parts for an event without start and end, want 1", len(parts))
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// powerPollInterval is how often the power state is sampled
+	powerPollInterval = 60 * time.Second
+	// powerRestampInterval re-stamps an unchanged power state so long
+	// stretches on the same source are still visible in the data
+	powerRestampInterval = 15 * time.Minute
+)
+
+// SetIncludePower enables stamping AC/battery state onto events. Only the
+// first event after the state changes (or after powerRestampInterval) carries
+// the fields. Must be called before Start.
+func (ts *TrackingService) SetIncludePower(enabled bool) {
+	ts.includePower = enabled
+}
+
+// startPowerMonitor samples the power state and starts the polling loop
+func (ts *TrackingService) startPowerMonitor() {
+	if !ts.includePower {
+		return
+	}
+
+	ts.pollPower()
+
+	ts.wg.Add(1)
+	go ts.powerLoop()
+}
+
+// powerLoop periodically samples the power state
+func (ts *TrackingService) powerLoop() {
+	defer ts.wg.Done()
+
+	ticker := time.NewTicker(powerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ts.pollPower()
+		case <-ts.stopChan:
+			return
+		}
+	}
+}
+
+// pollPower samples the power state and marks it for stamping when it changed
+// or hasn't been reported for a while
+func (ts *TrackingService) pollPower() {
+	status, err := ts.platform.GetPowerStatus()
+	if err != nil {
+		ts.logger.Debug("Failed to get power status", zap.Error(err))
+		return
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	changed := ts.powerStatus == nil || *ts.powerStatus != *status
+	if changed {
+		ts.logger.Debug("Power status changed",
+			zap.Bool("on_ac_power", status.OnACPower),
+			zap.Int("battery_percent", status.BatteryPercent),
+		)
+	}
+	ts.powerStatus = status
+	if changed || time.Since(ts.powerStampedAt) >= powerRestampInterval {
+		ts.powerPending = true
+	}
+}
+
+// stampPower adds the pending power state to event, if any
+func (ts *TrackingService) stampPower(event *models.TrackingEvent) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if !ts.powerPending || ts.powerStatus == nil {
+		return
+	}
+
+	onBattery := !ts.powerStatus.OnACPower
+	event.OnBattery = &onBattery
+	if ts.powerStatus.BatteryPercent >= 0 {
+		level := ts.powerStatus.BatteryPercent
+		event.BatteryLevel = &level
+	}
+
+	ts.powerPending = false
+	ts.powerStampedAt = time.Now()
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+)
+
+// stampedPower polls the power state and returns the power fields stamped
+// onto a fresh event
+func stampedPower(ts *testService) (onBattery *bool, level *int) {
+	ts.pollPower()
+	var event models.TrackingEvent
+	ts.stampPower(&event)
+	return event.OnBattery, event.BatteryLevel
+}
+
+func TestPowerStampedOnlyOnChange(t *testing.T) {
+	ts := newTestService(t, "http://unused")
+	ts.SetIncludePower(true)
+
+	ts.platform.SetPowerStatus(platform.PowerStatus{OnACPower: true, BatteryPercent: 80})
+	onBattery, level := stampedPower(ts)
+	if onBattery == nil || *onBattery || level == nil || *level != 80 {
+		t.Fatalf("first event: on_battery=%v level=%v, want false and 80", onBattery, level)
+	}
+
+	if onBattery, level := stampedPower(ts); onBattery != nil || level != nil {
+		t.Errorf("unchanged status stamped again: on_battery=%v level=%v", onBattery, level)
+	}
+
+	ts.platform.SetPowerStatus(platform.PowerStatus{OnACPower: false, BatteryPercent: 79})
+	onBattery, level = stampedPower(ts)
+	if onBattery == nil || !*onBattery || level == nil || *level != 79 {
+		t.Fatalf("after unplugging: on_battery=%v level=%v, want true and 79", onBattery, level)
+	}
+
+	if onBattery, _ := stampedPower(ts); onBattery != nil {
+		t.Error("unchanged status stamped again after unplugging")
+	}
+}
+
+func TestPowerRestampedAfterInterval(t *testing.T) {
+	ts := newTestService(t, "http://unused")
+	ts.SetIncludePower(true)
+	ts.platform.SetPowerStatus(platform.PowerStatus{OnACPower: true, BatteryPercent: -1})
+
+	if onBattery, level := stampedPower(ts); onBattery == nil || level != nil {
+		t.Fatalf("first event: on_battery=%v level=%v, want on_battery without a level", onBattery, level)
+	}
+
+	ts.powerStampedAt = time.Now().Add(-powerRestampInterval)
+	if onBattery, _ := stampedPower(ts); onBattery == nil {
+		t.Error("unchanged status not restamped after powerRestampInterval")
+	}
+}
+
+func TestPowerNotStampedWhenDisabled(t *testing.T) {
+	ts := newTestService(t, "http://unused")
+	ts.platform.SetPowerStatus(platform.PowerStatus{OnACPower: false, BatteryPercent: 50})
+
+	ts.startPowerMonitor()
+	var event models.TrackingEvent
+	ts.stampPower(&event)
+	if event.OnBattery != nil || event.BatteryLevel != nil {
+		t.Error("power stamped with SetIncludePower off")
+	}
+}
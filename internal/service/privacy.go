@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// redactedPlaceholder replaces the title and URL of redacted events
+const redactedPlaceholder = "[redacted]"
+
+// PrivacyAction is what happens to events matching the privacy blocklist
+type PrivacyAction string
+
+const (
+	// PrivacyRedact keeps the event (status, duration, application) but
+	// replaces its title and URL
+	PrivacyRedact PrivacyAction = "redact"
+	// PrivacyDrop discards the event entirely
+	PrivacyDrop PrivacyAction = "drop"
+)
+
+// PrivacyFilter matches events against application and domain blocklists.
+// Patterns are case-insensitive; patterns containing *, ? or [ are globs,
+// anything else matches as a substring.
+type PrivacyFilter struct {
+	action       PrivacyAction
+	applications []string
+	domains      []string
+}
+
+// NewPrivacyFilter creates a privacy filter. An empty action means redact.
+func NewPrivacyFilter(action string, applications, domains []string) (*PrivacyFilter, error) {
+	f := &PrivacyFilter{action: PrivacyAction(strings.ToLower(action))}
+	switch f.action {
+	case "":
+		f.action = PrivacyRedact
+	case PrivacyRedact, PrivacyDrop:
+	default:
+		return nil, fmt.Errorf("unknown privacy action %q (must be redact or drop)", action)
+	}
+
+	var err error
+	if f.applications, err = normalizePatterns(applications); err != nil {
+		return nil, err
+	}
+	if f.domains, err = normalizePatterns(domains); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func normalizePatterns(patterns []string) ([]string, error) {
+	normalized := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		normalized = append(normalized, p)
+	}
+	return normalized, nil
+}
+
+// Matches reports whether the event's application or URL host is blocklisted
+func (f *PrivacyFilter) Matches(event *models.TrackingEvent) bool {
+	if event.Application != nil && matchAny(f.applications, strings.ToLower(*event.Application)) {
+		return true
+	}
+	if event.URL != nil && len(f.domains) > 0 {
+		if u, err := url.Parse(*event.URL); err == nil && matchAny(f.domains, strings.ToLower(u.Hostname())) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if strings.ContainsAny(p, "*?[") {
+			if ok, _ := path.Match(p, value); ok {
+				return true
+			}
+		} else if strings.Contains(value, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPrivacyFilter sets the privacy blocklist; nil disables it. Safe to call
+// while tracking, e.g. on config reload.
+func (ts *TrackingService) SetPrivacyFilter(filter *PrivacyFilter) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.privacyFilter = filter
+}
+
+// applyPrivacy redacts event in place if it matches the blocklist, and
+// reports whether the event should be kept
+func (ts *TrackingService) applyPrivacy(event *models.TrackingEvent) bool {
+	ts.mu.RLock()
+	filter := ts.privacyFilter
+	ts.mu.RUnlock()
+
+	if filter == nil || !filter.Matches(event) {
+		return true
+	}
+	if filter.action == PrivacyDrop {
+		return false
+	}
+
+	placeholder := redactedPlaceholder
+	if event.Title != nil {
+		event.Title = &placeholder
+	}
+	if event.URL != nil {
+		event.URL = &placeholder
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+func TestPrivacyFilterMatches(t *testing.T) {
+	filter, err := NewPrivacyFilter("", []string{"KeePass", "*therapy*.exe"}, []string{"bank", "*.health.example"})
+	if err != nil {
+		t.Fatalf("NewPrivacyFilter: %v", err)
+	}
+
+	app := func(name string) *models.TrackingEvent { return &models.TrackingEvent{Application: &name} }
+	page := func(rawURL string) *models.TrackingEvent { return &models.TrackingEvent{URL: &rawURL} }
+	tests := []struct {
+		name  string
+		event *models.TrackingEvent
+		want  bool
+	}{
+		{"app substring, any case", app("keepassxc.exe"), true},
+		{"app glob", app("MyTherapyApp.exe"), true},
+		{"app glob needs the whole name", app("therapy.exe.bak"), false},
+		{"other app", app("code.exe"), false},
+		{"domain substring", page("https://online.mybank.com/accounts"), true},
+		{"domain glob", page("https://portal.health.example/visit"), true},
+		{"domain glob is the host only", page("https://example.com/?q=x.health.example"), false},
+		{"path isn't matched", page("https://example.com/bank"), false},
+		{"other domain", page("https://github.com/"), false},
+		{"no application or url", &models.TrackingEvent{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Matches(tt.event); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPrivacyFilterRejectsBadConfig(t *testing.T) {
+	if _, err := NewPrivacyFilter("hide", nil, nil); err == nil {
+		t.Error("unknown action accepted")
+	}
+	if _, err := NewPrivacyFilter("drop", []string{"[unclosed"}, nil); err == nil {
+		t.Error("malformed glob accepted")
+	}
+}
+
+func TestPrivacyRedactsAppTitlesAndExtensionURLs(t *testing.T) {
+	ts := newTestService(t, "http://unused")
+	filter, err := NewPrivacyFilter("redact", []string{"keepass"}, []string{"mybank.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.SetPrivacyFilter(filter)
+
+	end := time.Now()
+	events := endSessions(t, ts,
+		appSession("KeePass.exe", "Passwords.kdbx - KeePass", end),
+		browserSession("https://online.mybank.com/accounts", "My accounts", end),
+		appSession("code.exe", "main.go", end),
+	)
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+
+	byApp := make(map[string]models.TrackingEvent)
+	for _, event := range events {
+		byApp[*event.Application] = event
+	}
+
+	vault := byApp["KeePass.exe"]
+	if vault.Title == nil || *vault.Title != redactedPlaceholder {
+		t.Errorf("app title = %v, want redacted", vault.Title)
+	}
+	if vault.Duration == nil || *vault.Duration != time.Minute.Milliseconds() || vault.Status != models.StatusActive {
+		t.Errorf("redacted app event lost its duration or status: %+v", vault)
+	}
+
+	bank := byApp["chrome"]
+	if bank.URL == nil || *bank.URL != redactedPlaceholder || bank.Title == nil || *bank.Title != redactedPlaceholder {
+		t.Errorf("browser event url=%v title=%v, want both redacted", bank.URL, bank.Title)
+	}
+	if bank.Duration == nil || *bank.Duration != time.Minute.Milliseconds() {
+		t.Errorf("redacted browser event lost its duration: %+v", bank)
+	}
+
+	if editor := byApp["code.exe"]; editor.Title == nil || *editor.Title != "main.go" {
+		t.Errorf("unmatched app title = %v, want it kept", editor.Title)
+	}
+}
+
+func TestPrivacyDropAndReload(t *testing.T) {
+	ts := newTestService(t, "http://unused")
+	filter, err := NewPrivacyFilter("drop", []string{"keepass"}, []string{"mybank.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.SetPrivacyFilter(filter)
+
+	sink := &recordingSink{}
+	ts.SetDryRun(sink)
+	if err := ts.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	endAll := func(end time.Time) {
+		ts.OnSessionEnd(appSession("KeePass.exe", "Passwords.kdbx", end))
+		ts.OnSessionEnd(browserSession("https://online.mybank.com/", "Bank", end))
+		ts.OnSessionEnd(appSession("code.exe", "main.go", end))
+	}
+
+	endAll(time.Now().Add(-time.Minute))
+	// Replacing the filter while running, as a reload does, applies to the
+	// next sessions
+	ts.SetPrivacyFilter(nil)
+	endAll(time.Now())
+	ts.Stop()
+
+	var apps []string
+	for _, event := range eventsWithStatus(sink.Events(), models.StatusActive) {
+		apps = append(apps, *event.Application)
+	}
+	want := []string{"code.exe", "KeePass.exe", "chrome", "code.exe"}
+	if len(apps) != len(want) {
+		t.Fatalf("got events for %v, want %v", apps, want)
+	}
+	for i := range want {
+		if apps[i] != want[i] {
+			t.Fatalf("got events for %v, want %v", apps, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// ProjectRule attributes events to Project. Each non-empty list must have a
+// matching pattern (same syntax as the privacy blocklist), so a rule can
+// require both an application and a title, e.g. an editor with a repo name
+// in its window title.
+type ProjectRule struct {
+	Project      string
+	Applications []string
+	Titles       []string
+	Domains      []string
+}
+
+// ProjectResolver maps events to a project ID using rules evaluated in order
+type ProjectResolver struct {
+	rules []ProjectRule
+}
+
+// NewProjectResolver creates a resolver from rules; the first matching rule wins
+func NewProjectResolver(rules []ProjectRule) (*ProjectResolver, error) {
+	r := &ProjectResolver{}
+	for _, rule := range rules {
+		if rule.Project == "" {
+			return nil, fmt.Errorf("project rule without a project")
+		}
+		applications, err := normalizePatterns(rule.Applications)
+		if err != nil {
+			return nil, fmt.Errorf("project %q: %w", rule.Project, err)
+		}
+		titles, err := normalizePatterns(rule.Titles)
+		if err != nil {
+			return nil, fmt.Errorf("project %q: %w", rule.Project, err)
+		}
+		domains, err := normalizePatterns(rule.Domains)
+		if err != nil {
+			return nil, fmt.Errorf("project %q: %w", rule.Project, err)
+		}
+		if len(applications) == 0 && len(titles) == 0 && len(domains) == 0 {
+			return nil, fmt.Errorf("project %q: rule has no applications, titles or domains", rule.Project)
+		}
+		r.rules = append(r.rules, ProjectRule{
+			Project:      rule.Project,
+			Applications: applications,
+			Titles:       titles,
+			Domains:      domains,
+		})
+	}
+	return r, nil
+}
+
+// Resolve returns the project of the first rule matching event, or nil
+func (r *ProjectResolver) Resolve(event *models.TrackingEvent) *string {
+	var application, title, host string
+	if event.Application != nil {
+		application = strings.ToLower(*event.Application)
+	}
+	if event.Title != nil {
+		title = strings.ToLower(*event.Title)
+	}
+	if event.URL != nil {
+		if u, err := url.Parse(*event.URL); err == nil {
+			host = strings.ToLower(u.Hostname())
+		}
+	}
+
+	for _, rule := range r.rules {
+		if (len(rule.Applications) == 0 || matchAny(rule.Applications, application)) &&
+			(len(rule.Titles) == 0 || matchAny(rule.Titles, title)) &&
+			(len(rule.Domains) == 0 || matchAny(rule.Domains, host)) {
+			project := rule.Project
+			return &project
+		}
+	}
+	return nil
+}
+
+// SetProjectResolver enables stamping a project ID onto events; nil disables
+// it. Safe to call while tracking, e.g. on config reload.
+func (ts *TrackingService) SetProjectResolver(resolver *ProjectResolver) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.projectResolver = resolver
+}
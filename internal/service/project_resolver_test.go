@@ -0,0 +1,99 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// projectEvent returns an event for the non-empty fields
+func projectEvent(application, title, rawURL string) *models.TrackingEvent {
+	event := &models.TrackingEvent{}
+	if application != "" {
+		event.Application = &application
+	}
+	if title != "" {
+		event.Title = &title
+	}
+	if rawURL != "" {
+		event.URL = &rawURL
+	}
+	return event
+}
+
+func TestProjectRulePrecedence(t *testing.T) {
+	resolver, err := NewProjectResolver([]ProjectRule{
+		// Specific rules come first: an editor on the agent repo
+		{Project: "agent", Applications: []string{"code.exe"}, Titles: []string{"time-tracking-agent"}},
+		{Project: "backend", Domains: []string{"api.example.com"}},
+		// Broader rules later only catch what the specific ones didn't
+		{Project: "dev", Applications: []string{"code.exe", "*term*"}},
+		{Project: "web", Domains: []string{"*.example.com", "example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("NewProjectResolver: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		event *models.TrackingEvent
+		want  string
+	}{
+		{"first rule needs both application and title", projectEvent("Code.exe", "main.go - Time-Tracking-Agent", ""), "agent"},
+		{"title alone isn't enough", projectEvent("notepad.exe", "time-tracking-agent notes", ""), ""},
+		{"application without the title falls through", projectEvent("code.exe", "other-repo", ""), "dev"},
+		{"earlier domain rule wins over a broader one", projectEvent("chrome", "", "https://api.example.com/v1"), "backend"},
+		{"broader domain rule", projectEvent("chrome", "", "https://docs.example.com/"), "web"},
+		{"domain is the host, not the path", projectEvent("chrome", "", "https://other.org/api.example.com"), ""},
+		{"glob application", projectEvent("WindowsTerminal.exe", "pwsh", ""), "dev"},
+		{"no match", projectEvent("slack.exe", "general", ""), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolver.Resolve(tt.event)
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("Resolve = %q, want no project", *got)
+				}
+				return
+			}
+			if got == nil || *got != tt.want {
+				t.Fatalf("Resolve = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewProjectResolverRejectsBadRules(t *testing.T) {
+	for name, rule := range map[string]ProjectRule{
+		"no project":   {Applications: []string{"code.exe"}},
+		"no criteria":  {Project: "empty", Titles: []string{"  "}},
+		"invalid glob": {Project: "bad", Domains: []string{"[example"}},
+	} {
+		if _, err := NewProjectResolver([]ProjectRule{rule}); err == nil {
+			t.Errorf("%s: rule accepted", name)
+		}
+	}
+}
+
+func TestProjectStampedOnEvents(t *testing.T) {
+	ts := newTestService(t, "http://unused")
+	resolver, err := NewProjectResolver([]ProjectRule{{Project: "agent", Applications: []string{"code.exe"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.SetProjectResolver(resolver)
+
+	now := time.Now()
+	events := endSessions(t, ts, appSession("code.exe", "main.go", now), appSession("slack.exe", "general", now.Add(time.Minute)))
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].ProjectID == nil || *events[0].ProjectID != "agent" {
+		t.Errorf("code.exe project = %v, want agent", events[0].ProjectID)
+	}
+	if events[1].ProjectID != nil {
+		t.Errorf("slack.exe project = %q, want none", *events[1].ProjectID)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxQueueProcessInterval caps the queue retry cadence while the backend is
+// unreachable
+const maxQueueProcessInterval = 15 * time.Minute
+
+// queueProbeTimeout bounds the health check made before retrying a batch
+// while offline
+const queueProbeTimeout = 5 * time.Second
+
+// queueBackoff tracks consecutive queue send failures. It is only used from
+// the queue processor goroutine.
+type queueBackoff struct {
+	failures int
+	failedAt time.Time // last failure, to notice sends that succeeded since
+}
+
+// offline reports whether the last queue attempt failed
+func (b *queueBackoff) offline() bool {
+	return b.failures > 0
+}
+
+// fail records a failed attempt and returns the wait before the next one:
+// the regular cadence doubled per consecutive failure, up to the cap
+func (b *queueBackoff) fail() time.Duration {
+	b.failures++
+	b.failedAt = time.Now()
+	return b.interval()
+}
+
+// interval returns the wait before the next attempt
+func (b *queueBackoff) interval() time.Duration {
+	interval := queueProcessInterval
+	for i := 1; i < b.failures && interval < maxQueueProcessInterval; i++ {
+		interval *= 2
+	}
+	return min(interval, maxQueueProcessInterval)
+}
+
+// reset returns to the regular cadence and reports whether it was backing off
+func (b *queueBackoff) reset() bool {
+	wasOffline := b.offline()
+	b.failures = 0
+	b.failedAt = time.Time{}
+	return wasOffline
+}
+
+// backendBackOnline reports whether the backend looks reachable again while
+// the queue is backing off: either a live batch was sent since the last
+// failure or the health check passes. It's much cheaper than a failed batch
+// attempt.
+func (ts *TrackingService) backendBackOnline(ctx context.Context) bool {
+	if lastSync := ts.lastSyncAt.Load(); lastSync != 0 && time.Unix(0, lastSync).After(ts.queueBackoff.failedAt) {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queueProbeTimeout)
+	defer cancel()
+	if err := ts.apiClient.HealthCheck(ctx); err != nil {
+		ts.logger.Debug("Backend still unreachable, skipping queued batch",
+			zap.Error(err),
+			zap.Int("consecutive_failures", ts.queueBackoff.failures),
+		)
+		return false
+	}
+	return true
+}
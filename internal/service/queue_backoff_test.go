@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueBackoffGrowsAndResets(t *testing.T) {
+	var b queueBackoff
+	if b.offline() || b.interval() != queueProcessInterval {
+		t.Fatalf("new backoff: offline %v, interval %s", b.offline(), b.interval())
+	}
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, maxQueueProcessInterval, maxQueueProcessInterval}
+	for i, w := range want {
+		if got := b.fail(); got != w {
+			t.Errorf("failure %d: wait %s, want %s", i+1, got, w)
+		}
+	}
+	if !b.offline() {
+		t.Error("not offline after failures")
+	}
+
+	if !b.reset() {
+		t.Error("reset after failures reported it wasn't backing off")
+	}
+	if b.offline() || b.interval() != queueProcessInterval || !b.failedAt.IsZero() {
+		t.Errorf("after reset: offline %v, interval %s, failedAt %v", b.offline(), b.interval(), b.failedAt)
+	}
+	if b.reset() {
+		t.Error("second reset reported it was backing off")
+	}
+	if got := b.fail(); got != queueProcessInterval {
+		t.Errorf("first failure after reset: wait %s, want %s", got, queueProcessInterval)
+	}
+}
+
+func TestProcessQueueBacksOffWhileBackendDown(t *testing.T) {
+	var up atomic.Bool
+	var batches atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			batches.Add(1)
+		}
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	ts := newTestService(t, backend.URL)
+	if err := ts.queue.Enqueue(ts.deviceID, testEvents(2)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	ctx := context.Background()
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		if got := ts.processQueue(ctx); got != want {
+			t.Fatalf("attempt %d while down: wait %s, want %s", i+1, got, want)
+		}
+	}
+	// Only the first attempt spent a batch; the rest were health checks
+	if got := batches.Load(); got != 1 {
+		t.Fatalf("%d batches sent while down, want 1", got)
+	}
+
+	up.Store(true)
+	// A fresh event is due at once, while the failed ones wait out their retry
+	fresh := testEvents(3)[2:]
+	fresh[0].EventID = "fresh"
+	if err := ts.queue.Enqueue(ts.deviceID, fresh); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if got := ts.processQueue(ctx); got != queueProcessInterval {
+		t.Fatalf("attempt after recovery: wait %s, want %s", got, queueProcessInterval)
+	}
+	if ts.queueBackoff.offline() {
+		t.Fatalf("still backing off after a successful send (%d failures)", ts.queueBackoff.failures)
+	}
+
+	// The next failure starts from the regular cadence again
+	up.Store(false)
+	if err := ts.queue.Enqueue(ts.deviceID, testEvents(4)[3:]); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if got := ts.processQueue(ctx); got != queueProcessInterval {
+		t.Fatalf("first failure after recovery: wait %s, want %s", got, queueProcessInterval)
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// backendCheckTTL is how long a backend health result is reused, so
+	// frequent readiness probes don't hit the backend each time
+	backendCheckTTL = 30 * time.Second
+	// backendCheckTimeout bounds a single backend health check
+	backendCheckTimeout = 5 * time.Second
+)
+
+// readiness caches the last backend health check
+type readiness struct {
+	maxBacklog int // pending queue events above which the agent is not ready; <= 0 disables
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	backend   error
+}
+
+// SetReadyMaxBacklog sets how many queued events the agent may hold before
+// Ready reports it as not ready; <= 0 ignores the backlog
+func (ts *TrackingService) SetReadyMaxBacklog(maxBacklog int) {
+	ts.readiness.mu.Lock()
+	defer ts.readiness.mu.Unlock()
+	ts.readiness.maxBacklog = maxBacklog
+}
+
+// Ready reports whether the agent is doing useful work: the backend is
+// reachable and the retry queue is not backing up. It returns the first
+// problem found, or nil when ready.
+func (ts *TrackingService) Ready(ctx context.Context) error {
+	ts.mu.RLock()
+	stopped := ts.stopped
+	ts.mu.RUnlock()
+	if stopped {
+		return fmt.Errorf("tracking service is stopped")
+	}
+
+	if ts.dryRun == nil {
+		if err := ts.backendHealth(ctx); err != nil {
+			return fmt.Errorf("backend unreachable: %w", err)
+		}
+	}
+
+	ts.readiness.mu.Lock()
+	maxBacklog := ts.readiness.maxBacklog
+	ts.readiness.mu.Unlock()
+	if maxBacklog > 0 {
+		pending, err := ts.eventQueue.GetPendingCount(ts.deviceID)
+		if err != nil {
+			return fmt.Errorf("failed to read queue backlog: %w", err)
+		}
+		if pending > maxBacklog {
+			return fmt.Errorf("queue backlog of %d events exceeds %d", pending, maxBacklog)
+		}
+	}
+
+	return nil
+}
+
+// backendHealth returns the cached backend health check, refreshing it when
+// older than backendCheckTTL
+func (ts *TrackingService) backendHealth(ctx context.Context) error {
+	ts.readiness.mu.Lock()
+	defer ts.readiness.mu.Unlock()
+
+	if !ts.readiness.checkedAt.IsZero() && time.Since(ts.readiness.checkedAt) < backendCheckTTL {
+		return ts.readiness.backend
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, backendCheckTimeout)
+	defer cancel()
+	err := ts.apiClient.HealthCheck(checkCtx)
+	// A probe that gave up says nothing about the backend, so don't let it
+	// fail the probes that follow
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	ts.readiness.backend = err
+	ts.readiness.checkedAt = time.Now()
+	return err
+}
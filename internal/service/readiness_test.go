@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReadyDoesNotCacheCancelledCheck(t *testing.T) {
+	var checks atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	ts := newTestService(t, backend.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ts.Ready(ctx); err == nil {
+		t.Fatal("Ready with a cancelled context reported ready")
+	}
+
+	if err := ts.Ready(context.Background()); err != nil {
+		t.Fatalf("Ready after a cancelled probe: %v, want the backend checked again", err)
+	}
+	if err := ts.Ready(context.Background()); err != nil {
+		t.Fatalf("Ready: %v", err)
+	}
+	if got := checks.Load(); got != 1 {
+		t.Fatalf("backend checked %d times, want the successful check cached", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"Mansoor88-6/time-tracking-agent/internal/logger"
 	"Mansoor88-6/time-tracking-agent/internal/models"
 
 	"go.uber.org/zap"
@@ -19,9 +20,17 @@ type ActiveSession struct {
 	WindowID      int       // 0 for non-browser
 	URL           string    // Empty for non-browser
 	Title         string    // Empty for non-browser
+	Fullscreen    bool      // App window covered the whole monitor
 	StartTime     time.Time
 	LastEventTime time.Time
 	Sequence      int // Last sequence number
+	// Inactive is time within the session the user spent idle or away; it
+	// is not credited to the session's duration
+	Inactive time.Duration
+	// started is time.Now() when the session (or its current checkpoint
+	// span) began. Unlike StartTime it carries a monotonic reading, which is
+	// what detects wall-clock jumps.
+	started time.Time
 }
 
 // SessionManager manages active sessions and processes events immediately
@@ -33,6 +42,15 @@ type SessionManager struct {
 	onSessionEnd   func(*ActiveSession) // Callback when session ends
 	stopChan       chan struct{}
 	inactivityTimeout time.Duration
+	// browserEventsEnabled is true while the browser extension server is
+	// running; otherwise browsers are tracked as regular applications
+	browserEventsEnabled bool
+	// inactiveSince is when the user last left the active state, zero while
+	// they are active
+	inactiveSince time.Time
+	// accumulate keeps a session open across idle periods instead of
+	// closing it on inactivity, see SetAccumulate
+	accumulate bool
 }
 
 // NewSessionManager creates a new session manager
@@ -96,7 +114,7 @@ func (sm *SessionManager) ProcessAppFocusEvent(event *models.AppFocusEvent) {
 	defer sm.mu.Unlock()
 
 	// Skip browser applications - browser events are authoritative
-	if sm.isBrowserApplication(event.Application) {
+	if sm.browserEventsEnabled && sm.isBrowserApplication(event.Application) {
 		sm.logger.Debug("Skipping app focus event for browser, waiting for browser event",
 			zap.String("application", event.Application),
 		)
@@ -107,6 +125,76 @@ func (sm *SessionManager) ProcessAppFocusEvent(event *models.AppFocusEvent) {
 	sm.handleAppFocusEventLocked(event, eventTime)
 }
 
+// SetBrowserEventsEnabled tells the session manager whether browser events
+// from the extension are available. While disabled, browser focus is tracked
+// from app focus events like any other application.
+func (sm *SessionManager) SetBrowserEventsEnabled(enabled bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.browserEventsEnabled = enabled
+}
+
+// SetUserActive records an activity state change at the given time. Time
+// between leaving and re-entering the active state is subtracted from the
+// sessions it overlaps.
+func (sm *SessionManager) SetUserActive(active bool, at time.Time) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if !active {
+		if sm.inactiveSince.IsZero() {
+			sm.inactiveSince = at
+		}
+		return
+	}
+	if sm.inactiveSince.IsZero() {
+		return
+	}
+	if sm.currentSession != nil {
+		sm.currentSession.Inactive += inactiveOverlap(sm.currentSession, sm.inactiveSince, at)
+	}
+	sm.inactiveSince = time.Time{}
+}
+
+// SetAccumulate makes a session last until the window changes: instead of
+// being closed after the inactivity timeout and restarted, it stays open and
+// the idle time is left out of its duration, so one event carries all the
+// active time spent in the window. Safe to call while running.
+func (sm *SessionManager) SetAccumulate(accumulate bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.accumulate = accumulate
+}
+
+// Accumulating reports whether SetAccumulate is on
+func (sm *SessionManager) Accumulating() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.accumulate
+}
+
+// Resume moves the current session's last event to at, after a system
+// sleep, so the sleep doesn't look like inactivity that ended the session
+func (sm *SessionManager) Resume(at time.Time) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.currentSession != nil && sm.currentSession.LastEventTime.Before(at) {
+		sm.currentSession.LastEventTime = at
+	}
+}
+
+// inactiveOverlap returns how much of the inactive period [since, until)
+// falls within the session, which started at StartTime
+func inactiveOverlap(session *ActiveSession, since, until time.Time) time.Duration {
+	if since.Before(session.StartTime) {
+		since = session.StartTime
+	}
+	if !until.After(since) {
+		return 0
+	}
+	return until.Sub(since)
+}
+
 // isBrowserApplication checks if an application is a browser
 func (sm *SessionManager) isBrowserApplication(application string) bool {
 	appLower := strings.ToLower(application)
@@ -160,10 +248,11 @@ func (sm *SessionManager) handleBrowserEventLocked(event *models.BrowserEvent, e
 			StartTime:     eventTime,
 			LastEventTime: eventTime,
 			Sequence:      event.Sequence,
+			started:       time.Now(),
 		}
 		sm.logger.Info("Started new browser session",
-			zap.String("url", event.URL),
-			zap.String("title", event.Title),
+			logger.URL("url", event.URL),
+			logger.Title("title", event.Title),
 			zap.Int("tabId", event.TabID),
 			zap.Int("windowId", event.WindowID),
 		)
@@ -192,19 +281,24 @@ func (sm *SessionManager) handleAppFocusEventLocked(event *models.AppFocusEvent,
 		if sm.currentSession.Source == "browser" ||
 			sm.currentSession.Application != event.Application ||
 			sm.currentSession.PID != event.PID ||
-			sm.currentSession.Title != event.Title {
+			sm.currentSession.Title != event.Title ||
+			sm.currentSession.Fullscreen != event.Fullscreen {
 			closeReason := "source_change"
 			if sm.currentSession.Source == "app" && 
 				sm.currentSession.Application == event.Application &&
 				sm.currentSession.PID == event.PID &&
 				sm.currentSession.Title != event.Title {
 				closeReason = "title_change"
+			} else if sm.currentSession.Source == "app" &&
+				sm.currentSession.Application == event.Application &&
+				sm.currentSession.PID == event.PID {
+				closeReason = "fullscreen_change"
 			}
 			sm.logger.Info("Closing app session before starting new one",
 				zap.String("reason", closeReason),
 				zap.String("application", sm.currentSession.Application),
-				zap.String("old_title", sm.currentSession.Title),
-				zap.String("new_title", event.Title),
+				logger.Title("old_title", sm.currentSession.Title),
+				logger.Title("new_title", event.Title),
 			)
 			sm.closeSessionLocked(sm.currentSession, closeTime)
 			sm.currentSession = nil
@@ -221,20 +315,22 @@ func (sm *SessionManager) handleAppFocusEventLocked(event *models.AppFocusEvent,
 			WindowID:      0,
 			URL:           "",
 			Title:         event.Title,
+			Fullscreen:    event.Fullscreen,
 			StartTime:     eventTime,
 			LastEventTime: eventTime,
 			Sequence:      event.Sequence,
+			started:       time.Now(),
 		}
 		sm.logger.Info("Started new app session",
 			zap.String("application", event.Application),
 			zap.Int("pid", event.PID),
-			zap.String("title", event.Title),
+			logger.Title("title", event.Title),
 			zap.Bool("title_empty", event.Title == ""),
 			zap.Int("title_length", len(event.Title)),
 		)
 		sm.logger.Debug("App session created with title details",
-			zap.String("session_title", sm.currentSession.Title),
-			zap.String("event_title", event.Title),
+			logger.Title("session_title", sm.currentSession.Title),
+			logger.Title("event_title", event.Title),
 			zap.Bool("titles_match", sm.currentSession.Title == event.Title),
 		)
 	} else {
@@ -255,8 +351,19 @@ func (sm *SessionManager) closeSessionLocked(session *ActiveSession, endTime tim
 func (sm *SessionManager) closeSession(session *ActiveSession, endTime time.Time) {
 	// Update LastEventTime to the actual end time before calculating duration
 	// This ensures OnSessionEnd calculates duration correctly even if no events
-	// occurred between session start and close
+	// occurred between session start and close. If the wall clock was stepped
+	// during the session the end is moved so the span matches real time.
+	endTime = sm.correctForClockJump(session, endTime)
 	session.LastEventTime = endTime
+
+	// A session closed while the user is still idle or away doesn't get
+	// credited with that time either
+	sm.mu.RLock()
+	inactiveSince := sm.inactiveSince
+	sm.mu.RUnlock()
+	if !inactiveSince.IsZero() {
+		session.Inactive += inactiveOverlap(session, inactiveSince, endTime)
+	}
 	
 	sm.logger.Debug("Closing session",
 		zap.String("source", session.Source),
@@ -264,6 +371,7 @@ func (sm *SessionManager) closeSession(session *ActiveSession, endTime time.Time
 		zap.Time("start_time", session.StartTime),
 		zap.Time("end_time", endTime),
 		zap.Duration("duration", endTime.Sub(session.StartTime)),
+		zap.Duration("inactive", session.Inactive),
 	)
 	
 	if sm.onSessionEnd != nil {
@@ -271,6 +379,28 @@ func (sm *SessionManager) closeSession(session *ActiveSession, endTime time.Time
 	}
 }
 
+// Checkpoint reports the current session up to now and continues it from
+// now, so a long stay in one window still produces events. Returns false if
+// there is no session to report.
+func (sm *SessionManager) Checkpoint(now time.Time) bool {
+	sm.mu.Lock()
+	if sm.currentSession == nil || !now.After(sm.currentSession.StartTime) {
+		sm.mu.Unlock()
+		return false
+	}
+
+	// LastEventTime is left alone so inactivity detection still sees the
+	// last real event
+	reported := *sm.currentSession
+	sm.currentSession.StartTime = now
+	sm.currentSession.Inactive = 0
+	sm.currentSession.started = time.Now()
+	sm.mu.Unlock()
+
+	sm.closeSession(&reported, now)
+	return true
+}
+
 // inactivityLoop periodically checks for inactive sessions and closes them
 func (sm *SessionManager) inactivityLoop() {
 	// Check every 5 seconds for inactivity
@@ -283,47 +413,51 @@ func (sm *SessionManager) inactivityLoop() {
 			if sm.inactivityTimeout <= 0 {
 				continue
 			}
-
-			sm.mu.Lock()
-			if sm.currentSession != nil {
-				now := time.Now()
-				inactiveFor := now.Sub(sm.currentSession.LastEventTime)
-				if inactiveFor > sm.inactivityTimeout {
-					session := sm.currentSession
-					// Calculate end time: session ended when activity stopped
-					// Use LastEventTime + inactivityTimeout to represent when the session actually ended
-					// This ensures non-zero duration even if LastEventTime == StartTime
-					endTime := session.LastEventTime.Add(sm.inactivityTimeout)
-					// Ensure endTime is at least StartTime + inactivityTimeout
-					if endTime.Before(session.StartTime) {
-						endTime = session.StartTime.Add(sm.inactivityTimeout)
-					}
-					// Cap endTime at now to avoid future timestamps
-					if endTime.After(now) {
-						endTime = now
-					}
-					sm.logger.Info("Closing session due to inactivity",
-						zap.String("source", session.Source),
-						zap.String("application", session.Application),
-						zap.String("title", session.Title),
-						zap.Time("start_time", session.StartTime),
-						zap.Time("last_event_time", session.LastEventTime),
-						zap.Time("end_time", endTime),
-						zap.Duration("inactive_for", inactiveFor),
-						zap.Duration("timeout", sm.inactivityTimeout),
-						zap.Duration("calculated_duration", endTime.Sub(session.StartTime)),
-					)
-					sm.closeSessionLocked(session, endTime)
-					sm.currentSession = nil
-				}
-			}
-			sm.mu.Unlock()
+			sm.closeIfInactive(time.Now())
 		case <-sm.stopChan:
 			return
 		}
 	}
 }
 
+// closeIfInactive closes the current session if it has had no events for
+// the inactivity timeout as of now. Accumulated sessions are left open.
+func (sm *SessionManager) closeIfInactive(now time.Time) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.currentSession != nil && !sm.accumulate {
+		inactiveFor := now.Sub(sm.currentSession.LastEventTime)
+		if inactiveFor > sm.inactivityTimeout {
+			session := sm.currentSession
+			// Calculate end time: session ended when activity stopped
+			// Use LastEventTime + inactivityTimeout to represent when the session actually ended
+			// This ensures non-zero duration even if LastEventTime == StartTime
+			endTime := session.LastEventTime.Add(sm.inactivityTimeout)
+			// Ensure endTime is at least StartTime + inactivityTimeout
+			if endTime.Before(session.StartTime) {
+				endTime = session.StartTime.Add(sm.inactivityTimeout)
+			}
+			// Cap endTime at now to avoid future timestamps
+			if endTime.After(now) {
+				endTime = now
+			}
+			sm.logger.Info("Closing session due to inactivity",
+				zap.String("source", session.Source),
+				zap.String("application", session.Application),
+				logger.Title("title", session.Title),
+				zap.Time("start_time", session.StartTime),
+				zap.Time("last_event_time", session.LastEventTime),
+				zap.Time("end_time", endTime),
+				zap.Duration("inactive_for", inactiveFor),
+				zap.Duration("timeout", sm.inactivityTimeout),
+				zap.Duration("calculated_duration", endTime.Sub(session.StartTime)),
+			)
+			sm.closeSessionLocked(session, endTime)
+			sm.currentSession = nil
+		}
+	}
+}
+
 // GetCurrentSession returns the current active session (for debugging/monitoring)
 func (sm *SessionManager) GetCurrentSession() *ActiveSession {
 	sm.mu.RLock()
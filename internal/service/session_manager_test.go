@@ -0,0 +1,180 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// endedSessions records the sessions a SessionManager closes
+type endedSessions struct {
+	mu       sync.Mutex
+	sessions []ActiveSession
+}
+
+func (e *endedSessions) record(session *ActiveSession) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sessions = append(e.sessions, *session)
+}
+
+func (e *endedSessions) All() []ActiveSession {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]ActiveSession(nil), e.sessions...)
+}
+
+func newTestSessionManager(t *testing.T) (*SessionManager, *endedSessions) {
+	t.Helper()
+	ended := &endedSessions{}
+	sm := NewSessionManager(ended.record, zap.NewNop(), 0)
+	t.Cleanup(sm.Stop)
+	return sm, ended
+}
+
+// focusApp reports application gaining focus at at
+func focusApp(sm *SessionManager, application, title string, at time.Time) {
+	sm.ProcessAppFocusEvent(&models.AppFocusEvent{
+		Type:        "APP_FOCUS",
+		Application: application,
+		PID:         100,
+		Title:       title,
+		Timestamp:   at.UnixMilli(),
+	})
+}
+
+func TestInactiveTimeLeftOutOfSession(t *testing.T) {
+	sm, ended := newTestSessionManager(t)
+	start := time.Now().Truncate(time.Millisecond)
+
+	focusApp(sm, "code.exe", "main.go", start)
+	// active 10m, away 5m, active 5m, idle 2m, active 8m
+	sm.SetUserActive(false, start.Add(10*time.Minute))
+	sm.SetUserActive(true, start.Add(15*time.Minute))
+	sm.SetUserActive(false, start.Add(20*time.Minute))
+	sm.SetUserActive(true, start.Add(22*time.Minute))
+	if !sm.Checkpoint(start.Add(30 * time.Minute)) {
+		t.Fatal("Checkpoint reported no session")
+	}
+
+	sessions := ended.All()
+	if len(sessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(sessions))
+	}
+	session := sessions[0]
+	if got := session.LastEventTime.Sub(session.StartTime); got != 30*time.Minute {
+		t.Errorf("span = %s, want 30m", got)
+	}
+	if session.Inactive != 7*time.Minute {
+		t.Errorf("Inactive = %s, want 7m", session.Inactive)
+	}
+}
+
+func TestInactiveTimeClippedToSession(t *testing.T) {
+	sm, ended := newTestSessionManager(t)
+	start := time.Now().Truncate(time.Millisecond)
+
+	// Away from before the session starts until 4m into it
+	sm.SetUserActive(false, start.Add(-time.Hour))
+	focusApp(sm, "code.exe", "main.go", start)
+	sm.SetUserActive(true, start.Add(4*time.Minute))
+	// Away again from 6m, still away when the checkpoint closes the span
+	sm.SetUserActive(false, start.Add(6*time.Minute))
+	sm.Checkpoint(start.Add(10 * time.Minute))
+	// The away period carries into the next span, clipped to its start
+	sm.SetUserActive(true, start.Add(12*time.Minute))
+	sm.Checkpoint(start.Add(20 * time.Minute))
+
+	sessions := ended.All()
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	if got := sessions[0].Inactive; got != 8*time.Minute {
+		t.Errorf("first span Inactive = %s, want 8m", got)
+	}
+	if got := sessions[1].Inactive; got != 2*time.Minute {
+		t.Errorf("second span Inactive = %s, want 2m", got)
+	}
+}
+
+func TestSessionDurationExcludesInactiveTime(t *testing.T) {
+	ts := newTestService(t, "http://unused")
+	session := appSession("code.exe", "main.go", time.Now())
+	session.Inactive = 20 * time.Second
+
+	events := endSessions(t, ts, session)
+	if len(events) != 1 || events[0].Duration == nil {
+		t.Fatalf("got %v, want one active event with a duration", events)
+	}
+	if got := *events[0].Duration; got != 40000 {
+		t.Errorf("duration = %dms, want 40000ms", got)
+	}
+}
+
+func TestAccumulateKeepsSessionOpenAcrossPolls(t *testing.T) {
+	ended := &endedSessions{}
+	sm := NewSessionManager(ended.record, zap.NewNop(), time.Minute)
+	t.Cleanup(sm.Stop)
+	sm.SetAccumulate(true)
+	start := time.Now().Add(-30 * time.Minute).Truncate(time.Millisecond)
+
+	// Polls of the same window, then 17m idle, then more polls
+	for _, at := range []time.Duration{0, time.Minute, 2 * time.Minute} {
+		focusApp(sm, "code.exe", "main.go", start.Add(at))
+	}
+	sm.SetUserActive(false, start.Add(3*time.Minute))
+	sm.closeIfInactive(start.Add(10 * time.Minute))
+	sm.SetUserActive(true, start.Add(20*time.Minute))
+	sm.closeIfInactive(start.Add(20 * time.Minute))
+	for _, at := range []time.Duration{21 * time.Minute, 25 * time.Minute} {
+		focusApp(sm, "code.exe", "main.go", start.Add(at))
+	}
+
+	if sessions := ended.All(); len(sessions) != 0 {
+		t.Fatalf("accumulated session closed early: %+v", sessions)
+	}
+	if session := sm.GetCurrentSession(); session == nil || !session.StartTime.Equal(start) {
+		t.Fatalf("current session = %+v, want the one started at %v", session, start)
+	}
+
+	// The window change ends it as one session without the idle time
+	focusApp(sm, "chrome.exe", "docs", time.Now())
+	sessions := ended.All()
+	if len(sessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(sessions))
+	}
+	if got := sessions[0]; !got.StartTime.Equal(start) || got.Inactive != 17*time.Minute {
+		t.Errorf("session started %v with %s inactive, want %v and 17m", got.StartTime, got.Inactive, start)
+	}
+}
+
+func TestInactivityClosesSessionWithoutAccumulate(t *testing.T) {
+	ended := &endedSessions{}
+	sm := NewSessionManager(ended.record, zap.NewNop(), time.Minute)
+	t.Cleanup(sm.Stop)
+	start := time.Now().Add(-30 * time.Minute).Truncate(time.Millisecond)
+
+	focusApp(sm, "code.exe", "main.go", start)
+	focusApp(sm, "code.exe", "main.go", start.Add(2*time.Minute))
+	sm.closeIfInactive(start.Add(2*time.Minute + 30*time.Second))
+	if sessions := ended.All(); len(sessions) != 0 {
+		t.Fatalf("session closed within the timeout: %+v", sessions)
+	}
+
+	sm.closeIfInactive(start.Add(20 * time.Minute))
+	sessions := ended.All()
+	if len(sessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(sessions))
+	}
+	// It ends a timeout after the last poll
+	if got := sessions[0].LastEventTime.Sub(start); got != 3*time.Minute {
+		t.Errorf("span = %s, want 3m", got)
+	}
+	if sm.GetCurrentSession() != nil {
+		t.Error("session still current after closing")
+	}
+}
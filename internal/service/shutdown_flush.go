@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// shutdownFlush makes a final attempt to deliver everything still pending:
+// first the collector's buffered events, then whatever is in the retry
+// queue. Both share one shutdownSendTimeout deadline; events not delivered
+// by then stay queued for the next run.
+func (ts *TrackingService) shutdownFlush() {
+	ts.mu.Lock()
+	timeout := ts.shutdownSendTimeout
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	ts.shutdownCtx = ctx
+	ts.mu.Unlock()
+	defer cancel()
+
+	// Sends started before shutdown are cut off at the same deadline
+	stopCancelSends := context.AfterFunc(ctx, ts.cancelSends)
+	defer stopCancelSends()
+
+	// Batches are sent with shutdownCtx and queued if they can't be sent;
+	// closing the sender waits for the workers to finish them
+	ts.eventCollector.Flush()
+	ts.closeSender()
+
+	if ts.dryRun != nil {
+		return
+	}
+	sent := ts.drainQueue(ctx)
+	if sent > 0 {
+		ts.logger.Info("Sent queued events on shutdown", zap.Int("event_count", sent))
+	}
+}
+
+// drainQueue sends queued batches until the queue is empty, a send fails or
+// ctx is done, and returns the number of events sent. Failed batches stay
+// queued without counting a retry.
+func (ts *TrackingService) drainQueue(ctx context.Context) int {
+	sent := 0
+	for ctx.Err() == nil {
+		events, ids, err := ts.eventQueue.Dequeue(ts.deviceID, ts.dequeueBatchSize)
+		if err != nil {
+			ts.logger.Warn("Failed to dequeue events on shutdown", zap.Error(err))
+			return sent
+		}
+		if len(events) == 0 {
+			return sent
+		}
+
+		if err := ts.sendBatch(ctx, events); err != nil {
+			ts.logger.Info("Leaving queued events for the next run",
+				zap.Error(err),
+			)
+			return sent
+		}
+		if err := ts.eventQueue.Remove(ids); err != nil {
+			ts.logger.Error("Failed to remove sent events from queue", zap.Error(err))
+			return sent
+		}
+		sent += len(events)
+	}
+	return sent
+}
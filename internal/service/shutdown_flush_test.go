@@ -0,0 +1,51 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStopQueuesFlushWhenBackendHangs(t *testing.T) {
+	var requests atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		requests.Add(1)
+		<-r.Context().Done()
+	}))
+	defer backend.Close()
+
+	const budget = 300 * time.Millisecond
+	ts := newTestService(t, backend.URL)
+	ts.SetShutdownSendTimeout(budget)
+	if err := ts.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	for _, event := range testEvents(3) {
+		ts.eventCollector.AddEvent(event)
+	}
+
+	start := time.Now()
+	ts.Stop()
+	elapsed := time.Since(start)
+
+	// The client's own timeout is 5s; only the shutdown budget can end the
+	// send this early
+	if elapsed > budget+time.Second {
+		t.Errorf("Stop took %s with a %s send budget", elapsed, budget)
+	}
+	if requests.Load() == 0 {
+		t.Error("final flush never tried the backend")
+	}
+
+	stats, err := ts.queue.GetStats(ts.deviceID)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.Pending != 3 {
+		t.Errorf("queue after shutdown has %d pending, want the 3 flushed events", stats.Pending)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+	"Mansoor88-6/time-tracking-agent/internal/tracker"
+
+	"go.uber.org/zap"
+)
+
+// startSleepMonitor subscribes to system sleep and resume if the platform
+// supports it
+func (ts *TrackingService) startSleepMonitor() {
+	monitor, ok := ts.platform.(platform.SleepMonitor)
+	if !ok {
+		ts.logger.Debug("Sleep detection not supported on this platform")
+		return
+	}
+	if err := monitor.StartSleepMonitoring(ts.onSleepChange); err != nil {
+		ts.logger.Warn("Sleep notifications unavailable", zap.Error(err))
+	}
+}
+
+// stopSleepMonitor unsubscribes from sleep and resume notifications
+func (ts *TrackingService) stopSleepMonitor() {
+	if monitor, ok := ts.platform.(platform.SleepMonitor); ok {
+		monitor.StopSleepMonitoring()
+	}
+}
+
+// onSleepChange handles a system sleep or resume at the given time. The
+// session so far is reported when the system sleeps and an offline event
+// marks the sleep; the sleep itself is left out of the session's duration.
+func (ts *TrackingService) onSleepChange(asleep bool, at time.Time) {
+	ts.mu.RLock()
+	stopped := ts.stopped
+	paused := ts.isPaused
+	ts.mu.RUnlock()
+	if stopped {
+		return
+	}
+
+	if !asleep {
+		ts.logger.Info("System resumed")
+		ts.sessionManager.Resume(at)
+		ts.activityTracker.SetAsleep(false)
+		return
+	}
+
+	ts.logger.Info("System going to sleep", zap.Time("at", at))
+	ts.sessionManager.SetUserActive(false, at)
+	ts.sessionManager.Checkpoint(at)
+	if !paused {
+		timestamp := at.UnixMilli()
+		ts.addEvent(models.TrackingEvent{
+			DeviceID:  ts.deviceID,
+			Timestamp: timestamp,
+			Status:    string(tracker.StateOffline),
+			StartTime: &timestamp,
+			EndTime:   &timestamp,
+		})
+	}
+
+	// Switches to offline, which also flushes the collector
+	ts.activityTracker.SetAsleep(true)
+}
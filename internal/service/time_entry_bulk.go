@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// BulkCreateTimeEntries validates and inserts reqs, e.g. when importing from
+// another tool. Each row is checked like CreateTimeEntry, including for
+// overlaps with earlier rows of the same import; invalid rows are reported
+// and skipped while the rest are saved.
+func (s *TimeEntryService) BulkCreateTimeEntries(reqs []*models.CreateTimeEntryRequest) *models.BulkCreateResponse {
+	resp := &models.BulkCreateResponse{Results: make([]*models.BulkCreateResult, len(reqs))}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var accepted []*models.CreateTimeEntryRequest
+	var acceptedIndex []int
+	for i, req := range reqs {
+		resp.Results[i] = &models.BulkCreateResult{Index: i}
+		err := validateCreate(req, now)
+		if err == nil {
+			err = s.checkBatchOverlap(req, accepted, acceptedIndex)
+		}
+		if err == nil {
+			err = s.checkOverlap(req.UserID, req.StartTime, req.EndTime, 0)
+		}
+		if err != nil {
+			resp.Results[i].Error = err.Error()
+			continue
+		}
+		accepted = append(accepted, req)
+		acceptedIndex = append(acceptedIndex, i)
+	}
+
+	created, errs := s.repo.BulkCreate(accepted)
+	for j, i := range acceptedIndex {
+		if errs[j] != nil {
+			resp.Results[i].Error = errs[j].Error()
+			continue
+		}
+		resp.Results[i].Entry = created[j]
+	}
+
+	for _, result := range resp.Results {
+		if result.Error == "" {
+			resp.Created++
+		} else {
+			resp.Failed++
+		}
+	}
+	return resp
+}
+
+// checkBatchOverlap applies the overlap policy to req against the rows
+// already accepted from the same import, whose input positions are
+// acceptedIndex. Callers hold s.mu.
+func (s *TimeEntryService) checkBatchOverlap(req *models.CreateTimeEntryRequest, accepted []*models.CreateTimeEntryRequest, acceptedIndex []int) error {
+	for i, other := range accepted {
+		if other.UserID != req.UserID || !intervalsOverlap(req.StartTime, req.EndTime, other.StartTime, other.EndTime) {
+			continue
+		}
+		if s.overlapPolicy == OverlapWarn {
+			s.logger.Warn("Importing time entry that overlaps another row of the import",
+				zap.String("user_id", req.UserID),
+				zap.Int("overlaps_row", acceptedIndex[i]),
+			)
+			return nil
+		}
+		return fmt.Errorf("%w (row %d of this import)", ErrOverlap, acceptedIndex[i])
+	}
+	return nil
+}
+
+// intervalsOverlap reports whether [aStart, aEnd) and [bStart, bEnd)
+// overlap; a nil end is still open
+func intervalsOverlap(aStart time.Time, aEnd *time.Time, bStart time.Time, bEnd *time.Time) bool {
+	return (aEnd == nil || bStart.Before(*aEnd)) && (bEnd == nil || aStart.Before(*bEnd))
+}
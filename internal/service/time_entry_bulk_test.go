@@ -0,0 +1,63 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+func TestBulkCreatePartialSuccess(t *testing.T) {
+	s := newTestTimeEntryService(t)
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	existing := createEntries(t, s, "user-1", base, 1)[0] // [09:00, 10:00)
+
+	row := func(userID string, start, end time.Duration) *models.CreateTimeEntryRequest {
+		endTime := base.Add(end)
+		return &models.CreateTimeEntryRequest{UserID: userID, StartTime: base.Add(start), EndTime: &endTime}
+	}
+	reqs := []*models.CreateTimeEntryRequest{
+		row("user-1", 2*time.Hour, 3*time.Hour),                // valid
+		row("user-1", 5*time.Hour, 4*time.Hour),                // ends before it starts
+		row("user-1", 2*time.Hour+30*time.Minute, 4*time.Hour), // overlaps row 0
+		row("user-1", 30*time.Minute, 90*time.Minute),          // overlaps the stored entry
+		row("", 6*time.Hour, 7*time.Hour),                      // no user
+		row("user-1", 3*time.Hour, 4*time.Hour),                // valid, adjacent to row 0
+	}
+
+	resp := s.BulkCreateTimeEntries(reqs)
+	if resp.Created != 2 || resp.Failed != 4 {
+		t.Fatalf("created %d, failed %d, want 2 and 4", resp.Created, resp.Failed)
+	}
+	wantErrs := []string{"", "end_time is before start_time", "row 0 of this import", "overlaps", "user_id is required", ""}
+	for i, result := range resp.Results {
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+		if wantErrs[i] == "" {
+			if result.Error != "" || result.Entry == nil {
+				t.Errorf("row %d: error %q, entry %v, want it created", i, result.Error, result.Entry)
+			}
+			continue
+		}
+		if result.Entry != nil || !strings.Contains(result.Error, wantErrs[i]) {
+			t.Errorf("row %d: error %q, entry %v, want an error containing %q", i, result.Error, result.Entry, wantErrs[i])
+		}
+	}
+
+	// The valid rows are stored alongside the existing entry, and nothing else
+	page, err := s.ListTimeEntries(&models.TimeEntryFilter{UserID: "user-1"}, 0, 0)
+	if err != nil {
+		t.Fatalf("ListTimeEntries: %v", err)
+	}
+	if page.Total != 3 {
+		t.Fatalf("stored %d entries, want 3", page.Total)
+	}
+	stored := map[int64]bool{existing.ID: true, resp.Results[0].Entry.ID: true, resp.Results[5].Entry.ID: true}
+	for _, entry := range page.Items {
+		if !stored[entry.ID] {
+			t.Errorf("unexpected entry %d stored", entry.ID)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// ErrOverlap is returned when a time entry would overlap another entry of
+// the same user and OverlapReject is set
+var ErrOverlap = errors.New("time entry overlaps an existing entry")
+
+// OverlapPolicy is what happens when a time entry overlaps another entry of
+// the same user
+type OverlapPolicy string
+
+const (
+	// OverlapReject refuses the entry with ErrOverlap
+	OverlapReject OverlapPolicy = ""
+	// OverlapWarn saves the entry and logs a warning
+	OverlapWarn OverlapPolicy = "warn"
+)
+
+// SetOverlapPolicy sets how overlapping entries are handled. An unknown
+// policy returns an error and leaves overlaps rejected.
+func (s *TimeEntryService) SetOverlapPolicy(policy string) error {
+	parsed := OverlapPolicy(strings.ToLower(strings.TrimSpace(policy)))
+	var err error
+	switch parsed {
+	case OverlapReject, OverlapWarn:
+	case "reject":
+		parsed = OverlapReject
+	default:
+		err = fmt.Errorf("unknown overlap policy %q (must be empty, reject or warn)", policy)
+		parsed = OverlapReject
+	}
+
+	s.mu.Lock()
+	s.overlapPolicy = parsed
+	s.mu.Unlock()
+	return err
+}
+
+// checkOverlap applies the overlap policy to an entry of userID spanning
+// [start, end). excludeID is the entry being updated, or 0. Callers hold s.mu.
+func (s *TimeEntryService) checkOverlap(userID string, start time.Time, end *time.Time, excludeID int64) error {
+	existing, err := s.repo.FindOverlapping(userID, start, end, excludeID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+
+	if s.overlapPolicy == OverlapWarn {
+		s.logger.Warn("Saving time entry that overlaps an existing entry",
+			zap.String("user_id", userID),
+			zap.Int64("overlaps_id", existing.ID),
+		)
+		return nil
+	}
+	return fmt.Errorf("%w (id %d)", ErrOverlap, existing.ID)
+}
+
+// overlapInterval returns the interval an update leaves the current entry with
+func overlapInterval(current *models.TimeEntry, update *models.UpdateTimeEntryRequest) (time.Time, *time.Time) {
+	start, end := current.StartTime, current.EndTime
+	if update.StartTime != nil {
+		start = *update.StartTime
+	}
+	if update.EndTime != nil {
+		end = update.EndTime
+	}
+	return start, end
+}
@@ -1,19 +1,41 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
 	"Mansoor88-6/time-tracking-agent/internal/models"
 	"Mansoor88-6/time-tracking-agent/internal/repository"
+
+	"go.uber.org/zap"
 )
 
 type TimeEntryService struct {
-	repo *repository.TimeEntryRepository
+	repo   *repository.TimeEntryRepository
+	logger *zap.Logger
+
+	mu            sync.Mutex // serializes writes so overlap checks see each other
+	overlapPolicy OverlapPolicy
 }
 
-func NewTimeEntryService(repo *repository.TimeEntryRepository) *TimeEntryService {
-	return &TimeEntryService{repo: repo}
+func NewTimeEntryService(repo *repository.TimeEntryRepository, logger *zap.Logger) *TimeEntryService {
+	return &TimeEntryService{repo: repo, logger: logger}
 }
 
 func (s *TimeEntryService) CreateTimeEntry(req *models.CreateTimeEntryRequest) (*models.TimeEntry, error) {
+	if err := validateCreate(req, time.Now()); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkOverlap(req.UserID, req.StartTime, req.EndTime, 0); err != nil {
+		return nil, err
+	}
 	return s.repo.Create(req)
 }
 
@@ -22,16 +44,115 @@ func (s *TimeEntryService) GetTimeEntry(id int64) (*models.TimeEntry, error) {
 }
 
 func (s *TimeEntryService) GetTimeEntriesByUser(userID string, limit, offset int) ([]*models.TimeEntry, error) {
+	limit, offset = pageBounds(limit, offset)
+	return s.repo.GetByUserID(userID, limit, offset)
+}
+
+// ErrInvalidRange is returned when a filter's end is before its start
+var ErrInvalidRange = errors.New("end must not be before start")
+
+// ListTimeEntries returns a page of the entries matching filter along with
+// the total count, for building pagination
+func (s *TimeEntryService) ListTimeEntries(filter *models.TimeEntryFilter, limit, offset int) (*models.TimeEntryPage, error) {
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return nil, ErrInvalidRange
+	}
+	limit, offset = pageBounds(limit, offset)
+	entries, err := s.repo.List(filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(filter)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []*models.TimeEntry{}
+	}
+	return &models.TimeEntryPage{
+		Items:  entries,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// ErrInvalidSummary is returned for an unknown summary grouping or running
+// entry mode
+var ErrInvalidSummary = errors.New("invalid summary request")
+
+// SummarizeTimeEntries totals the durations of the entries matching filter.
+// groupBy may contain "project" and "day"; running is "exclude" (the
+// default) to leave out entries without an end, or "now" to count them up
+// to the current time.
+func (s *TimeEntryService) SummarizeTimeEntries(filter *models.TimeEntryFilter, groupBy []string, running string) (*models.TimeEntrySummary, error) {
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return nil, ErrInvalidRange
+	}
+
+	var byProject, byDay bool
+	for _, group := range groupBy {
+		switch strings.ToLower(strings.TrimSpace(group)) {
+		case "project":
+			byProject = true
+		case "day":
+			byDay = true
+		case "":
+		default:
+			return nil, fmt.Errorf("%w: unknown group_by %q (must be project or day)", ErrInvalidSummary, group)
+		}
+	}
+
+	var runningUntil *time.Time
+	switch running {
+	case "", "exclude":
+	case "now":
+		now := time.Now()
+		runningUntil = &now
+	default:
+		return nil, fmt.Errorf("%w: unknown running mode %q (must be exclude or now)", ErrInvalidSummary, running)
+	}
+
+	groups, err := s.repo.Summarize(filter, byProject, byDay, runningUntil)
+	if err != nil {
+		return nil, err
+	}
+	summary := &models.TimeEntrySummary{Groups: []*models.TimeEntryTotal{}}
+	for _, group := range groups {
+		summary.Groups = append(summary.Groups, group)
+		summary.TotalSeconds += group.TotalSeconds
+	}
+	return summary, nil
+}
+
+// pageBounds applies the default page size and clamps a negative offset
+func pageBounds(limit, offset int) (int, int) {
 	if limit <= 0 {
 		limit = 50
 	}
 	if offset < 0 {
 		offset = 0
 	}
-	return s.repo.GetByUserID(userID, limit, offset)
+	return limit, offset
 }
 
 func (s *TimeEntryService) UpdateTimeEntry(id int64, req *models.UpdateTimeEntryRequest) (*models.TimeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.StartTime != nil || req.EndTime != nil {
+		current, err := s.repo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		start, end := overlapInterval(current, req)
+		if err := validateInterval(start, end, time.Now()); err != nil {
+			return nil, err
+		}
+		if err := s.checkOverlap(current.UserID, start, end, id); err != nil {
+			return nil, err
+		}
+	}
 	return s.repo.Update(id, req)
 }
 
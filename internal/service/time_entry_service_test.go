@@ -0,0 +1,139 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+func newTestTimeEntryService(t *testing.T) *TimeEntryService {
+	t.Helper()
+	return NewTimeEntryService(repository.NewTimeEntryRepository(newTestDB(t).DB), zap.NewNop())
+}
+
+// createEntries creates n one-hour entries for userID, an hour apart,
+// starting at start
+func createEntries(t *testing.T, s *TimeEntryService, userID string, start time.Time, n int) []*models.TimeEntry {
+	t.Helper()
+	entries := make([]*models.TimeEntry, n)
+	for i := range entries {
+		entryStart := start.Add(time.Duration(i) * time.Hour)
+		end := entryStart.Add(time.Hour)
+		entry, err := s.CreateTimeEntry(&models.CreateTimeEntryRequest{UserID: userID, StartTime: entryStart, EndTime: &end})
+		if err != nil {
+			t.Fatalf("CreateTimeEntry %d: %v", i, err)
+		}
+		entries[i] = entry
+	}
+	return entries
+}
+
+func TestListTimeEntriesPagination(t *testing.T) {
+	s := newTestTimeEntryService(t)
+	start := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	createEntries(t, s, "user-1", start, 7)
+	createEntries(t, s, "user-2", start, 2)
+
+	filter := &models.TimeEntryFilter{UserID: "user-1"}
+	seen := make(map[int64]bool)
+	var previous time.Time
+	for offset := 0; offset < 9; offset += 3 {
+		page, err := s.ListTimeEntries(filter, 3, offset)
+		if err != nil {
+			t.Fatalf("ListTimeEntries offset %d: %v", offset, err)
+		}
+		if page.Total != 7 || page.Limit != 3 || page.Offset != offset {
+			t.Fatalf("offset %d: total=%d limit=%d offset=%d, want total 7 on every page", offset, page.Total, page.Limit, page.Offset)
+		}
+		if want := min(3, 7-offset); len(page.Items) != want {
+			t.Fatalf("offset %d: %d items, want %d", offset, len(page.Items), want)
+		}
+		for _, entry := range page.Items {
+			if seen[entry.ID] {
+				t.Fatalf("entry %d on two pages", entry.ID)
+			}
+			seen[entry.ID] = true
+			if !previous.IsZero() && !entry.StartTime.Before(previous) {
+				t.Fatalf("entries not newest first: %v after %v", entry.StartTime, previous)
+			}
+			previous = entry.StartTime
+		}
+	}
+	if len(seen) != 7 {
+		t.Fatalf("pages held %d entries, want 7", len(seen))
+	}
+
+	// Past the end: no items, still the total
+	page, err := s.ListTimeEntries(filter, 3, 30)
+	if err != nil || len(page.Items) != 0 || page.Items == nil || page.Total != 7 {
+		t.Fatalf("page past the end = %+v, %v, want empty items and total 7", page, err)
+	}
+}
+
+func TestListTimeEntriesPageBounds(t *testing.T) {
+	s := newTestTimeEntryService(t)
+	createEntries(t, s, "user-1", time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC), 2)
+
+	page, err := s.ListTimeEntries(&models.TimeEntryFilter{UserID: "user-1"}, 0, -5)
+	if err != nil {
+		t.Fatalf("ListTimeEntries: %v", err)
+	}
+	if page.Limit != 50 || page.Offset != 0 || len(page.Items) != 2 {
+		t.Fatalf("page = limit %d offset %d items %d, want the defaults", page.Limit, page.Offset, len(page.Items))
+	}
+}
+
+func TestSummarizeEntriesSpanningDays(t *testing.T) {
+	s := newTestTimeEntryService(t)
+	create := func(start time.Time, duration time.Duration) {
+		t.Helper()
+		end := start.Add(duration)
+		if _, err := s.CreateTimeEntry(&models.CreateTimeEntryRequest{UserID: "user-1", StartTime: start, EndTime: &end}); err != nil {
+			t.Fatalf("CreateTimeEntry: %v", err)
+		}
+	}
+	day1 := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	create(day1.Add(22*time.Hour), 3*time.Hour) // 22:00 on day 1 until 01:00 on day 2
+	create(day1.Add(33*time.Hour), time.Hour)
+	// 01:00 on day 3 until the end of day 4, sent from a zone where it
+	// started on day 2
+	create(day1.Add(49*time.Hour).In(time.FixedZone("UTC-5", -5*3600)), 47*time.Hour)
+
+	summary, err := s.SummarizeTimeEntries(&models.TimeEntryFilter{UserID: "user-1"}, []string{"day"}, "")
+	if err != nil {
+		t.Fatalf("SummarizeTimeEntries: %v", err)
+	}
+
+	// Each entry counts in full towards the UTC day it started on
+	want := []models.TimeEntryTotal{
+		{Day: "2026-03-02", TotalSeconds: 3 * 3600, Entries: 1},
+		{Day: "2026-03-03", TotalSeconds: 3600, Entries: 1},
+		{Day: "2026-03-04", TotalSeconds: 47 * 3600, Entries: 1},
+	}
+	if len(summary.Groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(summary.Groups), len(want))
+	}
+	for i, group := range summary.Groups {
+		if *group != want[i] {
+			t.Errorf("group %d = %+v, want %+v", i, *group, want[i])
+		}
+	}
+	if summary.TotalSeconds != 51*3600 {
+		t.Errorf("TotalSeconds = %d, want %d", summary.TotalSeconds, 51*3600)
+	}
+
+	// A range starting on day 2 leaves out the entry that started on day 1,
+	// including its hours on day 2
+	from := day1.Add(24 * time.Hour)
+	summary, err = s.SummarizeTimeEntries(&models.TimeEntryFilter{UserID: "user-1", From: &from}, nil, "")
+	if err != nil {
+		t.Fatalf("SummarizeTimeEntries from day 2: %v", err)
+	}
+	if summary.TotalSeconds != 48*3600 {
+		t.Errorf("TotalSeconds from day 2 = %d, want %d", summary.TotalSeconds, 48*3600)
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// ErrInvalidTimeEntry is returned for a time entry with missing or
+// inconsistent fields
+var ErrInvalidTimeEntry = errors.New("invalid time entry")
+
+// maxClockSkew is how far in the future an entry's times may be, to allow
+// for clients whose clocks run slightly ahead
+const maxClockSkew = time.Minute
+
+// validateCreate checks the fields of a new entry
+func validateCreate(req *models.CreateTimeEntryRequest, now time.Time) error {
+	if strings.TrimSpace(req.UserID) == "" {
+		return fmt.Errorf("%w: user_id is required", ErrInvalidTimeEntry)
+	}
+	return validateInterval(req.StartTime, req.EndTime, now)
+}
+
+// validateInterval checks that an entry starts, doesn't end before it starts
+// and doesn't lie in the future
+func validateInterval(start time.Time, end *time.Time, now time.Time) error {
+	if start.IsZero() {
+		return fmt.Errorf("%w: start_time is required", ErrInvalidTimeEntry)
+	}
+	if start.After(now.Add(maxClockSkew)) {
+		return fmt.Errorf("%w: start_time is in the future", ErrInvalidTimeEntry)
+	}
+	if end == nil {
+		return nil
+	}
+	if end.Before(start) {
+		return fmt.Errorf("%w: end_time is before start_time", ErrInvalidTimeEntry)
+	}
+	if end.After(now.Add(maxClockSkew)) {
+		return fmt.Errorf("%w: end_time is in the future", ErrInvalidTimeEntry)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+func TestValidateCreate(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) *time.Time {
+		tm := now.Add(offset)
+		return &tm
+	}
+	tests := []struct {
+		name    string
+		req     models.CreateTimeEntryRequest
+		wantErr bool
+	}{
+		{"valid", models.CreateTimeEntryRequest{UserID: "user-1", StartTime: *at(-time.Hour), EndTime: at(0)}, false},
+		{"running", models.CreateTimeEntryRequest{UserID: "user-1", StartTime: *at(-time.Hour)}, false},
+		{"zero duration", models.CreateTimeEntryRequest{UserID: "user-1", StartTime: *at(-time.Hour), EndTime: at(-time.Hour)}, false},
+		{"negative duration", models.CreateTimeEntryRequest{UserID: "user-1", StartTime: *at(-time.Hour), EndTime: at(-time.Hour - time.Second)}, true},
+		{"missing user", models.CreateTimeEntryRequest{StartTime: *at(-time.Hour), EndTime: at(0)}, true},
+		{"blank user", models.CreateTimeEntryRequest{UserID: "  ", StartTime: *at(-time.Hour), EndTime: at(0)}, true},
+		{"missing start", models.CreateTimeEntryRequest{UserID: "user-1", EndTime: at(0)}, true},
+		{"start within skew", models.CreateTimeEntryRequest{UserID: "user-1", StartTime: *at(maxClockSkew)}, false},
+		{"start in the future", models.CreateTimeEntryRequest{UserID: "user-1", StartTime: *at(maxClockSkew + time.Second)}, true},
+		{"end in the future", models.CreateTimeEntryRequest{UserID: "user-1", StartTime: *at(-time.Hour), EndTime: at(time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCreate(&tt.req, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateCreate = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTimeEntry) {
+				t.Errorf("error %v is not ErrInvalidTimeEntry", err)
+			}
+		})
+	}
+}
+
+func TestCreateTimeEntryRejectsInvalidEntries(t *testing.T) {
+	s := newTestTimeEntryService(t)
+	start := time.Now().Add(-time.Hour)
+	before := start.Add(-time.Minute)
+
+	if _, err := s.CreateTimeEntry(&models.CreateTimeEntryRequest{UserID: "user-1", StartTime: start, EndTime: &before}); !errors.Is(err, ErrInvalidTimeEntry) {
+		t.Errorf("negative duration: got %v, want ErrInvalidTimeEntry", err)
+	}
+	if _, err := s.CreateTimeEntry(&models.CreateTimeEntryRequest{StartTime: start}); !errors.Is(err, ErrInvalidTimeEntry) {
+		t.Errorf("missing user: got %v, want ErrInvalidTimeEntry", err)
+	}
+	if page, err := s.ListTimeEntries(&models.TimeEntryFilter{UserID: "user-1"}, 0, 0); err != nil || page.Total != 0 {
+		t.Errorf("invalid entries stored: %v, %v", page, err)
+	}
+}
@@ -1,19 +1,37 @@
 package service
 
 import (
+	"context"
+	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"Mansoor88-6/time-tracking-agent/internal/automation"
 	"Mansoor88-6/time-tracking-agent/internal/client"
 	"Mansoor88-6/time-tracking-agent/internal/collector"
+	"Mansoor88-6/time-tracking-agent/internal/logger"
+	"Mansoor88-6/time-tracking-agent/internal/metrics"
 	"Mansoor88-6/time-tracking-agent/internal/models"
 	"Mansoor88-6/time-tracking-agent/internal/platform"
 	"Mansoor88-6/time-tracking-agent/internal/queue"
+	"Mansoor88-6/time-tracking-agent/internal/repository"
 	"Mansoor88-6/time-tracking-agent/internal/tracker"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// queueProcessInterval is how often queued events are retried by default
+const queueProcessInterval = 60 * time.Second
+
+// defaultDequeueBatchSize is how many queued events are retried per request
+const defaultDequeueBatchSize = 100
+
+// defaultShutdownSendTimeout bounds the final flush's send during Stop, after
+// which the events are queued for the next run
+const defaultShutdownSendTimeout = 3 * time.Second
+
 // TrackingService orchestrates all tracking components
 type TrackingService struct {
 	platform        platform.Platform
@@ -27,10 +45,48 @@ type TrackingService struct {
 	logger          *zap.Logger
 	
 	currentState     tracker.ActivityState
+	stateSince       time.Time // When currentState began, per the activity tracker
 	stopped          bool
 	isPaused         bool
 	mu               sync.RWMutex
 	appSequenceCounter int // Sequence counter for app focus events
+
+	livenessStore    *repository.AgentStateRepository
+	livenessInterval time.Duration
+	splitAtMidnight  bool
+
+	shutdownSendTimeout time.Duration
+	shutdownCtx         context.Context // bounds sends during the final flush
+
+	dequeueBatchSize int
+	queueBackoff     queueBackoff // owned by the queue processor goroutine
+
+	sendWorkers int
+	sendCh      chan pendingBatch // nil when sending inline
+	sendCtx     context.Context
+	cancelSends context.CancelFunc
+	senderWG    sync.WaitGroup
+
+	heartbeatInterval time.Duration
+	lastEventAt       atomic.Int64 // unix nanos of the last event handed to the collector
+
+	includePower   bool
+	powerStatus    *platform.PowerStatus
+	powerPending   bool
+	powerStampedAt time.Time
+
+	metrics    *metrics.Metrics
+	automation *automation.Engine
+	eventSink  EventSink
+	dryRun     EventSink // receives batches instead of the backend and queue
+	lastSyncAt atomic.Int64 // unix nanos of the last successful send, 0 if none
+
+	privacyFilter    *PrivacyFilter
+	categorizer      *Categorizer
+	projectResolver  *ProjectResolver
+	fullscreenAction FullscreenAction
+
+	readiness readiness
 	
 	stopChan         chan struct{}
 	wg               sync.WaitGroup
@@ -60,9 +116,41 @@ func NewTrackingService(
 		logger:        logger,
 		stopChan:      make(chan struct{}),
 		currentState:  tracker.StateActive,
+		shutdownSendTimeout: defaultShutdownSendTimeout,
+		sendWorkers:   defaultSendWorkers,
+		dequeueBatchSize: defaultDequeueBatchSize,
+		metrics:       metrics.New(),
+	}
+}
+
+// SetAutomation sets the rule engine that receives every collected event.
+// Must be called before Start.
+func (ts *TrackingService) SetAutomation(engine *automation.Engine) {
+	ts.automation = engine
+}
+
+// SetDequeueBatchSize sets how many queued events are retried per send.
+// Non-positive sizes keep the default. Must be called before Start.
+func (ts *TrackingService) SetDequeueBatchSize(size int) {
+	if size > 0 {
+		ts.dequeueBatchSize = size
 	}
 }
 
+// SetEventSink sets a sink that receives a copy of every batch as it
+// becomes ready, in addition to sending. Must be called before Start.
+func (ts *TrackingService) SetEventSink(sink EventSink) {
+	ts.eventSink = sink
+}
+
+// SetShutdownSendTimeout sets how long the final flush during Stop may spend
+// sending pending and queued events before leaving them queued instead
+func (ts *TrackingService) SetShutdownSendTimeout(timeout time.Duration) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.shutdownSendTimeout = timeout
+}
+
 // Start begins tracking
 func (ts *TrackingService) Start() error {
 	ts.logger.Info("Starting tracking service", zap.String("device_id", ts.deviceID))
@@ -78,12 +166,29 @@ func (ts *TrackingService) Start() error {
 		return err
 	}
 
+	// Start the sender workers before the collector hands them batches
+	ts.startSender()
+
 	// Start event collector
 	ts.eventCollector.Start(ts.onBatchReady)
 
-	// Start queue processor
-	ts.wg.Add(1)
-	go ts.queueProcessor()
+	// Report downtime since the last run and start the liveness heartbeat
+	ts.startLiveness()
+
+	// Sample AC/battery state for events if enabled
+	ts.startPowerMonitor()
+
+	// Go offline across system sleep where the platform reports it
+	ts.startSleepMonitor()
+
+	// Report long-running sessions periodically if enabled
+	ts.startHeartbeat()
+
+	// Start queue processor; a dry run never sends, so leaves the queue alone
+	if ts.dryRun == nil {
+		ts.wg.Add(1)
+		go ts.queueProcessor()
+	}
 
 	ts.logger.Info("Tracking service started")
 	return nil
@@ -109,6 +214,7 @@ func (ts *TrackingService) Stop() {
 	ts.sessionManager.Stop()
 	
 	// Stop activity tracker FIRST (removes Windows hooks immediately)
+	ts.stopSleepMonitor()
 	ts.activityTracker.Stop()
 	
 	// Stop window tracker
@@ -131,8 +237,9 @@ func (ts *TrackingService) Stop() {
 		ts.logger.Warn("Some goroutines did not stop within timeout")
 	}
 
-	// Flush any remaining events (but don't wait for send)
-	ts.eventCollector.Flush()
+	// Send remaining and queued events; bounded by shutdownSendTimeout, and
+	// anything that can't be delivered is queued for the next run
+	ts.shutdownFlush()
 
 	ts.logger.Info("Tracking service stopped")
 }
@@ -163,13 +270,14 @@ func (ts *TrackingService) onAppFocus(appFocus *tracker.AppFocusInfo) {
 		Application: appFocus.Application,
 		PID:         appFocus.PID,
 		Title:       appFocus.Title,
+		Fullscreen:  appFocus.Fullscreen,
 		Timestamp:   appFocus.Timestamp.UnixMilli(),
 		Sequence:    sequence,
 	}
 
 	ts.logger.Debug("Creating AppFocusEvent with title",
 		zap.String("application", appFocus.Application),
-		zap.String("title", appFocus.Title),
+		logger.Title("title", appFocus.Title),
 		zap.Bool("title_empty", appFocus.Title == ""),
 		zap.Int("sequence", sequence),
 	)
@@ -179,19 +287,30 @@ func (ts *TrackingService) onAppFocus(appFocus *tracker.AppFocusInfo) {
 }
 
 // onActivityStateChange handles activity state changes
-func (ts *TrackingService) onActivityStateChange(state tracker.ActivityState) {
+func (ts *TrackingService) onActivityStateChange(state tracker.ActivityState, at time.Time) {
 	ts.mu.Lock()
 	oldState := ts.currentState
 	ts.currentState = state
+	ts.stateSince = at
 	ts.mu.Unlock()
 
 	// Activity state changes don't create sessions, they're metadata
-	// Sessions are created by app focus and browser events
+	// Sessions are created by app focus and browser events, but idle and
+	// away time is left out of their duration
+	ts.sessionManager.SetUserActive(state == tracker.StateActive, at)
 	if oldState != state {
 		ts.logger.Debug("Activity state changed",
 			zap.String("old_state", string(oldState)),
 			zap.String("new_state", string(state)),
+			zap.Time("at", at),
 		)
+
+		// The user left or the machine is going down: send what's buffered
+		// now rather than at the next flush interval, which may be after a
+		// long sleep
+		if state == tracker.StateAway || state == tracker.StateOffline {
+			ts.eventCollector.Flush()
+		}
 	}
 }
 
@@ -213,8 +332,8 @@ func (ts *TrackingService) OnSessionEnd(session *ActiveSession) {
 		return
 	}
 
-	// Calculate duration
-	duration := session.LastEventTime.Sub(session.StartTime).Milliseconds()
+	// Calculate duration, leaving out time the user was idle or away
+	duration := (session.LastEventTime.Sub(session.StartTime) - session.Inactive).Milliseconds()
 	
 	// Apply minimum duration threshold (500ms) to avoid skipping very short but valid sessions
 	// This handles cases where app switching happens very quickly
@@ -277,7 +396,7 @@ func (ts *TrackingService) OnSessionEnd(session *ActiveSession) {
 			event.Title = &session.Title
 			ts.logger.Debug("Setting title for app event",
 				zap.String("application", session.Application),
-				zap.String("title", session.Title),
+				logger.Title("title", session.Title),
 				zap.Int("title_length", len(session.Title)),
 				)
 			} else {
@@ -296,6 +415,10 @@ func (ts *TrackingService) OnSessionEnd(session *ActiveSession) {
 	event.StartTime = &startTime
 	event.EndTime = &endTime
 
+	// Input rate over the session
+	intensity := string(ts.activityTracker.Intensity(session.StartTime))
+	event.Intensity = &intensity
+
 	eventTitleValue := "<nil>"
 	if event.Title != nil {
 		eventTitleValue = *event.Title
@@ -303,36 +426,119 @@ func (ts *TrackingService) OnSessionEnd(session *ActiveSession) {
 	ts.logger.Info("Session ended, creating event",
 		zap.String("source", session.Source),
 		zap.String("application", session.Application),
-		zap.String("url", session.URL),
-		zap.String("session_title", session.Title),
+		logger.URL("url", session.URL),
+		logger.Title("session_title", session.Title),
 		zap.Bool("title_set", event.Title != nil),
-		zap.String("event_title", eventTitleValue),
+		logger.Title("event_title", eventTitleValue),
 		zap.Int64("duration_ms", duration),
 		zap.Time("start_time", session.StartTime),
 		zap.Time("end_time", session.LastEventTime),
 	)
 
-	// Add to event collector
-	ts.eventCollector.AddEvent(event)
+	// Categorize and attribute a project before privacy redaction hides the
+	// title and URL
+	ts.mu.RLock()
+	categorizer := ts.categorizer
+	projectResolver := ts.projectResolver
+	ts.mu.RUnlock()
+	if categorizer != nil {
+		category := categorizer.Categorize(&event)
+		event.Category = &category
+	}
+	if projectResolver != nil {
+		event.ProjectID = projectResolver.Resolve(&event)
+	}
+	if session.Fullscreen && !ts.applyFullscreen(&event) {
+		ts.logger.Debug("Dropping fullscreen session",
+			zap.String("application", session.Application),
+		)
+		return
+	}
+
+	// Drop or redact sensitive apps/domains before anything leaves the service
+	if !ts.applyPrivacy(&event) {
+		ts.logger.Debug("Dropping event matched by privacy blocklist",
+			zap.String("source", session.Source),
+		)
+		return
+	}
+
+	// Add to event collector, split per local day if configured
+	ts.mu.RLock()
+	splitAtMidnight := ts.splitAtMidnight
+	ts.mu.RUnlock()
+	if splitAtMidnight {
+		parts := splitEventAtMidnight(event, time.Local)
+		if len(parts) > 1 {
+			ts.logger.Debug("Split event at midnight",
+				zap.String("application", session.Application),
+				zap.Int("parts", len(parts)),
+			)
+		}
+		for _, part := range parts {
+			ts.addEvent(part)
+		}
+	} else {
+		ts.addEvent(event)
+	}
 	ts.logger.Debug("Event added to collector",
 		zap.String("source", session.Source),
 		zap.String("application", session.Application),
 	)
 }
 
-// onBatchReady handles when a batch is ready to be sent
-func (ts *TrackingService) onBatchReady(events []models.TrackingEvent) {
-	if len(events) == 0 {
-		ts.logger.Debug("Batch ready but empty, skipping")
+// addEvent hands an event to the collector and counts it
+func (ts *TrackingService) addEvent(event models.TrackingEvent) {
+	// Idempotency key so the queue and backend can drop re-sends
+	if event.EventID == "" {
+		event.EventID = uuid.New().String()
+	}
+	if reason, ok := validateEvent(&event, time.Now()); !ok {
+		ts.metrics.EventsRejected.Inc()
+		ts.logger.Warn("Dropping invalid event",
+			zap.String("reason", reason),
+			zap.String("event_id", event.EventID),
+			zap.Int64("timestamp", event.Timestamp),
+			zap.String("status", event.Status),
+		)
 		return
+	} else if reason != "" {
+		ts.logger.Warn("Clamped event",
+			zap.String("reason", reason),
+			zap.String("event_id", event.EventID),
+			zap.Int64("timestamp", event.Timestamp),
+		)
+	}
+	if ts.includePower {
+		ts.stampPower(&event)
 	}
+	ts.metrics.EventsCollected.Inc()
+	ts.lastEventAt.Store(time.Now().UnixNano())
+	ts.eventCollector.AddEvent(event)
+	if ts.automation != nil {
+		ts.automation.Submit(event)
+	}
+}
 
-	ts.logger.Info("Batch ready to send",
-		zap.Int("event_count", len(events)),
-	)
+// sendBatch sends events to the backend, recording latency and outcome
+func (ts *TrackingService) sendBatch(ctx context.Context, events []models.TrackingEvent) error {
+	start := time.Now()
+	err := ts.apiClient.SendBatch(ctx, ts.deviceID, events)
+	ts.metrics.SendBatchLatency.ObserveDuration(time.Since(start))
+	if err != nil {
+		ts.metrics.BatchesFailed.Inc()
+	} else {
+		ts.metrics.BatchesSent.Inc()
+		ts.lastSyncAt.Store(time.Now().UnixNano())
+	}
+	return err
+}
 
+// deliverBatch sends a batch, queuing it locally if the send fails. It
+// returns an error only if the events could neither be sent nor queued.
+func (ts *TrackingService) deliverBatch(ctx context.Context, events []models.TrackingEvent) error {
 	// Try to send to backend
-	err := ts.apiClient.SendBatch(ts.deviceID, events)
+	err := ts.sendBatch(ctx, events)
 	if err != nil {
 		ts.logger.Warn("Failed to send batch, queuing locally",
 			zap.Error(err),
@@ -344,40 +550,63 @@ func (ts *TrackingService) onBatchReady(events []models.TrackingEvent) {
 			ts.logger.Error("Failed to queue events",
 				zap.Error(queueErr),
 			)
+			return queueErr
 		}
 	}
+	return nil
 }
 
 // queueProcessor processes queued events in the background
 func (ts *TrackingService) queueProcessor() {
 	defer ts.wg.Done()
 
-	ticker := time.NewTicker(60 * time.Second) // Check queue every minute
-	defer ticker.Stop()
+	// Cancel any in-flight upload as soon as Stop is called so shutdown
+	// isn't held up by the HTTP client timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ts.stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	timer := time.NewTimer(queueProcessInterval)
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			ts.processQueue()
+		case <-timer.C:
+			timer.Reset(ts.processQueue(ctx))
 		case <-ts.stopChan:
-			// Process queue one more time before stopping
-			ts.processQueue()
+			// Queued events stay persisted and are retried on next start
 			return
 		}
 	}
 }
 
-// processQueue attempts to send queued events
-func (ts *TrackingService) processQueue() {
+// processQueue attempts to send queued events and returns how long to wait
+// before the next attempt
+func (ts *TrackingService) processQueue(ctx context.Context) time.Duration {
 	// Get pending count
 	pendingCount, err := ts.eventQueue.GetPendingCount(ts.deviceID)
 	if err != nil {
 		ts.logger.Error("Failed to get pending count", zap.Error(err))
-		return
+		return queueProcessInterval
 	}
 
 	if pendingCount == 0 {
-		return
+		return queueProcessInterval
+	}
+
+	// While the backend is down, probe it cheaply instead of spending a
+	// batch attempt (and a retry count) on every tick
+	if ts.queueBackoff.offline() && !ts.backendBackOnline(ctx) {
+		if ctx.Err() != nil {
+			return queueProcessInterval
+		}
+		return ts.queueBackoff.fail()
 	}
 
 	ts.logger.Debug("Processing queued events",
@@ -385,37 +614,51 @@ func (ts *TrackingService) processQueue() {
 	)
 
 	// Dequeue a batch
-	events, ids, err := ts.eventQueue.Dequeue(ts.deviceID, 100)
+	events, ids, err := ts.eventQueue.Dequeue(ts.deviceID, ts.dequeueBatchSize)
 	if err != nil {
 		ts.logger.Error("Failed to dequeue events", zap.Error(err))
-		return
+		return queueProcessInterval
 	}
 
 	if len(events) == 0 {
-		return
+		return queueProcessInterval
 	}
 
 	// Try to send
-	err = ts.apiClient.SendBatch(ts.deviceID, events)
+	err = ts.sendBatch(ctx, events)
 	if err != nil {
+		// Cancelled by shutdown: leave the events queued without counting a retry
+		if ctx.Err() != nil {
+			ts.logger.Info("Queued batch send cancelled by shutdown",
+				zap.Int("event_count", len(events)),
+			)
+			return queueProcessInterval
+		}
+
 		// Check if this is a non-retryable error (Bad Request, Auth failure).
 		// In these cases, the backend will always reject these events, so we
-		// remove them from the queue immediately rather than retrying forever.
+		// move them to the dead-letter table rather than retrying forever.
 		switch err.(type) {
 		case *client.BadRequestError, *client.AuthError:
-			ts.logger.Warn("Dropping non-retryable queued events (bad request / auth error)",
+			ts.logger.Warn("Dead-lettering non-retryable queued events (bad request / auth error)",
 				zap.Error(err),
 				zap.Int("event_count", len(events)),
 			)
-			if removeErr := ts.eventQueue.Remove(ids); removeErr != nil {
+			if removeErr := ts.eventQueue.DeadLetter(ids, err.Error()); removeErr != nil {
 				ts.logger.Error("Failed to remove non-retryable events from queue", zap.Error(removeErr))
 			}
-			return
+			// The backend answered, so it's reachable
+			ts.queueBackoff.reset()
+			return queueProcessInterval
 		}
 
+		stats, _ := ts.eventQueue.GetStats(ts.deviceID)
 		ts.logger.Warn("Failed to send queued batch",
 			zap.Error(err),
 			zap.Int("event_count", len(events)),
+			zap.Int("queue_pending", stats.Pending),
+			zap.Float64("queue_oldest_age_seconds", stats.OldestAgeSecs),
+			zap.Int("queue_max_retry_count", stats.MaxRetryCount),
 		)
 
 		// Increment retry count
@@ -423,9 +666,22 @@ func (ts *TrackingService) processQueue() {
 			ts.logger.Error("Failed to increment retry count", zap.Error(retryErr))
 		}
 
+		// Honor the backend's Retry-After instead of the regular cadence
+		if rateLimitErr, ok := err.(*client.RateLimitError); ok && rateLimitErr.RetryAfter > 0 {
+			ts.logger.Info("Delaying queue processing per Retry-After",
+				zap.Duration("retry_after", rateLimitErr.RetryAfter),
+			)
+			return rateLimitErr.RetryAfter
+		}
+
 		// Check if we should give up (too many retries)
 		// This is handled by the cleanup function
-		return
+		next := ts.queueBackoff.fail()
+		ts.logger.Info("Backing off queue processing while the backend is unreachable",
+			zap.Int("consecutive_failures", ts.queueBackoff.failures),
+			zap.Duration("next_attempt_in", next),
+		)
+		return next
 	}
 
 	// Successfully sent, remove from queue
@@ -436,6 +692,11 @@ func (ts *TrackingService) processQueue() {
 			zap.Int("event_count", len(events)),
 		)
 	}
+	if ts.queueBackoff.reset() {
+		ts.logger.Info("Backend reachable again, resuming regular queue processing")
+	}
+
+	return queueProcessInterval
 }
 
 // SetPaused sets the pause state of tracking
@@ -464,6 +725,10 @@ func (ts *TrackingService) GetStatus() map[string]interface{} {
 	defer ts.mu.RUnlock()
 
 	pendingCount, _ := ts.eventQueue.GetPendingCount(ts.deviceID)
+	queueStats, err := ts.eventQueue.GetStats(ts.deviceID)
+	if err != nil {
+		ts.logger.Debug("Failed to get queue stats", zap.Error(err))
+	}
 
 	currentSession := ts.sessionManager.GetCurrentSession()
 	sessionInfo := map[string]interface{}{}
@@ -475,11 +740,39 @@ func (ts *TrackingService) GetStatus() map[string]interface{} {
 		}
 	}
 
+	var stateSince interface{}
+	if !ts.stateSince.IsZero() {
+		stateSince = ts.stateSince.UTC().Format(time.RFC3339)
+	}
+
+	var lastSync interface{}
+	if nanos := ts.lastSyncAt.Load(); nanos != 0 {
+		lastSync = time.Unix(0, nanos).UTC().Format(time.RFC3339)
+	}
+
 	return map[string]interface{}{
 		"device_id":      ts.deviceID,
 		"current_state":  string(ts.currentState),
+		"state_since":    stateSince,
+		"intensity":      string(ts.activityTracker.Intensity(time.Now().Add(-time.Minute))),
+		"paused":         ts.isPaused,
 		"pending_events": pendingCount,
 		"collector_pending": ts.eventCollector.GetPendingCount(),
 		"current_session": sessionInfo,
+		"last_sync":      lastSync,
+		"queue":          queueStats,
 	}
 }
+
+// WriteMetrics writes the agent's counters and the queue gauges from
+// GetStatus in the Prometheus text exposition format
+func (ts *TrackingService) WriteMetrics(w io.Writer) error {
+	status := ts.GetStatus()
+	pending, _ := status["pending_events"].(int)
+	collectorPending, _ := status["collector_pending"].(int)
+
+	return ts.metrics.WritePrometheus(w,
+		metrics.Gauge{Name: "queue_pending", Help: "Events waiting in the local retry queue.", Value: float64(pending)},
+		metrics.Gauge{Name: "collector_pending", Help: "Events buffered in the collector awaiting the next batch.", Value: float64(collectorPending)},
+	)
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/client"
+	"Mansoor88-6/time-tracking-agent/internal/collector"
+	"Mansoor88-6/time-tracking-agent/internal/database"
+	"Mansoor88-6/time-tracking-agent/internal/models"
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+	"Mansoor88-6/time-tracking-agent/internal/queue"
+	"Mansoor88-6/time-tracking-agent/internal/tracker"
+
+	"go.uber.org/zap"
+)
+
+// recordingSink collects every batch written to it
+type recordingSink struct {
+	mu     sync.Mutex
+	events []models.TrackingEvent
+}
+
+func (s *recordingSink) WriteEvents(events []models.TrackingEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *recordingSink) Events() []models.TrackingEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.TrackingEvent(nil), s.events...)
+}
+
+// testService is a TrackingService wired to a fake platform, a temporary
+// database and a backend at backendURL
+type testService struct {
+	*TrackingService
+	platform *platform.FakePlatform
+	db       *database.DB
+	queue    *queue.EventQueue
+	sessions *SessionManager
+}
+
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "agent.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestService(t *testing.T, backendURL string) *testService {
+	t.Helper()
+	logger := zap.NewNop()
+	fake := platform.NewFakePlatform()
+	db := newTestDB(t)
+	eventQueue := queue.NewEventQueue(db.DB, logger)
+
+	var ts *TrackingService
+	sessions := NewSessionManager(func(session *ActiveSession) { ts.OnSessionEnd(session) }, logger, 0)
+	ts = NewTrackingService(
+		fake,
+		tracker.NewWindowTracker(fake, time.Hour, logger),
+		tracker.NewActivityTracker(fake, time.Minute, 5*time.Minute, logger),
+		collector.NewEventCollector(100, time.Hour, logger),
+		client.NewAPIClient(backendURL, "", 5*time.Second, logger),
+		eventQueue,
+		sessions,
+		"test-device",
+		logger,
+	)
+	return &testService{TrackingService: ts, platform: fake, db: db, queue: eventQueue, sessions: sessions}
+}
+
+// eventsWithStatus returns the events with the given status
+func eventsWithStatus(events []models.TrackingEvent, status string) []models.TrackingEvent {
+	var matched []models.TrackingEvent
+	for _, event := range events {
+		if event.Status == status {
+			matched = append(matched, event)
+		}
+	}
+	return matched
+}
+
+// endSessions runs ts in dry-run mode, ends each session through
+// OnSessionEnd and returns the active events that came out
+func endSessions(t *testing.T, ts *testService, sessions ...*ActiveSession) []models.TrackingEvent {
+	t.Helper()
+	sink := &recordingSink{}
+	ts.SetDryRun(sink)
+	if err := ts.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	for _, session := range sessions {
+		ts.OnSessionEnd(session)
+	}
+	ts.Stop()
+	return eventsWithStatus(sink.Events(), models.StatusActive)
+}
+
+// appSession returns an app session that ran for the minute before end
+func appSession(application, title string, end time.Time) *ActiveSession {
+	return &ActiveSession{
+		Source:        "app",
+		Application:   application,
+		Title:         title,
+		StartTime:     end.Add(-time.Minute),
+		LastEventTime: end,
+	}
+}
+
+// browserSession returns a browser session that ran for the minute before end
+func browserSession(rawURL, title string, end time.Time) *ActiveSession {
+	return &ActiveSession{
+		Source:        "browser",
+		Application:   "chrome",
+		URL:           rawURL,
+		Title:         title,
+		TabID:         1,
+		WindowID:      1,
+		StartTime:     end.Add(-time.Minute),
+		LastEventTime: end,
+	}
+}
+
+// testEvents returns n distinct active events ending now
+func testEvents(n int) []models.TrackingEvent {
+	now := time.Now()
+	events := make([]models.TrackingEvent, n)
+	for i := range events {
+		duration := int64(1000)
+		start := now.Add(-time.Duration(n-i) * time.Second).UnixMilli()
+		end := start + duration
+		events[i] = models.TrackingEvent{
+			EventID:   fmt.Sprintf("event-%d", i),
+			DeviceID:  "test-device",
+			Timestamp: start,
+			Status:    models.StatusActive,
+			Duration:  &duration,
+			StartTime: &start,
+			EndTime:   &end,
+		}
+	}
+	return events
+}
+
+func TestProcessQueueCancelledMidRequestKeepsEvents(t *testing.T) {
+	arrived := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		close(arrived)
+		<-r.Context().Done()
+	}))
+	defer backend.Close()
+
+	ts := newTestService(t, backend.URL)
+	if err := ts.queue.Enqueue(ts.deviceID, testEvents(3)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	next := make(chan time.Duration, 1)
+	go func() { next <- ts.processQueue(ctx) }()
+
+	select {
+	case <-arrived:
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued batch was never sent")
+	}
+	cancel()
+
+	select {
+	case wait := <-next:
+		if wait != queueProcessInterval {
+			t.Fatalf("next attempt in %v, want the regular %v", wait, queueProcessInterval)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("processQueue did not return after cancellation")
+	}
+
+	stats, err := ts.queue.GetStats(ts.deviceID)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.Pending != 3 || stats.MaxRetryCount != 0 {
+		t.Fatalf("queue after cancelled send = %+v, want 3 pending without a retry counted", stats)
+	}
+	if ts.queueBackoff.failures != 0 {
+		t.Fatalf("cancellation counted as %d backend failures", ts.queueBackoff.failures)
+	}
+}
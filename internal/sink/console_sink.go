@@ -0,0 +1,65 @@
+package sink
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// ConsoleSink prints events as readable lines, one per event, showing
+// everything that would be sent. Safe for concurrent use.
+type ConsoleSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConsoleSink creates a sink that prints to w
+func NewConsoleSink(w io.Writer) *ConsoleSink {
+	return &ConsoleSink{w: w}
+}
+
+// WriteEvents prints each event on its own line
+func (s *ConsoleSink) WriteEvents(events []models.TrackingEvent) error {
+	var b strings.Builder
+	for _, event := range events {
+		formatEvent(&b, event)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := io.WriteString(s.w, b.String())
+	return err
+}
+
+// formatEvent writes the time, status and duration followed by the set
+// optional fields as key=value pairs
+func formatEvent(b *strings.Builder, event models.TrackingEvent) {
+	fmt.Fprintf(b, "%s  %-7s",
+		time.UnixMilli(event.Timestamp).Format("2006-01-02 15:04:05"),
+		event.Status,
+	)
+	if event.Duration != nil {
+		fmt.Fprintf(b, "  %8s", (time.Duration(*event.Duration) * time.Millisecond).Round(time.Second))
+	}
+
+	field := func(key string, value *string) {
+		if value != nil && *value != "" {
+			fmt.Fprintf(b, "  %s=%q", key, *value)
+		}
+	}
+	field("source", event.Source)
+	field("app", event.Application)
+	field("title", event.Title)
+	field("url", event.URL)
+	field("category", event.Category)
+	field("project", event.ProjectID)
+	field("intensity", event.Intensity)
+	if event.OnBattery != nil {
+		fmt.Fprintf(b, "  on_battery=%t", *event.OnBattery)
+	}
+	b.WriteString("\n")
+}
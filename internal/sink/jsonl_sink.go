@@ -0,0 +1,132 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+// JSONLSink appends events to a file as JSON lines, rotating it when it
+// grows past a size limit. Safe for concurrent use; writes are serialized.
+type JSONLSink struct {
+	path       string
+	maxBytes   int64 // 0 disables rotation
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewJSONLSink opens (or creates) the file at path for appending. When it
+// would grow past maxBytes it is renamed to path.1 (shifting older backups
+// up to path.<maxBackups>) and a new file is started.
+func NewJSONLSink(path string, maxBytes int64, maxBackups int) (*JSONLSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create event log directory: %w", err)
+	}
+	s := &JSONLSink{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the log file for appending and records its current size
+func (s *JSONLSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat event log: %w", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// WriteEvents appends one JSON line per event
+func (s *JSONLSink) WriteEvents(events []models.TrackingEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var data []byte
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("event log is closed")
+	}
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(data)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts the backups, moves the current file to path.1 and opens a
+// new one. Must hold s.mu.
+func (s *JSONLSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close event log: %w", err)
+	}
+	s.file = nil
+
+	if s.maxBackups > 0 {
+		os.Remove(s.backupPath(s.maxBackups))
+		for i := s.maxBackups - 1; i >= 1; i-- {
+			os.Rename(s.backupPath(i), s.backupPath(i+1))
+		}
+		if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+			return fmt.Errorf("failed to rotate event log: %w", err)
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return fmt.Errorf("failed to rotate event log: %w", err)
+	}
+
+	return s.open()
+}
+
+func (s *JSONLSink) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", s.path, n)
+}
+
+// Close closes the log file
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
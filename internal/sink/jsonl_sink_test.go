@@ -0,0 +1,123 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"Mansoor88-6/time-tracking-agent/internal/models"
+)
+
+func event(id string) models.TrackingEvent {
+	return models.TrackingEvent{EventID: id, DeviceID: "device-1", Status: models.StatusActive}
+}
+
+// readEvents returns the event IDs in the JSON lines file at path, failing on
+// any line that isn't a whole event
+func readEvents(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e models.TrackingEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("%s has a broken line %q: %v", filepath.Base(path), scanner.Text(), err)
+		}
+		ids = append(ids, e.EventID)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestJSONLSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	line, err := json.Marshal(event("event-0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Room for exactly one event per file
+	s, err := NewJSONLSink(path, int64(len(line)+1), 2)
+	if err != nil {
+		t.Fatalf("NewJSONLSink: %v", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 4; i++ {
+		if err := s.WriteEvents([]models.TrackingEvent{event(fmt.Sprintf("event-%d", i))}); err != nil {
+			t.Fatalf("WriteEvents: %v", err)
+		}
+	}
+
+	for file, want := range map[string]string{
+		path:        "event-3",
+		path + ".1": "event-2",
+		path + ".2": "event-1",
+	} {
+		if got := readEvents(t, file); len(got) != 1 || got[0] != want {
+			t.Errorf("%s holds %v, want [%s]", filepath.Base(file), got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 exists (%v), want the oldest backup dropped", filepath.Base(path), err)
+	}
+}
+
+func TestJSONLSinkConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	s, err := NewJSONLSink(path, 0, 0)
+	if err != nil {
+		t.Fatalf("NewJSONLSink: %v", err)
+	}
+
+	const writers, batches, batchSize = 8, 50, 3
+	// Batches well past PIPE_BUF, so only the sink keeps them whole
+	title := strings.Repeat("x", 4096)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for b := 0; b < batches; b++ {
+				events := make([]models.TrackingEvent, batchSize)
+				for i := range events {
+					events[i] = event(fmt.Sprintf("%d-%d-%d", w, b, i))
+					events[i].Title = &title
+				}
+				if err := s.WriteEvents(events); err != nil {
+					t.Errorf("WriteEvents: %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	ids := readEvents(t, path)
+	if len(ids) != writers*batches*batchSize {
+		t.Fatalf("%d events written, want %d", len(ids), writers*batches*batchSize)
+	}
+	// Each batch's lines stay together and in order
+	for i := 0; i < len(ids); i += batchSize {
+		batch := strings.TrimSuffix(ids[i], "-0")
+		for j := 0; j < batchSize; j++ {
+			if want := fmt.Sprintf("%s-%d", batch, j); ids[i+j] != want {
+				t.Fatalf("line %d is %s, want %s: batches interleaved", i+j+1, ids[i+j], want)
+			}
+		}
+	}
+}
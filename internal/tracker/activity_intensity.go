@@ -0,0 +1,82 @@
+package tracker
+
+import "time"
+
+// ActivityIntensity buckets the input event rate so passive use (reading)
+// can be told apart from heavy input (typing, editing)
+type ActivityIntensity string
+
+const (
+	IntensityLow    ActivityIntensity = "low"
+	IntensityMedium ActivityIntensity = "medium"
+	IntensityHigh   ActivityIntensity = "high"
+)
+
+// Events per minute at which intensity becomes medium and high. Mouse moves
+// arrive in bursts, so the bars sit well above a typing rate.
+const (
+	intensityMediumPerMinute = 60
+	intensityHighPerMinute   = 300
+)
+
+// intensityWindowMinutes is how far back activity counts are kept
+const intensityWindowMinutes = 60
+
+// activityCounter counts activity events in per-minute buckets over the last
+// intensityWindowMinutes. Not safe for concurrent use.
+type activityCounter struct {
+	minutes [intensityWindowMinutes]int64 // Unix minute each bucket holds
+	counts  [intensityWindowMinutes]int
+}
+
+// add counts one event at t
+func (c *activityCounter) add(t time.Time) {
+	minute := t.Unix() / 60
+	i := minute % intensityWindowMinutes
+	if c.minutes[i] != minute {
+		c.minutes[i] = minute
+		c.counts[i] = 0
+	}
+	c.counts[i]++
+}
+
+// rate returns the average events per minute between since and now, counting
+// whole minute buckets. Periods older than the window are not counted.
+func (c *activityCounter) rate(since, now time.Time) float64 {
+	last := now.Unix() / 60
+	first := since.Unix() / 60
+	if oldest := last - intensityWindowMinutes + 1; first < oldest {
+		first = oldest
+	}
+	if first > last {
+		first = last
+	}
+
+	total := 0
+	for i := range c.minutes {
+		if c.minutes[i] >= first && c.minutes[i] <= last {
+			total += c.counts[i]
+		}
+	}
+	return float64(total) / float64(last-first+1)
+}
+
+// intensityForRate buckets an events-per-minute rate
+func intensityForRate(perMinute float64) ActivityIntensity {
+	switch {
+	case perMinute >= intensityHighPerMinute:
+		return IntensityHigh
+	case perMinute >= intensityMediumPerMinute:
+		return IntensityMedium
+	default:
+		return IntensityLow
+	}
+}
+
+// Intensity returns the activity intensity between since and now (at least
+// the current minute, at most the last hour)
+func (at *ActivityTracker) Intensity(since time.Time) ActivityIntensity {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return intensityForRate(at.activity.rate(since, time.Now()))
+}
@@ -1,6 +1,7 @@
 package tracker
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -19,14 +20,23 @@ const (
 	StateOffline ActivityState = "offline"
 )
 
+// presenceTimeout is how long a presence signal stays valid, so a sensor
+// service that stops reporting can't keep the user active forever
+const presenceTimeout = 2 * time.Minute
+
 // ActivityTracker monitors user activity and determines idle/away states
 type ActivityTracker struct {
 	platform        platform.Platform
 	idleThreshold   time.Duration
 	awayThreshold   time.Duration
 	lastActivity    time.Time
+	present         bool      // Last external presence signal
+	presenceAt      time.Time // When the presence signal was received
+	locked          bool      // Session is locked; the user is offline until unlock
+	asleep          bool      // System is asleep; the user is offline until resume
+	activity        activityCounter // Per-minute event counts for intensity
 	currentState    ActivityState
-	onStateChange   func(ActivityState)
+	onStateChange   func(ActivityState, time.Time)
 	logger          *zap.Logger
 	mu              sync.RWMutex
 	checkTicker      *time.Ticker
@@ -52,8 +62,10 @@ func NewActivityTracker(
 	}
 }
 
-// Start begins monitoring activity
-func (at *ActivityTracker) Start(onStateChange func(ActivityState)) error {
+// Start begins monitoring activity. onStateChange receives each new state
+// with the time the transition actually happened, which for idle and away is
+// when the threshold was crossed rather than when it was noticed.
+func (at *ActivityTracker) Start(onStateChange func(ActivityState, time.Time)) error {
 	at.onStateChange = onStateChange
 
 	// Start activity monitoring with platform
@@ -61,6 +73,13 @@ func (at *ActivityTracker) Start(onStateChange func(ActivityState)) error {
 		return err
 	}
 
+	// Treat screen lock as an explicit offline signal where supported
+	if monitor, ok := at.platform.(platform.SessionMonitor); ok {
+		if err := monitor.StartSessionMonitoring(at.SetLocked); err != nil {
+			at.logger.Warn("Session lock notifications unavailable", zap.Error(err))
+		}
+	}
+
 	// Start state checking loop - check more frequently for better responsiveness
 	at.checkTicker = time.NewTicker(5 * time.Second) // Check state every 5 seconds
 	at.wg.Add(1)
@@ -89,12 +108,23 @@ func (at *ActivityTracker) Stop() {
 	
 	at.wg.Wait()
 	at.platform.StopActivityMonitoring()
+	if monitor, ok := at.platform.(platform.SessionMonitor); ok {
+		monitor.StopSessionMonitoring()
+	}
 	if at.checkTicker != nil {
 		at.checkTicker.Stop()
 	}
 	at.logger.Info("Activity tracker stopped")
 }
 
+// SetMouseMoveInterval coalesces mouse moves to at most one per interval on
+// platforms that support it; 0 reports every move
+func (at *ActivityTracker) SetMouseMoveInterval(interval time.Duration) {
+	if sampler, ok := at.platform.(platform.MouseMoveSampler); ok {
+		sampler.SetMouseMoveInterval(interval)
+	}
+}
+
 // GetCurrentState returns the current activity state
 func (at *ActivityTracker) GetCurrentState() ActivityState {
 	at.mu.RLock()
@@ -112,27 +142,143 @@ func (at *ActivityTracker) GetLastActivity() time.Time {
 func (at *ActivityTracker) handleActivityEvent(event platform.ActivityEvent) {
 	at.mu.Lock()
 	at.lastActivity = event.Timestamp
+	at.activity.add(event.Timestamp)
 	currentState := at.currentState
+	held := at.locked || at.asleep
 	at.mu.Unlock()
 
 	// Any activity should immediately switch to active if we're not already active
 	// This ensures we don't stay in idle/away state when user is clearly active
-	if currentState != StateActive {
-		at.setState(StateActive)
+	if currentState != StateActive && !held {
+		at.setState(StateActive, event.Timestamp)
 	}
 }
 
 // RecordActivity manually records activity (e.g., from window changes)
 // This allows window switches to also count as user activity
 func (at *ActivityTracker) RecordActivity() {
+	now := time.Now()
 	at.mu.Lock()
-	at.lastActivity = time.Now()
+	at.lastActivity = now
 	currentState := at.currentState
+	held := at.locked || at.asleep
 	at.mu.Unlock()
 
 	// Window changes indicate user activity, so switch to active if not already
-	if currentState != StateActive {
-		at.setState(StateActive)
+	if currentState != StateActive && !held {
+		at.setState(StateActive, now)
+	}
+}
+
+// SetIdleThreshold changes how long without input makes the user idle. It
+// takes effect at the next state check. Non-positive values are ignored.
+func (at *ActivityTracker) SetIdleThreshold(threshold time.Duration) {
+	if threshold <= 0 {
+		return
+	}
+	at.mu.Lock()
+	changed := at.idleThreshold != threshold
+	at.idleThreshold = threshold
+	at.mu.Unlock()
+
+	if changed {
+		at.logger.Info("Idle threshold changed", zap.Duration("idle_threshold", threshold))
+	}
+}
+
+// SetAwayThreshold changes how long without input makes the user away. It
+// takes effect at the next state check. Non-positive values are ignored.
+func (at *ActivityTracker) SetAwayThreshold(threshold time.Duration) {
+	if threshold <= 0 {
+		return
+	}
+	at.mu.Lock()
+	changed := at.awayThreshold != threshold
+	at.awayThreshold = threshold
+	at.mu.Unlock()
+
+	if changed {
+		at.logger.Info("Away threshold changed", zap.Duration("away_threshold", threshold))
+	}
+}
+
+// SetThresholds changes the idle and away thresholds together; a zero
+// threshold is unchanged. It changes neither and returns an error if away
+// would not be greater than idle.
+func (at *ActivityTracker) SetThresholds(idle, away time.Duration) error {
+	at.mu.Lock()
+	newIdle, newAway := at.idleThreshold, at.awayThreshold
+	if idle > 0 {
+		newIdle = idle
+	}
+	if away > 0 {
+		newAway = away
+	}
+	if newAway <= newIdle {
+		at.mu.Unlock()
+		return fmt.Errorf("away threshold %s must be greater than idle threshold %s", newAway, newIdle)
+	}
+	changed := newIdle != at.idleThreshold || newAway != at.awayThreshold
+	at.idleThreshold, at.awayThreshold = newIdle, newAway
+	at.mu.Unlock()
+
+	if changed {
+		at.logger.Info("Activity thresholds changed",
+			zap.Duration("idle_threshold", newIdle),
+			zap.Duration("away_threshold", newAway),
+		)
+	}
+	return nil
+}
+
+// SetPresence records an external human-presence signal. While a recent
+// signal reports the user as present, lack of input doesn't make them idle
+// or away.
+func (at *ActivityTracker) SetPresence(present bool) {
+	at.mu.Lock()
+	at.present = present
+	at.presenceAt = time.Now()
+	at.mu.Unlock()
+}
+
+// SetLocked records a session lock or unlock. Locking switches straight to
+// offline and holds it there; unlocking counts as activity.
+func (at *ActivityTracker) SetLocked(locked bool) {
+	at.mu.Lock()
+	at.locked = locked
+	if !locked {
+		at.lastActivity = time.Now()
+	}
+	asleep := at.asleep
+	at.mu.Unlock()
+
+	at.logger.Info("Session lock state changed", zap.Bool("locked", locked))
+
+	if locked {
+		at.setState(StateOffline, time.Now())
+	} else if !asleep {
+		at.setState(StateActive, time.Now())
+	}
+}
+
+// SetAsleep records a system sleep or resume. Sleep switches straight to
+// offline and holds it there; resume counts as activity unless the session
+// is still locked.
+func (at *ActivityTracker) SetAsleep(asleep bool) {
+	at.mu.Lock()
+	at.asleep = asleep
+	if !asleep {
+		at.lastActivity = time.Now()
+	}
+	locked := at.locked
+	at.mu.Unlock()
+
+	at.logger.Info("System sleep state changed", zap.Bool("asleep", asleep))
+
+	if asleep {
+		at.setState(StateOffline, time.Now())
+	} else if !locked {
+		at.setState(StateActive, time.Now())
 	}
 }
 
@@ -158,10 +304,20 @@ func (at *ActivityTracker) checkState() {
 	}
 
 	at.mu.Lock()
-	idleDuration := time.Since(at.lastActivity)
+	lastActivity := at.lastActivity
+	idleDuration := time.Since(lastActivity)
+	idleThreshold := at.idleThreshold
+	awayThreshold := at.awayThreshold
 	currentState := at.currentState
+	present := at.present && time.Since(at.presenceAt) < presenceTimeout
+	held := at.locked || at.asleep
 	at.mu.Unlock()
 
+	// A locked session or sleeping system stays offline until unlock or resume
+	if held {
+		return
+	}
+
 	// Check again
 	select {
 	case <-at.stopChan:
@@ -169,22 +325,34 @@ func (at *ActivityTracker) checkState() {
 	default:
 	}
 
+	// The transition happened when the threshold was crossed, up to a check
+	// interval before now
 	var newState ActivityState
+	var changedAt time.Time
 	switch {
-	case idleDuration >= at.awayThreshold:
-		newState = StateAway
-	case idleDuration >= at.idleThreshold:
-		newState = StateIdle
+	case present:
+		newState, changedAt = StateActive, time.Now()
+	case idleDuration >= awayThreshold:
+		newState, changedAt = StateAway, lastActivity.Add(awayThreshold)
+	case idleDuration >= idleThreshold:
+		newState, changedAt = StateIdle, lastActivity.Add(idleThreshold)
 	default:
-		newState = StateActive
+		newState, changedAt = StateActive, lastActivity
+	}
+
+	// Going straight from active to away (e.g. a stalled check) still
+	// passed through idle first
+	if currentState == StateActive && newState == StateAway && idleThreshold < awayThreshold {
+		at.setState(StateIdle, lastActivity.Add(idleThreshold))
 	}
 
 	if newState != currentState {
-		at.setState(newState)
+		at.setState(newState, changedAt)
 	}
 }
 
-func (at *ActivityTracker) setState(newState ActivityState) {
+// setState switches to newState, reporting that it happened at changedAt
+func (at *ActivityTracker) setState(newState ActivityState, changedAt time.Time) {
 	// Check if we should stop before state change
 	select {
 	case <-at.stopChan:
@@ -208,10 +376,11 @@ func (at *ActivityTracker) setState(newState ActivityState) {
 		at.logger.Info("Activity state changed",
 			zap.String("old_state", string(oldState)),
 			zap.String("new_state", string(newState)),
+			zap.Time("changed_at", changedAt),
 		)
 
 		if at.onStateChange != nil {
-			at.onStateChange(newState)
+			at.onStateChange(newState, changedAt)
 		}
 	}
 }
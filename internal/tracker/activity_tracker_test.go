@@ -0,0 +1,263 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+
+	"go.uber.org/zap"
+)
+
+// stateChange is one onStateChange callback
+type stateChange struct {
+	state ActivityState
+	at    time.Time
+}
+
+func startActivityTracker(t *testing.T, fake *platform.FakePlatform, idle, away time.Duration) (*ActivityTracker, *[]stateChange) {
+	t.Helper()
+	at := NewActivityTracker(fake, idle, away, zap.NewNop())
+	var changes []stateChange
+	err := at.Start(func(state ActivityState, changedAt time.Time) {
+		changes = append(changes, stateChange{state, changedAt})
+	})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(at.Stop)
+	return at, &changes
+}
+
+// setLastActivity backdates the last input so checkState sees an idle gap
+func (at *ActivityTracker) setLastActivity(last time.Time) {
+	at.mu.Lock()
+	at.lastActivity = last
+	at.mu.Unlock()
+}
+
+func TestActivityTrackerIdleAwayAndBack(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	at, changes := startActivityTracker(t, fake, time.Minute, 5*time.Minute)
+
+	last := time.Now().Add(-2 * time.Minute)
+	at.setLastActivity(last)
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateIdle {
+		t.Fatalf("state = %s, want idle", got)
+	}
+	if (*changes)[0].at != last.Add(time.Minute) {
+		t.Fatalf("idle reported at %v, want threshold crossing %v", (*changes)[0].at, last.Add(time.Minute))
+	}
+
+	at.setLastActivity(time.Now().Add(-10 * time.Minute))
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateAway {
+		t.Fatalf("state = %s, want away", got)
+	}
+
+	if !fake.EmitActivity(platform.ActivityEvent{Type: platform.ActivityKeyPress}) {
+		t.Fatal("activity monitoring not started")
+	}
+	if got := at.GetCurrentState(); got != StateActive {
+		t.Fatalf("state after input = %s, want active", got)
+	}
+
+	want := []ActivityState{StateIdle, StateAway, StateActive}
+	if len(*changes) != len(want) {
+		t.Fatalf("changes = %v, want %v", *changes, want)
+	}
+	for i, change := range *changes {
+		if change.state != want[i] {
+			t.Fatalf("change %d = %s, want %s", i, change.state, want[i])
+		}
+	}
+}
+
+func TestActivityTrackerActiveToAwayPassesThroughIdle(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	at, changes := startActivityTracker(t, fake, time.Minute, 5*time.Minute)
+
+	at.setLastActivity(time.Now().Add(-10 * time.Minute))
+	at.checkState()
+
+	if len(*changes) != 2 || (*changes)[0].state != StateIdle || (*changes)[1].state != StateAway {
+		t.Fatalf("changes = %v, want idle then away", *changes)
+	}
+}
+
+func TestActivityTrackerLockHoldsOffline(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	at, _ := startActivityTracker(t, fake, time.Minute, 5*time.Minute)
+
+	fake.EmitLock(true)
+	if got := at.GetCurrentState(); got != StateOffline {
+		t.Fatalf("state after lock = %s, want offline", got)
+	}
+
+	// Input and state checks while locked don't leave offline
+	fake.EmitActivity(platform.ActivityEvent{Type: platform.ActivityMouseMove})
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateOffline {
+		t.Fatalf("state while locked = %s, want offline", got)
+	}
+
+	fake.EmitLock(false)
+	if got := at.GetCurrentState(); got != StateActive {
+		t.Fatalf("state after unlock = %s, want active", got)
+	}
+}
+
+func TestActivityTrackerPresenceSuppressesIdle(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	at, changes := startActivityTracker(t, fake, time.Minute, 5*time.Minute)
+
+	at.setLastActivity(time.Now().Add(-10 * time.Minute))
+	at.SetPresence(true)
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateActive {
+		t.Fatalf("state while present = %s, want active", got)
+	}
+	if len(*changes) != 0 {
+		t.Fatalf("changes while present = %v, want none", *changes)
+	}
+
+	at.SetPresence(false)
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateAway {
+		t.Fatalf("state once absent = %s, want away", got)
+	}
+}
+
+func TestActivityTrackerPresenceExpires(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	at, _ := startActivityTracker(t, fake, time.Minute, 5*time.Minute)
+
+	at.setLastActivity(time.Now().Add(-2 * time.Minute))
+	at.SetPresence(true)
+	at.mu.Lock()
+	at.presenceAt = time.Now().Add(-presenceTimeout)
+	at.mu.Unlock()
+
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateIdle {
+		t.Fatalf("state with a stale presence signal = %s, want idle", got)
+	}
+}
+
+func TestThresholdChangeMovesTransition(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	at, changes := startActivityTracker(t, fake, 5*time.Minute, 15*time.Minute)
+
+	// Three minutes without input is still active under a 5m idle threshold
+	last := time.Now().Add(-3 * time.Minute)
+	at.setLastActivity(last)
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateActive {
+		t.Fatalf("state = %s, want active", got)
+	}
+
+	// Lowering the threshold makes the same gap idle, crossing at the new value
+	at.SetIdleThreshold(2 * time.Minute)
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateIdle {
+		t.Fatalf("state after lowering the idle threshold = %s, want idle", got)
+	}
+	if len(*changes) != 1 || (*changes)[0].at != last.Add(2*time.Minute) {
+		t.Fatalf("changes = %v, want idle at %v", *changes, last.Add(2*time.Minute))
+	}
+
+	// Likewise for away
+	at.SetAwayThreshold(150 * time.Second)
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateAway {
+		t.Fatalf("state after lowering the away threshold = %s, want away", got)
+	}
+
+	// Raising both back returns the gap to active
+	at.SetIdleThreshold(5 * time.Minute)
+	at.SetAwayThreshold(15 * time.Minute)
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateActive {
+		t.Fatalf("state after raising the thresholds = %s, want active", got)
+	}
+
+	// Non-positive values are ignored
+	at.SetIdleThreshold(0)
+	at.checkState()
+	if got := at.GetCurrentState(); got != StateActive {
+		t.Fatalf("state after a zero threshold = %s, want active", got)
+	}
+}
+
+func TestMouseMoveIntervalThrottlesBurst(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	at, _ := startActivityTracker(t, fake, time.Minute, 5*time.Minute)
+	start := time.Now()
+
+	// emitBurst sends 1000 moves a millisecond apart from base and returns
+	// how many got through
+	emitBurst := func(base time.Time, activityType platform.ActivityType) int {
+		delivered := 0
+		for i := 0; i < 1000; i++ {
+			if fake.EmitActivity(platform.ActivityEvent{Type: activityType, Timestamp: base.Add(time.Duration(i) * time.Millisecond)}) {
+				delivered++
+			}
+		}
+		return delivered
+	}
+
+	if got := emitBurst(start, platform.ActivityMouseMove); got != 1000 {
+		t.Fatalf("without an interval %d of 1000 moves delivered", got)
+	}
+
+	at.SetMouseMoveInterval(200 * time.Millisecond)
+	next := start.Add(time.Second)
+	if got := emitBurst(next, platform.ActivityMouseMove); got != 5 {
+		t.Errorf("with a 200ms interval %d of 1000 moves delivered, want 5", got)
+	}
+	if got := emitBurst(next.Add(time.Second), platform.ActivityMouseDrag); got != 5 {
+		t.Errorf("with a 200ms interval %d of 1000 drags delivered, want 5", got)
+	}
+	if got := at.GetLastActivity(); !got.Equal(next.Add(time.Second + 800*time.Millisecond)) {
+		t.Errorf("last activity = %v, want the last sampled drag", got)
+	}
+
+	// Clicks and keys are never throttled
+	for _, activityType := range []platform.ActivityType{platform.ActivityMouseClick, platform.ActivityKeyPress, platform.ActivityScroll} {
+		if got := emitBurst(next.Add(2*time.Second), activityType); got != 1000 {
+			t.Errorf("%s: %d of 1000 delivered", activityType, got)
+		}
+	}
+}
+
+func TestSetThresholdsChecksCurrentValues(t *testing.T) {
+	at := NewActivityTracker(platform.NewFakePlatform(), time.Minute, 5*time.Minute, zap.NewNop())
+
+	tests := []struct {
+		name     string
+		idle     time.Duration
+		away     time.Duration
+		wantErr  bool
+		wantIdle time.Duration
+		wantAway time.Duration
+	}{
+		{"idle past current away", 10 * time.Minute, 0, true, time.Minute, 5 * time.Minute},
+		{"away before current idle", 0, 30 * time.Second, true, time.Minute, 5 * time.Minute},
+		{"both, away not after idle", 10 * time.Minute, 10 * time.Minute, true, time.Minute, 5 * time.Minute},
+		{"idle only", 2 * time.Minute, 0, false, 2 * time.Minute, 5 * time.Minute},
+		{"both past the old away", 10 * time.Minute, 20 * time.Minute, false, 10 * time.Minute, 20 * time.Minute},
+	}
+	for _, tt := range tests {
+		err := at.SetThresholds(tt.idle, tt.away)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		at.mu.RLock()
+		idle, away := at.idleThreshold, at.awayThreshold
+		at.mu.RUnlock()
+		if idle != tt.wantIdle || away != tt.wantAway {
+			t.Fatalf("%s: thresholds %s/%s, want %s/%s", tt.name, idle, away, tt.wantIdle, tt.wantAway)
+		}
+	}
+}
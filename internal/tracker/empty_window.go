@@ -0,0 +1,62 @@
+package tracker
+
+import (
+	"fmt"
+	"strings"
+
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+)
+
+// NoActiveWindow is the application reported for a foreground window with
+// neither an application nor a title when EmptyWindowReport is set
+const NoActiveWindow = "(no active window)"
+
+// EmptyWindowPolicy is what happens when the foreground window has neither
+// an application nor a title (the desktop, a splash screen, a window being
+// torn down)
+type EmptyWindowPolicy string
+
+const (
+	// EmptyWindowSkip ignores such windows: the previous focus continues
+	// and no event is produced, so brief blanks don't split sessions
+	EmptyWindowSkip EmptyWindowPolicy = ""
+	// EmptyWindowReport reports them as a focus change to NoActiveWindow
+	EmptyWindowReport EmptyWindowPolicy = "report"
+)
+
+// SetEmptyWindowPolicy sets how windows with no application or title are
+// handled. An unknown policy returns an error and leaves them skipped. Safe
+// to call while running.
+func (wt *WindowTracker) SetEmptyWindowPolicy(policy string) error {
+	parsed := EmptyWindowPolicy(strings.ToLower(strings.TrimSpace(policy)))
+	var err error
+	switch parsed {
+	case EmptyWindowSkip, EmptyWindowReport:
+	case "skip":
+		parsed = EmptyWindowSkip
+	default:
+		err = fmt.Errorf("unknown empty window policy %q (must be empty, skip or report)", policy)
+		parsed = EmptyWindowSkip
+	}
+	wt.mu.Lock()
+	wt.emptyWindow = parsed
+	wt.mu.Unlock()
+	return err
+}
+
+// applyEmptyWindowPolicy returns the window to track, or nil if it should be
+// ignored
+func (wt *WindowTracker) applyEmptyWindowPolicy(window *platform.WindowInfo) *platform.WindowInfo {
+	if window.Application != "" || window.Title != "" {
+		return window
+	}
+	wt.mu.RLock()
+	policy := wt.emptyWindow
+	wt.mu.RUnlock()
+	if policy == EmptyWindowSkip {
+		return nil
+	}
+	marked := *window
+	marked.Application = NoActiveWindow
+	return &marked
+}
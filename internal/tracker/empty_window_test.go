@@ -0,0 +1,64 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+
+	"go.uber.org/zap"
+)
+
+func TestEmptyWindowSkippedByDefault(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "editor.exe", Title: "main.go", ProcessID: 10}, nil)
+	wt, focus := startWindowTracker(t, fake)
+	focus.next(t)
+
+	fake.SetActiveWindow(&platform.WindowInfo{}, nil)
+	wt.signalFocusChanged()
+	focus.none(t, 50*time.Millisecond)
+	if current := wt.GetCurrentAppFocus(); current == nil || current.Application != "editor.exe" {
+		t.Fatalf("current focus = %+v, want editor.exe kept", current)
+	}
+
+	// Returning to the same window continues it without a new focus
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "editor.exe", Title: "main.go", ProcessID: 10}, nil)
+	wt.signalFocusChanged()
+	focus.none(t, 50*time.Millisecond)
+}
+
+func TestEmptyWindowReported(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "editor.exe", Title: "main.go", ProcessID: 10}, nil)
+	wt, focus := startWindowTracker(t, fake)
+	if err := wt.SetEmptyWindowPolicy("report"); err != nil {
+		t.Fatalf("SetEmptyWindowPolicy: %v", err)
+	}
+	focus.next(t)
+
+	fake.SetActiveWindow(&platform.WindowInfo{ProcessID: 4}, nil)
+	wt.signalFocusChanged()
+	if got := focus.next(t); got.Application != NoActiveWindow || got.Title != "" {
+		t.Fatalf("empty window focus = %+v, want %q", got, NoActiveWindow)
+	}
+}
+
+func TestSetEmptyWindowPolicy(t *testing.T) {
+	wt := NewWindowTracker(platform.NewFakePlatform(), time.Hour, zap.NewNop())
+	for _, policy := range []string{"", "skip", " Report "} {
+		if err := wt.SetEmptyWindowPolicy(policy); err != nil {
+			t.Errorf("SetEmptyWindowPolicy(%q): %v", policy, err)
+		}
+	}
+	if err := wt.SetEmptyWindowPolicy("hide"); err == nil {
+		t.Fatal("unknown policy accepted")
+	}
+	if wt.applyEmptyWindowPolicy(&platform.WindowInfo{}) != nil {
+		t.Error("unknown policy doesn't fall back to skipping")
+	}
+	// Windows with only a title or only an application aren't empty
+	if got := wt.applyEmptyWindowPolicy(&platform.WindowInfo{Title: "Desktop"}); got == nil || got.Application != "" {
+		t.Errorf("title-only window = %+v, want it unchanged", got)
+	}
+}
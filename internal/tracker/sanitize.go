@@ -0,0 +1,43 @@
+package tracker
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+)
+
+// sanitizeWindow returns window with its application and title made safe to
+// store and send: invalid UTF-8 (e.g. from lone UTF-16 surrogates) becomes
+// U+FFFD and control characters are removed. The original is not modified.
+func sanitizeWindow(window *platform.WindowInfo) *platform.WindowInfo {
+	application := sanitizeText(window.Application)
+	title := sanitizeText(window.Title)
+	if application == window.Application && title == window.Title {
+		return window
+	}
+	clean := *window
+	clean.Application = application
+	clean.Title = title
+	return &clean
+}
+
+// sanitizeText replaces invalid UTF-8 and drops control characters, turning
+// tabs and line breaks into spaces so words stay separated
+func sanitizeText(s string) string {
+	if utf8.ValidString(s) && strings.IndexFunc(s, unicode.IsControl) < 0 {
+		return s
+	}
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
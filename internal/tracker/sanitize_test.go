@@ -0,0 +1,75 @@
+package tracker
+
+import (
+	"encoding/json"
+	"testing"
+
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+)
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"clean", "main.go - Visual Studio Code", "main.go - Visual Studio Code"},
+		{"non-ASCII kept", "Résumé — naïve 日本語 😀", "Résumé — naïve 日本語 😀"},
+		{"invalid byte", "bad\xffbyte", "bad�byte"},
+		{"truncated sequence", "cut \xe6\x97", "cut �"},
+		{"lone surrogate", "pair \xed\xa0\x80 half", "pair � half"},
+		{"NUL and escape", "a\x00b\x1b[31mc", "ab[31mc"},
+		{"DEL and C1", "a\x7fb\u0085c", "abc"},
+		{"line breaks and tabs", "line one\r\nline\ttwo", "line one  line two"},
+		{"trailing newline", "title\n", "title"},
+		{"only controls", "\x00\x01\x02", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeText(tt.in); got != tt.want {
+				t.Errorf("sanitizeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeWindow(t *testing.T) {
+	clean := &platform.WindowInfo{Application: "code.exe", Title: "main.go", ProcessID: 7}
+	if got := sanitizeWindow(clean); got != clean {
+		t.Error("clean window was copied")
+	}
+
+	dirty := &platform.WindowInfo{Application: "app\x00.exe", Title: "doc\xff\n", ProcessID: 7}
+	got := sanitizeWindow(dirty)
+	if got.Application != "app.exe" || got.Title != "doc�" || got.ProcessID != 7 {
+		t.Fatalf("sanitized window = %+v", got)
+	}
+	if dirty.Application != "app\x00.exe" {
+		t.Error("original window modified")
+	}
+
+	// The result survives a JSON round trip unchanged
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded platform.WindowInfo
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Title != got.Title || decoded.Application != got.Application {
+		t.Fatalf("round trip = %+v, want %+v", decoded, got)
+	}
+}
+
+func TestWindowTrackerSanitizesReportedFocus(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "term\x07.exe", Title: "build\x1b output\xfe", ProcessID: 3}, nil)
+	_, focus := startWindowTracker(t, fake)
+
+	got := focus.next(t)
+	if got.Application != "term.exe" || got.Title != "build output�" {
+		t.Fatalf("focus = %q %q, want sanitized text", got.Application, got.Title)
+	}
+}
@@ -1,19 +1,40 @@
 package tracker
 
 import (
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"Mansoor88-6/time-tracking-agent/internal/logger"
 	"Mansoor88-6/time-tracking-agent/internal/platform"
 
 	"go.uber.org/zap"
 )
 
+// activeWindowTimeout bounds one GetActiveWindow call. A call that takes
+// longer (e.g. querying a hung or protected process) is abandoned and the
+// next poll tries again. A variable so tests can shorten it.
+var activeWindowTimeout = 5 * time.Second
+
+var (
+	errActiveWindowTimeout = errors.New("GetActiveWindow timed out")
+	errActiveWindowBusy    = errors.New("previous GetActiveWindow call still running")
+	errWindowTrackerStop   = errors.New("window tracker stopping")
+)
+
+// activeWindowResult carries a GetActiveWindow result across goroutines
+type activeWindowResult struct {
+	window *platform.WindowInfo
+	err    error
+}
+
 // AppFocusInfo represents application focus information (simplified from WindowInfo)
 type AppFocusInfo struct {
 	Application string
 	PID         int
 	Title       string
+	Fullscreen  bool
 	Timestamp   time.Time
 }
 
@@ -21,6 +42,7 @@ type AppFocusInfo struct {
 type WindowTracker struct {
 	platform        platform.Platform
 	pollInterval     time.Duration
+	pollTicker       *time.Ticker
 	currentAppFocus  *AppFocusInfo
 	onAppFocus       func(*AppFocusInfo)
 	logger           *zap.Logger
@@ -28,6 +50,11 @@ type WindowTracker struct {
 	wg               sync.WaitGroup
 	mu               sync.RWMutex
 	sequenceCounter  int // Sequence counter for app focus events
+	focusChanged     chan struct{} // Signalled by the platform's foreground hook, if any
+	minDwell         time.Duration // How long a window must stay focused before it is reported
+	pendingAppFocus  *AppFocusInfo // Window waiting out minDwell
+	windowCallBusy   atomic.Bool   // A GetActiveWindow call, possibly abandoned, is running
+	emptyWindow      EmptyWindowPolicy
 }
 
 // NewWindowTracker creates a new window tracker
@@ -37,22 +64,68 @@ func NewWindowTracker(platform platform.Platform, pollInterval time.Duration, lo
 		pollInterval: pollInterval,
 		logger:       logger,
 		stopChan:     make(chan struct{}),
+		focusChanged: make(chan struct{}, 1),
 	}
 }
 
+// SetPollInterval changes how often the active window is polled. Safe to
+// call while running; non-positive intervals are ignored.
+func (wt *WindowTracker) SetPollInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	wt.mu.Lock()
+	wt.pollInterval = interval
+	ticker := wt.pollTicker
+	wt.mu.Unlock()
+
+	if ticker != nil {
+		ticker.Reset(interval)
+	}
+}
+
+// SetMinDwell sets how long a window must stay focused before its focus is
+// reported. Windows left sooner (rapid alt-tabbing, flashing dialogs) are
+// dropped and only the window that sticks is reported, with the time it was
+// first seen. Must be called before Start.
+func (wt *WindowTracker) SetMinDwell(minDwell time.Duration) {
+	wt.minDwell = minDwell
+}
+
 // Start begins monitoring application focus changes
 func (wt *WindowTracker) Start(onAppFocus func(*AppFocusInfo)) error {
 	wt.onAppFocus = onAppFocus
 
+	// Prefer event-driven foreground changes where the platform supports
+	// them; polling continues to pick up title changes within a window
+	eventDriven := false
+	if monitor, ok := wt.platform.(platform.WindowMonitor); ok {
+		if err := monitor.StartWindowMonitoring(wt.signalFocusChanged); err != nil {
+			wt.logger.Warn("Foreground window hook unavailable, using polling only", zap.Error(err))
+		} else {
+			eventDriven = true
+		}
+	}
+
 	wt.wg.Add(1)
 	go wt.pollLoop()
 
 	wt.logger.Info("Window tracker started",
 		zap.Duration("poll_interval", wt.pollInterval),
+		zap.Bool("event_driven", eventDriven),
 	)
 	return nil
 }
 
+// signalFocusChanged schedules an immediate window check. It never blocks,
+// as it runs on the platform's hook thread.
+func (wt *WindowTracker) signalFocusChanged() {
+	select {
+	case wt.focusChanged <- struct{}{}:
+	default:
+	}
+}
+
 // Stop stops monitoring window changes
 func (wt *WindowTracker) Stop() {
 	wt.mu.Lock()
@@ -66,6 +139,10 @@ func (wt *WindowTracker) Stop() {
 	}
 	wt.mu.Unlock()
 	
+	if monitor, ok := wt.platform.(platform.WindowMonitor); ok {
+		monitor.StopWindowMonitoring()
+	}
+
 	wt.wg.Wait()
 	wt.logger.Info("Window tracker stopped")
 }
@@ -85,7 +162,10 @@ func (wt *WindowTracker) GetCurrentAppFocus() *AppFocusInfo {
 func (wt *WindowTracker) pollLoop() {
 	defer wt.wg.Done()
 
+	wt.mu.Lock()
 	ticker := time.NewTicker(wt.pollInterval)
+	wt.pollTicker = ticker
+	wt.mu.Unlock()
 	defer ticker.Stop()
 
 	// Initial poll
@@ -95,12 +175,48 @@ func (wt *WindowTracker) pollLoop() {
 		select {
 		case <-ticker.C:
 			wt.checkWindow()
+		case <-wt.focusChanged:
+			wt.checkWindow()
 		case <-wt.stopChan:
 			return
 		}
 	}
 }
 
+// getActiveWindow calls the platform with activeWindowTimeout. While an
+// abandoned call is still running no new one is started, so a process that
+// hangs every call can't pile up goroutines.
+func (wt *WindowTracker) getActiveWindow() (*platform.WindowInfo, error) {
+	if !wt.windowCallBusy.CompareAndSwap(false, true) {
+		return nil, errActiveWindowBusy
+	}
+
+	result := make(chan activeWindowResult, 1)
+	go func() {
+		defer wt.windowCallBusy.Store(false)
+		started := time.Now()
+		window, err := wt.platform.GetActiveWindow()
+		if elapsed := time.Since(started); elapsed >= activeWindowTimeout {
+			wt.logger.Info("Stalled active window lookup finished",
+				zap.Duration("elapsed", elapsed),
+			)
+		}
+		result <- activeWindowResult{window: window, err: err}
+	}()
+
+	timer := time.NewTimer(activeWindowTimeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-result:
+		return r.window, r.err
+	case <-timer.C:
+		return nil, errActiveWindowTimeout
+	case <-wt.stopChan:
+		return nil, errWindowTrackerStop
+	}
+}
+
 func (wt *WindowTracker) checkWindow() {
 	// Check if we should stop
 	select {
@@ -109,12 +225,33 @@ func (wt *WindowTracker) checkWindow() {
 	default:
 	}
 
-	window, err := wt.platform.GetActiveWindow()
-	if err != nil {
+	window, err := wt.getActiveWindow()
+	switch {
+	case err == nil:
+	case errors.Is(err, errWindowTrackerStop):
+		return
+	case errors.Is(err, errActiveWindowTimeout):
+		wt.logger.Warn("Active window lookup stalled, skipping this poll",
+			zap.Duration("timeout", activeWindowTimeout),
+		)
+		return
+	case errors.Is(err, errActiveWindowBusy):
+		wt.logger.Debug("Active window lookup still stalled, skipping this poll")
+		return
+	case errors.Is(err, platform.ErrActiveWindowUnsupported):
+		wt.logger.Debug("Active window tracking not supported on this platform")
+		return
+	default:
 		wt.logger.Error("Failed to get active window", zap.Error(err))
 		return
 	}
 
+	window = sanitizeWindow(window)
+	if window = wt.applyEmptyWindowPolicy(window); window == nil {
+		wt.logger.Debug("Ignoring foreground window with no application or title")
+		return
+	}
+
 	// Check again after potentially slow operation
 	select {
 	case <-wt.stopChan:
@@ -124,13 +261,26 @@ func (wt *WindowTracker) checkWindow() {
 
 	wt.mu.Lock()
 	hasChanged := wt.hasAppFocusChanged(window)
+	if !hasChanged {
+		// Back on the current window before a pending one stuck
+		wt.pendingAppFocus = nil
+	} else if wt.minDwell > 0 && !wt.dwellElapsed(window) {
+		// Hold the change back until the window has stayed focused for minDwell
+		wt.mu.Unlock()
+		return
+	}
 	if hasChanged {
-		now := time.Now()
+		focusedAt := time.Now()
+		if wt.pendingAppFocus != nil {
+			focusedAt = wt.pendingAppFocus.Timestamp
+			wt.pendingAppFocus = nil
+		}
 		wt.currentAppFocus = &AppFocusInfo{
 			Application: window.Application,
 			PID:         window.ProcessID,
 			Title:       window.Title,
-			Timestamp:   now,
+			Fullscreen:  window.IsFullscreen,
+			Timestamp:   focusedAt,
 		}
 		wt.mu.Unlock()
 
@@ -144,12 +294,13 @@ func (wt *WindowTracker) checkWindow() {
 		wt.logger.Debug("App focus changed",
 			zap.String("application", window.Application),
 			zap.Int("pid", window.ProcessID),
-			zap.String("title", window.Title),
+			logger.Title("title", window.Title),
 			zap.String("title_length", string(rune(len(window.Title)))),
+			wt.processCacheStats(),
 		)
 		wt.logger.Info("AppFocusInfo created with title",
 			zap.String("application", window.Application),
-			zap.String("title", window.Title),
+			logger.Title("title", window.Title),
 			zap.Bool("title_empty", window.Title == ""),
 		)
 
@@ -161,12 +312,33 @@ func (wt *WindowTracker) checkWindow() {
 	}
 }
 
+// dwellElapsed reports whether window has been focused for at least minDwell,
+// starting the dwell period if window is newly seen. Must hold wt.mu.
+func (wt *WindowTracker) dwellElapsed(window *platform.WindowInfo) bool {
+	pending := wt.pendingAppFocus
+	if pending == nil || pending.PID != window.ProcessID ||
+		pending.Application != window.Application || pending.Title != window.Title {
+		wt.pendingAppFocus = &AppFocusInfo{
+			Application: window.Application,
+			PID:         window.ProcessID,
+			Title:       window.Title,
+			Timestamp:   time.Now(),
+		}
+		// Re-check when the dwell period ends so the window is reported
+		// even if nothing else triggers a check
+		time.AfterFunc(wt.minDwell, wt.signalFocusChanged)
+		return false
+	}
+
+	return time.Since(pending.Timestamp) >= wt.minDwell
+}
+
 func (wt *WindowTracker) hasAppFocusChanged(newWindow *platform.WindowInfo) bool {
 	if wt.currentAppFocus == nil {
 		wt.logger.Debug("App focus changed: no previous focus",
 			zap.String("new_application", newWindow.Application),
 			zap.Int("new_pid", newWindow.ProcessID),
-			zap.String("new_title", newWindow.Title),
+			logger.Title("new_title", newWindow.Title),
 		)
 		return true
 	}
@@ -194,8 +366,18 @@ func (wt *WindowTracker) hasAppFocusChanged(newWindow *platform.WindowInfo) bool
 		wt.logger.Debug("App focus changed: title changed",
 			zap.String("application", newWindow.Application),
 			zap.Int("pid", newWindow.ProcessID),
-			zap.String("old_title", wt.currentAppFocus.Title),
-			zap.String("new_title", newWindow.Title),
+			logger.Title("old_title", wt.currentAppFocus.Title),
+			logger.Title("new_title", newWindow.Title),
+		)
+		return true
+	}
+
+	// Entering or leaving fullscreen starts a new session so fullscreen time
+	// can be handled separately
+	if wt.currentAppFocus.Fullscreen != newWindow.IsFullscreen {
+		wt.logger.Debug("App focus changed: fullscreen changed",
+			zap.String("application", newWindow.Application),
+			zap.Bool("fullscreen", newWindow.IsFullscreen),
 		)
 		return true
 	}
@@ -203,7 +385,19 @@ func (wt *WindowTracker) hasAppFocusChanged(newWindow *platform.WindowInfo) bool
 	wt.logger.Debug("App focus unchanged",
 		zap.String("application", newWindow.Application),
 		zap.Int("pid", newWindow.ProcessID),
-		zap.String("title", newWindow.Title),
+		logger.Title("title", newWindow.Title),
+		wt.processCacheStats(),
 	)
 	return false
 }
+
+// processCacheStats returns the platform's process lookup cache counters as
+// a log field, or a no-op field if the platform has no cache
+func (wt *WindowTracker) processCacheStats() zap.Field {
+	cache, ok := wt.platform.(platform.ProcessCache)
+	if !ok {
+		return zap.Skip()
+	}
+	hits, misses := cache.ProcessCacheStats()
+	return zap.Dict("process_cache", zap.Uint64("hits", hits), zap.Uint64("misses", misses))
+}
@@ -0,0 +1,227 @@
+package tracker
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Mansoor88-6/time-tracking-agent/internal/platform"
+
+	"go.uber.org/zap"
+)
+
+// focusRecorder collects the app focus callbacks of a running tracker
+type focusRecorder chan *AppFocusInfo
+
+func (r focusRecorder) record(info *AppFocusInfo) {
+	copied := *info
+	r <- &copied
+}
+
+func (r focusRecorder) next(t *testing.T) *AppFocusInfo {
+	t.Helper()
+	select {
+	case info := <-r:
+		return info
+	case <-time.After(2 * time.Second):
+		t.Fatal("no app focus reported")
+		return nil
+	}
+}
+
+func (r focusRecorder) none(t *testing.T, wait time.Duration) {
+	t.Helper()
+	select {
+	case info := <-r:
+		t.Fatalf("unexpected app focus %q %q", info.Application, info.Title)
+	case <-time.After(wait):
+	}
+}
+
+func startWindowTracker(t *testing.T, fake *platform.FakePlatform) (*WindowTracker, focusRecorder) {
+	t.Helper()
+	wt := NewWindowTracker(fake, time.Hour, zap.NewNop())
+	focus := make(focusRecorder, 16)
+	if err := wt.Start(focus.record); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(wt.Stop)
+	return wt, focus
+}
+
+func TestWindowTrackerReportsFocusChanges(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "editor.exe", Title: "main.go", ProcessID: 10}, nil)
+
+	wt, focus := startWindowTracker(t, fake)
+
+	first := focus.next(t)
+	if first.Application != "editor.exe" || first.Title != "main.go" || first.PID != 10 {
+		t.Fatalf("first focus = %+v", first)
+	}
+
+	// Polling the same window again reports nothing
+	wt.signalFocusChanged()
+	focus.none(t, 50*time.Millisecond)
+
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "browser.exe", Title: "docs", ProcessID: 20}, nil)
+	wt.signalFocusChanged()
+
+	second := focus.next(t)
+	if second.Application != "browser.exe" || second.PID != 20 {
+		t.Fatalf("second focus = %+v", second)
+	}
+	if current := wt.GetCurrentAppFocus(); current == nil || current.Application != "browser.exe" {
+		t.Fatalf("GetCurrentAppFocus = %+v", current)
+	}
+}
+
+func TestWindowTrackerIgnoresLookupErrors(t *testing.T) {
+	fake := platform.NewFakePlatform()
+	fake.SetActiveWindow(nil, platform.ErrActiveWindowUnsupported)
+
+	wt, focus := startWindowTracker(t, fake)
+	focus.none(t, 50*time.Millisecond)
+	if current := wt.GetCurrentAppFocus(); current != nil {
+		t.Fatalf("GetCurrentAppFocus = %+v, want nil", current)
+	}
+}
+
+func TestWindowTrackerMinDwellReportsOnlyWindowThatSticks(t *testing.T) {
+	const minDwell = 100 * time.Millisecond
+	fake := platform.NewFakePlatform()
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "editor.exe", Title: "main.go", ProcessID: 10}, nil)
+
+	wt := NewWindowTracker(fake, time.Hour, zap.NewNop())
+	wt.SetMinDwell(minDwell)
+	focus := make(focusRecorder, 16)
+	if err := wt.Start(focus.record); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(wt.Stop)
+
+	// The first window is held back for minDwell, then reported
+	focus.none(t, minDwell/2)
+	if first := focus.next(t); first.Application != "editor.exe" {
+		t.Fatalf("first focus = %+v, want editor.exe", first)
+	}
+
+	// Alt-tab through a dialog to the browser; only the browser stays
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "dialog.exe", Title: "Save?", ProcessID: 20}, nil)
+	wt.signalFocusChanged()
+	time.Sleep(minDwell / 4)
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "browser.exe", Title: "docs", ProcessID: 30}, nil)
+	switchedAt := time.Now()
+	wt.signalFocusChanged()
+
+	second := focus.next(t)
+	if second.Application != "browser.exe" {
+		t.Fatalf("second focus = %+v, want browser.exe", second)
+	}
+	if elapsed := time.Since(switchedAt); elapsed < minDwell {
+		t.Errorf("browser reported after %s, before minDwell %s", elapsed, minDwell)
+	}
+	if second.Timestamp.Before(switchedAt.Add(-10*time.Millisecond)) || second.Timestamp.After(switchedAt.Add(minDwell/2)) {
+		t.Errorf("browser focus stamped %v, want when it was first seen (%v)", second.Timestamp, switchedAt)
+	}
+	focus.none(t, 2*minDwell)
+}
+
+func TestWindowTrackerMinDwellReturnToCurrentWindow(t *testing.T) {
+	const minDwell = 100 * time.Millisecond
+	fake := platform.NewFakePlatform()
+	editor := &platform.WindowInfo{Application: "editor.exe", Title: "main.go", ProcessID: 10}
+	fake.SetActiveWindow(editor, nil)
+
+	wt := NewWindowTracker(fake, time.Hour, zap.NewNop())
+	wt.SetMinDwell(minDwell)
+	focus := make(focusRecorder, 16)
+	if err := wt.Start(focus.record); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(wt.Stop)
+	focus.next(t)
+
+	// A notification steals focus briefly, then the editor is back
+	fake.SetActiveWindow(&platform.WindowInfo{Application: "toast.exe", Title: "New mail", ProcessID: 40}, nil)
+	wt.signalFocusChanged()
+	time.Sleep(minDwell / 4)
+	fake.SetActiveWindow(editor, nil)
+	wt.signalFocusChanged()
+
+	focus.none(t, 2*minDwell)
+}
+
+func TestGetActiveWindowTimesOut(t *testing.T) {
+	saved := activeWindowTimeout
+	activeWindowTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { activeWindowTimeout = saved })
+
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fake := platform.NewFakePlatform()
+	fake.SetActiveWindowFunc(func() (*platform.WindowInfo, error) {
+		calls.Add(1)
+		<-release
+		return &platform.WindowInfo{Application: "slow.exe", Title: "hung"}, nil
+	})
+	wt := NewWindowTracker(fake, time.Hour, zap.NewNop())
+
+	started := time.Now()
+	if _, err := wt.getActiveWindow(); !errors.Is(err, errActiveWindowTimeout) {
+		t.Fatalf("blocked lookup = %v, want errActiveWindowTimeout", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("blocked lookup returned after %s, want about activeWindowTimeout", elapsed)
+	}
+
+	// The abandoned call is still running, so no new one starts
+	if _, err := wt.getActiveWindow(); !errors.Is(err, errActiveWindowBusy) {
+		t.Fatalf("lookup while stalled = %v, want errActiveWindowBusy", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("platform called %d times, want 1", got)
+	}
+
+	// Once the stalled call finishes lookups work again
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for wt.windowCallBusy.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("stalled lookup never finished")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	window, err := wt.getActiveWindow()
+	if err != nil || window.Application != "slow.exe" {
+		t.Fatalf("lookup after recovery = %+v, %v", window, err)
+	}
+}
+
+func TestGetActiveWindowReturnsOnStop(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	fake := platform.NewFakePlatform()
+	fake.SetActiveWindowFunc(func() (*platform.WindowInfo, error) {
+		<-release
+		return nil, nil
+	})
+	wt := NewWindowTracker(fake, time.Hour, zap.NewNop())
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := wt.getActiveWindow()
+		errs <- err
+	}()
+	close(wt.stopChan)
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, errWindowTrackerStop) {
+			t.Fatalf("lookup during stop = %v, want errWindowTrackerStop", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lookup kept blocking after stop")
+	}
+}
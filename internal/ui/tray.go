@@ -29,13 +29,20 @@ type TrayManager struct {
 	isPaused        bool
 	pauseMu         sync.RWMutex
 	quitChan        chan struct{}
-	
+
+	// Re-authorization hooks, set via SetReauth
+	needsReauth func() bool
+	reauthorize func(ctx context.Context) error
+	reauthMu    sync.Mutex
+	reauthing   bool
+
 	// Menu items
-	statusItem      *systray.MenuItem
-	pauseItem       *systray.MenuItem
-	dashboardItem   *systray.MenuItem
-	logsItem        *systray.MenuItem
-	quitItem        *systray.MenuItem
+	statusItem    *systray.MenuItem
+	pauseItem     *systray.MenuItem
+	dashboardItem *systray.MenuItem
+	logsItem      *systray.MenuItem
+	reauthItem    *systray.MenuItem
+	quitItem      *systray.MenuItem
 }
 
 // NewTrayManager creates a new tray manager
@@ -57,6 +64,14 @@ func NewTrayManager(
 	}
 }
 
+// SetReauth lets the tray tell the user when the device token can no longer
+// be refreshed and offers a menu item that signs the device in again.
+// Must be called before Start.
+func (tm *TrayManager) SetReauth(needsReauth func() bool, reauthorize func(ctx context.Context) error) {
+	tm.needsReauth = needsReauth
+	tm.reauthorize = reauthorize
+}
+
 // Start starts the tray icon (must be called from main goroutine on Windows)
 func (tm *TrayManager) Start(ctx context.Context) error {
 	if runtime.GOOS != "windows" {
@@ -96,11 +111,13 @@ func (tm *TrayManager) onReady() {
 	systray.AddSeparator()
 
 	tm.pauseItem = systray.AddMenuItem("Pause Tracking", "Temporarily stop tracking")
-	
+
 	systray.AddSeparator()
 
 	tm.dashboardItem = systray.AddMenuItem("Open Dashboard", "Open web dashboard in browser")
 	tm.logsItem = systray.AddMenuItem("View Logs", "Open logs folder")
+	tm.reauthItem = systray.AddMenuItem("Sign In Again", "Authorize this device again")
+	tm.reauthItem.Hide()
 
 	systray.AddSeparator()
 
@@ -111,7 +128,7 @@ func (tm *TrayManager) onReady() {
 
 	// Handle menu clicks
 	go tm.handleMenuClicks()
-	
+
 	// Update tooltip to show auth status if needed
 	tm.updateAuthStatus("")
 }
@@ -132,6 +149,8 @@ func (tm *TrayManager) handleMenuClicks() {
 			tm.openDashboard()
 		case <-tm.logsItem.ClickedCh:
 			tm.openLogs()
+		case <-tm.reauthItem.ClickedCh:
+			go tm.signInAgain()
 		case <-tm.quitItem.ClickedCh:
 			tm.quit()
 		}
@@ -175,7 +194,7 @@ func (tm *TrayManager) openDashboard() {
 	if dashboardURL == "" {
 		dashboardURL = "http://localhost:4000"
 	}
-	
+
 	// Open in default browser
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
@@ -245,6 +264,7 @@ func (tm *TrayManager) statusUpdateLoop(ctx context.Context) {
 		select {
 		case <-ticker.C:
 			tm.updateStatus()
+			tm.updateReauth()
 		case <-ctx.Done():
 			return
 		}
@@ -289,6 +309,46 @@ func (tm *TrayManager) updateTooltip(text string) {
 	systray.SetTooltip(text)
 }
 
+// updateReauth shows the sign-in item and a tooltip while the device needs
+// to be authorized again
+func (tm *TrayManager) updateReauth() {
+	if tm.needsReauth == nil || tm.reauthItem == nil {
+		return
+	}
+	if tm.needsReauth() {
+		tm.reauthItem.Show()
+		tm.updateAuthStatus("Sign in again to resume syncing")
+	} else {
+		tm.reauthItem.Hide()
+	}
+}
+
+// signInAgain runs the interactive re-authorization started from the menu
+func (tm *TrayManager) signInAgain() {
+	if tm.reauthorize == nil {
+		return
+	}
+	tm.reauthMu.Lock()
+	if tm.reauthing {
+		tm.reauthMu.Unlock()
+		return
+	}
+	tm.reauthing = true
+	tm.reauthMu.Unlock()
+	defer func() {
+		tm.reauthMu.Lock()
+		tm.reauthing = false
+		tm.reauthMu.Unlock()
+	}()
+
+	tm.logger.Info("Re-authorizing device from tray")
+	if err := tm.reauthorize(context.Background()); err != nil {
+		tm.logger.Warn("Re-authorization failed", zap.Error(err))
+		return
+	}
+	tm.updateReauth()
+}
+
 // updateAuthStatus updates the tray status based on auth state
 func (tm *TrayManager) updateAuthStatus(authStatus string) {
 	var tooltip string
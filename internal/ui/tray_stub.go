@@ -39,6 +39,11 @@ func NewTrayManager(
 	}
 }
 
+// SetReauth is a no-op on non-Windows platforms; re-authorization there
+// happens by restarting the agent without a device token
+func (tm *TrayManager) SetReauth(needsReauth func() bool, reauthorize func(ctx context.Context) error) {
+}
+
 // Start starts the tray icon (no-op on non-Windows)
 func (tm *TrayManager) Start(ctx context.Context) error {
 	// Tray icon not supported on this platform